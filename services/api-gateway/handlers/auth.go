@@ -7,10 +7,12 @@ import (
 
 	m "remaster/services/api-gateway/models"
 	u "remaster/services/api-gateway/utils"
+	"remaster/shared/ctxkeys"
 	"remaster/shared/errors"
 	auth_pb "remaster/shared/proto/auth"
 
 	"github.com/gin-gonic/gin"
+	"google.golang.org/protobuf/types/known/timestamppb"
 )
 
 type AuthHandler struct {
@@ -40,7 +42,7 @@ func (h *AuthHandler) Register(c *gin.Context) {
 
 	h.logger.InfoContext(ctx, "Processing registration", "email", dto.Email, "user_type", dto.UserType)
 
-	resp, err := h.client.Registration(c, &auth_pb.RegisterRequest{
+	resp, err := h.client.Registration(ctx, &auth_pb.RegisterRequest{
 		Email:     dto.Email,
 		Password:  dto.Password,
 		FirstName: dto.FirstName,
@@ -68,7 +70,7 @@ func (h *AuthHandler) Register(c *gin.Context) {
 		UserType:     resp.UserType,
 	}
 
-	u.SuccessResponse(c, resp.Message, responseData)
+	u.CreatedResponse(c, resp.Message, responseData)
 }
 
 func (h *AuthHandler) Login(c *gin.Context) {
@@ -82,7 +84,7 @@ func (h *AuthHandler) Login(c *gin.Context) {
 
 	h.logger.InfoContext(ctx, "Processing login", "email", dto.Email)
 
-	resp, err := h.client.Login(c, &auth_pb.LoginRequest{
+	resp, err := h.client.Login(ctx, &auth_pb.LoginRequest{
 		Email:    dto.Email,
 		Password: dto.Password,
 	})
@@ -99,11 +101,13 @@ func (h *AuthHandler) Login(c *gin.Context) {
 	)
 
 	responseData := &m.AuthResponse{
-		UserID:       resp.UserId,
-		AccessToken:  resp.AccessToken,
-		RefreshToken: resp.RefreshToken,
-		ExpiresAt:    resp.ExpiresAt,
-		UserType:     resp.UserType,
+		UserID:          resp.UserId,
+		AccessToken:     resp.AccessToken,
+		RefreshToken:    resp.RefreshToken,
+		ExpiresAt:       resp.ExpiresAt,
+		UserType:        resp.UserType,
+		PasswordExpired: resp.PasswordExpired,
+		NewDevice:       resp.NewDevice,
 	}
 
 	u.SuccessResponse(c, resp.Message, responseData)
@@ -121,7 +125,7 @@ func (h *AuthHandler) OAuthLogin(c *gin.Context) {
 	provider := req.Provider
 	h.logger.InfoContext(ctx, "Processing OAuth login", "provider", provider)
 
-	resp, err := h.client.OAuthLogin(c, &auth_pb.OAuthLoginRequest{
+	resp, err := h.client.OAuthLogin(ctx, &auth_pb.OAuthLoginRequest{
 		Provider: provider,
 		IdToken:  req.IDToken,
 	})
@@ -208,15 +212,60 @@ func (h *AuthHandler) ValidateToken(c *gin.Context) {
 	h.logger.InfoContext(ctx, "Token validation successful")
 
 	responseData := &m.ValidateTokenResponse{
-		Valid:     resp.Valid,
-		UserID:    resp.UserId,
-		UserType:  resp.UserType,
-		ExpiresAt: resp.ExpiresAt,
+		Valid:         resp.Valid,
+		UserID:        resp.UserId,
+		UserType:      resp.UserType,
+		ExpiresAt:     resp.ExpiresAt,
+		ExpiresIn:     resp.ExpiresIn,
+		ShouldRefresh: resp.ShouldRefresh,
+		OrgID:         resp.OrgId,
+		Scope:         resp.Scope,
 	}
 
 	u.SuccessResponse(c, resp.Message, responseData)
 }
 
+func (h *AuthHandler) ValidateTokens(c *gin.Context) {
+	dto, ok := u.BindAndValidate[m.ValidateTokensDTO](c, h.logger)
+	if !ok {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), h.timeout)
+	defer cancel()
+
+	h.logger.InfoContext(ctx, "Processing batch token validation", "count", len(dto.AccessTokens))
+
+	resp, err := h.client.ValidateTokens(ctx, &auth_pb.ValidateTokensRequest{
+		AccessTokens: dto.AccessTokens,
+	})
+	if err != nil {
+		h.logger.ErrorContext(ctx, "Batch token validation failed", "error", err)
+		h.errorHandler.HandleGrpcToHttp(c, err)
+		return
+	}
+
+	results := make([]m.TokenValidationResult, 0, len(resp.Results))
+	for _, r := range resp.Results {
+		result := m.TokenValidationResult{
+			Valid:         r.Valid,
+			UserID:        r.UserId,
+			UserType:      r.UserType,
+			ExpiresAt:     r.ExpiresAt,
+			ExpiresIn:     r.ExpiresIn,
+			ShouldRefresh: r.ShouldRefresh,
+			OrgID:         r.OrgId,
+			Scope:         r.Scope,
+		}
+		if !r.Valid {
+			result.Error = r.Message
+		}
+		results = append(results, result)
+	}
+
+	u.SuccessResponse(c, "Tokens validated", results)
+}
+
 func (h *AuthHandler) Logout(c *gin.Context) {
 	type rt struct {
 		RefreshToken string `json:"refresh_token" binding:"required"`
@@ -281,6 +330,280 @@ func (h *AuthHandler) ChangePassword(c *gin.Context) {
 	u.SuccessResponse(c, resp.Message, nil)
 }
 
+func (h *AuthHandler) UpdateProfileImage(c *gin.Context) {
+	dto, ok := u.BindAndValidate[m.ProfileImageDTO](c, h.logger)
+	if !ok {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), h.timeout)
+	defer cancel()
+
+	userID := ctxkeys.UserID(c.Request.Context())
+	h.logger.InfoContext(ctx, "Processing profile image update", "user_id", userID)
+
+	resp, err := h.client.UpdateProfileImage(ctx, &auth_pb.UpdateProfileImageRequest{
+		UserId:   userID,
+		MediaKey: dto.MediaKey,
+	})
+	if err != nil {
+		h.logger.ErrorContext(ctx, "Profile image update failed", "error", err, "user_id", userID)
+		h.errorHandler.HandleGrpcToHttp(c, err)
+		return
+	}
+
+	h.logger.InfoContext(ctx, "Profile image update successful", "user_id", userID)
+
+	u.SuccessResponse(c, resp.Message, &m.ProfileImageResponse{
+		ProfileImage: resp.ProfileImage,
+	})
+}
+
+func (h *AuthHandler) UnlockAccount(c *gin.Context) {
+	ctx, cancel := context.WithTimeout(c.Request.Context(), h.timeout)
+	defer cancel()
+
+	userID := c.Param("id")
+	actorID := ctxkeys.UserID(c.Request.Context())
+	h.logger.InfoContext(ctx, "Processing account unlock", "user_id", userID, "actor_id", actorID)
+
+	resp, err := h.client.UnlockAccount(ctx, &auth_pb.UnlockAccountRequest{
+		UserId:  userID,
+		ActorId: actorID,
+	})
+	if err != nil {
+		h.logger.ErrorContext(ctx, "Account unlock failed", "error", err, "user_id", userID)
+		h.errorHandler.HandleGrpcToHttp(c, err)
+		return
+	}
+
+	u.SuccessResponse(c, resp.Message, nil)
+}
+
+func (h *AuthHandler) AdminCreateUser(c *gin.Context) {
+	dto, ok := u.BindAndValidate[m.AdminCreateUserDTO](c, h.logger)
+	if !ok {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), h.timeout)
+	defer cancel()
+
+	actorID := ctxkeys.UserID(c.Request.Context())
+	h.logger.InfoContext(ctx, "Processing admin user creation", "email", dto.Email, "user_type", dto.UserType, "actor_id", actorID)
+
+	resp, err := h.client.AdminCreateUser(ctx, &auth_pb.AdminCreateUserRequest{
+		Email:     dto.Email,
+		FirstName: dto.FirstName,
+		LastName:  dto.LastName,
+		Phone:     dto.Phone,
+		UserType:  dto.UserType,
+		Verified:  dto.Verified,
+		ActorId:   actorID,
+	})
+	if err != nil {
+		h.logger.ErrorContext(ctx, "Admin user creation failed", "error", err, "email", dto.Email)
+		h.errorHandler.HandleGrpcToHttp(c, err)
+		return
+	}
+
+	h.logger.InfoContext(ctx, "Admin user creation successful", "user_id", resp.UserId)
+
+	u.CreatedResponse(c, resp.Message, &m.AdminCreateUserResponse{
+		UserID:            resp.UserId,
+		UserType:          resp.UserType,
+		IsVerified:        resp.IsVerified,
+		TemporaryPassword: resp.TemporaryPassword,
+		CreatedAt:         resp.CreatedAt.AsTime().Unix(),
+	})
+}
+
+func (h *AuthHandler) ListAuditEntries(c *gin.Context) {
+	var q m.AuditLogQuery
+	if err := c.ShouldBindQuery(&q); err != nil {
+		h.logger.WarnContext(c.Request.Context(), "Invalid audit log query", "error", err)
+		c.Error(errors.NewValidationError("Query parameters are invalid", map[string]string{
+			"field": "query",
+			"issue": err.Error(),
+		}))
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), h.timeout)
+	defer cancel()
+
+	h.logger.InfoContext(ctx, "Processing audit log request", "target_user_id", q.UserID)
+
+	resp, err := h.client.ListAuditEntries(ctx, &auth_pb.ListAuditEntriesRequest{
+		TargetUserId: q.UserID,
+		Page:         q.Page,
+		PageSize:     q.PageSize,
+		Cursor:       q.Cursor,
+	})
+	if err != nil {
+		h.logger.ErrorContext(ctx, "Audit log request failed", "error", err)
+		h.errorHandler.HandleGrpcToHttp(c, err)
+		return
+	}
+
+	entries := make([]m.AuditEntryResponse, 0, len(resp.Entries))
+	for _, e := range resp.Entries {
+		entries = append(entries, m.AuditEntryResponse{
+			ID:           e.Id,
+			ActorID:      e.ActorId,
+			Action:       e.Action,
+			TargetUserID: e.TargetUserId,
+			IP:           e.Ip,
+			UserAgent:    e.UserAgent,
+			CreatedAt:    e.CreatedAt.GetSeconds(),
+			PrevHash:     e.PrevHash,
+			Hash:         e.Hash,
+		})
+	}
+
+	u.SuccessResponse(c, resp.Message, &m.AuditLogResponse{
+		Entries:    entries,
+		Total:      resp.Total,
+		Page:       resp.Page,
+		PageSize:   resp.PageSize,
+		TotalPages: resp.TotalPages,
+		NextCursor: resp.NextCursor,
+	})
+}
+
+func (h *AuthHandler) SearchUsers(c *gin.Context) {
+	var q m.UserSearchQuery
+	if err := c.ShouldBindQuery(&q); err != nil {
+		h.logger.WarnContext(c.Request.Context(), "Invalid user search query", "error", err)
+		c.Error(errors.NewValidationError("Query parameters are invalid", map[string]string{
+			"field": "query",
+			"issue": err.Error(),
+		}))
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), h.timeout)
+	defer cancel()
+
+	h.logger.InfoContext(ctx, "Processing user search", "user_type", q.UserType, "page", q.Page)
+
+	req := &auth_pb.SearchUsersRequest{
+		Email:      q.Email,
+		UserType:   q.UserType,
+		IsActive:   q.IsActive,
+		IsVerified: q.IsVerified,
+		Page:       q.Page,
+		PageSize:   q.PageSize,
+	}
+	if !q.CreatedFrom.IsZero() {
+		req.CreatedFrom = timestamppb.New(q.CreatedFrom)
+	}
+	if !q.CreatedTo.IsZero() {
+		req.CreatedTo = timestamppb.New(q.CreatedTo)
+	}
+
+	resp, err := h.client.SearchUsers(ctx, req)
+	if err != nil {
+		h.logger.ErrorContext(ctx, "User search failed", "error", err)
+		h.errorHandler.HandleGrpcToHttp(c, err)
+		return
+	}
+
+	users := make([]m.AdminUserResponse, 0, len(resp.Users))
+	for _, pu := range resp.Users {
+		user := m.AdminUserResponse{
+			ID:         pu.Id,
+			Email:      pu.Email,
+			FirstName:  pu.FirstName,
+			LastName:   pu.LastName,
+			Phone:      pu.Phone,
+			UserType:   pu.UserType,
+			IsActive:   pu.IsActive,
+			IsVerified: pu.IsVerified,
+			CreatedAt:  pu.CreatedAt.GetSeconds(),
+		}
+		if pu.LastLoginAt != nil {
+			user.LastLoginAt = pu.LastLoginAt.GetSeconds()
+		}
+		users = append(users, user)
+	}
+
+	u.SuccessResponse(c, resp.Message, &m.UserSearchResponse{
+		Users:      users,
+		Total:      resp.Total,
+		Page:       resp.Page,
+		PageSize:   resp.PageSize,
+		TotalPages: resp.TotalPages,
+	})
+}
+
+func (h *AuthHandler) ListSessions(c *gin.Context) {
+	var q m.SessionsQuery
+	if err := c.ShouldBindQuery(&q); err != nil {
+		h.logger.WarnContext(c.Request.Context(), "Invalid sessions query", "error", err)
+		c.Error(errors.NewValidationError("Query parameters are invalid", map[string]string{
+			"field": "query",
+			"issue": err.Error(),
+		}))
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), h.timeout)
+	defer cancel()
+
+	userID := ctxkeys.UserID(c.Request.Context())
+	h.logger.InfoContext(ctx, "Processing sessions request", "user_id", userID)
+
+	resp, err := h.client.ListSessions(ctx, &auth_pb.ListSessionsRequest{
+		UserId: userID,
+		Cursor: q.Cursor,
+		Limit:  q.Limit,
+	})
+	if err != nil {
+		h.logger.ErrorContext(ctx, "Sessions request failed", "error", err)
+		h.errorHandler.HandleGrpcToHttp(c, err)
+		return
+	}
+
+	sessions := make([]m.SessionResponse, 0, len(resp.Sessions))
+	for _, s := range resp.Sessions {
+		sessions = append(sessions, m.SessionResponse{
+			ID:        s.Id,
+			DeviceID:  s.DeviceId,
+			UserAgent: s.UserAgent,
+			IP:        s.Ip,
+			CreatedAt: s.CreatedAt.GetSeconds(),
+			ExpiresAt: s.ExpiresAt.GetSeconds(),
+		})
+	}
+
+	u.SuccessResponse(c, resp.Message, &m.SessionsResponse{
+		Sessions:   sessions,
+		NextCursor: resp.NextCursor,
+	})
+}
+
+func (h *AuthHandler) IssueGuestToken(c *gin.Context) {
+	ctx, cancel := context.WithTimeout(c.Request.Context(), h.timeout)
+	defer cancel()
+
+	resp, err := h.client.IssueGuestToken(ctx, &auth_pb.IssueGuestTokenRequest{})
+	if err != nil {
+		h.errorHandler.HandleGrpcToHttp(c, err)
+		return
+	}
+	h.logger.InfoContext(ctx, "Guest token issued", "user_id", resp.UserId)
+
+	responseData := &m.GuestTokenResponse{
+		UserID:      resp.UserId,
+		AccessToken: resp.AccessToken,
+		ExpiresAt:   resp.ExpiresAt,
+		UserType:    resp.UserType,
+	}
+
+	u.SuccessResponse(c, resp.Message, responseData)
+}
+
 func (h *AuthHandler) Health(c *gin.Context) {
 	ctx, cancel := context.WithTimeout(c.Request.Context(), h.timeout)
 	defer cancel()
@@ -0,0 +1,94 @@
+package handlers
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	m "remaster/services/api-gateway/models"
+	u "remaster/services/api-gateway/utils"
+	"remaster/shared/ctxkeys"
+	"remaster/shared/errors"
+	media_pb "remaster/shared/proto/media"
+
+	"github.com/gin-gonic/gin"
+)
+
+type MediaHandler struct {
+	client       media_pb.MediaServiceClient
+	errorHandler *errors.ErrorHandler
+	logger       *slog.Logger
+	timeout      time.Duration
+}
+
+func NewMediaHandler(client media_pb.MediaServiceClient, logger *slog.Logger, errorHandler *errors.ErrorHandler) *MediaHandler {
+	return &MediaHandler{
+		client:       client,
+		errorHandler: errorHandler,
+		logger:       logger.With(slog.String("api-gateway", "media")),
+		timeout:      10 * time.Second,
+	}
+}
+
+func (h *MediaHandler) RequestUploadURL(c *gin.Context) {
+	dto, ok := u.BindAndValidate[m.UploadURLDTO](c, h.logger)
+	if !ok {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), h.timeout)
+	defer cancel()
+
+	userID := ctxkeys.UserID(c.Request.Context())
+	h.logger.InfoContext(ctx, "Processing upload URL request",
+		"user_id", userID, "entity_type", dto.EntityType, "content_type", dto.ContentType)
+
+	resp, err := h.client.CreateUploadURL(ctx, &media_pb.CreateUploadURLRequest{
+		OwnerId:     userID,
+		EntityType:  dto.EntityType,
+		EntityId:    dto.EntityID,
+		ContentType: dto.ContentType,
+		Size:        dto.Size,
+	})
+	if err != nil {
+		h.logger.ErrorContext(ctx, "gRPC upload URL request failed", "error", err, "user_id", userID)
+		h.errorHandler.HandleGrpcToHttp(c, err)
+		return
+	}
+
+	u.SuccessResponse(c, resp.Message, &m.UploadURLResponse{
+		MediaID:   resp.MediaId,
+		Key:       resp.Key,
+		UploadURL: resp.UploadUrl,
+		ExpiresAt: resp.ExpiresAt,
+	})
+}
+
+// ConfirmUpload is called by the client once the PUT to the upload url finished
+func (h *MediaHandler) ConfirmUpload(c *gin.Context) {
+	ctx, cancel := context.WithTimeout(c.Request.Context(), h.timeout)
+	defer cancel()
+
+	mediaID := c.Param("id")
+	userID := ctxkeys.UserID(c.Request.Context())
+	h.logger.InfoContext(ctx, "Processing upload confirmation", "user_id", userID, "media_id", mediaID)
+
+	resp, err := h.client.ConfirmUpload(ctx, &media_pb.ConfirmUploadRequest{
+		MediaId: mediaID,
+		OwnerId: userID,
+	})
+	if err != nil {
+		h.logger.ErrorContext(ctx, "gRPC confirm upload failed", "error", err, "user_id", userID)
+		h.errorHandler.HandleGrpcToHttp(c, err)
+		return
+	}
+
+	media := resp.GetMedia()
+	u.SuccessResponse(c, resp.Message, &m.MediaResponse{
+		MediaID:     media.GetId(),
+		Key:         media.GetKey(),
+		ContentType: media.GetContentType(),
+		Size:        media.GetSize(),
+		Status:      media.GetStatus(),
+	})
+}
@@ -1,15 +1,24 @@
 package middleware
 
 import (
+	"log/slog"
+	"remaster/shared/ctxkeys"
 	"remaster/shared/errors"
-	auth_pb "remaster/shared/proto/auth"
+	"remaster/shared/logger"
+	"remaster/shared/tokenauth"
 	"slices"
 	"strings"
 
 	"github.com/gin-gonic/gin"
+	"google.golang.org/grpc/metadata"
 )
 
-func RequireAuth(authClient auth_pb.AuthServiceClient) gin.HandlerFunc {
+// TokenRefreshHeader is set on responses whose access token is about to expire
+const TokenRefreshHeader = "X-Token-Refresh-Recommended"
+
+// RequireAuth validates the bearer token with validator, a failing validator is a 503
+// rather than a 401 so clients don't drop tokens that are still good
+func RequireAuth(validator tokenauth.TokenValidator) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		authHeader := c.GetHeader("Authorization")
 		if authHeader == "" || !strings.HasPrefix(authHeader, "Bearer ") {
@@ -25,36 +34,63 @@ func RequireAuth(authClient auth_pb.AuthServiceClient) gin.HandlerFunc {
 			return
 		}
 
-		resp, err := authClient.ValidateToken(c.Request.Context(), &auth_pb.ValidateTokenRequest{
-			AccessToken: token,
-		})
-		if err != nil || !resp.Valid {
+		ctx := c.Request.Context()
+		claims, err := validator.Validate(ctx, token)
+		if err != nil {
+			if !tokenauth.IsInvalidToken(err) {
+				logger.FromContext(ctx, slog.Default()).WarnContext(ctx, "Token validator unavailable", slog.Any("error", err))
+				c.Error(errors.NewServiceUnavailableError("Token validation unavailable", err))
+				c.Abort()
+				return
+			}
 			c.Error(errors.NewUnauthorizedError("Invalid token"))
 			c.Abort()
 			return
 		}
+		// only good for ChangePassword, which takes the old password instead of a token
+		if claims.Scope == tokenauth.ScopePasswordChange {
+			c.Error(errors.NewForbiddenError("Password expired, change it to continue"))
+			c.Abort()
+			return
+		}
 
-		c.Set("user_id", resp.UserId)
+		ctx = ctxkeys.WithUser(ctx, claims.UserID, claims.UserType)
+		// services scope queries by x-org-id, single-tenant users have none
+		if claims.OrgID != "" {
+			ctx = ctxkeys.WithOrgID(ctx, claims.OrgID)
+			ctx = metadata.AppendToOutgoingContext(ctx, "x-org-id", claims.OrgID)
+		}
+		c.Request = c.Request.WithContext(ctx)
+		if claims.ShouldRefresh {
+			c.Header(TokenRefreshHeader, "true")
+		}
 
 		c.Next()
 	}
 }
+
+// user roles as stored in the request context by RequireAuth, anonymous is a guest token
+const (
+	RoleAnonymous = "anonymous"
+	RoleClient    = "client"
+	RoleMaster    = "master"
+	RoleAdmin     = "admin"
+)
+
+// RegisteredRoles is every role except anonymous
+var RegisteredRoles = []string{RoleClient, RoleMaster, RoleAdmin}
+
+// RequireRole lets the request through only when the caller's role is one of roles,
+// guests need RoleAnonymous listed explicitly
 func RequireRole(roles ...string) gin.HandlerFunc {
 	return func(c *gin.Context) {
-		userRole, exists := c.Get("user_role")
-		if !exists {
+		roleStr := ctxkeys.UserType(c.Request.Context())
+		if roleStr == "" {
 			c.Error(errors.NewUnauthorizedError("Missing user role"))
 			c.Abort()
 			return
 		}
 
-		roleStr, ok := userRole.(string)
-		if !ok {
-			c.Error(errors.NewUnauthorizedError("Invalid user role"))
-			c.Abort()
-			return
-		}
-
 		if slices.Contains(roles, roleStr) {
 			c.Next()
 			return
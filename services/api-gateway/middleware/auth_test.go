@@ -0,0 +1,214 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"remaster/shared/ctxkeys"
+	"remaster/shared/errors"
+	"remaster/shared/tokenauth"
+
+	"github.com/gin-gonic/gin"
+	"google.golang.org/grpc/metadata"
+)
+
+type staticValidator struct {
+	claims *tokenauth.Claims
+}
+
+func (v staticValidator) Validate(context.Context, string) (*tokenauth.Claims, error) {
+	return v.claims, nil
+}
+
+// serveWithAuth runs one request through RequireAuth and reports whether the
+// handler ran and which error the middleware recorded
+func serveWithAuth(t *testing.T, claims *tokenauth.Claims) (bool, *errors.AppError) {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+
+	var appErr *errors.AppError
+	handled := false
+	router := gin.New()
+	router.Use(func(c *gin.Context) {
+		c.Next()
+		if last := c.Errors.Last(); last != nil {
+			appErr, _ = errors.AsAppError(last.Err)
+		}
+	})
+	router.GET("/media", RequireAuth(staticValidator{claims: claims}), func(c *gin.Context) {
+		handled = true
+		c.Status(http.StatusOK)
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/media", nil)
+	req.Header.Set("Authorization", "Bearer token")
+	router.ServeHTTP(httptest.NewRecorder(), req)
+	return handled, appErr
+}
+
+func TestRequireAuthAcceptsFullAccessToken(t *testing.T) {
+	handled, appErr := serveWithAuth(t, &tokenauth.Claims{UserID: "u1", UserType: RoleClient})
+	if !handled || appErr != nil {
+		t.Fatalf("handled = %v, error = %v, want the request through", handled, appErr)
+	}
+}
+
+func TestRequireAuthRejectsPasswordChangeToken(t *testing.T) {
+	handled, appErr := serveWithAuth(t, &tokenauth.Claims{
+		UserID:   "u1",
+		UserType: RoleClient,
+		Scope:    tokenauth.ScopePasswordChange,
+	})
+	if handled {
+		t.Fatal("password change token reached the handler")
+	}
+	if appErr == nil || appErr.StatusCode != http.StatusForbidden {
+		t.Fatalf("error = %v, want a 403", appErr)
+	}
+}
+
+// serveAdmin sends a create-user request through the admin group middleware
+func serveAdmin(t *testing.T, role string) (bool, *errors.AppError) {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+
+	var appErr *errors.AppError
+	handled := false
+	router := gin.New()
+	router.Use(func(c *gin.Context) {
+		c.Next()
+		if last := c.Errors.Last(); last != nil {
+			appErr, _ = errors.AsAppError(last.Err)
+		}
+	})
+	admin := router.Group("/admin",
+		RequireAuth(staticValidator{claims: &tokenauth.Claims{UserID: "u1", UserType: role}}),
+		RequireRole(RoleAdmin),
+	)
+	admin.POST("/users", func(c *gin.Context) {
+		handled = true
+		c.Status(http.StatusCreated)
+	})
+
+	req := httptest.NewRequest(http.MethodPost, "/admin/users", nil)
+	req.Header.Set("Authorization", "Bearer token")
+	router.ServeHTTP(httptest.NewRecorder(), req)
+	return handled, appErr
+}
+
+func TestRequireRoleAdminLetsAdminsCreateUsers(t *testing.T) {
+	handled, appErr := serveAdmin(t, RoleAdmin)
+	if !handled || appErr != nil {
+		t.Fatalf("handled = %v, error = %v, want the request through", handled, appErr)
+	}
+}
+
+func TestRequireRoleAdminRejectsOtherRoles(t *testing.T) {
+	for _, role := range []string{RoleClient, RoleMaster, RoleAnonymous} {
+		t.Run(role, func(t *testing.T) {
+			handled, appErr := serveAdmin(t, role)
+			if handled {
+				t.Fatalf("%s reached the admin handler", role)
+			}
+			if appErr == nil || appErr.StatusCode != http.StatusForbidden {
+				t.Fatalf("error = %v, want a 403", appErr)
+			}
+		})
+	}
+}
+
+func TestRequireRoleTreatsGuestsAsTheirOwnRole(t *testing.T) {
+	tests := []struct {
+		name    string
+		allowed []string
+		handled bool
+	}{
+		{"client only", []string{RoleClient}, false},
+		{"registered roles", RegisteredRoles, false},
+		{"guests listed", []string{RoleAnonymous, RoleClient}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gin.SetMode(gin.TestMode)
+			router := gin.New()
+			var appErr *errors.AppError
+			router.Use(func(c *gin.Context) {
+				c.Next()
+				if last := c.Errors.Last(); last != nil {
+					appErr, _ = errors.AsAppError(last.Err)
+				}
+			})
+			handled := false
+			router.GET("/catalog",
+				RequireAuth(staticValidator{claims: &tokenauth.Claims{UserID: "guest-1", UserType: RoleAnonymous}}),
+				RequireRole(tt.allowed...),
+				func(c *gin.Context) { handled = true },
+			)
+
+			req := httptest.NewRequest(http.MethodGet, "/catalog", nil)
+			req.Header.Set("Authorization", "Bearer guest-token")
+			router.ServeHTTP(httptest.NewRecorder(), req)
+
+			if handled != tt.handled {
+				t.Fatalf("handled = %v, want %v", handled, tt.handled)
+			}
+			if !tt.handled && (appErr == nil || appErr.StatusCode != http.StatusForbidden) {
+				t.Fatalf("error = %v, want a 403", appErr)
+			}
+		})
+	}
+}
+
+func TestRequireAuthHintsRefreshNearExpiry(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	for _, shouldRefresh := range []bool{false, true} {
+		router := gin.New()
+		router.GET("/media",
+			RequireAuth(staticValidator{claims: &tokenauth.Claims{UserID: "u1", UserType: RoleClient, ShouldRefresh: shouldRefresh}}),
+			func(c *gin.Context) { c.Status(http.StatusOK) },
+		)
+
+		req := httptest.NewRequest(http.MethodGet, "/media", nil)
+		req.Header.Set("Authorization", "Bearer token")
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+
+		if got := rec.Header().Get(TokenRefreshHeader) == "true"; got != shouldRefresh {
+			t.Fatalf("ShouldRefresh %v: refresh header = %q", shouldRefresh, rec.Header().Get(TokenRefreshHeader))
+		}
+	}
+}
+
+func TestRequireAuthScopesRequestsByOrg(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	for _, orgID := range []string{"", "org-42"} {
+		var ctxOrg string
+		var forwarded []string
+		router := gin.New()
+		router.GET("/media",
+			RequireAuth(staticValidator{claims: &tokenauth.Claims{UserID: "u1", UserType: RoleClient, OrgID: orgID}}),
+			func(c *gin.Context) {
+				ctxOrg = ctxkeys.OrgID(c.Request.Context())
+				md, _ := metadata.FromOutgoingContext(c.Request.Context())
+				forwarded = md.Get("x-org-id")
+				c.Status(http.StatusOK)
+			},
+		)
+
+		req := httptest.NewRequest(http.MethodGet, "/media", nil)
+		req.Header.Set("Authorization", "Bearer token")
+		router.ServeHTTP(httptest.NewRecorder(), req)
+
+		if ctxOrg != orgID {
+			t.Fatalf("org %q: context org = %q", orgID, ctxOrg)
+		}
+		if orgID == "" && len(forwarded) != 0 {
+			t.Fatalf("single-tenant request forwarded x-org-id %v", forwarded)
+		}
+		if orgID != "" && (len(forwarded) != 1 || forwarded[0] != orgID) {
+			t.Fatalf("forwarded x-org-id = %v, want [%s]", forwarded, orgID)
+		}
+	}
+}
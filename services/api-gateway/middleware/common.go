@@ -5,33 +5,142 @@ import (
 	"fmt"
 	"log/slog"
 	"net"
+	"net/http"
 	"os"
+	config "remaster/shared"
+	"remaster/shared/ctxkeys"
 	"remaster/shared/errors"
 	"remaster/shared/logger"
+	"remaster/shared/validation"
 	"strings"
 	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
 	"github.com/redis/go-redis/v9"
+	"google.golang.org/grpc/metadata"
 )
 
-func RequestLogger(baseLogger *slog.Logger, eh *errors.ErrorHandler) gin.HandlerFunc {
+const (
+	CorrelationIDHeader = "X-Correlation-ID"
+	RequestIDHeader     = "X-Request-ID"
+	DeviceIDHeader      = "X-Device-ID"
+
+	// keeps forwarded metadata well under grpc.max_metadata_bytes
+	maxForwardedUserAgent = 512
+)
+
+// RequestIDs assigns a correlation ID (kept from the client when supplied, spans the
+// whole transaction) and a fresh request ID (unique per hop), echoes both back and
+// forwards them to downstream gRPC calls. It also records the request start time
+func RequestIDs() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Set(errors.RequestStartKey, time.Now())
+
+		// services reject malformed ids, so replace rather than forward them
+		correlationID := c.GetHeader(CorrelationIDHeader)
+		if !validation.ValidHeaderID(correlationID) {
+			correlationID = uuid.New().String()
+		}
+		requestID := uuid.New().String()
+
+		c.Header(CorrelationIDHeader, correlationID)
+		c.Header(RequestIDHeader, requestID)
+
+		ctx := ctxkeys.WithCorrelationID(c.Request.Context(), correlationID)
+		ctx = ctxkeys.WithRequestID(ctx, requestID)
+		ctx = metadata.AppendToOutgoingContext(ctx,
+			"x-correlation-id", correlationID,
+			"x-request-id", requestID,
+		)
+		c.Request = c.Request.WithContext(ctx)
+
+		c.Next()
+	}
+}
+
+// ClientMetadata forwards the client's IP, User-Agent and device id to downstream gRPC
+// calls, services use them for session records and device checks. grpc-go owns the
+// user-agent key, so the client's goes as x-client-user-agent
+func ClientMetadata() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		pairs := []string{"x-forwarded-for", c.ClientIP()}
+		if ua := printableASCII(c.Request.UserAgent()); ua != "" {
+			if len(ua) > maxForwardedUserAgent {
+				ua = ua[:maxForwardedUserAgent]
+			}
+			pairs = append(pairs, "x-client-user-agent", ua)
+		}
+		if id := c.GetHeader(DeviceIDHeader); validation.ValidHeaderID(id) {
+			pairs = append(pairs, "x-device-id", id)
+		}
+
+		ctx := metadata.AppendToOutgoingContext(c.Request.Context(), pairs...)
+		c.Request = c.Request.WithContext(ctx)
+		c.Next()
+	}
+}
+
+// RequestDeadline rejects requests whose context already ended (the client hung up) before
+// any work is done, and gives the rest a deadline of timeout (0 = none) that every
+// downstream call inherits, including token validation
+func RequestDeadline(timeout time.Duration) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx := c.Request.Context()
+		switch ctx.Err() {
+		case nil:
+		case context.DeadlineExceeded:
+			c.Error(errors.NewDeadlineExceededError("Request deadline exceeded", ctx.Err()))
+			c.Abort()
+			return
+		default:
+			c.Error(errors.NewClientClosedError("Client closed request", ctx.Err()))
+			c.Abort()
+			return
+		}
+
+		if _, ok := ctx.Deadline(); !ok && timeout > 0 {
+			var cancel context.CancelFunc
+			ctx, cancel = context.WithTimeout(ctx, timeout)
+			defer cancel()
+			c.Request = c.Request.WithContext(ctx)
+		}
+		c.Next()
+	}
+}
+
+// grpc rejects metadata values outside printable ASCII
+func printableASCII(s string) string {
+	return strings.Map(func(r rune) rune {
+		if r < 0x20 || r > 0x7e {
+			return -1
+		}
+		return r
+	}, s)
+}
+
+// RequestLogger logs every request, those slower than slowThreshold (0 = off) at
+// warn or above with slow_request=true so latency can be alerted on
+func RequestLogger(baseLogger *slog.Logger, eh *errors.ErrorHandler, slowThreshold time.Duration) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		start := time.Now()
 
 		// Get or generate correlation ID
-		correlationID, _ := c.Get("correlation_id")
-		if correlationID == nil {
+		ctx := c.Request.Context()
+		correlationID := ctxkeys.CorrelationID(ctx)
+		if correlationID == "" {
 			correlationID = uuid.New().String()
-			c.Set("correlation_id", correlationID)
+			ctx = ctxkeys.WithCorrelationID(ctx, correlationID)
 		}
 
 		// Create request-specific logger
-		requestLogger := logger.WithCorrelationID(baseLogger, correlationID.(string))
+		requestLogger := logger.WithCorrelationID(baseLogger, correlationID)
+		if requestID := ctxkeys.RequestID(ctx); requestID != "" {
+			requestLogger = logger.WithRequestID(requestLogger, requestID)
+		}
 
 		// Add to context for handlers
-		ctx := logger.ToContext(c.Request.Context(), requestLogger)
+		ctx = logger.ToContext(ctx, requestLogger)
 		c.Request = c.Request.WithContext(ctx)
 
 		// Process request
@@ -53,6 +162,10 @@ func RequestLogger(baseLogger *slog.Logger, eh *errors.ErrorHandler) gin.Handler
 			slog.Duration("latency", latency),
 			slog.Int("body_size", c.Writer.Size()),
 		}
+		slow := slowThreshold > 0 && latency > slowThreshold
+		if slow {
+			logAttrs = append(logAttrs, slog.Bool("slow_request", true))
+		}
 
 		// Handle errors if present
 		if len(c.Errors) > 0 {
@@ -66,9 +179,9 @@ func RequestLogger(baseLogger *slog.Logger, eh *errors.ErrorHandler) gin.Handler
 			requestLogger.LogAttrs(c.Request.Context(), slog.LevelError,
 				"HTTP request completed with error", logAttrs...)
 		} else {
-			// Determine log level based on status
+			// Determine log level based on status and latency
 			logLevel := slog.LevelInfo
-			if c.Writer.Status() >= 400 {
+			if c.Writer.Status() >= 400 || slow {
 				logLevel = slog.LevelWarn
 			}
 
@@ -117,11 +230,15 @@ func Recovery(baseLogger *slog.Logger) gin.HandlerFunc {
 						slog.Any("panic", err),
 					)
 
+					// write the 500 here, the error middleware may not run or may sit in the wrong order
 					if !c.Writer.Written() {
-						c.Error(errors.NewInternalError(
-							fmt.Sprintf("Panic recovered while handling %s", httpPath),
-							fmt.Errorf("%v", err),
-						))
+						c.Abort()
+						errors.WriteError(c, http.StatusInternalServerError, errors.ErrorResponse{
+							Success: false,
+							Error:   "Internal server error",
+							Code:    string(errors.CodeInternal),
+						})
+						return
 					}
 				}
 
@@ -132,13 +249,54 @@ func Recovery(baseLogger *slog.Logger) gin.HandlerFunc {
 	}
 }
 
+// Deprecated marks responses from legacy routes and points clients at the versioned prefix
+func Deprecated(successor string) gin.HandlerFunc {
+	link := fmt.Sprintf("<%s>; rel=\"successor-version\"", successor)
+	return func(c *gin.Context) {
+		c.Header("Deprecation", "true")
+		c.Header("Link", link)
+		c.Next()
+	}
+}
+
+// SecurityHeaders sets the configured hardening headers, HSTS only when production is set
+// since browsers pin it and local http setups would break
+func SecurityHeaders(cfg config.SecurityHeadersConfig, production bool) gin.HandlerFunc {
+	hsts := ""
+	if production && cfg.HSTSMaxAge > 0 {
+		hsts = fmt.Sprintf("max-age=%d", int(cfg.HSTSMaxAge.Seconds()))
+		if cfg.HSTSIncludeSubdomains {
+			hsts += "; includeSubDomains"
+		}
+	}
+
+	headers := map[string]string{
+		"X-Content-Type-Options":    cfg.ContentTypeOptions,
+		"X-Frame-Options":           cfg.FrameOptions,
+		"Referrer-Policy":           cfg.ReferrerPolicy,
+		"Strict-Transport-Security": hsts,
+	}
+	for name, value := range headers {
+		if value == "" {
+			delete(headers, name)
+		}
+	}
+
+	return func(c *gin.Context) {
+		for name, value := range headers {
+			c.Header(name, value)
+		}
+		c.Next()
+	}
+}
+
 func CORS() gin.HandlerFunc {
 	return func(c *gin.Context) {
 		origin := c.Request.Header.Get("Origin")
 
 		c.Header("Access-Control-Allow-Origin", origin)
 		c.Header("Access-Control-Allow-Credentials", "true")
-		c.Header("Access-Control-Allow-Headers", "Content-Type, Content-Length, Accept-Encoding, X-CSRF-Token, Authorization, accept, origin, Cache-Control, X-Requested-With")
+		c.Header("Access-Control-Allow-Headers", "Content-Type, Content-Length, Accept-Encoding, X-CSRF-Token, Authorization, accept, origin, Cache-Control, X-Requested-With, Idempotency-Key, X-Device-ID")
 		c.Header("Access-Control-Allow-Methods", "POST, GET, OPTIONS, PUT, DELETE, PATCH")
 
 		if c.Request.Method == "OPTIONS" {
@@ -160,18 +318,30 @@ func GinErrorMiddleware(eh *errors.ErrorHandler) gin.HandlerFunc {
 	}
 }
 
-func RateLimiter(rdb *redis.Client, limit int, window time.Duration) gin.HandlerFunc {
+// RateLimiter limits requests per client IP. On Redis errors it lets the request
+// through when failOpen is set, otherwise it rejects it. The IP comes from
+// c.ClientIP, which only honours X-Forwarded-For from http.trusted_proxies
+func RateLimiter(rdb redis.UniversalClient, settings *config.Live[config.RateLimitConfig]) gin.HandlerFunc {
 	return func(c *gin.Context) {
+		rl := settings.Load()
+		limit, window, failOpen := rl.Requests, rl.Window, rl.FailOpen
+
 		ctx := context.Background()
 		ip := c.ClientIP()
 		key := "ratelimit:" + ip
 
 		count, err := rdb.Incr(ctx, key).Result()
 		if err != nil {
-			c.Error(errors.NewInternalError(
-				"Rate limiter error",
-				err,
-			))
+			if failOpen {
+				logger.FromContext(c.Request.Context(), slog.Default()).WarnContext(c.Request.Context(),
+					"Rate limiter unavailable, allowing request",
+					slog.String("client_ip", ip),
+					slog.Any("error", err),
+				)
+				c.Next()
+				return
+			}
+			c.Error(errors.NewServiceUnavailableError("Rate limiter unavailable", err))
 			c.Abort()
 			return
 		}
@@ -181,7 +351,11 @@ func RateLimiter(rdb *redis.Client, limit int, window time.Duration) gin.Handler
 		}
 
 		if int(count) > limit {
-			c.Error(errors.NewRateLimitError("Too many requests"))
+			retryAfter, err := rdb.TTL(ctx, key).Result()
+			if err != nil || retryAfter <= 0 {
+				retryAfter = window
+			}
+			c.Error(errors.NewRateLimitError("Too many requests").WithRetryAfter(retryAfter))
 			c.Abort()
 			return
 		}
@@ -0,0 +1,394 @@
+package middleware
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	config "remaster/shared"
+	"remaster/shared/ctxkeys"
+	"remaster/shared/errors"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/gin-gonic/gin"
+	"github.com/redis/go-redis/v9"
+	"google.golang.org/grpc/metadata"
+)
+
+func newRateLimitedRouter(t *testing.T, rl config.RateLimitConfig) (*gin.Engine, *miniredis.Miniredis) {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+
+	mr := miniredis.RunT(t)
+	rdb := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() { _ = rdb.Close() })
+
+	eh := errors.NewErrorHandler(slog.New(slog.NewTextHandler(io.Discard, nil)))
+	router := gin.New()
+	router.Use(GinErrorMiddleware(eh))
+	router.Use(RateLimiter(rdb, config.NewLive(rl)))
+	router.GET("/ping", func(c *gin.Context) { c.Status(http.StatusOK) })
+	return router, mr
+}
+
+func ping(router *gin.Engine) *httptest.ResponseRecorder {
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/ping", nil))
+	return rec
+}
+
+func TestRateLimiterSetsRetryAfterOn429(t *testing.T) {
+	router, mr := newRateLimitedRouter(t, config.RateLimitConfig{Requests: 2, Window: time.Minute})
+
+	for i := 0; i < 2; i++ {
+		if rec := ping(router); rec.Code != http.StatusOK {
+			t.Fatalf("request %d status = %d, want 200", i+1, rec.Code)
+		}
+	}
+
+	rec := ping(router)
+	if rec.Code != http.StatusTooManyRequests {
+		t.Fatalf("status over the limit = %d, want 429", rec.Code)
+	}
+	if got := rec.Header().Get("Retry-After"); got != "60" {
+		t.Fatalf("Retry-After = %q, want the full window of 60", got)
+	}
+
+	// the header follows the key's remaining ttl, not the configured window
+	mr.FastForward(45 * time.Second)
+	rec = ping(router)
+	if rec.Code != http.StatusTooManyRequests {
+		t.Fatalf("status later in the window = %d, want 429", rec.Code)
+	}
+	if got := rec.Header().Get("Retry-After"); got != "15" {
+		t.Fatalf("Retry-After = %q, want the remaining 15", got)
+	}
+}
+
+func TestRateLimiterAllowsRequestsUnderTheLimit(t *testing.T) {
+	router, _ := newRateLimitedRouter(t, config.RateLimitConfig{Requests: 5, Window: time.Minute})
+
+	rec := ping(router)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want 200", rec.Code)
+	}
+	if got := rec.Header().Get("Retry-After"); got != "" {
+		t.Fatalf("Retry-After = %q on an allowed request", got)
+	}
+}
+
+func TestRateLimiterWhenRedisFails(t *testing.T) {
+	tests := []struct {
+		name     string
+		failOpen bool
+		status   int
+	}{
+		{"fail open", true, http.StatusOK},
+		{"fail closed", false, http.StatusServiceUnavailable},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			router, mr := newRateLimitedRouter(t, config.RateLimitConfig{Requests: 5, Window: time.Minute, FailOpen: tt.failOpen})
+			mr.SetError("LOADING Redis is loading the dataset in memory")
+
+			if rec := ping(router); rec.Code != tt.status {
+				t.Fatalf("status = %d, want %d", rec.Code, tt.status)
+			}
+		})
+	}
+}
+
+// serveWithDeadline runs one request with ctx through RequestDeadline
+func serveWithDeadline(t *testing.T, ctx context.Context, timeout time.Duration) (*httptest.ResponseRecorder, bool, time.Time) {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+
+	eh := errors.NewErrorHandler(slog.New(slog.NewTextHandler(io.Discard, nil)))
+	handled := false
+	var deadline time.Time
+	router := gin.New()
+	router.Use(GinErrorMiddleware(eh), RequestDeadline(timeout))
+	router.GET("/ping", func(c *gin.Context) {
+		handled = true
+		deadline, _ = c.Request.Context().Deadline()
+		c.Status(http.StatusOK)
+	})
+
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/ping", nil).WithContext(ctx))
+	return rec, handled, deadline
+}
+
+func TestRequestDeadlineRejectsExpiredContexts(t *testing.T) {
+	expired, cancelExpired := context.WithDeadline(context.Background(), time.Now().Add(-time.Second))
+	defer cancelExpired()
+	cancelled, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	for name, tt := range map[string]struct {
+		ctx    context.Context
+		status int
+	}{
+		"expired deadline": {expired, http.StatusGatewayTimeout},
+		"client went away": {cancelled, errors.StatusClientClosedRequest},
+	} {
+		t.Run(name, func(t *testing.T) {
+			rec, handled, _ := serveWithDeadline(t, tt.ctx, time.Minute)
+			if handled {
+				t.Fatal("handler ran for a request that was already over")
+			}
+			if rec.Code != tt.status {
+				t.Fatalf("status = %d, want %d", rec.Code, tt.status)
+			}
+		})
+	}
+}
+
+func TestRequestDeadlinePassesLiveRequests(t *testing.T) {
+	t.Run("caller deadline is kept", func(t *testing.T) {
+		ctx, cancel := context.WithTimeout(context.Background(), time.Hour)
+		defer cancel()
+		want, _ := ctx.Deadline()
+
+		rec, handled, deadline := serveWithDeadline(t, ctx, time.Minute)
+		if !handled || rec.Code != http.StatusOK {
+			t.Fatalf("handled = %v, status = %d, want the request through", handled, rec.Code)
+		}
+		if !deadline.Equal(want) {
+			t.Fatalf("deadline = %v, want the caller's %v", deadline, want)
+		}
+	})
+
+	t.Run("missing deadline gets the default", func(t *testing.T) {
+		start := time.Now()
+		rec, handled, deadline := serveWithDeadline(t, context.Background(), time.Minute)
+		if !handled || rec.Code != http.StatusOK {
+			t.Fatalf("handled = %v, status = %d, want the request through", handled, rec.Code)
+		}
+		if deadline.Before(start.Add(time.Minute)) || deadline.After(time.Now().Add(time.Minute)) {
+			t.Fatalf("deadline = %v, want about a minute from the request", deadline)
+		}
+	})
+}
+
+// serveWithIDs runs one request through RequestIDs and returns the response
+// and the request context the handler saw
+func serveWithIDs(t *testing.T, correlationID string) (*httptest.ResponseRecorder, context.Context) {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+
+	var ctx context.Context
+	router := gin.New()
+	router.Use(RequestIDs())
+	router.GET("/ping", func(c *gin.Context) {
+		ctx = c.Request.Context()
+		c.Status(http.StatusOK)
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/ping", nil)
+	if correlationID != "" {
+		req.Header.Set(CorrelationIDHeader, correlationID)
+	}
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+	return rec, ctx
+}
+
+func TestRequestIDsEchoesBothHeaders(t *testing.T) {
+	rec, ctx := serveWithIDs(t, "")
+
+	correlationID, requestID := rec.Header().Get(CorrelationIDHeader), rec.Header().Get(RequestIDHeader)
+	if correlationID == "" || requestID == "" || correlationID == requestID {
+		t.Fatalf("correlation id = %q, request id = %q, want two distinct ids", correlationID, requestID)
+	}
+	if ctxkeys.CorrelationID(ctx) != correlationID || ctxkeys.RequestID(ctx) != requestID {
+		t.Fatalf("context carries %q/%q, want the echoed ids", ctxkeys.CorrelationID(ctx), ctxkeys.RequestID(ctx))
+	}
+	md, _ := metadata.FromOutgoingContext(ctx)
+	if got := md.Get("x-correlation-id"); len(got) != 1 || got[0] != correlationID {
+		t.Fatalf("outgoing x-correlation-id = %v, want %q", got, correlationID)
+	}
+	if got := md.Get("x-request-id"); len(got) != 1 || got[0] != requestID {
+		t.Fatalf("outgoing x-request-id = %v, want %q", got, requestID)
+	}
+}
+
+func TestRequestIDsKeepsTheClientsCorrelationID(t *testing.T) {
+	const supplied = "3b1f6c2e-8d4a-4f7e-9c5b-1a2d3e4f5a6b"
+
+	first, _ := serveWithIDs(t, supplied)
+	second, _ := serveWithIDs(t, supplied)
+	for _, rec := range []*httptest.ResponseRecorder{first, second} {
+		if got := rec.Header().Get(CorrelationIDHeader); got != supplied {
+			t.Fatalf("correlation id = %q, want the supplied %q", got, supplied)
+		}
+	}
+	// one transaction, two hops
+	if first.Header().Get(RequestIDHeader) == second.Header().Get(RequestIDHeader) {
+		t.Fatal("two requests got the same request id")
+	}
+}
+
+func TestRequestIDsReplacesMalformedCorrelationID(t *testing.T) {
+	const malformed = "bad id\twith spaces"
+	rec, _ := serveWithIDs(t, malformed)
+	if got := rec.Header().Get(CorrelationIDHeader); got == "" || got == malformed {
+		t.Fatalf("correlation id = %q, want a fresh one", got)
+	}
+}
+
+// servePanic runs handler behind Recovery alone, without the error middleware
+func servePanic(t *testing.T, handler gin.HandlerFunc) *httptest.ResponseRecorder {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+
+	router := gin.New()
+	router.Use(Recovery(slog.New(slog.NewTextHandler(io.Discard, nil))))
+	router.GET("/boom", handler)
+
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/boom", nil))
+	return rec
+}
+
+func TestRecoveryWritesJSON500(t *testing.T) {
+	rec := servePanic(t, func(c *gin.Context) { panic("nil map write") })
+
+	if rec.Code != http.StatusInternalServerError {
+		t.Fatalf("status = %d, want 500", rec.Code)
+	}
+	if ct := rec.Header().Get("Content-Type"); !strings.HasPrefix(ct, "application/json") {
+		t.Fatalf("Content-Type = %q, want json", ct)
+	}
+	var resp errors.ErrorResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("decode %q: %v", rec.Body, err)
+	}
+	if resp.Success || resp.Code != string(errors.CodeInternal) || resp.Error == "" {
+		t.Fatalf("body = %+v, want an internal error", resp)
+	}
+	if strings.Contains(rec.Body.String(), "nil map write") {
+		t.Fatalf("body leaks the panic value: %s", rec.Body)
+	}
+}
+
+func TestRecoveryLeavesStartedResponsesAlone(t *testing.T) {
+	rec := servePanic(t, func(c *gin.Context) {
+		c.String(http.StatusOK, "partial")
+		panic("after the first byte")
+	})
+
+	if rec.Code != http.StatusOK || rec.Body.String() != "partial" {
+		t.Fatalf("response = %d %q, want the untouched partial body", rec.Code, rec.Body)
+	}
+}
+
+func TestSecurityHeaders(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	headers := config.SecurityHeadersConfig{
+		ContentTypeOptions:    "nosniff",
+		FrameOptions:          "DENY",
+		ReferrerPolicy:        "strict-origin-when-cross-origin",
+		HSTSMaxAge:            365 * 24 * time.Hour,
+		HSTSIncludeSubdomains: true,
+	}
+
+	tests := []struct {
+		name       string
+		cfg        config.SecurityHeadersConfig
+		production bool
+		hsts       string
+	}{
+		{"production", headers, true, "max-age=31536000; includeSubDomains"},
+		{"development", headers, false, ""},
+		{"production without max age", config.SecurityHeadersConfig{ContentTypeOptions: "nosniff", FrameOptions: "DENY", ReferrerPolicy: "no-referrer"}, true, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			router := gin.New()
+			router.Use(SecurityHeaders(tt.cfg, tt.production))
+			router.GET("/ping", func(c *gin.Context) { c.Status(http.StatusOK) })
+			rec := ping(router)
+
+			for name, want := range map[string]string{
+				"X-Content-Type-Options": tt.cfg.ContentTypeOptions,
+				"X-Frame-Options":        tt.cfg.FrameOptions,
+				"Referrer-Policy":        tt.cfg.ReferrerPolicy,
+			} {
+				if got := rec.Header().Get(name); got != want {
+					t.Errorf("%s = %q, want %q", name, got, want)
+				}
+			}
+			if got := rec.Header().Get("Strict-Transport-Security"); got != tt.hsts {
+				t.Errorf("Strict-Transport-Security = %q, want %q", got, tt.hsts)
+			}
+		})
+	}
+}
+
+func TestSecurityHeadersSkipsEmptyValues(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(SecurityHeaders(config.SecurityHeadersConfig{ContentTypeOptions: "nosniff"}, true))
+	router.GET("/ping", func(c *gin.Context) { c.Status(http.StatusOK) })
+	rec := ping(router)
+
+	for _, name := range []string{"X-Frame-Options", "Referrer-Policy", "Strict-Transport-Security"} {
+		if values := rec.Header().Values(name); len(values) > 0 {
+			t.Errorf("%s = %v, want it left out", name, values)
+		}
+	}
+}
+
+// logRequest serves one request that takes delay behind RequestLogger and returns its log line
+func logRequest(t *testing.T, threshold, delay time.Duration) map[string]any {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+
+	var buf strings.Builder
+	log := slog.New(slog.NewJSONHandler(&buf, nil))
+	router := gin.New()
+	router.Use(RequestLogger(log, errors.NewErrorHandler(log), threshold))
+	router.GET("/work", func(c *gin.Context) {
+		time.Sleep(delay)
+		c.Status(http.StatusOK)
+	})
+	router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/work", nil))
+
+	var line map[string]any
+	if err := json.Unmarshal([]byte(buf.String()), &line); err != nil {
+		t.Fatalf("decode log %q: %v", buf.String(), err)
+	}
+	return line
+}
+
+func TestRequestLoggerFlagsSlowRequests(t *testing.T) {
+	tests := []struct {
+		name      string
+		threshold time.Duration
+		delay     time.Duration
+		level     string
+		slow      bool
+	}{
+		{"fast", 50 * time.Millisecond, 0, "INFO", false},
+		{"slow", 20 * time.Millisecond, 50 * time.Millisecond, "WARN", true},
+		{"threshold off", 0, 50 * time.Millisecond, "INFO", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			line := logRequest(t, tt.threshold, tt.delay)
+			if line["level"] != tt.level {
+				t.Fatalf("level = %v, want %s", line["level"], tt.level)
+			}
+			if _, got := line["slow_request"]; got != tt.slow {
+				t.Fatalf("slow_request present = %v, want %v: %v", got, tt.slow, line)
+			}
+		})
+	}
+}
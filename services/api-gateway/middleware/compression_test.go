@@ -0,0 +1,113 @@
+package middleware
+
+import (
+	"compress/gzip"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	config "remaster/shared"
+
+	"github.com/gin-gonic/gin"
+)
+
+const compressMinBytes = 512
+
+// serveCompressed runs handler behind Compression and returns the raw response
+func serveCompressed(t *testing.T, acceptEncoding string, handler gin.HandlerFunc) *httptest.ResponseRecorder {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+
+	router := gin.New()
+	router.Use(Compression(config.CompressionConfig{
+		Enabled:      true,
+		MinBytes:     compressMinBytes,
+		ContentTypes: []string{"application/json"},
+	}))
+	router.GET("/list", handler)
+
+	req := httptest.NewRequest(http.MethodGet, "/list", nil)
+	if acceptEncoding != "" {
+		req.Header.Set("Accept-Encoding", acceptEncoding)
+	}
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+	return rec
+}
+
+func jsonBody(size int) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Data(http.StatusOK, "application/json; charset=utf-8", []byte(`"`+strings.Repeat("a", size)+`"`))
+	}
+}
+
+func TestCompressionGzipsLargeBodies(t *testing.T) {
+	rec := serveCompressed(t, "br, gzip", func(c *gin.Context) {
+		c.JSON(http.StatusCreated, gin.H{"items": strings.Repeat("session ", 200)})
+	})
+
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("status = %d, want the handler's 201", rec.Code)
+	}
+	if rec.Header().Get("Content-Encoding") != "gzip" || rec.Header().Get("Content-Length") != "" {
+		t.Fatalf("headers = %v, want gzip without the plain Content-Length", rec.Header())
+	}
+	if rec.Header().Get("Vary") != "Accept-Encoding" {
+		t.Fatalf("Vary = %q, want Accept-Encoding", rec.Header().Get("Vary"))
+	}
+
+	zr, err := gzip.NewReader(rec.Body)
+	if err != nil {
+		t.Fatalf("gzip reader: %v", err)
+	}
+	body, err := io.ReadAll(zr)
+	if err != nil {
+		t.Fatalf("decompress: %v", err)
+	}
+	if want := `{"items":"` + strings.Repeat("session ", 200) + `"}`; string(body) != want {
+		t.Fatalf("decompressed body differs, got %d bytes want %d", len(body), len(want))
+	}
+}
+
+func TestCompressionLeavesResponsesAlone(t *testing.T) {
+	tests := []struct {
+		name           string
+		acceptEncoding string
+		handler        gin.HandlerFunc
+	}{
+		{"small body", "gzip", jsonBody(compressMinBytes / 4)},
+		{"client without gzip", "", jsonBody(4 * compressMinBytes)},
+		{"gzip refused", "gzip;q=0, identity", jsonBody(4 * compressMinBytes)},
+		{"content type not listed", "gzip", func(c *gin.Context) {
+			c.Data(http.StatusOK, "image/png", []byte(strings.Repeat("p", 4*compressMinBytes)))
+		}},
+		{"already encoded", "gzip", func(c *gin.Context) {
+			c.Header("Content-Encoding", "br")
+			c.Data(http.StatusOK, "application/json", []byte(strings.Repeat("b", 4*compressMinBytes)))
+		}},
+		{"no content", "gzip", func(c *gin.Context) { c.Status(http.StatusNoContent) }},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// what the handler sends without the middleware
+			plain := httptest.NewRecorder()
+			c, _ := gin.CreateTestContext(plain)
+			tt.handler(c)
+			c.Writer.WriteHeaderNow()
+			want := plain.Body.String()
+
+			rec := serveCompressed(t, tt.acceptEncoding, tt.handler)
+			if rec.Code != plain.Code {
+				t.Fatalf("status = %d, want %d", rec.Code, plain.Code)
+			}
+			if got := rec.Header().Get("Content-Encoding"); got != plain.Header().Get("Content-Encoding") {
+				t.Fatalf("Content-Encoding = %q, want the handler's %q", got, plain.Header().Get("Content-Encoding"))
+			}
+			if rec.Body.String() != want {
+				t.Fatalf("body changed: %d bytes, want %d", rec.Body.Len(), len(want))
+			}
+		})
+	}
+}
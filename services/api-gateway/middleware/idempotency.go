@@ -0,0 +1,141 @@
+package middleware
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"remaster/shared/connection"
+	"remaster/shared/errors"
+	"remaster/shared/logger"
+	"remaster/shared/validation"
+
+	"github.com/gin-gonic/gin"
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	IdempotencyKeyHeader      = "Idempotency-Key"
+	IdempotentReplayedHeader  = "Idempotent-Replayed"
+	maxIdempotentRequestBytes = 1 << 20 // 1MB
+)
+
+// idempotentResponse is stored as soon as a key is claimed, Status stays 0 until
+// the first request finished so concurrent retries can tell it is still running
+type idempotentResponse struct {
+	BodyHash    string `json:"body_hash"`
+	Status      int    `json:"status"`
+	ContentType string `json:"content_type,omitempty"`
+	Body        []byte `json:"body,omitempty"`
+}
+
+type bodyRecorder struct {
+	gin.ResponseWriter
+	body bytes.Buffer
+}
+
+func (w *bodyRecorder) Write(b []byte) (int, error) {
+	w.body.Write(b)
+	return w.ResponseWriter.Write(b)
+}
+
+func (w *bodyRecorder) WriteString(s string) (int, error) {
+	w.body.WriteString(s)
+	return w.ResponseWriter.WriteString(s)
+}
+
+// Idempotency replays the first response for a repeated Idempotency-Key within ttl.
+// Reusing a key with a different body is a conflict. Errors that are worth retrying
+// (5xx, 429, errors left for GinErrorMiddleware) release the key instead of being stored.
+// Requests without the header pass through, as do all requests while Redis is down
+func Idempotency(rdb redis.UniversalClient, ttl time.Duration) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		key := c.GetHeader(IdempotencyKeyHeader)
+		if key == "" {
+			c.Next()
+			return
+		}
+		if !validation.ValidHeaderID(key) {
+			c.Error(errors.NewValidationError("Idempotency-Key is invalid", map[string]string{
+				"field": IdempotencyKeyHeader,
+				"issue": "must be 1-128 letters, digits or . _ : -",
+			}))
+			c.Abort()
+			return
+		}
+
+		body, err := io.ReadAll(http.MaxBytesReader(c.Writer, c.Request.Body, maxIdempotentRequestBytes))
+		if err != nil {
+			c.Error(errors.NewBadRequestError("Request body is too large or unreadable"))
+			c.Abort()
+			return
+		}
+		c.Request.Body = io.NopCloser(bytes.NewReader(body))
+		sum := sha256.Sum256(body)
+		bodyHash := hex.EncodeToString(sum[:])
+
+		ctx := c.Request.Context()
+		log := logger.FromContext(ctx, slog.Default())
+		redisKey := "idempotency:" + c.FullPath() + ":" + key
+
+		pending, _ := json.Marshal(idempotentResponse{BodyHash: bodyHash})
+		claimed, err := rdb.SetNX(ctx, redisKey, pending, ttl).Result()
+		if err != nil {
+			log.WarnContext(ctx, "Idempotency store unavailable, handling request normally", slog.Any("error", err))
+			c.Next()
+			return
+		}
+
+		if !claimed {
+			stored, ok, err := connection.GetJSON[idempotentResponse](ctx, rdb, redisKey)
+			switch {
+			case err != nil:
+				log.WarnContext(ctx, "Failed to read idempotent response", slog.Any("error", err))
+				c.Error(errors.NewServiceUnavailableError("Idempotency store unavailable", err))
+			case !ok:
+				// expired between SETNX and GET, the client can simply retry
+				c.Error(errors.NewConflictError("Idempotency-Key expired, retry the request", nil))
+			case stored.BodyHash != bodyHash:
+				c.Error(errors.NewConflictError("Idempotency-Key was already used with a different request body", nil))
+			case stored.Status == 0:
+				c.Error(errors.NewConflictError("A request with this Idempotency-Key is still in progress", nil).
+					WithRetryAfter(time.Second))
+			default:
+				c.Header(IdempotentReplayedHeader, "true")
+				c.Data(stored.Status, stored.ContentType, stored.Body)
+			}
+			c.Abort()
+			return
+		}
+
+		rec := &bodyRecorder{ResponseWriter: c.Writer}
+		c.Writer = rec
+		c.Next()
+
+		// the outcome must be recorded even if the client hung up meanwhile
+		ctx = context.WithoutCancel(ctx)
+		status := rec.Status()
+		if len(c.Errors) > 0 || !rec.Written() || status >= 500 || status == http.StatusTooManyRequests {
+			if err := rdb.Del(ctx, redisKey).Err(); err != nil {
+				log.WarnContext(ctx, "Failed to release idempotency key", slog.Any("error", err))
+			}
+			return
+		}
+
+		err = connection.SetJSON(ctx, rdb, redisKey, idempotentResponse{
+			BodyHash:    bodyHash,
+			Status:      status,
+			ContentType: rec.Header().Get("Content-Type"),
+			Body:        rec.body.Bytes(),
+		}, ttl)
+		if err != nil {
+			log.WarnContext(ctx, "Failed to store idempotent response", slog.Any("error", err))
+		}
+	}
+}
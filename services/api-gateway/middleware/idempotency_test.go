@@ -0,0 +1,135 @@
+package middleware
+
+import (
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"remaster/shared/errors"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/gin-gonic/gin"
+	"github.com/redis/go-redis/v9"
+)
+
+// registerRouter mounts a register handler behind Idempotency that counts its
+// calls and answers with status
+func registerRouter(t *testing.T, status *int) (*gin.Engine, *miniredis.Miniredis, *int) {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+
+	mr := miniredis.RunT(t)
+	rdb := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() { _ = rdb.Close() })
+
+	calls := 0
+	router := gin.New()
+	router.Use(GinErrorMiddleware(errors.NewErrorHandler(slog.New(slog.NewTextHandler(io.Discard, nil)))))
+	router.POST("/auth/register", Idempotency(rdb, time.Minute), func(c *gin.Context) {
+		calls++
+		body, _ := io.ReadAll(c.Request.Body)
+		c.JSON(*status, gin.H{"call": calls, "echo": string(body)})
+	})
+	return router, mr, &calls
+}
+
+func register(router *gin.Engine, key, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodPost, "/auth/register", strings.NewReader(body))
+	if key != "" {
+		req.Header.Set(IdempotencyKeyHeader, key)
+	}
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestIdempotencyReplaysTheFirstResponse(t *testing.T) {
+	status := http.StatusCreated
+	router, _, calls := registerRouter(t, &status)
+
+	first := register(router, "signup-1", `{"email":"a@example.com"}`)
+	replay := register(router, "signup-1", `{"email":"a@example.com"}`)
+
+	if *calls != 1 {
+		t.Fatalf("handler ran %d times, want once", *calls)
+	}
+	if replay.Code != http.StatusCreated || replay.Body.String() != first.Body.String() {
+		t.Fatalf("replay = %d %s, want the first response %d %s", replay.Code, replay.Body, first.Code, first.Body)
+	}
+	if replay.Header().Get(IdempotentReplayedHeader) != "true" || first.Header().Get(IdempotentReplayedHeader) != "" {
+		t.Fatal("only the replay should be marked as replayed")
+	}
+	if ct := replay.Header().Get("Content-Type"); !strings.HasPrefix(ct, "application/json") {
+		t.Fatalf("replay Content-Type = %q", ct)
+	}
+}
+
+func TestIdempotencyRejectsAReusedKeyWithADifferentBody(t *testing.T) {
+	status := http.StatusCreated
+	router, _, calls := registerRouter(t, &status)
+
+	register(router, "signup-1", `{"email":"a@example.com"}`)
+	rec := register(router, "signup-1", `{"email":"b@example.com"}`)
+
+	if rec.Code != http.StatusConflict {
+		t.Fatalf("status = %d (%s), want 409", rec.Code, rec.Body)
+	}
+	if *calls != 1 {
+		t.Fatalf("handler ran %d times, want once", *calls)
+	}
+}
+
+func TestIdempotencyReleasesTheKeyOnServerErrors(t *testing.T) {
+	status := http.StatusServiceUnavailable
+	router, _, calls := registerRouter(t, &status)
+
+	register(router, "signup-1", `{}`)
+	status = http.StatusCreated
+	if rec := register(router, "signup-1", `{}`); rec.Code != http.StatusCreated || rec.Header().Get(IdempotentReplayedHeader) != "" {
+		t.Fatalf("retry after a 503 = %d, replayed %q, want a fresh 201", rec.Code, rec.Header().Get(IdempotentReplayedHeader))
+	}
+	if *calls != 2 {
+		t.Fatalf("handler ran %d times, want the retry to run it again", *calls)
+	}
+}
+
+func TestIdempotencyReportsARequestInProgress(t *testing.T) {
+	status := http.StatusCreated
+	router, mr, _ := registerRouter(t, &status)
+	// claimed by a first request that hasn't finished, the body hash of "{}"
+	if err := mr.Set("idempotency:/auth/register:signup-1",
+		`{"body_hash":"44136fa355b3678a1146ad16f7e8649e94fb4fc21fe77e8310c060f61caaff8a","status":0}`); err != nil {
+		t.Fatalf("seed: %v", err)
+	}
+
+	rec := register(router, "signup-1", `{}`)
+	if rec.Code != http.StatusConflict || rec.Header().Get("Retry-After") != "1" {
+		t.Fatalf("status = %d, Retry-After %q, want 409 with a 1s retry", rec.Code, rec.Header().Get("Retry-After"))
+	}
+}
+
+func TestIdempotencyPassesThrough(t *testing.T) {
+	status := http.StatusCreated
+	router, mr, calls := registerRouter(t, &status)
+
+	register(router, "", `{}`)
+	register(router, "", `{}`)
+	if *calls != 2 {
+		t.Fatalf("requests without a key ran the handler %d times, want 2", *calls)
+	}
+
+	if rec := register(router, "bad key!", `{}`); rec.Code != http.StatusBadRequest {
+		t.Fatalf("malformed key status = %d, want 400", rec.Code)
+	}
+
+	mr.SetError("redis down")
+	register(router, "signup-1", `{}`)
+	register(router, "signup-1", `{}`)
+	if *calls != 4 {
+		t.Fatalf("handler ran %d times with redis down, want every request handled", *calls)
+	}
+}
@@ -0,0 +1,53 @@
+package middleware
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+
+	"github.com/gin-gonic/gin"
+)
+
+// InFlight tracks requests that are still being handled so shutdown can wait
+// for them before tearing down the gRPC connections they depend on
+type InFlight struct {
+	wg    sync.WaitGroup
+	count atomic.Int64
+}
+
+func NewInFlight() *InFlight {
+	return &InFlight{}
+}
+
+func (f *InFlight) Middleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		f.wg.Add(1)
+		f.count.Add(1)
+		defer func() {
+			f.count.Add(-1)
+			f.wg.Done()
+		}()
+		c.Next()
+	}
+}
+
+func (f *InFlight) Count() int64 {
+	return f.count.Load()
+}
+
+// Wait blocks until every tracked request finished or ctx is done,
+// false means requests were still running when it gave up
+func (f *InFlight) Wait(ctx context.Context) bool {
+	done := make(chan struct{})
+	go func() {
+		f.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
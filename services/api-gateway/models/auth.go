@@ -1,12 +1,14 @@
 package models
 
+import "time"
+
 type RegisterDTO struct {
-	Email     string `json:"email"`
-	Password  string `json:"password"`
-	FirstName string `json:"first_name"`
-	LastName  string `json:"last_name"`
-	Phone     string `json:"phone"`
-	UserType  string `json:"user_type"`
+	Email     string `json:"email" validate:"required,email"`
+	Password  string `json:"password" validate:"required,min=8"`
+	FirstName string `json:"first_name" validate:"required,min=2,max=50"`
+	LastName  string `json:"last_name" validate:"required,min=2,max=50"`
+	Phone     string `json:"phone" validate:"required,max=32"` // normalized to E.164 by the auth service
+	UserType  string `json:"user_type" validate:"required,oneof=client master"`
 }
 
 type AuthResponse struct {
@@ -15,6 +17,16 @@ type AuthResponse struct {
 	RefreshToken string `json:"refresh_token"`
 	ExpiresAt    int64  `json:"expires_at"`
 	UserType     string `json:"user_type"`
+	// login only, PasswordExpired asks for a new password and NewDevice for a confirmation
+	PasswordExpired bool `json:"password_expired,omitempty"`
+	NewDevice       bool `json:"new_device,omitempty"`
+}
+
+type GuestTokenResponse struct {
+	UserID      string `json:"user_id"`
+	AccessToken string `json:"access_token"`
+	ExpiresAt   int64  `json:"expires_at"`
+	UserType    string `json:"user_type"`
 }
 
 type RefreshTokenResponse struct {
@@ -26,15 +38,35 @@ type RefreshTokenResponse struct {
 }
 
 type ValidateTokenResponse struct {
-	Valid     bool   `json:"valid"`
-	UserID    string `json:"user_id"`
-	UserType  string `json:"user_type"`
-	ExpiresAt int64  `json:"expires_at"`
+	Valid         bool   `json:"valid"`
+	UserID        string `json:"user_id"`
+	UserType      string `json:"user_type"`
+	ExpiresAt     int64  `json:"expires_at"`
+	ExpiresIn     int64  `json:"expires_in"`
+	ShouldRefresh bool   `json:"should_refresh"`
+	OrgID         string `json:"org_id,omitempty"`
+	Scope         string `json:"scope,omitempty"`
+}
+
+type ValidateTokensDTO struct {
+	AccessTokens []string `json:"access_tokens" validate:"required,min=1,max=100"`
+}
+
+type TokenValidationResult struct {
+	Valid         bool   `json:"valid"`
+	UserID        string `json:"user_id,omitempty"`
+	UserType      string `json:"user_type,omitempty"`
+	ExpiresAt     int64  `json:"expires_at,omitempty"`
+	ExpiresIn     int64  `json:"expires_in,omitempty"`
+	ShouldRefresh bool   `json:"should_refresh,omitempty"`
+	OrgID         string `json:"org_id,omitempty"`
+	Scope         string `json:"scope,omitempty"`
+	Error         string `json:"error,omitempty"`
 }
 
 type LoginDTO struct {
-	Email    string `json:"email"`
-	Password string `json:"password"`
+	Email    string `json:"email" validate:"required,email"`
+	Password string `json:"password" validate:"required"`
 }
 
 type OAuthTokenRequest struct {
@@ -42,8 +74,115 @@ type OAuthTokenRequest struct {
 	IDToken  string `json:"id_token" binding:"required"`
 }
 
+type ProfileImageDTO struct {
+	MediaKey string `json:"media_key" validate:"required"`
+}
+
+type ProfileImageResponse struct {
+	ProfileImage string `json:"profile_image"`
+}
+
 type HealthResponse struct {
 	Status    string            `json:"status"`
 	Timestamp int64             `json:"timestamp"`
 	Checks    map[string]string `json:"checks"`
 }
+
+type AuditLogQuery struct {
+	UserID   string `form:"user_id"`
+	Page     int64  `form:"page" binding:"omitempty,min=1"`
+	PageSize int64  `form:"page_size" binding:"omitempty,min=1,max=100"`
+	// next_cursor of a previous response, page is ignored when set
+	Cursor string `form:"cursor"`
+}
+
+type AuditEntryResponse struct {
+	ID           string `json:"id"`
+	ActorID      string `json:"actor_id"`
+	Action       string `json:"action"`
+	TargetUserID string `json:"target_user_id"`
+	IP           string `json:"ip,omitempty"`
+	UserAgent    string `json:"user_agent,omitempty"`
+	CreatedAt    int64  `json:"created_at"`
+	PrevHash     string `json:"prev_hash"`
+	Hash         string `json:"hash"`
+}
+
+type AuditLogResponse struct {
+	Entries    []AuditEntryResponse `json:"entries"`
+	Total      int64                `json:"total"`
+	Page       int64                `json:"page"`
+	PageSize   int64                `json:"page_size"`
+	TotalPages int64                `json:"total_pages"`
+	NextCursor string               `json:"next_cursor,omitempty"`
+}
+
+type SessionsQuery struct {
+	Cursor string `form:"cursor"`
+	Limit  int64  `form:"limit" binding:"omitempty,min=1,max=100"`
+}
+
+type SessionResponse struct {
+	ID        string `json:"id"`
+	DeviceID  string `json:"device_id,omitempty"`
+	UserAgent string `json:"user_agent,omitempty"`
+	IP        string `json:"ip,omitempty"`
+	CreatedAt int64  `json:"created_at"`
+	ExpiresAt int64  `json:"expires_at"`
+}
+
+type SessionsResponse struct {
+	Sessions   []SessionResponse `json:"sessions"`
+	NextCursor string            `json:"next_cursor,omitempty"`
+}
+
+type AdminCreateUserDTO struct {
+	Email     string `json:"email" validate:"required,email"`
+	FirstName string `json:"first_name" validate:"required,min=2,max=50"`
+	LastName  string `json:"last_name" validate:"required,min=2,max=50"`
+	Phone     string `json:"phone" validate:"required,max=32"`
+	UserType  string `json:"user_type" validate:"required,oneof=client master admin"`
+	Verified  bool   `json:"verified"`
+}
+
+// TemporaryPassword is only ever returned in this response
+type AdminCreateUserResponse struct {
+	UserID            string `json:"user_id"`
+	UserType          string `json:"user_type"`
+	IsVerified        bool   `json:"is_verified"`
+	TemporaryPassword string `json:"temporary_password"`
+	CreatedAt         int64  `json:"created_at"`
+}
+
+// UserSearchQuery filters GET /admin/users, dates are RFC 3339
+type UserSearchQuery struct {
+	Email       string    `form:"email" binding:"omitempty,max=254"`
+	UserType    string    `form:"user_type" binding:"omitempty,oneof=client master admin"`
+	IsActive    *bool     `form:"is_active"`
+	IsVerified  *bool     `form:"is_verified"`
+	CreatedFrom time.Time `form:"created_from" time_format:"2006-01-02T15:04:05Z07:00"`
+	CreatedTo   time.Time `form:"created_to" time_format:"2006-01-02T15:04:05Z07:00"`
+	Page        int64     `form:"page" binding:"omitempty,min=1"`
+	PageSize    int64     `form:"page_size" binding:"omitempty,min=1,max=100"`
+}
+
+type AdminUserResponse struct {
+	ID          string `json:"id"`
+	Email       string `json:"email"`
+	FirstName   string `json:"first_name"`
+	LastName    string `json:"last_name"`
+	Phone       string `json:"phone"`
+	UserType    string `json:"user_type"`
+	IsActive    bool   `json:"is_active"`
+	IsVerified  bool   `json:"is_verified"`
+	CreatedAt   int64  `json:"created_at"`
+	LastLoginAt int64  `json:"last_login_at,omitempty"`
+}
+
+type UserSearchResponse struct {
+	Users      []AdminUserResponse `json:"users"`
+	Total      int64               `json:"total"`
+	Page       int64               `json:"page"`
+	PageSize   int64               `json:"page_size"`
+	TotalPages int64               `json:"total_pages"`
+}
@@ -0,0 +1,23 @@
+package models
+
+type UploadURLDTO struct {
+	EntityType  string `json:"entity_type" validate:"required,oneof=user master order review"`
+	EntityID    string `json:"entity_id"`
+	ContentType string `json:"content_type" validate:"required"`
+	Size        int64  `json:"size" validate:"required,min=1"`
+}
+
+type UploadURLResponse struct {
+	MediaID   string `json:"media_id"`
+	Key       string `json:"key"`
+	UploadURL string `json:"upload_url"`
+	ExpiresAt int64  `json:"expires_at"`
+}
+
+type MediaResponse struct {
+	MediaID     string `json:"media_id"`
+	Key         string `json:"key"`
+	ContentType string `json:"content_type"`
+	Size        int64  `json:"size"`
+	Status      string `json:"status"`
+}
@@ -0,0 +1,20 @@
+package server
+
+import (
+	"context"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/encoding/gzip"
+	"google.golang.org/protobuf/proto"
+)
+
+// compressionUnaryInterceptor gzips requests of at least minBytes, smaller ones go out as is.
+// Registering gzip also advertises it, so services can compress large responses.
+func compressionUnaryInterceptor(minBytes int) grpc.UnaryClientInterceptor {
+	return func(ctx context.Context, method string, req, reply any, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+		if m, ok := req.(proto.Message); ok && proto.Size(m) >= minBytes {
+			opts = append(opts, grpc.UseCompressor(gzip.Name))
+		}
+		return invoker(ctx, method, req, reply, cc, opts...)
+	}
+}
@@ -0,0 +1,130 @@
+package server
+
+import (
+	"context"
+	"net"
+	"strings"
+	"sync"
+	"testing"
+
+	auth_pb "remaster/shared/proto/auth"
+	sharedserver "remaster/shared/server"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/stats"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+const compressionMinBytes = 1024
+
+// payloadSizes records the size of each received message before and after decompression
+type payloadSizes struct {
+	mu       sync.Mutex
+	payloads []stats.InPayload
+}
+
+func (p *payloadSizes) TagRPC(ctx context.Context, _ *stats.RPCTagInfo) context.Context { return ctx }
+func (p *payloadSizes) TagConn(ctx context.Context, _ *stats.ConnTagInfo) context.Context {
+	return ctx
+}
+func (p *payloadSizes) HandleConn(context.Context, stats.ConnStats) {}
+
+func (p *payloadSizes) HandleRPC(_ context.Context, s stats.RPCStats) {
+	if in, ok := s.(*stats.InPayload); ok {
+		p.mu.Lock()
+		p.payloads = append(p.payloads, stats.InPayload{Length: in.Length, CompressedLength: in.CompressedLength})
+		p.mu.Unlock()
+	}
+}
+
+func (p *payloadSizes) last(t *testing.T) stats.InPayload {
+	t.Helper()
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if len(p.payloads) == 0 {
+		t.Fatal("no payload received")
+	}
+	return p.payloads[len(p.payloads)-1]
+}
+
+// echoUsers answers GetUsers with one profile per requested id
+type echoUsers struct {
+	auth_pb.UnimplementedAuthServiceServer
+}
+
+func (echoUsers) GetUsers(_ context.Context, req *auth_pb.GetUsersRequest) (*auth_pb.GetUsersResponse, error) {
+	users := make([]*auth_pb.UserProfile, 0, len(req.UserIds))
+	for _, id := range req.UserIds {
+		users = append(users, &auth_pb.UserProfile{Id: id, FirstName: "Echo"})
+	}
+	return &auth_pb.GetUsersResponse{Users: users}, nil
+}
+
+// dialCompressed connects the gateway's compression interceptor to a server
+// running the services' one, recording what each side receives
+func dialCompressed(t *testing.T) (auth_pb.AuthServiceClient, *payloadSizes, *payloadSizes) {
+	t.Helper()
+	serverIn, clientIn := &payloadSizes{}, &payloadSizes{}
+
+	lis := bufconn.Listen(1 << 20)
+	gs := grpc.NewServer(
+		grpc.UnaryInterceptor(sharedserver.CompressionUnary(compressionMinBytes)),
+		grpc.StatsHandler(serverIn),
+	)
+	auth_pb.RegisterAuthServiceServer(gs, echoUsers{})
+	go func() { _ = gs.Serve(lis) }()
+	t.Cleanup(gs.Stop)
+
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) { return lis.DialContext(ctx) }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+		grpc.WithUnaryInterceptor(compressionUnaryInterceptor(compressionMinBytes)),
+		grpc.WithStatsHandler(clientIn),
+	)
+	if err != nil {
+		t.Fatalf("dial: %v", err)
+	}
+	t.Cleanup(func() { _ = conn.Close() })
+	return auth_pb.NewAuthServiceClient(conn), serverIn, clientIn
+}
+
+func userIDs(n int) []string {
+	ids := make([]string, n)
+	for i := range ids {
+		ids[i] = strings.Repeat("0", 23) + string(rune('a'+i%26))
+	}
+	return ids
+}
+
+func TestCompressionRoundTripsLargeMessages(t *testing.T) {
+	client, serverIn, clientIn := dialCompressed(t)
+
+	ids := userIDs(500)
+	resp, err := client.GetUsers(context.Background(), &auth_pb.GetUsersRequest{UserIds: ids})
+	if err != nil {
+		t.Fatalf("GetUsers: %v", err)
+	}
+	if len(resp.Users) != len(ids) || resp.Users[499].Id != ids[499] {
+		t.Fatalf("got %d users, want the %d requested back intact", len(resp.Users), len(ids))
+	}
+
+	for side, p := range map[string]stats.InPayload{"request": serverIn.last(t), "response": clientIn.last(t)} {
+		if p.CompressedLength >= p.Length {
+			t.Fatalf("%s of %d bytes sent as %d, want it gzipped", side, p.Length, p.CompressedLength)
+		}
+	}
+}
+
+func TestCompressionSkipsSmallMessages(t *testing.T) {
+	client, serverIn, clientIn := dialCompressed(t)
+
+	if _, err := client.GetUsers(context.Background(), &auth_pb.GetUsersRequest{UserIds: userIDs(2)}); err != nil {
+		t.Fatalf("GetUsers: %v", err)
+	}
+	for side, p := range map[string]stats.InPayload{"request": serverIn.last(t), "response": clientIn.last(t)} {
+		if p.Length >= compressionMinBytes || p.CompressedLength != p.Length {
+			t.Fatalf("%s of %d bytes sent as %d, want it uncompressed", side, p.Length, p.CompressedLength)
+		}
+	}
+}
@@ -0,0 +1,105 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	auth_pb "remaster/shared/proto/auth"
+
+	"github.com/gin-gonic/gin"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+
+	cfg "remaster/shared"
+)
+
+// fakeHealthClient answers Health with a fixed response or error
+type fakeHealthClient struct {
+	auth_pb.AuthServiceClient
+	resp *auth_pb.HealthResponse
+	err  error
+}
+
+func (c fakeHealthClient) Health(context.Context, *auth_pb.HealthRequest, ...grpc.CallOption) (*auth_pb.HealthResponse, error) {
+	return c.resp, c.err
+}
+
+// getHealth serves GET /health with the auth service answering through client
+func getHealth(t *testing.T, client auth_pb.AuthServiceClient) (int, string, HealthStatus) {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+
+	conn, err := grpc.NewClient("127.0.0.1:1", grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatalf("grpc client: %v", err)
+	}
+	t.Cleanup(func() { _ = conn.Close() })
+
+	s := NewServer(&cfg.Config{}, slog.New(slog.NewTextHandler(io.Discard, nil)), nil, nil)
+	s.grpcConnections["auth"] = newConnPool([]*grpc.ClientConn{conn})
+	s.healthChecks["auth"] = authHealthCheck(client)
+
+	router := gin.New()
+	router.GET("/health", s.handleHealth)
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/health", nil))
+
+	var health HealthStatus
+	if err := json.Unmarshal(rec.Body.Bytes(), &health); err != nil {
+		t.Fatalf("decode %s: %v", rec.Body, err)
+	}
+	return rec.Code, rec.Body.String(), health
+}
+
+func TestHealthReportsHealthyService(t *testing.T) {
+	code, _, health := getHealth(t, fakeHealthClient{resp: &auth_pb.HealthResponse{
+		Status: "ok",
+		Checks: map[string]string{"mongodb": "ok", "redis": "ok"},
+	}})
+
+	if code != http.StatusOK || !health.Healthy {
+		t.Fatalf("code = %d, healthy = %v, want 200 and healthy", code, health.Healthy)
+	}
+	if got := health.Details["auth"].Checks["redis"]; got != "ok" {
+		t.Fatalf("auth redis check = %q, want ok", got)
+	}
+}
+
+func TestHealthHidesDownstreamErrors(t *testing.T) {
+	const internal = "dial tcp 10.0.3.7:50051: connect: connection refused"
+	code, body, health := getHealth(t, fakeHealthClient{err: errors.New(internal)})
+
+	if code != http.StatusServiceUnavailable || health.Healthy {
+		t.Fatalf("code = %d, healthy = %v, want 503 and unhealthy", code, health.Healthy)
+	}
+	if health.Services["auth"] != "unhealthy" {
+		t.Fatalf("auth = %q, want unhealthy", health.Services["auth"])
+	}
+	if strings.Contains(body, "10.0.3.7") || strings.Contains(body, "connection refused") {
+		t.Fatalf("response leaks the downstream error: %s", body)
+	}
+}
+
+func TestHealthReducesDependencyChecksToStatusWords(t *testing.T) {
+	code, body, health := getHealth(t, fakeHealthClient{resp: &auth_pb.HealthResponse{
+		Status: "unhealthy",
+		Checks: map[string]string{"mongodb": "ok", "redis": "unhealthy: dial tcp 10.0.3.8:6379: i/o timeout"},
+	}})
+
+	if code != http.StatusServiceUnavailable {
+		t.Fatalf("code = %d, want 503", code)
+	}
+	if got := health.Details["auth"].Checks["redis"]; got != "unhealthy" {
+		t.Fatalf("auth redis check = %q, want unhealthy", got)
+	}
+	if strings.Contains(body, "10.0.3.8") {
+		t.Fatalf("response leaks a dependency error: %s", body)
+	}
+}
@@ -0,0 +1,94 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync/atomic"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/connectivity"
+)
+
+// connPool spreads calls round-robin over several connections to one service,
+// a single HTTP/2 connection is capped by the server's max_concurrent_streams
+type connPool struct {
+	conns []*grpc.ClientConn
+	next  atomic.Uint64
+}
+
+var _ grpc.ClientConnInterface = (*connPool)(nil)
+
+func newConnPool(conns []*grpc.ClientConn) *connPool {
+	return &connPool{conns: conns}
+}
+
+func (p *connPool) pick() *grpc.ClientConn {
+	n := p.next.Add(1) - 1
+	return p.conns[n%uint64(len(p.conns))]
+}
+
+func (p *connPool) Invoke(ctx context.Context, method string, args, reply any, opts ...grpc.CallOption) error {
+	return p.pick().Invoke(ctx, method, args, reply, opts...)
+}
+
+func (p *connPool) NewStream(ctx context.Context, desc *grpc.StreamDesc, method string, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+	return p.pick().NewStream(ctx, desc, method, opts...)
+}
+
+// stateRank orders states from most to least usable
+var stateRank = map[connectivity.State]int{
+	connectivity.Ready:            0,
+	connectivity.Idle:             1,
+	connectivity.Connecting:       2,
+	connectivity.TransientFailure: 3,
+	connectivity.Shutdown:         4,
+}
+
+// State returns the most usable state in the pool and how many connections are Ready,
+// one Ready connection is enough to serve calls
+func (p *connPool) State() (connectivity.State, int) {
+	best := connectivity.Shutdown
+	ready := 0
+	for _, conn := range p.conns {
+		state := conn.GetState()
+		if state == connectivity.Ready {
+			ready++
+		}
+		if stateRank[state] < stateRank[best] {
+			best = state
+		}
+	}
+	return best, ready
+}
+
+// WaitReady connects every idle connection and waits until all of them are Ready,
+// a connection that fails keeps retrying until ctx is done
+func (p *connPool) WaitReady(ctx context.Context) error {
+	for _, conn := range p.conns {
+		conn.Connect()
+		for state := conn.GetState(); state != connectivity.Ready; state = conn.GetState() {
+			if state == connectivity.Shutdown {
+				return errors.New("connection is shut down")
+			}
+			if !conn.WaitForStateChange(ctx, state) {
+				return fmt.Errorf("connection not ready, last state %s: %w", state, ctx.Err())
+			}
+		}
+	}
+	return nil
+}
+
+func (p *connPool) Size() int {
+	return len(p.conns)
+}
+
+func (p *connPool) Close() error {
+	var errs []error
+	for _, conn := range p.conns {
+		if err := conn.Close(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
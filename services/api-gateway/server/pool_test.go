@@ -0,0 +1,118 @@
+package server
+
+import (
+	"context"
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	auth_pb "remaster/shared/proto/auth"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/connectivity"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+// namedAuth answers Health with its own name and records which backend each call hit
+type namedAuth struct {
+	auth_pb.UnimplementedAuthServiceServer
+	name string
+	mu   *sync.Mutex
+	hits *[]string
+}
+
+func (a namedAuth) Health(context.Context, *auth_pb.HealthRequest) (*auth_pb.HealthResponse, error) {
+	a.mu.Lock()
+	*a.hits = append(*a.hits, a.name)
+	a.mu.Unlock()
+	return &auth_pb.HealthResponse{Status: a.name}, nil
+}
+
+// newTestPool dials one in-memory server per name, in order, and pools the connections
+func newTestPool(t *testing.T, names ...string) (*connPool, func() []string) {
+	t.Helper()
+	var mu sync.Mutex
+	var hits []string
+
+	conns := make([]*grpc.ClientConn, 0, len(names))
+	for _, name := range names {
+		lis := bufconn.Listen(1 << 16)
+		srv := grpc.NewServer()
+		auth_pb.RegisterAuthServiceServer(srv, namedAuth{name: name, mu: &mu, hits: &hits})
+		go func() { _ = srv.Serve(lis) }()
+		t.Cleanup(srv.Stop)
+
+		conn, err := grpc.NewClient("passthrough:///"+name,
+			grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) { return lis.DialContext(ctx) }),
+			grpc.WithTransportCredentials(insecure.NewCredentials()),
+		)
+		if err != nil {
+			t.Fatalf("dial %s: %v", name, err)
+		}
+		conns = append(conns, conn)
+	}
+
+	pool := newConnPool(conns)
+	t.Cleanup(func() { _ = pool.Close() })
+	return pool, func() []string {
+		mu.Lock()
+		defer mu.Unlock()
+		return append([]string(nil), hits...)
+	}
+}
+
+func TestConnPoolRoundRobin(t *testing.T) {
+	pool, hits := newTestPool(t, "a", "b", "c")
+	client := auth_pb.NewAuthServiceClient(pool)
+
+	for range 9 {
+		if _, err := client.Health(context.Background(), &auth_pb.HealthRequest{}); err != nil {
+			t.Fatalf("Health: %v", err)
+		}
+	}
+
+	want := []string{"a", "b", "c", "a", "b", "c", "a", "b", "c"}
+	got := hits()
+	if len(got) != len(want) {
+		t.Fatalf("calls hit %v, want %v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("calls hit %v, want %v", got, want)
+		}
+	}
+}
+
+func TestConnPoolStateAggregatesConnections(t *testing.T) {
+	pool, _ := newTestPool(t, "a", "b", "c")
+
+	if state, ready := pool.State(); state != connectivity.Idle || ready != 0 {
+		t.Fatalf("fresh pool = %s with %d ready, want Idle with 0", state, ready)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := pool.WaitReady(ctx); err != nil {
+		t.Fatalf("WaitReady: %v", err)
+	}
+	if state, ready := pool.State(); state != connectivity.Ready || ready != 3 {
+		t.Fatalf("connected pool = %s with %d ready, want Ready with 3", state, ready)
+	}
+
+	// one Ready connection is enough to serve calls
+	_ = pool.conns[0].Close()
+	_ = pool.conns[1].Close()
+	if state, ready := pool.State(); state != connectivity.Ready || ready != 1 {
+		t.Fatalf("pool with two closed = %s with %d ready, want Ready with 1", state, ready)
+	}
+
+	_ = pool.conns[2].Close()
+	if state, ready := pool.State(); state != connectivity.Shutdown || ready != 0 {
+		t.Fatalf("closed pool = %s with %d ready, want Shutdown with 0", state, ready)
+	}
+	if err := pool.WaitReady(ctx); err == nil {
+		t.Fatal("WaitReady succeeded on a closed pool")
+	}
+}
@@ -0,0 +1,70 @@
+package server
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	cfg "remaster/shared"
+)
+
+// retryUnaryInterceptor retries transient failures of opted-in methods.
+// Mutations like Registration are never retried unless listed in config
+func retryUnaryInterceptor(config cfg.RetryConfig, logger *slog.Logger) grpc.UnaryClientInterceptor {
+	methods := make(map[string]struct{}, len(config.Methods))
+	for _, m := range config.Methods {
+		methods[m] = struct{}{}
+	}
+
+	return func(ctx context.Context, method string, req, reply any, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+		if _, ok := methods[method]; !ok || config.MaxAttempts <= 1 {
+			return invoker(ctx, method, req, reply, cc, opts...)
+		}
+
+		backoff := config.InitialBackoff
+		for attempt := 1; ; attempt++ {
+			err := invoker(ctx, method, req, reply, cc, opts...)
+			if err == nil || attempt >= config.MaxAttempts || !isRetryable(ctx, err) {
+				return err
+			}
+
+			// don't wait past the caller's deadline
+			if deadline, ok := ctx.Deadline(); ok && time.Until(deadline) < backoff {
+				return err
+			}
+
+			logger.WarnContext(ctx, "Retrying gRPC call",
+				"method", method,
+				"attempt", attempt,
+				"backoff", backoff,
+				"error", err,
+			)
+
+			select {
+			case <-ctx.Done():
+				return err
+			case <-time.After(backoff):
+			}
+
+			backoff = min(backoff*2, config.MaxBackoff)
+		}
+	}
+}
+
+func isRetryable(ctx context.Context, err error) bool {
+	// caller gave up, the error is not the server's fault
+	if ctx.Err() != nil {
+		return false
+	}
+
+	switch status.Code(err) {
+	case codes.Unavailable, codes.DeadlineExceeded:
+		return true
+	default:
+		return false
+	}
+}
@@ -0,0 +1,160 @@
+package server
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"net"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	auth_pb "remaster/shared/proto/auth"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+
+	cfg "remaster/shared"
+)
+
+// flakyAuthServer fails the first failures calls with code, then succeeds
+type flakyAuthServer struct {
+	auth_pb.UnimplementedAuthServiceServer
+	failures int32
+	code     codes.Code
+	calls    atomic.Int32
+}
+
+func (s *flakyAuthServer) Health(context.Context, *auth_pb.HealthRequest) (*auth_pb.HealthResponse, error) {
+	if s.calls.Add(1) <= s.failures {
+		return nil, status.Error(s.code, "flaky")
+	}
+	return &auth_pb.HealthResponse{Status: "healthy"}, nil
+}
+
+func (s *flakyAuthServer) Registration(context.Context, *auth_pb.RegisterRequest) (*auth_pb.RegisterResponse, error) {
+	s.calls.Add(1)
+	return nil, status.Error(s.code, "flaky")
+}
+
+// dialFlaky serves srv in memory and dials it through the retry interceptor
+func dialFlaky(t *testing.T, srv *flakyAuthServer, config cfg.RetryConfig) auth_pb.AuthServiceClient {
+	t.Helper()
+	lis := bufconn.Listen(1 << 20)
+	gs := grpc.NewServer()
+	auth_pb.RegisterAuthServiceServer(gs, srv)
+	go func() { _ = gs.Serve(lis) }()
+	t.Cleanup(gs.Stop)
+
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) { return lis.DialContext(ctx) }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+		grpc.WithUnaryInterceptor(retryUnaryInterceptor(config, slog.New(slog.NewTextHandler(io.Discard, nil)))),
+	)
+	if err != nil {
+		t.Fatalf("dial: %v", err)
+	}
+	t.Cleanup(func() { _ = conn.Close() })
+	return auth_pb.NewAuthServiceClient(conn)
+}
+
+func testRetryConfig() cfg.RetryConfig {
+	return cfg.RetryConfig{
+		MaxAttempts:    3,
+		InitialBackoff: time.Millisecond,
+		MaxBackoff:     5 * time.Millisecond,
+		Methods:        []string{auth_pb.AuthService_Health_FullMethodName},
+	}
+}
+
+func TestRetryInterceptorRecoversFromTransientFailures(t *testing.T) {
+	for _, code := range []codes.Code{codes.Unavailable, codes.DeadlineExceeded} {
+		t.Run(code.String(), func(t *testing.T) {
+			srv := &flakyAuthServer{failures: 2, code: code}
+			client := dialFlaky(t, srv, testRetryConfig())
+
+			if _, err := client.Health(context.Background(), &auth_pb.HealthRequest{}); err != nil {
+				t.Fatalf("Health: %v", err)
+			}
+			if got := srv.calls.Load(); got != 3 {
+				t.Fatalf("calls = %d, want 3", got)
+			}
+		})
+	}
+}
+
+func TestRetryInterceptorGivesUp(t *testing.T) {
+	tests := []struct {
+		name  string
+		srv   *flakyAuthServer
+		call  func(auth_pb.AuthServiceClient) error
+		code  codes.Code
+		calls int32
+	}{
+		{
+			name: "after max attempts",
+			srv:  &flakyAuthServer{failures: 10, code: codes.Unavailable},
+			call: func(c auth_pb.AuthServiceClient) error {
+				_, err := c.Health(context.Background(), &auth_pb.HealthRequest{})
+				return err
+			},
+			code:  codes.Unavailable,
+			calls: 3,
+		},
+		{
+			name: "on a permanent error",
+			srv:  &flakyAuthServer{failures: 10, code: codes.InvalidArgument},
+			call: func(c auth_pb.AuthServiceClient) error {
+				_, err := c.Health(context.Background(), &auth_pb.HealthRequest{})
+				return err
+			},
+			code:  codes.InvalidArgument,
+			calls: 1,
+		},
+		{
+			name: "for methods not opted in",
+			srv:  &flakyAuthServer{code: codes.Unavailable},
+			call: func(c auth_pb.AuthServiceClient) error {
+				_, err := c.Registration(context.Background(), &auth_pb.RegisterRequest{})
+				return err
+			},
+			code:  codes.Unavailable,
+			calls: 1,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.call(dialFlaky(t, tt.srv, testRetryConfig()))
+			if status.Code(err) != tt.code {
+				t.Fatalf("error = %v, want %s", err, tt.code)
+			}
+			if got := tt.srv.calls.Load(); got != tt.calls {
+				t.Fatalf("calls = %d, want %d", got, tt.calls)
+			}
+		})
+	}
+}
+
+func TestRetryInterceptorRespectsDeadline(t *testing.T) {
+	srv := &flakyAuthServer{failures: 10, code: codes.Unavailable}
+	config := testRetryConfig()
+	config.InitialBackoff = time.Second
+	client := dialFlaky(t, srv, config)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	if _, err := client.Health(ctx, &auth_pb.HealthRequest{}); status.Code(err) != codes.Unavailable {
+		t.Fatalf("error = %v, want the last Unavailable", err)
+	}
+	if elapsed := time.Since(start); elapsed > 200*time.Millisecond {
+		t.Fatalf("returned after %v, want before the deadline", elapsed)
+	}
+	if got := srv.calls.Load(); got != 1 {
+		t.Fatalf("calls = %d, want no retry that can't finish in time", got)
+	}
+}
@@ -1,7 +1,10 @@
 package server
 
 import (
+	"context"
+	"fmt"
 	"net/http"
+	"path"
 	"time"
 
 	"github.com/gin-gonic/gin"
@@ -9,43 +12,121 @@ import (
 
 	"remaster/services/api-gateway/handlers"
 	"remaster/services/api-gateway/middleware"
+	"remaster/services/api-gateway/utils"
 )
 
 func (s *Server) setupRoutes() {
 	s.router = gin.New()
+	// untrusted peers can't spoof X-Forwarded-For, their socket address is the client IP
+	if err := s.router.SetTrustedProxies(s.Config.HTTP.TrustedProxies); err != nil {
+		s.Logger.Error("Invalid trusted proxies, trusting none", "error", err)
+		_ = s.router.SetTrustedProxies(nil)
+	}
+	utils.RegisterBindingTagNames()
 
 	s.router.Use(
-		middleware.RequestLogger(s.Logger, s.errorHandler),
-		middleware.RateLimiter(s.RedisManager.GetClient(), 100, 1*time.Minute),
+		s.inFlight.Middleware(),
+		middleware.RequestIDs(),
+		middleware.ClientMetadata(),
+		middleware.RequestLogger(s.Logger, s.errorHandler, s.Config.Log.SlowRequestThreshold),
+	)
+	// inside the logger so it logs the bytes actually sent
+	if s.Config.HTTP.Compression.Enabled {
+		s.router.Use(middleware.Compression(s.Config.HTTP.Compression))
+	}
+	s.router.Use(
+		middleware.RequestDeadline(s.Config.HTTP.RequestTimeout),
+		middleware.RateLimiter(s.RedisManager.GetClient(), s.rateLimit),
+		middleware.SecurityHeaders(s.Config.HTTP.SecurityHeaders, s.Config.App.Environment == "production"),
 		middleware.CORS(),
 		middleware.GinErrorMiddleware(s.errorHandler),
 		middleware.Recovery(s.Logger),
 	)
 
+	// unversioned on purpose, probes shouldn't follow API versions
 	s.router.GET("/health", s.handleHealth)
 
-	s.setupAuthRoutes()
+	v1 := s.router.Group(path.Join("/", s.Config.HTTP.BasePath, "v1"))
+	v1.GET("/health", s.handleHealth)
+	s.setupAPIRoutes(v1)
+
+	// unversioned paths from before /v1, kept for one release
+	if s.Config.HTTP.LegacyRoutes {
+		s.setupAPIRoutes(s.router.Group("/", middleware.Deprecated(v1.BasePath())))
+	}
 
-	s.Logger.Info("Routes configured successfully")
+	s.Logger.Info("Routes configured successfully", "base_path", v1.BasePath(), "legacy_routes", s.Config.HTTP.LegacyRoutes)
 }
 
-func (s *Server) setupAuthRoutes() {
-	auth := s.router.Group("/auth")
+// setupAPIRoutes mounts one version of the API on rg
+func (s *Server) setupAPIRoutes(rg *gin.RouterGroup) {
+	s.setupAuthRoutes(rg)
+	s.setupMediaRoutes(rg)
+	s.setupAdminRoutes(rg)
+}
+
+func (s *Server) setupAuthRoutes(rg *gin.RouterGroup) {
+	auth := rg.Group("/auth")
 
 	authHandler := handlers.NewAuthHandler(s.authClient, s.Logger, s.errorHandler)
 
-	auth.POST("/register", authHandler.Register)
+	auth.POST("/register",
+		middleware.Idempotency(s.RedisManager.GetClient(), s.Config.HTTP.IdempotencyTTL),
+		authHandler.Register,
+	)
 	auth.POST("/login", authHandler.Login)
 	auth.POST("/provider", authHandler.OAuthLogin)
 	auth.POST("/refresh-token", authHandler.RefreshToken)
+	auth.POST("/guest", authHandler.IssueGuestToken)
 	auth.POST("/validate-token", authHandler.ValidateToken)
+	auth.POST("/validate-tokens", authHandler.ValidateTokens)
 	auth.POST("/change-password", authHandler.ChangePassword)
 	auth.POST("/logout", authHandler.Logout)
 	auth.GET("/health", authHandler.Health)
+	auth.GET("/sessions",
+		middleware.RequireAuth(s.tokenValidator),
+		middleware.RequireRole(middleware.RegisteredRoles...),
+		authHandler.ListSessions,
+	)
+	auth.PUT("/profile/image",
+		middleware.RequireAuth(s.tokenValidator),
+		middleware.RequireRole(middleware.RegisteredRoles...),
+		authHandler.UpdateProfileImage,
+	)
 
 	s.Logger.Debug("Auth routes registered")
 }
 
+func (s *Server) setupMediaRoutes(rg *gin.RouterGroup) {
+	media := rg.Group("/media",
+		middleware.RequireAuth(s.tokenValidator),
+		middleware.RequireRole(middleware.RegisteredRoles...),
+	)
+
+	mediaHandler := handlers.NewMediaHandler(s.mediaClient, s.Logger, s.errorHandler)
+
+	media.POST("/upload-url", mediaHandler.RequestUploadURL)
+	media.POST("/:id/confirm", mediaHandler.ConfirmUpload)
+
+	s.Logger.Debug("Media routes registered")
+}
+
+func (s *Server) setupAdminRoutes(rg *gin.RouterGroup) {
+	admin := rg.Group("/admin",
+		middleware.RequireAuth(s.tokenValidator),
+		middleware.RequireRole(middleware.RoleAdmin),
+	)
+
+	admin.GET("/health", s.handleHealth)
+	authHandler := handlers.NewAuthHandler(s.authClient, s.Logger, s.errorHandler)
+	admin.GET("/audit", authHandler.ListAuditEntries)
+	admin.GET("/users", authHandler.SearchUsers)
+	admin.POST("/users", authHandler.AdminCreateUser)
+	admin.POST("/users/:id/unlock", authHandler.UnlockAccount)
+
+	s.Logger.Debug("Admin routes registered")
+}
+
 // Health check handlers
 func (s *Server) handleHealth(c *gin.Context) {
 	s.connMutex.RLock()
@@ -55,8 +136,8 @@ func (s *Server) handleHealth(c *gin.Context) {
 	services := make(map[string]string)
 	details := make(map[string]ServiceHealth)
 
-	for serviceName, conn := range s.grpcConnections {
-		if conn == nil {
+	for serviceName, pool := range s.grpcConnections {
+		if pool == nil {
 			services[serviceName] = "NOT_CONNECTED"
 			details[serviceName] = ServiceHealth{
 				Status:  "NOT_CONNECTED",
@@ -66,19 +147,13 @@ func (s *Server) handleHealth(c *gin.Context) {
 			continue
 		}
 
-		state := conn.GetState()
-		services[serviceName] = state.String()
-
-		serviceHealthy := state == connectivity.Ready
-		if !serviceHealthy {
+		serviceHealth := s.checkService(c.Request.Context(), serviceName, pool)
+		if !serviceHealth.Healthy {
 			healthy = false
 		}
 
-		details[serviceName] = ServiceHealth{
-			Status:  state.String(),
-			Healthy: serviceHealthy,
-			Message: getStateMessage(state),
-		}
+		services[serviceName] = serviceHealth.Status
+		details[serviceName] = serviceHealth
 	}
 
 	health := HealthStatus{
@@ -98,6 +173,60 @@ func (s *Server) handleHealth(c *gin.Context) {
 	c.JSON(status, health)
 }
 
+// checkService calls the service's Health RPC, a Ready connection alone says nothing about its dependencies
+// Connection state is the best one in the pool
+func (s *Server) checkService(ctx context.Context, serviceName string, pool *connPool) ServiceHealth {
+	state, ready := pool.State()
+	conns := fmt.Sprintf("%d/%d ready", ready, pool.Size())
+
+	check, ok := s.healthChecks[serviceName]
+	if !ok {
+		return ServiceHealth{
+			Status:      state.String(),
+			Healthy:     state == connectivity.Ready,
+			Message:     getStateMessage(state),
+			Connections: conns,
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, healthCheckTimeout)
+	defer cancel()
+
+	status, checks, err := check(ctx)
+	if err != nil {
+		// the error names hosts and addresses, /health is public so it only goes to the log
+		s.Logger.WarnContext(ctx, "Service health check failed", "service", serviceName, "error", err)
+		return ServiceHealth{
+			Status:      "unhealthy",
+			Message:     "Health check failed",
+			Connections: conns,
+		}
+	}
+
+	return ServiceHealth{
+		Status:      status,
+		Healthy:     status != "unhealthy",
+		Message:     getStateMessage(state),
+		Checks:      publicChecks(checks),
+		Connections: conns,
+	}
+}
+
+// publicChecks keeps only "ok" or "unhealthy" per dependency, whatever detail a service sends
+func publicChecks(checks map[string]string) map[string]string {
+	if len(checks) == 0 {
+		return nil
+	}
+	public := make(map[string]string, len(checks))
+	for name, status := range checks {
+		if status != "ok" {
+			status = "unhealthy"
+		}
+		public[name] = status
+	}
+	return public
+}
+
 // Health status types
 type HealthStatus struct {
 	Status    string                   `json:"status"`
@@ -109,11 +238,18 @@ type HealthStatus struct {
 }
 
 type ServiceHealth struct {
-	Status  string `json:"status"`
-	Healthy bool   `json:"healthy"`
-	Message string `json:"message,omitempty"`
+	Status  string            `json:"status"`
+	Healthy bool              `json:"healthy"`
+	Message string            `json:"message,omitempty"`
+	Checks  map[string]string `json:"checks,omitempty"`
+	// ready connections out of the pool size, e.g. "2/3 ready"
+	Connections string `json:"connections,omitempty"`
 }
 
+type healthCheckFunc func(ctx context.Context) (status string, checks map[string]string, err error)
+
+const healthCheckTimeout = 2 * time.Second
+
 func getStateMessage(state connectivity.State) string {
 	switch state {
 	case connectivity.Idle:
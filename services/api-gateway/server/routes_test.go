@@ -0,0 +1,198 @@
+package server
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"remaster/services/api-gateway/middleware"
+	cfg "remaster/shared"
+	"remaster/shared/connection"
+	"remaster/shared/errors"
+	"remaster/shared/tokenauth"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// roleValidator accepts any token as a user of role
+type roleValidator string
+
+func (v roleValidator) Validate(context.Context, string) (*tokenauth.Claims, error) {
+	return &tokenauth.Claims{UserID: "u1", UserType: string(v)}, nil
+}
+
+// callAdmin sends a request to an admin route as a user of role
+func callAdmin(t *testing.T, role, method, path string) int {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	s := NewServer(&cfg.Config{}, logger, errors.NewErrorHandler(logger), nil)
+	s.tokenValidator = roleValidator(role)
+
+	router := gin.New()
+	router.Use(middleware.GinErrorMiddleware(s.errorHandler))
+	s.setupAdminRoutes(router.Group("/v1"))
+
+	req := httptest.NewRequest(method, path, nil)
+	req.Header.Set("Authorization", "Bearer token")
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+	return rec.Code
+}
+
+func TestAdminRoutesRequireTheAdminRole(t *testing.T) {
+	tests := []struct {
+		role   string
+		status int
+	}{
+		{middleware.RoleAdmin, http.StatusOK},
+		{middleware.RoleClient, http.StatusForbidden},
+		{middleware.RoleMaster, http.StatusForbidden},
+	}
+	for _, tt := range tests {
+		t.Run(tt.role, func(t *testing.T) {
+			if got := callAdmin(t, tt.role, http.MethodGet, "/v1/admin/health"); got != tt.status {
+				t.Fatalf("status = %d, want %d", got, tt.status)
+			}
+		})
+	}
+}
+
+func TestUnlockAccountRequiresTheAdminRole(t *testing.T) {
+	for _, role := range []string{middleware.RoleClient, middleware.RoleMaster, middleware.RoleAnonymous} {
+		path := "/v1/admin/users/" + primitive.NewObjectID().Hex() + "/unlock"
+		if got := callAdmin(t, role, http.MethodPost, path); got != http.StatusForbidden {
+			t.Fatalf("%s unlocking an account: status = %d, want 403", role, got)
+		}
+	}
+}
+
+var (
+	routedRedisOnce sync.Once
+	routedRedis     *miniredis.Miniredis
+	routedRedisMgr  *connection.RedisManager
+	routedRedisErr  error
+)
+
+// newServerOnRedis builds a gateway server on an in-process redis, routes are
+// left to the caller. The redis manager is a process-wide singleton that keeps
+// its first config, so every test shares one redis, flushed in between
+func newServerOnRedis(t *testing.T, config *cfg.Config) (*Server, *miniredis.Miniredis) {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+
+	routedRedisOnce.Do(func() {
+		routedRedis = miniredis.NewMiniRedis()
+		if routedRedisErr = routedRedis.Start(); routedRedisErr != nil {
+			return
+		}
+		host, port, _ := net.SplitHostPort(routedRedis.Addr())
+		routedRedisMgr = connection.NewRedisManager(&cfg.RedisConfig{Host: host, Port: port})
+		routedRedisErr = routedRedisMgr.Connect(context.Background())
+	})
+	if routedRedisErr != nil {
+		t.Fatalf("redis: %v", routedRedisErr)
+	}
+	routedRedis.FlushAll()
+
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	s := NewServer(config, logger, errors.NewErrorHandler(logger), routedRedisMgr)
+	return s, routedRedis
+}
+
+func TestRateLimitKeyIgnoresXFFFromUntrustedPeers(t *testing.T) {
+	s, mr := newServerOnRedis(t, &cfg.Config{
+		HTTP:      cfg.HTTPConfig{TrustedProxies: []string{"10.0.0.0/8"}, RequestTimeout: time.Minute},
+		RateLimit: cfg.RateLimitConfig{Requests: 100, Window: time.Minute},
+	})
+	s.setupRoutes()
+
+	tests := []struct {
+		name   string
+		remote string
+		xff    string
+		key    string
+	}{
+		{"spoofed header from the internet", "203.0.113.5:40000", "198.51.100.7", "ratelimit:203.0.113.5"},
+		{"header from the load balancer", "10.1.2.3:40000", "198.51.100.8", "ratelimit:198.51.100.8"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/health", nil)
+			req.RemoteAddr = tt.remote
+			req.Header.Set("X-Forwarded-For", tt.xff)
+			rec := httptest.NewRecorder()
+			s.router.ServeHTTP(rec, req)
+
+			if rec.Code != http.StatusOK {
+				t.Fatalf("status = %d (%s), want 200", rec.Code, rec.Body)
+			}
+			if !mr.Exists(tt.key) {
+				t.Fatalf("rate limit keys = %v, want %s", mr.Keys(), tt.key)
+			}
+		})
+	}
+	if mr.Exists("ratelimit:198.51.100.7") {
+		t.Fatal("the spoofed address got a rate limit bucket")
+	}
+}
+
+func TestVersionedAndLegacyPaths(t *testing.T) {
+	config := &cfg.Config{
+		HTTP:      cfg.HTTPConfig{BasePath: "api", LegacyRoutes: true, RequestTimeout: time.Minute},
+		RateLimit: cfg.RateLimitConfig{Requests: 100, Window: time.Minute},
+	}
+	s, _ := newServerOnRedis(t, config)
+	s.tokenValidator = roleValidator(middleware.RoleAdmin)
+	s.setupRoutes()
+
+	tests := []struct {
+		path       string
+		status     int
+		deprecated bool
+	}{
+		{"/health", http.StatusOK, false},
+		{"/api/v1/health", http.StatusOK, false},
+		{"/api/v1/admin/health", http.StatusOK, false},
+		{"/admin/health", http.StatusOK, true},
+		{"/v1/admin/health", http.StatusNotFound, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, tt.path, nil)
+			req.Header.Set("Authorization", "Bearer token")
+			rec := httptest.NewRecorder()
+			s.router.ServeHTTP(rec, req)
+
+			if rec.Code != tt.status {
+				t.Fatalf("status = %d (%s), want %d", rec.Code, rec.Body, tt.status)
+			}
+			if got := rec.Header().Get("Deprecation") == "true"; got != tt.deprecated {
+				t.Fatalf("Deprecation header = %q, want deprecated %v", rec.Header().Get("Deprecation"), tt.deprecated)
+			}
+			if tt.deprecated && rec.Header().Get("Link") != `</api/v1>; rel="successor-version"` {
+				t.Fatalf("Link = %q, want the /api/v1 successor", rec.Header().Get("Link"))
+			}
+		})
+	}
+
+	// without the alias only the versioned paths exist
+	config.HTTP.LegacyRoutes = false
+	s.setupRoutes()
+	rec := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodGet, "/admin/health", nil)
+	req.Header.Set("Authorization", "Bearer token")
+	s.router.ServeHTTP(rec, req)
+	if rec.Code != http.StatusNotFound {
+		t.Fatalf("legacy path with the alias off = %d, want 404", rec.Code)
+	}
+}
@@ -18,10 +18,14 @@ import (
 	"google.golang.org/grpc/credentials/insecure"
 	"google.golang.org/grpc/keepalive"
 
+	"remaster/services/api-gateway/middleware"
 	cfg "remaster/shared"
 	"remaster/shared/connection"
 	"remaster/shared/errors"
+	"remaster/shared/logger"
 	auth_pb "remaster/shared/proto/auth"
+	media_pb "remaster/shared/proto/media"
+	"remaster/shared/tokenauth"
 )
 
 type Server struct {
@@ -32,12 +36,21 @@ type Server struct {
 
 	httpServer *http.Server
 	router     *gin.Engine
+	inFlight   *middleware.InFlight
 
-	grpcConnections map[string]*grpc.ClientConn
+	grpcConnections map[string]*connPool
+	healthChecks    map[string]healthCheckFunc
 	RedisManager    *connection.RedisManager
 
+	// hot-reloadable settings
+	rateLimit *cfg.Live[cfg.RateLimitConfig]
+
 	// GRPC clients
-	authClient auth_pb.AuthServiceClient
+	authClient  auth_pb.AuthServiceClient
+	mediaClient media_pb.MediaServiceClient
+
+	// checks bearer tokens for RequireAuth, remote through the auth service
+	tokenValidator tokenauth.TokenValidator
 }
 
 func NewServer(config *cfg.Config, logger *slog.Logger, errorHandler *errors.ErrorHandler, redisMgr *connection.RedisManager) *Server {
@@ -46,13 +59,27 @@ func NewServer(config *cfg.Config, logger *slog.Logger, errorHandler *errors.Err
 		Logger:          logger,
 		errorHandler:    errorHandler,
 		RedisManager:    redisMgr,
-		grpcConnections: make(map[string]*grpc.ClientConn),
+		grpcConnections: make(map[string]*connPool),
+		healthChecks:    make(map[string]healthCheckFunc),
+		inFlight:        middleware.NewInFlight(),
+		rateLimit:       cfg.NewLive(config.RateLimit),
 	}
 }
 
+// applyConfig takes the runtime-safe subset of a reloaded config, the rest
+// needs a restart
+func (s *Server) applyConfig(next *cfg.Config) {
+	logger.SetLevel(next.Log.Level)
+	s.rateLimit.Store(next.RateLimit)
+	s.Logger.Info("Runtime config applied",
+		"log_level", next.Log.Level,
+		"rate_limit_requests", next.RateLimit.Requests,
+		"rate_limit_window", next.RateLimit.Window,
+	)
+}
+
 func (s *Server) Start() error {
-	ctx, cancel := context.WithCancel(context.Background())
-	defer cancel()
+	ctx := context.Background()
 
 	s.Logger.Info("Creating API Gateway server",
 		"environment", s.Config.App.Environment,
@@ -67,28 +94,60 @@ func (s *Server) Start() error {
 
 	// Setup routes
 	s.setupRoutes()
+	cfg.WatchConfig(s.applyConfig)
+
+	return s.serve(ctx)
+}
+
+// serve runs the HTTP server until a signal or ctx ends it, and only returns once
+// in-flight requests drained and the gRPC connections are closed
+func (s *Server) serve(ctx context.Context) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
 
-	// Start server with graceful shutdown
 	g, ctx := errgroup.WithContext(ctx)
 
 	g.Go(func() error {
 		return s.runHTTPServer(ctx)
 	})
 
-	go s.shutdown(ctx, cancel)
+	g.Go(func() error {
+		return s.shutdown(ctx, cancel)
+	})
 
 	return g.Wait()
 }
 
+func authHealthCheck(client auth_pb.AuthServiceClient) healthCheckFunc {
+	return func(ctx context.Context) (string, map[string]string, error) {
+		resp, err := client.Health(ctx, &auth_pb.HealthRequest{})
+		return resp.GetStatus(), resp.GetChecks(), err
+	}
+}
+
+func mediaHealthCheck(client media_pb.MediaServiceClient) healthCheckFunc {
+	return func(ctx context.Context) (string, map[string]string, error) {
+		resp, err := client.Health(ctx, &media_pb.HealthRequest{})
+		return resp.GetStatus(), resp.GetChecks(), err
+	}
+}
+
 func (s *Server) initializeGRPCClients() error {
 	s.Logger.Info("Initializing server components")
 
+	// keep in sync with cfg.GatewayServices, which config validation checks
 	services := []struct {
 		name string
-		init func(*grpc.ClientConn)
+		init func(grpc.ClientConnInterface)
 	}{
-		{name: "auth", init: func(conn *grpc.ClientConn) {
+		{name: "auth", init: func(conn grpc.ClientConnInterface) {
 			s.authClient = auth_pb.NewAuthServiceClient(conn)
+			s.tokenValidator = tokenauth.NewRemoteValidator(s.authClient)
+			s.healthChecks["auth"] = authHealthCheck(s.authClient)
+		}},
+		{name: "media", init: func(conn grpc.ClientConnInterface) {
+			s.mediaClient = media_pb.NewMediaServiceClient(conn)
+			s.healthChecks["media"] = mediaHealthCheck(s.mediaClient)
 		}},
 	}
 
@@ -98,37 +157,74 @@ func (s *Server) initializeGRPCClients() error {
 			return fmt.Errorf("failed to get %s service address: %w", service.name, err)
 		}
 
-		conn, err := s.connectToService(service.name, address)
-		if err != nil {
-			s.Logger.Error("Failed to connect to service",
-				"service", service.name, "error", err)
-			return fmt.Errorf("service %s not available: %w", service.name, err)
-
-		}
-		if conn == nil {
-			s.Logger.Error("nil connection for service", "service", service.name)
-			return fmt.Errorf("service %s connection is nil", service.name)
-
+		size := max(s.Config.Services[service.name].PoolSize, 1)
+		conns := make([]*grpc.ClientConn, 0, size)
+		for range size {
+			conn, err := s.connectToService(service.name, address)
+			if err != nil {
+				s.Logger.Error("Failed to connect to service",
+					"service", service.name, "error", err)
+				_ = newConnPool(conns).Close()
+				return fmt.Errorf("service %s not available: %w", service.name, err)
+			}
+			conns = append(conns, conn)
 		}
+		pool := newConnPool(conns)
 
 		s.connMutex.Lock()
-		s.grpcConnections[service.name] = conn
+		s.grpcConnections[service.name] = pool
 		s.connMutex.Unlock()
 
-		service.init(conn)
-		s.Logger.Info("Successfully connected to service", "service", service.name)
+		service.init(pool)
+
+		if s.Config.GRPC.RequireDownstreamOnStart {
+			if err := s.probeService(service.name, pool); err != nil {
+				s.Logger.Error("Service not ready at startup", "service", service.name, "error", err)
+				return fmt.Errorf("service %s not available: %w", service.name, err)
+			}
+		}
+		s.Logger.Info("Successfully connected to service", "service", service.name, "pool_size", size)
 	}
 
 	s.Logger.Info("Server initialization completed successfully")
 	return nil
 }
 
+// probeService waits for the pool to connect and for the service's Health RPC to pass.
+// grpc.NewClient never dials, so without it a dead service only shows on the first request
+func (s *Server) probeService(serviceName string, pool *connPool) error {
+	ctx, cancel := context.WithTimeout(context.Background(), s.Config.GRPC.ConnectionTimeout)
+	defer cancel()
+
+	if err := pool.WaitReady(ctx); err != nil {
+		return err
+	}
+
+	check, ok := s.healthChecks[serviceName]
+	if !ok {
+		return nil
+	}
+	status, _, err := check(ctx)
+	if err != nil {
+		return fmt.Errorf("health check failed: %w", err)
+	}
+	if status == "unhealthy" {
+		return fmt.Errorf("service reports %s", status)
+	}
+	return nil
+}
+
 func (s *Server) connectToService(serviceName, address string) (*grpc.ClientConn, error) {
 	s.Logger.Info("Connecting to gRPC service",
 		"service", serviceName,
 		"address", address)
 
-	// Create connection with retry interceptor
+	// retry wraps compression so every attempt is compressed the same way
+	interceptors := []grpc.UnaryClientInterceptor{retryUnaryInterceptor(s.Config.Retry, s.Logger)}
+	if s.Config.GRPC.Compression {
+		interceptors = append(interceptors, compressionUnaryInterceptor(s.Config.GRPC.CompressionMinBytes))
+	}
+
 	conn, err := grpc.NewClient(address,
 		grpc.WithTransportCredentials(insecure.NewCredentials()),
 		grpc.WithConnectParams(grpc.ConnectParams{
@@ -145,6 +241,7 @@ func (s *Server) connectToService(serviceName, address string) (*grpc.ClientConn
 			Timeout:             5 * time.Second,
 			PermitWithoutStream: true,
 		}),
+		grpc.WithChainUnaryInterceptor(interceptors...),
 	)
 	if err != nil {
 		s.Logger.Error("failed to create gRPC client",
@@ -200,6 +297,7 @@ func (s *Server) shutdown(ctx context.Context, cancel context.CancelFunc) error
 	// Setup signal handling
 	sigChan := make(chan os.Signal, 1)
 	signal.Notify(sigChan, syscall.SIGINT, syscall.SIGTERM)
+	defer signal.Stop(sigChan)
 
 	select {
 	case sig := <-sigChan:
@@ -211,13 +309,24 @@ func (s *Server) shutdown(ctx context.Context, cancel context.CancelFunc) error
 
 	// Start cleanup
 	s.Logger.Info("Starting graceful shutdown")
+
+	// requests still running need their gRPC connections, drain them first
+	s.Logger.Info("Waiting for in-flight requests", "in_flight", s.inFlight.Count())
+	drainCtx, drainCancel := context.WithTimeout(context.Background(), s.Config.HTTP.ShutdownTimeout)
+	if s.inFlight.Wait(drainCtx) {
+		s.Logger.Info("In-flight requests drained")
+	} else {
+		s.Logger.Warn("Drain timeout, closing with requests still in flight", "in_flight", s.inFlight.Count())
+	}
+	drainCancel()
+
 	s.Logger.Info("Starting cleanup process")
 
 	// Close GRPC connections
 	s.connMutex.Lock()
-	for name, conn := range s.grpcConnections {
-		if conn != nil {
-			if err := conn.Close(); err != nil {
+	for name, pool := range s.grpcConnections {
+		if pool != nil {
+			if err := pool.Close(); err != nil {
 				s.Logger.Error("Failed to close GRPC connection",
 					"service", name,
 					"error", err,
@@ -0,0 +1,102 @@
+package server
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/connectivity"
+	"google.golang.org/grpc/credentials/insecure"
+
+	cfg "remaster/shared"
+)
+
+func freePort(t *testing.T) string {
+	t.Helper()
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	defer lis.Close()
+	_, port, _ := net.SplitHostPort(lis.Addr().String())
+	return port
+}
+
+func TestServeDrainsInFlightRequestsBeforeClosingConnections(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	conn, err := grpc.NewClient("127.0.0.1:1", grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatalf("grpc client: %v", err)
+	}
+
+	config := &cfg.Config{HTTP: cfg.HTTPConfig{Host: "127.0.0.1", Port: freePort(t), ShutdownTimeout: 5 * time.Second}}
+	s := NewServer(config, slog.New(slog.NewTextHandler(io.Discard, nil)), nil, nil)
+	s.grpcConnections["auth"] = newConnPool([]*grpc.ClientConn{conn})
+
+	started := make(chan struct{})
+	stateAtEnd := make(chan connectivity.State, 1)
+	s.router = gin.New()
+	s.router.Use(s.inFlight.Middleware())
+	s.router.GET("/slow", func(c *gin.Context) {
+		close(started)
+		time.Sleep(300 * time.Millisecond)
+		stateAtEnd <- conn.GetState()
+		c.String(http.StatusOK, "done")
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	served := make(chan error, 1)
+	go func() { served <- s.serve(ctx) }()
+
+	url := "http://" + net.JoinHostPort(config.HTTP.Host, config.HTTP.Port) + "/slow"
+	respCh := make(chan *http.Response, 1)
+	go func() {
+		for range 50 {
+			resp, err := http.Get(url)
+			if err == nil {
+				respCh <- resp
+				return
+			}
+			time.Sleep(20 * time.Millisecond)
+		}
+		close(respCh)
+	}()
+
+	select {
+	case <-started:
+	case <-time.After(2 * time.Second):
+		t.Fatal("slow request never started")
+	}
+	cancel()
+
+	resp, ok := <-respCh
+	if !ok {
+		t.Fatal("request failed")
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("status = %d, want 200", resp.StatusCode)
+	}
+	if state := <-stateAtEnd; state == connectivity.Shutdown {
+		t.Fatal("gRPC connection was closed while the request was still running")
+	}
+
+	select {
+	case err := <-served:
+		if err != nil {
+			t.Fatalf("serve: %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("serve did not return after shutdown")
+	}
+	if state := conn.GetState(); state != connectivity.Shutdown {
+		t.Fatalf("connection state after serve = %s, want SHUTDOWN", state)
+	}
+}
@@ -0,0 +1,117 @@
+package server
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	auth_pb "remaster/shared/proto/auth"
+	media_pb "remaster/shared/proto/media"
+
+	"google.golang.org/grpc"
+
+	cfg "remaster/shared"
+)
+
+type statusAuth struct {
+	auth_pb.UnimplementedAuthServiceServer
+	status string
+}
+
+func (a statusAuth) Health(context.Context, *auth_pb.HealthRequest) (*auth_pb.HealthResponse, error) {
+	return &auth_pb.HealthResponse{Status: a.status}, nil
+}
+
+type statusMedia struct {
+	media_pb.UnimplementedMediaServiceServer
+	status string
+}
+
+func (m statusMedia) Health(context.Context, *media_pb.HealthRequest) (*media_pb.HealthResponse, error) {
+	return &media_pb.HealthResponse{Status: m.status}, nil
+}
+
+// serveDownstream runs auth and media on one local port, both answering Health with status
+func serveDownstream(t *testing.T, status string) string {
+	t.Helper()
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	srv := grpc.NewServer()
+	auth_pb.RegisterAuthServiceServer(srv, statusAuth{status: status})
+	media_pb.RegisterMediaServiceServer(srv, statusMedia{status: status})
+	go func() { _ = srv.Serve(lis) }()
+	t.Cleanup(srv.Stop)
+
+	_, port, _ := net.SplitHostPort(lis.Addr().String())
+	return port
+}
+
+// initClients runs the gateway's startup dial against port, failFast sets require_downstream_on_start
+func initClients(t *testing.T, port string, failFast bool) (time.Duration, error) {
+	t.Helper()
+	config := &cfg.Config{
+		GRPC: cfg.GRPCConfig{ConnectionTimeout: time.Second, RequireDownstreamOnStart: failFast},
+		Services: map[string]cfg.ServiceAddr{
+			"auth":  {Host: "127.0.0.1", GRPCPort: port, PoolSize: 2},
+			"media": {Host: "127.0.0.1", GRPCPort: port},
+		},
+	}
+	s := NewServer(config, slog.New(slog.NewTextHandler(io.Discard, nil)), nil, nil)
+	t.Cleanup(func() {
+		for _, pool := range s.grpcConnections {
+			_ = pool.Close()
+		}
+	})
+
+	start := time.Now()
+	err := s.initializeGRPCClients()
+	return time.Since(start), err
+}
+
+func TestStartupWithDownstreamDown(t *testing.T) {
+	dead := freePort(t)
+
+	t.Run("lazy", func(t *testing.T) {
+		// grpc.NewClient doesn't dial, calls fail per request later on
+		if _, err := initClients(t, dead, false); err != nil {
+			t.Fatalf("lazy startup = %v, want it to go ahead", err)
+		}
+	})
+
+	t.Run("fail fast", func(t *testing.T) {
+		elapsed, err := initClients(t, dead, true)
+		if err == nil || !strings.Contains(err.Error(), "service auth not available") {
+			t.Fatalf("startup = %v, want auth reported unavailable", err)
+		}
+		if elapsed > 3*time.Second {
+			t.Fatalf("gave up after %v, want about the 1s connection timeout", elapsed)
+		}
+	})
+}
+
+func TestStartupWithDownstreamUp(t *testing.T) {
+	tests := []struct {
+		name     string
+		status   string
+		failFast bool
+		wantErr  bool
+	}{
+		{"fail fast and healthy", "ok", true, false},
+		{"fail fast and unhealthy", "unhealthy", true, true},
+		{"lazy and unhealthy", "unhealthy", false, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := initClients(t, serveDownstream(t, tt.status), tt.failFast)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("startup = %v, want error %v", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -4,10 +4,20 @@ import (
 	"log/slog"
 	"net/http"
 	"remaster/shared/errors"
+	"remaster/shared/validation"
 
 	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+	"github.com/go-playground/validator/v10"
 )
 
+// RegisterBindingTagNames makes gin's binding validator report json field names
+func RegisterBindingTagNames() {
+	if v, ok := binding.Validator.Engine().(*validator.Validate); ok {
+		v.RegisterTagNameFunc(validation.JSONTagName)
+	}
+}
+
 func BindAndValidate[T any](c *gin.Context, logger *slog.Logger) (*T, bool) {
 	var dto T
 	if err := c.ShouldBindJSON(&dto); err != nil {
@@ -15,6 +25,11 @@ func BindAndValidate[T any](c *gin.Context, logger *slog.Logger) (*T, bool) {
 			"Validation failed",
 			slog.Any("validation_errors", err.Error()),
 		)
+		if ve, ok := err.(validator.ValidationErrors); ok {
+			c.Error(errors.NewValidationError("Request data is invalid", validation.FieldErrors(ve)))
+			return nil, false
+		}
+		// malformed JSON, wrong types etc.
 		c.Error(errors.NewValidationError(
 			"Request data is invalid",
 			map[string]string{
@@ -24,13 +39,53 @@ func BindAndValidate[T any](c *gin.Context, logger *slog.Logger) (*T, bool) {
 		))
 		return nil, false
 	}
+	if err := validation.Validate(&dto); err != nil {
+		logger.WarnContext(c.Request.Context(),
+			"Validation failed",
+			slog.Any("validation_errors", err.Error()),
+		)
+		c.Error(err)
+		return nil, false
+	}
 	return &dto, true
 }
 
-func SuccessResponse(c *gin.Context, msg string, data any) {
-	c.JSON(http.StatusOK, gin.H{
+// Pagination is sent next to the items by PaginatedResponse, NextCursor only for cursor paging
+type Pagination struct {
+	Total      int64  `json:"total"`
+	Page       int64  `json:"page"`
+	PageSize   int64  `json:"page_size"`
+	TotalPages int64  `json:"total_pages"`
+	NextCursor string `json:"next_cursor,omitempty"`
+}
+
+// Respond writes the {success, message, data} envelope with any 2xx status
+func Respond(c *gin.Context, status int, msg string, data any) {
+	c.JSON(status, gin.H{
 		"success": true,
 		"message": msg,
 		"data":    data,
 	})
 }
+
+func SuccessResponse(c *gin.Context, msg string, data any) {
+	Respond(c, http.StatusOK, msg, data)
+}
+
+func CreatedResponse(c *gin.Context, msg string, data any) {
+	Respond(c, http.StatusCreated, msg, data)
+}
+
+// PaginatedResponse puts items in data and the page metadata in pagination,
+// an empty page is sent as [] rather than null
+func PaginatedResponse[T any](c *gin.Context, msg string, items []T, page Pagination) {
+	if items == nil {
+		items = []T{}
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"success":    true,
+		"message":    msg,
+		"data":       items,
+		"pagination": page,
+	})
+}
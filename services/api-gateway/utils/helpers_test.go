@@ -0,0 +1,180 @@
+package utils
+
+import (
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"remaster/shared/errors"
+
+	"github.com/gin-gonic/gin"
+)
+
+type registerDTO struct {
+	Email    string `json:"email" validate:"required,email"`
+	Password string `json:"password" validate:"required,min=8"`
+	UserType string `json:"user_type" validate:"required,oneof=client master"`
+}
+
+type oauthDTO struct {
+	Provider string `json:"provider" binding:"required,oneof=google facebook"`
+}
+
+type deviceDTO struct {
+	DeviceID string `json:"device_id" binding:"required"`
+	Platform string `json:"platform" binding:"required,oneof=ios android web"`
+	Name     string `json:"name" binding:"max=5"`
+}
+
+// bind runs BindAndValidate on body and returns the dto and the recorded error
+func bind[T any](t *testing.T, body string) (*T, *errors.AppError) {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+	RegisterBindingTagNames()
+
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+	c.Request = httptest.NewRequest(http.MethodPost, "/api/v1/auth/register", strings.NewReader(body))
+	c.Request.Header.Set("Content-Type", "application/json")
+
+	dto, ok := BindAndValidate[T](c, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	if ok != (len(c.Errors) == 0) {
+		t.Fatalf("ok = %v with errors %v", ok, c.Errors)
+	}
+	if !ok {
+		appErr, isApp := errors.AsAppError(c.Errors.Last().Err)
+		if !isApp {
+			t.Fatalf("recorded error %v is not an AppError", c.Errors.Last().Err)
+		}
+		return nil, appErr
+	}
+	return dto, nil
+}
+
+func TestBindAndValidateReportsFieldErrors(t *testing.T) {
+	_, appErr := bind[registerDTO](t, `{"email":"nope","password":"short","user_type":"admin"}`)
+	if appErr == nil || appErr.Code != errors.CodeValidation {
+		t.Fatalf("error = %v, want a validation error", appErr)
+	}
+	want := map[string]string{
+		"email":     "must be a valid email address",
+		"password":  "must be at least 8 characters long",
+		"user_type": "must be one of: client, master",
+	}
+	for field, msg := range want {
+		if got := appErr.Details[field]; got != msg {
+			t.Errorf("details[%q] = %q, want %q", field, got, msg)
+		}
+	}
+}
+
+func TestBindAndValidateReportsBindingTagsByJSONName(t *testing.T) {
+	_, appErr := bind[oauthDTO](t, `{"provider":"myspace"}`)
+	if appErr == nil {
+		t.Fatal("invalid provider accepted")
+	}
+	if got := appErr.Details["provider"]; got != "must be one of: google, facebook" {
+		t.Fatalf("details = %v, want a provider violation", appErr.Details)
+	}
+}
+
+func TestBindAndValidateReportsEveryBindingFailure(t *testing.T) {
+	_, appErr := bind[deviceDTO](t, `{"platform":"palm","name":"far too long"}`)
+	if appErr == nil || appErr.Code != errors.CodeValidation {
+		t.Fatalf("error = %v, want a validation error", appErr)
+	}
+	want := map[string]string{
+		"device_id": "is required",
+		"platform":  "must be one of: ios, android, web",
+		"name":      "must be at most 5 characters long",
+	}
+	for field, msg := range want {
+		if got := appErr.Details[field]; got != msg {
+			t.Errorf("details[%q] = %q, want %q", field, got, msg)
+		}
+	}
+}
+
+func TestBindAndValidateRejectsMalformedJSON(t *testing.T) {
+	_, appErr := bind[registerDTO](t, `{"email":`)
+	if appErr == nil || appErr.Code != errors.CodeValidation {
+		t.Fatalf("error = %v, want a validation error", appErr)
+	}
+	if appErr.Details["field"] != "request_body" {
+		t.Fatalf("details = %v, want the request body reported", appErr.Details)
+	}
+}
+
+func TestBindAndValidateReturnsValidDTO(t *testing.T) {
+	dto, appErr := bind[registerDTO](t, `{"email":"user@example.com","password":"long-enough","user_type":"client"}`)
+	if appErr != nil {
+		t.Fatalf("error = %v, want none", appErr)
+	}
+	if dto.Email != "user@example.com" || dto.UserType != "client" {
+		t.Fatalf("dto = %+v", dto)
+	}
+}
+
+// respond records what write sends and decodes the envelope
+func respond(t *testing.T, write func(c *gin.Context)) (int, map[string]json.RawMessage) {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+	rec := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(rec)
+	write(c)
+
+	var body map[string]json.RawMessage
+	if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+		t.Fatalf("decode %s: %v", rec.Body, err)
+	}
+	return rec.Code, body
+}
+
+func TestResponseHelpersEnvelope(t *testing.T) {
+	data := map[string]string{"id": "u1"}
+	tests := []struct {
+		name   string
+		write  func(c *gin.Context)
+		status int
+	}{
+		{"success", func(c *gin.Context) { SuccessResponse(c, "ok", data) }, http.StatusOK},
+		{"created", func(c *gin.Context) { CreatedResponse(c, "ok", data) }, http.StatusCreated},
+		{"respond", func(c *gin.Context) { Respond(c, http.StatusAccepted, "ok", data) }, http.StatusAccepted},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			status, body := respond(t, tt.write)
+			if status != tt.status {
+				t.Fatalf("status = %d, want %d", status, tt.status)
+			}
+			if len(body) != 3 || string(body["success"]) != "true" || string(body["message"]) != `"ok"` ||
+				string(body["data"]) != `{"id":"u1"}` {
+				t.Fatalf("envelope = %s, want {success, message, data}", body)
+			}
+		})
+	}
+}
+
+func TestPaginatedResponse(t *testing.T) {
+	page := Pagination{Total: 3, Page: 2, PageSize: 2, TotalPages: 2}
+	status, body := respond(t, func(c *gin.Context) { PaginatedResponse(c, "ok", []string{"c"}, page) })
+	if status != http.StatusOK || string(body["success"]) != "true" || string(body["data"]) != `["c"]` {
+		t.Fatalf("status %d, envelope %s", status, body)
+	}
+	if got := string(body["pagination"]); got != `{"total":3,"page":2,"page_size":2,"total_pages":2}` {
+		t.Fatalf("pagination = %s", got)
+	}
+
+	_, body = respond(t, func(c *gin.Context) {
+		PaginatedResponse[string](c, "ok", nil, Pagination{PageSize: 2, NextCursor: "abc"})
+	})
+	if string(body["data"]) != "[]" {
+		t.Fatalf("empty page data = %s, want []", body["data"])
+	}
+	if got := string(body["pagination"]); !strings.Contains(got, `"next_cursor":"abc"`) {
+		t.Fatalf("pagination = %s, want the next cursor", got)
+	}
+}
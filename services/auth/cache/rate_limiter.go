@@ -13,32 +13,39 @@ const (
 	LockoutDuration  = 15 * time.Minute
 )
 
+// IPLimit throttles failed logins per client address across all emails
+type IPLimit struct {
+	MaxAttempts int
+	Window      time.Duration
+}
+
 type RateLimiter struct {
-	client *redis.Client
+	client redis.UniversalClient
 }
 
-func NewRateLimiter(client *redis.Client) *RateLimiter {
+func NewRateLimiter(client redis.UniversalClient) *RateLimiter {
 	return &RateLimiter{client: client}
 }
 
-// Check quantity of login attempts
-func (rl *RateLimiter) CheckLoginAttempts(ctx context.Context, email string) (bool, int, error) {
+// CheckLoginAttempts reports whether the email may try another login and, when it
+// may not, how long until the counter expires. err is only set when Redis fails
+func (rl *RateLimiter) CheckLoginAttempts(ctx context.Context, email string) (bool, int, time.Duration, error) {
 	key := fmt.Sprintf("login:attempts:%s", email)
 
 	count, err := rl.client.Get(ctx, key).Int()
 	if err == redis.Nil {
-		return true, 0, nil // first attempt
+		return true, 0, 0, nil // first attempt
 	}
 	if err != nil {
-		return false, 0, err
+		return false, 0, 0, err
 	}
 
 	if count >= MaxLoginAttempts {
 		ttl, _ := rl.client.TTL(ctx, key).Result()
-		return false, count, fmt.Errorf("too many login attempts, try again in %s", ttl)
+		return false, count, ttl, nil
 	}
 
-	return true, count, nil
+	return true, count, 0, nil
 }
 
 func (rl *RateLimiter) IncrementLoginAttempts(ctx context.Context, email string) error {
@@ -56,3 +63,34 @@ func (rl *RateLimiter) ResetLoginAttempts(ctx context.Context, email string) err
 	key := fmt.Sprintf("login:attempts:%s", email)
 	return rl.client.Del(ctx, key).Err()
 }
+
+// CheckIPLoginAttempts reports whether the address may try another login
+func (rl *RateLimiter) CheckIPLoginAttempts(ctx context.Context, ip string, limit IPLimit) (bool, time.Duration, error) {
+	key := fmt.Sprintf("login:ip:%s", ip)
+
+	count, err := rl.client.Get(ctx, key).Int()
+	if err == redis.Nil {
+		return true, 0, nil
+	}
+	if err != nil {
+		return false, 0, err
+	}
+
+	if count >= limit.MaxAttempts {
+		ttl, _ := rl.client.TTL(ctx, key).Result()
+		return false, ttl, nil
+	}
+	return true, 0, nil
+}
+
+// IncrementIPLoginAttempts counts a failed login, the window starts at the first failure
+func (rl *RateLimiter) IncrementIPLoginAttempts(ctx context.Context, ip string, limit IPLimit) error {
+	key := fmt.Sprintf("login:ip:%s", ip)
+
+	pipe := rl.client.Pipeline()
+	pipe.Incr(ctx, key)
+	pipe.ExpireNX(ctx, key, limit.Window)
+
+	_, err := pipe.Exec(ctx)
+	return err
+}
@@ -3,92 +3,134 @@ package cache
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"time"
 
+	"remaster/services/auth/utils"
+	"remaster/shared/connection"
+
 	"github.com/redis/go-redis/v9"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
 type RefreshTokenData struct {
 	UserID    string    `json:"user_id"`
-	Token     string    `json:"token"`
 	ExpiresAt time.Time `json:"expires_at"`
 	CreatedAt time.Time `json:"created_at"`
 }
 
 type RefreshTokenStore struct {
-	client *redis.Client
+	client redis.UniversalClient
 }
 
-func NewRefreshTokenStore(client *redis.Client) *RefreshTokenStore {
+func NewRefreshTokenStore(client redis.UniversalClient) *RefreshTokenStore {
 	return &RefreshTokenStore{client: client}
 }
 
+// keys hold the token hash, same as mongo, so neither store leaks usable tokens
+func tokenKey(hash string) string {
+	return fmt.Sprintf("refresh:token:%s", hash)
+}
+
+// set of the user's token keys, so revoking all of them doesn't scan every token
+func userTokensKey(userID string) string {
+	return fmt.Sprintf("user:tokens:%s", userID)
+}
+
+// how many keys RevokeAllUserTokens deletes per round trip
+const revokeBatchSize = 100
+
 func (rts *RefreshTokenStore) SaveRefreshToken(ctx context.Context, userID primitive.ObjectID, token string, expiresAt time.Time) error {
-	key := fmt.Sprintf("refresh:token:%s", token)
+	key := tokenKey(utils.HashRefreshToken(token))
+	setKey := userTokensKey(userID.Hex())
+	ttl := time.Until(expiresAt)
 
 	data := RefreshTokenData{
 		UserID:    userID.Hex(),
-		Token:     token,
 		ExpiresAt: expiresAt,
 		CreatedAt: time.Now(),
 	}
 
-	jsonData, err := json.Marshal(data)
-	if err != nil {
-		return err
-	}
-
-	ttl := time.Until(expiresAt)
-	return rts.client.Set(ctx, key, jsonData, ttl).Err()
+	// not a transaction, the keys may live on different cluster slots. Tokens share one
+	// lifetime, so the newest token's ttl also covers every older member of the set
+	_, err := rts.client.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		if err := connection.SetJSON(ctx, pipe, key, data, ttl); err != nil {
+			return err
+		}
+		pipe.SAdd(ctx, setKey, key)
+		pipe.Expire(ctx, setKey, ttl)
+		return nil
+	})
+	return err
 }
 
 func (rts *RefreshTokenStore) FindRefreshToken(ctx context.Context, token string) (*RefreshTokenData, error) {
-	key := fmt.Sprintf("refresh:token:%s", token)
+	key := tokenKey(utils.HashRefreshToken(token))
 
-	data, err := rts.client.Get(ctx, key).Bytes()
-	if err == redis.Nil {
-		return nil, fmt.Errorf("refresh token not found or expired")
-	}
+	tokenData, ok, err := connection.GetJSON[RefreshTokenData](ctx, rts.client, key)
 	if err != nil {
 		return nil, err
 	}
-
-	var tokenData RefreshTokenData
-	if err := json.Unmarshal(data, &tokenData); err != nil {
-		return nil, err
+	if !ok {
+		return nil, fmt.Errorf("refresh token not found or expired")
 	}
 
 	return &tokenData, nil
 }
 
 func (rts *RefreshTokenStore) RevokeRefreshToken(ctx context.Context, token string) error {
-	key := fmt.Sprintf("refresh:token:%s", token)
-	return rts.client.Del(ctx, key).Err()
+	return rts.RevokeRefreshTokenHash(ctx, utils.HashRefreshToken(token))
 }
 
-func (rts *RefreshTokenStore) RevokeAllUserTokens(ctx context.Context, userID primitive.ObjectID) error {
-	pattern := "refresh:token:*"
+// RevokeRefreshTokenHash is for callers that only have the stored hash, e.g. session eviction
+func (rts *RefreshTokenStore) RevokeRefreshTokenHash(ctx context.Context, hash string) error {
+	key := tokenKey(hash)
 
-	iter := rts.client.Scan(ctx, 0, pattern, 100).Iterator()
-	for iter.Next(ctx) {
-		key := iter.Val()
+	raw, err := rts.client.GetDel(ctx, key).Bytes()
+	if errors.Is(err, redis.Nil) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
 
-		data, err := rts.client.Get(ctx, key).Bytes()
-		if err != nil {
-			continue
-		}
+	var data RefreshTokenData
+	if err := json.Unmarshal(raw, &data); err != nil {
+		// the token is gone, a stale set member only costs a no-op delete later
+		return nil
+	}
+	return rts.client.SRem(ctx, userTokensKey(data.UserID), key).Err()
+}
 
-		var tokenData RefreshTokenData
-		if err := json.Unmarshal(data, &tokenData); err != nil {
-			continue
-		}
+// RevokeAllUserTokens deletes the user's tokens in batches and stops early once ctx is done
+func (rts *RefreshTokenStore) RevokeAllUserTokens(ctx context.Context, userID primitive.ObjectID) error {
+	setKey := userTokensKey(userID.Hex())
+
+	keys, err := rts.client.SMembers(ctx, setKey).Result()
+	if err != nil {
+		return err
+	}
 
-		if tokenData.UserID == userID.Hex() {
-			_ = rts.client.Del(ctx, key)
+	for start := 0; start < len(keys); start += revokeBatchSize {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		batch := keys[start:min(start+revokeBatchSize, len(keys))]
+
+		// one DEL per key, a multi key DEL fails across cluster slots
+		_, err := rts.client.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+			for _, key := range batch {
+				pipe.Del(ctx, key)
+			}
+			pipe.SRem(ctx, setKey, batch)
+			return nil
+		})
+		if err != nil {
+			return err
 		}
 	}
 
-	return iter.Err()
+	// no DEL of the set itself, it would drop a token saved while we were revoking
+	return nil
 }
@@ -0,0 +1,163 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"remaster/services/auth/utils"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func newTestStore(t *testing.T) (*RefreshTokenStore, *miniredis.Miniredis) {
+	t.Helper()
+	mr := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() { _ = client.Close() })
+	return NewRefreshTokenStore(client), mr
+}
+
+// assertNotStored fails when raw shows up in any key, string value or set member
+func assertNotStored(t *testing.T, mr *miniredis.Miniredis, raw string) {
+	t.Helper()
+	for _, key := range mr.Keys() {
+		if strings.Contains(key, raw) {
+			t.Fatalf("key %q contains the raw token", key)
+		}
+		switch mr.Type(key) {
+		case "string":
+			if value, _ := mr.Get(key); strings.Contains(value, raw) {
+				t.Fatalf("value of %q contains the raw token", key)
+			}
+		case "set":
+			members, _ := mr.Members(key)
+			for _, member := range members {
+				if strings.Contains(member, raw) {
+					t.Fatalf("member of %q contains the raw token", key)
+				}
+			}
+		}
+	}
+}
+
+func TestRefreshTokenStoreKeepsOnlyHashes(t *testing.T) {
+	store, mr := newTestStore(t)
+	ctx := context.Background()
+	userID := primitive.NewObjectID()
+	const raw = "9c2e4f6a8b0d1e3f5a7c9e1b3d5f7a9c"
+
+	if err := store.SaveRefreshToken(ctx, userID, raw, time.Now().Add(time.Hour)); err != nil {
+		t.Fatalf("SaveRefreshToken: %v", err)
+	}
+	assertNotStored(t, mr, raw)
+	if !mr.Exists(tokenKey(utils.HashRefreshToken(raw))) {
+		t.Fatalf("keys = %v, want the token stored under its hash", mr.Keys())
+	}
+
+	data, err := store.FindRefreshToken(ctx, raw)
+	if err != nil {
+		t.Fatalf("FindRefreshToken: %v", err)
+	}
+	if data.UserID != userID.Hex() {
+		t.Fatalf("UserID = %q, want %q", data.UserID, userID.Hex())
+	}
+}
+
+func TestRefreshTokenStoreRotateAndRevokeByHash(t *testing.T) {
+	store, _ := newTestStore(t)
+	ctx := context.Background()
+	userID := primitive.NewObjectID()
+
+	// rotation saves the new token and revokes the old one by its raw value
+	if err := store.SaveRefreshToken(ctx, userID, "old-token", time.Now().Add(time.Hour)); err != nil {
+		t.Fatalf("SaveRefreshToken: %v", err)
+	}
+	if err := store.SaveRefreshToken(ctx, userID, "new-token", time.Now().Add(time.Hour)); err != nil {
+		t.Fatalf("SaveRefreshToken: %v", err)
+	}
+	if err := store.RevokeRefreshToken(ctx, "old-token"); err != nil {
+		t.Fatalf("RevokeRefreshToken: %v", err)
+	}
+	if _, err := store.FindRefreshToken(ctx, "old-token"); err == nil {
+		t.Fatal("rotated token still found")
+	}
+	if _, err := store.FindRefreshToken(ctx, "new-token"); err != nil {
+		t.Fatalf("new token lost in the rotation: %v", err)
+	}
+
+	// session eviction only knows the stored hash
+	if err := store.RevokeRefreshTokenHash(ctx, utils.HashRefreshToken("new-token")); err != nil {
+		t.Fatalf("RevokeRefreshTokenHash: %v", err)
+	}
+	if _, err := store.FindRefreshToken(ctx, "new-token"); err == nil {
+		t.Fatal("token revoked by hash still found")
+	}
+}
+
+func TestRevokeAllUserTokensLeavesOtherUsersAlone(t *testing.T) {
+	store, mr := newTestStore(t)
+	ctx := context.Background()
+	expiresAt := time.Now().Add(time.Hour)
+	alice, bob := primitive.NewObjectID(), primitive.NewObjectID()
+
+	// more than one batch, so the loop is exercised past the first round trip
+	aliceTokens := make([]string, revokeBatchSize+5)
+	for i := range aliceTokens {
+		aliceTokens[i] = fmt.Sprintf("alice-token-%d", i)
+		if err := store.SaveRefreshToken(ctx, alice, aliceTokens[i], expiresAt); err != nil {
+			t.Fatalf("SaveRefreshToken: %v", err)
+		}
+	}
+	bobTokens := []string{"bob-token-1", "bob-token-2"}
+	for _, token := range bobTokens {
+		if err := store.SaveRefreshToken(ctx, bob, token, expiresAt); err != nil {
+			t.Fatalf("SaveRefreshToken: %v", err)
+		}
+	}
+
+	if err := store.RevokeAllUserTokens(ctx, alice); err != nil {
+		t.Fatalf("RevokeAllUserTokens: %v", err)
+	}
+
+	for _, token := range aliceTokens {
+		if _, err := store.FindRefreshToken(ctx, token); err == nil {
+			t.Fatalf("%s still found after revoke-all", token)
+		}
+	}
+	if members, _ := mr.Members(userTokensKey(alice.Hex())); len(members) != 0 {
+		t.Fatalf("alice's set still holds %d keys", len(members))
+	}
+	for _, token := range bobTokens {
+		if _, err := store.FindRefreshToken(ctx, token); err != nil {
+			t.Fatalf("%s of another user revoked: %v", token, err)
+		}
+	}
+	if members, _ := mr.Members(userTokensKey(bob.Hex())); len(members) != len(bobTokens) {
+		t.Fatalf("bob's set holds %d keys, want %d", len(members), len(bobTokens))
+	}
+}
+
+func TestRevokeAllUserTokensStopsOnCancelledContext(t *testing.T) {
+	store, mr := newTestStore(t)
+	userID := primitive.NewObjectID()
+	for i := 0; i < 3; i++ {
+		if err := store.SaveRefreshToken(context.Background(), userID, fmt.Sprintf("token-%d", i), time.Now().Add(time.Hour)); err != nil {
+			t.Fatalf("SaveRefreshToken: %v", err)
+		}
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := store.RevokeAllUserTokens(ctx, userID); !errors.Is(err, context.Canceled) {
+		t.Fatalf("RevokeAllUserTokens = %v, want context.Canceled", err)
+	}
+	if members, _ := mr.Members(userTokensKey(userID.Hex())); len(members) != 3 {
+		t.Fatalf("set holds %d keys after a cancelled revoke, want all 3", len(members))
+	}
+}
@@ -9,10 +9,10 @@ import (
 )
 
 type TokenBlacklist struct {
-	client *redis.Client
+	client redis.UniversalClient
 }
 
-func NewTokenBlacklist(client *redis.Client) *TokenBlacklist {
+func NewTokenBlacklist(client redis.UniversalClient) *TokenBlacklist {
 	return &TokenBlacklist{client: client}
 }
 
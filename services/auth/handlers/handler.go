@@ -3,7 +3,13 @@ package handlers
 import (
 	"context"
 	"log/slog"
+	"net"
+	"net/netip"
+	"strings"
+	"time"
 
+	"remaster/shared/connection"
+	"remaster/shared/db"
 	"remaster/shared/errors"
 	pb "remaster/shared/proto/auth"
 
@@ -19,12 +25,25 @@ type AuthHandler struct {
 	pb.UnimplementedAuthServiceServer
 	errorHandler *errors.ErrorHandler
 	authService  *services.AuthService
+	healthChecks map[string]connection.HealthChecker
+	trustedPeers []netip.Prefix
 	logger       *slog.Logger
 }
 
-func NewAuthHandler(authService *services.AuthService, errorHandler *errors.ErrorHandler, logger *slog.Logger) *AuthHandler {
+// per dependency, checks run concurrently and have to answer within the gateway's 2s
+const healthCheckTimeout = 1500 * time.Millisecond
+
+func NewAuthHandler(
+	authService *services.AuthService,
+	errorHandler *errors.ErrorHandler,
+	healthChecks map[string]connection.HealthChecker,
+	trustedPeers []netip.Prefix,
+	logger *slog.Logger,
+) *AuthHandler {
 	return &AuthHandler{
 		authService:  authService,
+		healthChecks: healthChecks,
+		trustedPeers: trustedPeers,
 		logger:       logger.With(slog.String("auth", "handler")),
 		errorHandler: errorHandler,
 	}
@@ -33,18 +52,9 @@ func NewAuthHandler(authService *services.AuthService, errorHandler *errors.Erro
 func (h *AuthHandler) Registration(ctx context.Context, req *pb.RegisterRequest) (*pb.RegisterResponse, error) {
 	h.logger.Info("Registration request", "email", req.Email)
 
-	metadata := extractRequestMetadata(ctx)
-
-	registerReq := &models.RegisterRequest{
-		Email:     req.Email,
-		Password:  req.Password,
-		FirstName: req.FirstName,
-		LastName:  req.LastName,
-		Phone:     req.Phone,
-		UserType:  models.UserType(req.UserType),
-	}
+	metadata := h.extractRequestMetadata(ctx)
 
-	resp, err := h.authService.CreateUser(ctx, registerReq, metadata)
+	resp, err := h.authService.CreateUser(ctx, registerRequest(req), metadata)
 	if err != nil {
 		h.logger.Error("Registration failed", "error", err, "email", req.Email)
 		return nil, h.errorHandler.HandleGrpcError(err)
@@ -70,14 +80,9 @@ func (h *AuthHandler) Registration(ctx context.Context, req *pb.RegisterRequest)
 func (h *AuthHandler) Login(ctx context.Context, req *pb.LoginRequest) (*pb.LoginResponse, error) {
 	h.logger.Info("Login request", "email", req.Email)
 
-	metadata := extractRequestMetadata(ctx)
-
-	loginReq := &models.LoginRequest{
-		Email:    req.Email,
-		Password: req.Password,
-	}
+	metadata := h.extractRequestMetadata(ctx)
 
-	resp, err := h.authService.AuthenticateUser(ctx, loginReq, metadata)
+	resp, err := h.authService.AuthenticateUser(ctx, loginRequest(req), metadata)
 	if err != nil {
 		h.logger.Error("Login failed", "error", err, "email", req.Email)
 		return nil, h.errorHandler.HandleGrpcError(err)
@@ -94,6 +99,11 @@ func (h *AuthHandler) Login(ctx context.Context, req *pb.LoginRequest) (*pb.Logi
 		IsActive:     resp.User.IsActive,
 		IsVerified:   resp.User.IsVerified,
 	}
+	if resp.PasswordExpired {
+		pbResp.Message = "Password expired, change it to continue"
+		pbResp.PasswordExpired = true
+	}
+	pbResp.NewDevice = resp.NewDevice
 
 	h.logger.Info("User logged in successfully:", req.Email, resp.User.ID)
 
@@ -103,14 +113,9 @@ func (h *AuthHandler) Login(ctx context.Context, req *pb.LoginRequest) (*pb.Logi
 func (h *AuthHandler) OAuthLogin(ctx context.Context, req *pb.OAuthLoginRequest) (*pb.OAuthLoginResponse, error) {
 	h.logger.Info("OAuth login request", "provider", req.Provider)
 
-	metadata := extractRequestMetadata(ctx)
-
-	oauthReq := &models.OAuthLoginRequest{
-		Provider: req.Provider,
-		IDToken:  req.IdToken,
-	}
+	metadata := h.extractRequestMetadata(ctx)
 
-	resp, err := h.authService.OAuthLogin(ctx, oauthReq, metadata)
+	resp, err := h.authService.OAuthLogin(ctx, oauthLoginRequest(req), metadata)
 	if err != nil {
 		h.logger.Error("OAuth login failed", "provider", req.Provider, "error", err)
 		return nil, h.errorHandler.HandleGrpcError(err)
@@ -128,15 +133,11 @@ func (h *AuthHandler) OAuthLogin(ctx context.Context, req *pb.OAuthLoginRequest)
 }
 
 func (h *AuthHandler) RefreshToken(ctx context.Context, req *pb.RefreshTokenRequest) (*pb.RefreshTokenResponse, error) {
-	h.logger.Info("Token refresh request", "refresh_token", req.RefreshToken)
+	h.logger.Info("Token refresh request")
 
-	metadata := extractRequestMetadata(ctx)
+	metadata := h.extractRequestMetadata(ctx)
 
-	refreshReq := &models.RefreshTokenRequest{
-		RefreshToken: req.RefreshToken,
-	}
-
-	resp, err := h.authService.RefreshToken(ctx, refreshReq, metadata)
+	resp, err := h.authService.RefreshToken(ctx, refreshTokenRequest(req), metadata)
 	if err != nil {
 		h.logger.Error("Token refresh failed", "error", err)
 		return nil, h.errorHandler.HandleGrpcError(err)
@@ -151,8 +152,27 @@ func (h *AuthHandler) RefreshToken(ctx context.Context, req *pb.RefreshTokenRequ
 	}, nil
 }
 
+func (h *AuthHandler) IssueGuestToken(ctx context.Context, _ *pb.IssueGuestTokenRequest) (*pb.IssueGuestTokenResponse, error) {
+	h.logger.Info("Guest token request")
+
+	resp, err := h.authService.IssueGuestToken(ctx)
+	if err != nil {
+		h.logger.Error("Guest token issue failed", "error", err)
+		return nil, h.errorHandler.HandleGrpcError(err)
+	}
+
+	return &pb.IssueGuestTokenResponse{
+		Success:     true,
+		Message:     "Guest token issued",
+		UserId:      resp.UserID,
+		AccessToken: resp.AccessToken,
+		ExpiresAt:   resp.ExpiresAt,
+		UserType:    string(models.UserTypeAnonymous),
+	}, nil
+}
+
 func (h *AuthHandler) ValidateToken(ctx context.Context, req *pb.ValidateTokenRequest) (*pb.ValidateTokenResponse, error) {
-	h.logger.Info("Token validation request", "access_token", req.AccessToken)
+	h.logger.Debug("Token validation request")
 
 	validateReq := &models.ValidateTokenRequest{
 		AccessToken: req.AccessToken,
@@ -167,14 +187,55 @@ func (h *AuthHandler) ValidateToken(ctx context.Context, req *pb.ValidateTokenRe
 	return &pb.ValidateTokenResponse{
 		Valid:      resp.Valid,
 		UserId:     resp.UserID,
+		Email:      resp.Email,
 		UserType:   string(resp.UserType),
 		IsActive:   resp.IsActive,
 		IsVerified: resp.IsVerified,
 		ExpiresAt:  resp.ExpiresAt,
+		OrgId:      resp.OrgID,
+		Scope:      resp.Scope,
 		Message:    "Token validated",
+
+		ExpiresIn:     resp.ExpiresIn,
+		ShouldRefresh: resp.ShouldRefresh,
 	}, nil
 }
 
+func (h *AuthHandler) ValidateTokens(ctx context.Context, req *pb.ValidateTokensRequest) (*pb.ValidateTokensResponse, error) {
+	h.logger.Info("Batch token validation request", "count", len(req.AccessTokens))
+
+	results, err := h.authService.ValidateTokens(ctx, req.AccessTokens)
+	if err != nil {
+		h.logger.Error("Batch token validation failed", "error", err)
+		return nil, h.errorHandler.HandleGrpcError(err)
+	}
+
+	pbResults := make([]*pb.ValidateTokenResponse, 0, len(results))
+	for _, r := range results {
+		message := "Token validated"
+		if !r.Valid {
+			message = r.Error
+		}
+		pbResults = append(pbResults, &pb.ValidateTokenResponse{
+			Valid:      r.Valid,
+			UserId:     r.UserID,
+			Email:      r.Email,
+			UserType:   string(r.UserType),
+			IsActive:   r.IsActive,
+			IsVerified: r.IsVerified,
+			ExpiresAt:  r.ExpiresAt,
+			OrgId:      r.OrgID,
+			Scope:      r.Scope,
+			Message:    message,
+
+			ExpiresIn:     r.ExpiresIn,
+			ShouldRefresh: r.ShouldRefresh,
+		})
+	}
+
+	return &pb.ValidateTokensResponse{Results: pbResults}, nil
+}
+
 func (h *AuthHandler) Logout(ctx context.Context, req *pb.LogoutRequest) (*pb.LogoutResponse, error) {
 	h.logger.Info("Logout request")
 
@@ -197,13 +258,7 @@ func (h *AuthHandler) Logout(ctx context.Context, req *pb.LogoutRequest) (*pb.Lo
 func (h *AuthHandler) ChangePassword(ctx context.Context, req *pb.ChangePasswordRequest) (*pb.ChangePasswordResponse, error) {
 	h.logger.Info("Change password request")
 
-	changeReq := &models.ChangePasswordRequest{
-		UserID:      req.UserId,
-		OldPassword: req.OldPassword,
-		NewPassword: req.NewPassword,
-	}
-
-	err := h.authService.ChangePassword(ctx, changeReq)
+	err := h.authService.ChangePassword(ctx, changePasswordRequest(req), h.extractRequestMetadata(ctx))
 	if err != nil {
 		h.logger.Error("Password change failed", "error", err)
 		return nil, h.errorHandler.HandleGrpcError(err)
@@ -215,20 +270,270 @@ func (h *AuthHandler) ChangePassword(ctx context.Context, req *pb.ChangePassword
 	}, nil
 }
 
+func (h *AuthHandler) UpdateProfileImage(ctx context.Context, req *pb.UpdateProfileImageRequest) (*pb.UpdateProfileImageResponse, error) {
+	h.logger.Info("Update profile image request", "user_id", req.UserId)
+
+	imageURL, err := h.authService.UpdateProfileImage(ctx, updateProfileImageRequest(req))
+	if err != nil {
+		h.logger.Error("Profile image update failed", "error", err)
+		return nil, h.errorHandler.HandleGrpcError(err)
+	}
+
+	return &pb.UpdateProfileImageResponse{
+		Success:      true,
+		Message:      "Profile image updated successfully",
+		ProfileImage: imageURL,
+	}, nil
+}
+
+func (h *AuthHandler) ListAuditEntries(ctx context.Context, req *pb.ListAuditEntriesRequest) (*pb.ListAuditEntriesResponse, error) {
+	h.logger.Info("List audit entries request", "target_user_id", req.TargetUserId)
+
+	if req.Cursor != "" {
+		result, err := h.authService.ListAuditEntriesByCursor(ctx, req.TargetUserId, db.CursorRequest{
+			Cursor: req.Cursor,
+			Limit:  req.PageSize,
+		})
+		if err != nil {
+			h.logger.Error("List audit entries failed", "error", err)
+			return nil, h.errorHandler.HandleGrpcError(err)
+		}
+		return &pb.ListAuditEntriesResponse{
+			Success:    true,
+			Message:    "Audit entries fetched successfully",
+			Entries:    toPbAuditEntries(result.Items),
+			PageSize:   int64(len(result.Items)),
+			NextCursor: result.NextCursor,
+		}, nil
+	}
+
+	result, err := h.authService.ListAuditEntries(ctx, req.TargetUserId, db.PageRequest{
+		Page:     req.Page,
+		PageSize: req.PageSize,
+	})
+	if err != nil {
+		h.logger.Error("List audit entries failed", "error", err)
+		return nil, h.errorHandler.HandleGrpcError(err)
+	}
+
+	// lets a client switch from the first offset page to the cursor
+	var nextCursor string
+	if result.Page < result.TotalPages && len(result.Items) > 0 {
+		nextCursor = db.EncodeCursor(result.Items[len(result.Items)-1].ID)
+	}
+
+	return &pb.ListAuditEntriesResponse{
+		Success:    true,
+		Message:    "Audit entries fetched successfully",
+		Entries:    toPbAuditEntries(result.Items),
+		Total:      result.Total,
+		Page:       result.Page,
+		PageSize:   result.PageSize,
+		TotalPages: result.TotalPages,
+		NextCursor: nextCursor,
+	}, nil
+}
+
+func (h *AuthHandler) ListSessions(ctx context.Context, req *pb.ListSessionsRequest) (*pb.ListSessionsResponse, error) {
+	h.logger.Info("List sessions request", "user_id", req.UserId)
+
+	result, err := h.authService.ListSessions(ctx, req.UserId, db.CursorRequest{
+		Cursor: req.Cursor,
+		Limit:  req.Limit,
+	})
+	if err != nil {
+		h.logger.Error("List sessions failed", "error", err)
+		return nil, h.errorHandler.HandleGrpcError(err)
+	}
+
+	sessions := make([]*pb.Session, 0, len(result.Items))
+	for _, t := range result.Items {
+		sessions = append(sessions, &pb.Session{
+			Id:        t.ID.Hex(),
+			DeviceId:  t.DeviceID,
+			UserAgent: t.UserAgent,
+			Ip:        t.IP,
+			CreatedAt: timestamppb.New(t.CreatedAt),
+			ExpiresAt: timestamppb.New(t.ExpiresAt),
+		})
+	}
+
+	return &pb.ListSessionsResponse{
+		Success:    true,
+		Message:    "Sessions fetched successfully",
+		Sessions:   sessions,
+		NextCursor: result.NextCursor,
+	}, nil
+}
+
+func toPbAuditEntries(items []*models.AuditEntry) []*pb.AuditEntry {
+	entries := make([]*pb.AuditEntry, 0, len(items))
+	for _, e := range items {
+		entries = append(entries, &pb.AuditEntry{
+			Id:           e.ID.Hex(),
+			ActorId:      e.ActorID,
+			Action:       string(e.Action),
+			TargetUserId: e.TargetUserID,
+			Ip:           e.IP,
+			UserAgent:    e.UserAgent,
+			CreatedAt:    timestamppb.New(e.CreatedAt),
+			PrevHash:     e.PrevHash,
+			Hash:         e.Hash,
+		})
+	}
+	return entries
+}
+
+func (h *AuthHandler) UnlockAccount(ctx context.Context, req *pb.UnlockAccountRequest) (*pb.UnlockAccountResponse, error) {
+	h.logger.Info("Unlock account request", "user_id", req.UserId, "actor_id", req.ActorId)
+
+	if err := h.authService.UnlockAccount(ctx, req.UserId, req.ActorId, h.extractRequestMetadata(ctx)); err != nil {
+		h.logger.Error("Unlock account failed", "error", err)
+		return nil, h.errorHandler.HandleGrpcError(err)
+	}
+
+	return &pb.UnlockAccountResponse{
+		Success: true,
+		Message: "Account unlocked",
+	}, nil
+}
+
+func (h *AuthHandler) AdminCreateUser(ctx context.Context, req *pb.AdminCreateUserRequest) (*pb.AdminCreateUserResponse, error) {
+	h.logger.Info("Admin create user request", "email", req.Email, "user_type", req.UserType, "actor_id", req.ActorId)
+
+	resp, err := h.authService.AdminCreateUser(ctx, adminCreateUserRequest(req), req.ActorId, h.extractRequestMetadata(ctx))
+	if err != nil {
+		h.logger.Error("Admin create user failed", "error", err)
+		return nil, h.errorHandler.HandleGrpcError(err)
+	}
+
+	return &pb.AdminCreateUserResponse{
+		Success:           true,
+		Message:           "User created",
+		UserId:            resp.User.ID,
+		UserType:          string(resp.User.UserType),
+		IsVerified:        resp.User.IsVerified,
+		TemporaryPassword: resp.TemporaryPassword,
+		CreatedAt:         timestamppb.New(resp.User.CreatedAt),
+	}, nil
+}
+
+func (h *AuthHandler) SearchUsers(ctx context.Context, req *pb.SearchUsersRequest) (*pb.SearchUsersResponse, error) {
+	h.logger.Info("Search users request", "user_type", req.UserType, "page", req.Page)
+
+	criteria := models.UserSearchCriteria{
+		Email:      req.Email,
+		UserType:   models.UserType(req.UserType),
+		IsActive:   req.IsActive,
+		IsVerified: req.IsVerified,
+	}
+	if req.CreatedFrom != nil {
+		t := req.CreatedFrom.AsTime()
+		criteria.CreatedFrom = &t
+	}
+	if req.CreatedTo != nil {
+		t := req.CreatedTo.AsTime()
+		criteria.CreatedTo = &t
+	}
+
+	result, err := h.authService.SearchUsers(ctx, criteria, db.PageRequest{
+		Page:     req.Page,
+		PageSize: req.PageSize,
+	})
+	if err != nil {
+		h.logger.Error("Search users failed", "error", err)
+		return nil, h.errorHandler.HandleGrpcError(err)
+	}
+
+	users := make([]*pb.AdminUser, 0, len(result.Items))
+	for _, u := range result.Items {
+		user := &pb.AdminUser{
+			Id:         u.ID,
+			Email:      u.Email,
+			FirstName:  u.FirstName,
+			LastName:   u.LastName,
+			Phone:      u.Phone,
+			UserType:   string(u.UserType),
+			IsActive:   u.IsActive,
+			IsVerified: u.IsVerified,
+			CreatedAt:  timestamppb.New(u.CreatedAt),
+		}
+		if u.LastLoginAt != nil {
+			user.LastLoginAt = timestamppb.New(*u.LastLoginAt)
+		}
+		users = append(users, user)
+	}
+
+	return &pb.SearchUsersResponse{
+		Success:    true,
+		Message:    "Users fetched successfully",
+		Users:      users,
+		Total:      result.Total,
+		Page:       result.Page,
+		PageSize:   result.PageSize,
+		TotalPages: result.TotalPages,
+	}, nil
+}
+
+func (h *AuthHandler) GetUsers(ctx context.Context, req *pb.GetUsersRequest) (*pb.GetUsersResponse, error) {
+	h.logger.Info("Get users request", "count", len(req.UserIds))
+
+	users, err := h.authService.GetUsers(ctx, req.UserIds)
+	if err != nil {
+		h.logger.Error("Get users failed", "error", err)
+		return nil, h.errorHandler.HandleGrpcError(err)
+	}
+
+	profiles := make([]*pb.UserProfile, 0, len(users))
+	for _, u := range users {
+		profiles = append(profiles, &pb.UserProfile{
+			Id:           u.ID,
+			FirstName:    u.FirstName,
+			LastName:     u.LastName,
+			UserType:     string(u.UserType),
+			ProfileImage: u.ProfileImage,
+			IsActive:     u.IsActive,
+			IsVerified:   u.IsVerified,
+			CreatedAt:    timestamppb.New(u.CreatedAt),
+		})
+	}
+
+	return &pb.GetUsersResponse{Users: profiles}, nil
+}
+
 func (h *AuthHandler) Health(ctx context.Context, _ *pb.HealthRequest) (*pb.HealthResponse, error) {
+	status := "ok"
+	checks := make(map[string]string, len(h.healthChecks))
+
+	for name, err := range connection.CheckAll(ctx, h.healthChecks, healthCheckTimeout) {
+		if err != nil {
+			// the detail names hosts and addresses, callers only get the status word
+			h.logger.Warn("Dependency health check failed", "dependency", name, "error", err)
+			checks[name] = "unhealthy"
+			status = "unhealthy"
+			continue
+		}
+		checks[name] = "ok"
+	}
+
 	return &pb.HealthResponse{
-		Status:    "ok",
+		Status:    status,
 		Timestamp: timestamppb.Now(),
-		Checks:    map[string]string{},
+		Checks:    checks,
 	}, nil
 }
 
-func extractRequestMetadata(ctx context.Context) *models.RequestMetadata {
+// extractRequestMetadata takes the client address from forwarded metadata only when the
+// peer is a trusted gateway, a direct caller could rotate it past the per-IP login throttle
+func (h *AuthHandler) extractRequestMetadata(ctx context.Context) *models.RequestMetadata {
 	md, _ := metadata.FromIncomingContext(ctx)
 
 	var userAgent, deviceID, ipAddress string
 
-	if val, ok := md["user-agent"]; ok && len(val) > 0 {
+	// the gateway forwards the client's, user-agent is its own grpc client
+	if val, ok := md["x-client-user-agent"]; ok && len(val) > 0 {
+		userAgent = val[0]
+	} else if val, ok := md["user-agent"]; ok && len(val) > 0 {
 		userAgent = val[0]
 	}
 
@@ -237,7 +542,14 @@ func extractRequestMetadata(ctx context.Context) *models.RequestMetadata {
 	}
 
 	if p, ok := peer.FromContext(ctx); ok {
-		ipAddress = p.Addr.String()
+		ipAddress = stripPort(p.Addr.String())
+	}
+	if h.trustedPeer(ipAddress) {
+		if val, ok := md["x-forwarded-for"]; ok && len(val) > 0 {
+			ipAddress = stripPort(strings.TrimSpace(strings.Split(val[0], ",")[0]))
+		} else if val, ok := md["x-real-ip"]; ok && len(val) > 0 {
+			ipAddress = stripPort(strings.TrimSpace(val[0]))
+		}
 	}
 
 	return &models.RequestMetadata{
@@ -246,3 +558,25 @@ func extractRequestMetadata(ctx context.Context) *models.RequestMetadata {
 		DeviceID:  deviceID,
 	}
 }
+
+func (h *AuthHandler) trustedPeer(addr string) bool {
+	ip, err := netip.ParseAddr(addr)
+	if err != nil {
+		return false
+	}
+	ip = ip.Unmap()
+	for _, prefix := range h.trustedPeers {
+		if prefix.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// stripPort drops the port from "host:port" and "[ipv6]:port" addresses
+func stripPort(addr string) string {
+	if host, _, err := net.SplitHostPort(addr); err == nil {
+		return host
+	}
+	return strings.Trim(addr, "[]")
+}
@@ -0,0 +1,223 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"net"
+	"net/netip"
+	"strings"
+	"testing"
+
+	"remaster/services/auth/utils"
+	"remaster/shared/connection"
+	pb "remaster/shared/proto/auth"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+func TestExtractRequestMetadataIPAddress(t *testing.T) {
+	h := &AuthHandler{trustedPeers: []netip.Prefix{
+		netip.MustParsePrefix("127.0.0.1/32"),
+		netip.MustParsePrefix("10.0.0.0/8"),
+		netip.MustParsePrefix("fd00::/8"),
+	}}
+
+	tests := []struct {
+		name string
+		peer net.Addr
+		md   metadata.MD
+		want string
+	}{
+		{
+			name: "ipv4 peer",
+			peer: &net.TCPAddr{IP: net.ParseIP("203.0.113.5"), Port: 51234},
+			want: "203.0.113.5",
+		},
+		{
+			name: "ipv6 peer",
+			peer: &net.TCPAddr{IP: net.ParseIP("2001:db8::1"), Port: 51234},
+			want: "2001:db8::1",
+		},
+		{
+			name: "forwarded for by trusted gateway",
+			peer: &net.TCPAddr{IP: net.ParseIP("127.0.0.1"), Port: 51234},
+			md:   metadata.Pairs("x-forwarded-for", "198.51.100.7, 10.1.1.1"),
+			want: "198.51.100.7",
+		},
+		{
+			name: "ipv6 forwarded by trusted cidr",
+			peer: &net.TCPAddr{IP: net.ParseIP("10.1.2.3"), Port: 51234},
+			md:   metadata.Pairs("x-forwarded-for", "[2001:db8::2]:443"),
+			want: "2001:db8::2",
+		},
+		{
+			name: "real ip from ipv6 gateway",
+			peer: &net.TCPAddr{IP: net.ParseIP("fd00::10"), Port: 51234},
+			md:   metadata.Pairs("x-real-ip", "198.51.100.8"),
+			want: "198.51.100.8",
+		},
+		{
+			name: "ipv4-mapped gateway address",
+			peer: &net.TCPAddr{IP: net.ParseIP("::ffff:127.0.0.1"), Port: 51234},
+			md:   metadata.Pairs("x-forwarded-for", "198.51.100.9"),
+			want: "198.51.100.9",
+		},
+		{
+			name: "forwarded for ignored from untrusted peer",
+			peer: &net.TCPAddr{IP: net.ParseIP("203.0.113.5"), Port: 51234},
+			md:   metadata.Pairs("x-forwarded-for", "198.51.100.7"),
+			want: "203.0.113.5",
+		},
+		{
+			name: "real ip ignored from untrusted ipv6 peer",
+			peer: &net.TCPAddr{IP: net.ParseIP("2001:db8::1"), Port: 51234},
+			md:   metadata.Pairs("x-real-ip", "198.51.100.7"),
+			want: "2001:db8::1",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := peer.NewContext(context.Background(), &peer.Peer{Addr: tt.peer})
+			if tt.md != nil {
+				ctx = metadata.NewIncomingContext(ctx, tt.md)
+			}
+			if got := h.extractRequestMetadata(ctx).IPAddress; got != tt.want {
+				t.Fatalf("IPAddress = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestExtractRequestMetadataTrustsNoPeerByDefault(t *testing.T) {
+	h := &AuthHandler{}
+	ctx := peer.NewContext(context.Background(), &peer.Peer{Addr: &net.TCPAddr{IP: net.ParseIP("127.0.0.1"), Port: 1}})
+	ctx = metadata.NewIncomingContext(ctx, metadata.Pairs("x-forwarded-for", "198.51.100.7"))
+
+	if got := h.extractRequestMetadata(ctx).IPAddress; got != "127.0.0.1" {
+		t.Fatalf("IPAddress = %q, want the peer address", got)
+	}
+}
+
+type fakeChecker struct {
+	err error
+}
+
+func (c fakeChecker) HealthCheck(context.Context) error { return c.err }
+
+func TestHealthReportsEachDependencyIndependently(t *testing.T) {
+	dependencies := []string{"mongodb", "redis", "kafka", "s3"}
+
+	t.Run("all healthy", func(t *testing.T) {
+		checks := make(map[string]connection.HealthChecker, len(dependencies))
+		for _, name := range dependencies {
+			checks[name] = fakeChecker{}
+		}
+		h := NewAuthHandler(nil, nil, checks, nil, slog.New(slog.NewTextHandler(io.Discard, nil)))
+
+		resp, err := h.Health(context.Background(), &pb.HealthRequest{})
+		if err != nil {
+			t.Fatalf("Health: %v", err)
+		}
+		if resp.Status != "ok" {
+			t.Fatalf("status = %q, want ok", resp.Status)
+		}
+		for _, name := range dependencies {
+			if resp.Checks[name] != "ok" {
+				t.Fatalf("%s = %q, want ok", name, resp.Checks[name])
+			}
+		}
+	})
+
+	for _, failing := range dependencies {
+		t.Run(failing+" unhealthy", func(t *testing.T) {
+			checks := make(map[string]connection.HealthChecker, len(dependencies))
+			for _, name := range dependencies {
+				checks[name] = fakeChecker{}
+			}
+			checks[failing] = fakeChecker{err: errors.New("dial tcp 10.0.3.7:27017: connection refused")}
+			h := NewAuthHandler(nil, nil, checks, nil, slog.New(slog.NewTextHandler(io.Discard, nil)))
+
+			resp, err := h.Health(context.Background(), &pb.HealthRequest{})
+			if err != nil {
+				t.Fatalf("Health: %v", err)
+			}
+			if resp.Status != "unhealthy" {
+				t.Fatalf("status = %q, want unhealthy", resp.Status)
+			}
+			for _, name := range dependencies {
+				want := "ok"
+				if name == failing {
+					want = "unhealthy"
+				}
+				if resp.Checks[name] != want {
+					t.Fatalf("%s = %q, want %s", name, resp.Checks[name], want)
+				}
+			}
+		})
+	}
+}
+
+func TestHealthKeepsErrorDetailOutOfTheResponse(t *testing.T) {
+	h := NewAuthHandler(nil, nil, map[string]connection.HealthChecker{
+		"mongodb": fakeChecker{err: errors.New("server selection error: mongo-0.internal:27017")},
+	}, nil, slog.New(slog.NewTextHandler(io.Discard, nil)))
+
+	resp, err := h.Health(context.Background(), &pb.HealthRequest{})
+	if err != nil {
+		t.Fatalf("Health: %v", err)
+	}
+	for name, check := range resp.Checks {
+		if strings.Contains(check, "mongo-0.internal") {
+			t.Fatalf("%s = %q, leaks the dependency error", name, check)
+		}
+	}
+}
+
+func TestGetUsersSkipsUnknownIDs(t *testing.T) {
+	h, jwtUtils, token := newTestAuthHandler(t)
+	ctx := context.Background()
+	claims, err := utils.NewLocalValidator(jwtUtils).Validate(ctx, token)
+	if err != nil {
+		t.Fatalf("Validate: %v", err)
+	}
+
+	unknown := primitive.NewObjectID().Hex()
+	resp, err := h.GetUsers(ctx, &pb.GetUsersRequest{UserIds: []string{unknown, claims.UserID, claims.UserID}})
+	if err != nil {
+		t.Fatalf("GetUsers: %v", err)
+	}
+	if len(resp.Users) != 1 {
+		t.Fatalf("got %d users, want the one registered user once", len(resp.Users))
+	}
+	if u := resp.Users[0]; u.Id != claims.UserID || u.FirstName != "Side" || u.UserType != "client" {
+		t.Fatalf("profile = %+v, want the registered user", u)
+	}
+
+	empty, err := h.GetUsers(ctx, &pb.GetUsersRequest{})
+	if err != nil || len(empty.Users) != 0 {
+		t.Fatalf("GetUsers() = %v, %v, want no users and no error", empty, err)
+	}
+}
+
+func TestGetUsersRejectsInvalidIDs(t *testing.T) {
+	h, _, _ := newTestAuthHandler(t)
+	ids := make([]string, 101)
+	for i := range ids {
+		ids[i] = primitive.NewObjectID().Hex()
+	}
+
+	for name, req := range map[string]*pb.GetUsersRequest{
+		"malformed id": {UserIds: []string{"not-an-id"}},
+		"too many ids": {UserIds: ids},
+	} {
+		if _, err := h.GetUsers(context.Background(), req); status.Code(err) != codes.InvalidArgument {
+			t.Fatalf("%s: GetUsers = %v, want InvalidArgument", name, err)
+		}
+	}
+}
@@ -0,0 +1,79 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+
+	et "remaster/shared/errors"
+	pb "remaster/shared/proto/auth"
+
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/encoding/protojson"
+)
+
+// access tokens are well under this, anything bigger is not a token
+const maxValidateBodyBytes = 16 << 10
+
+var validateJSON = protojson.MarshalOptions{UseProtoNames: true}
+
+// ValidateTokenHTTP serves ValidateToken for sidecars that can't speak gRPC. The token comes
+// from the Authorization bearer header or a {"access_token": ...} body, the response is the
+// gRPC ValidateTokenResponse as JSON and errors keep their gRPC mapped HTTP status
+func (h *AuthHandler) ValidateTokenHTTP(w http.ResponseWriter, r *http.Request) {
+	token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if !ok {
+		var body struct {
+			AccessToken string `json:"access_token"`
+		}
+		err := json.NewDecoder(io.LimitReader(r.Body, maxValidateBodyBytes)).Decode(&body)
+		if err != nil && !errors.Is(err, io.EOF) {
+			h.writeHTTPError(w, et.NewBadRequestError("request body must be JSON"))
+			return
+		}
+		token = body.AccessToken
+	}
+	if token == "" {
+		h.writeHTTPError(w, et.NewValidationError("access token is required",
+			map[string]string{"access_token": "is required"}))
+		return
+	}
+
+	resp, err := h.ValidateToken(r.Context(), &pb.ValidateTokenRequest{AccessToken: token})
+	if err != nil {
+		h.writeHTTPError(w, err)
+		return
+	}
+
+	b, err := validateJSON.Marshal(resp)
+	if err != nil {
+		h.logger.Error("Failed to encode validate response", "error", err)
+		h.writeHTTPError(w, et.NewInternalError("failed to encode response", err))
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	_, _ = w.Write(b)
+}
+
+// writeHTTPError goes through HandleGrpcError so the body carries the same code the gateway would return
+func (h *AuthHandler) writeHTTPError(w http.ResponseWriter, err error) {
+	if _, isStatus := status.FromError(err); !isStatus {
+		err = h.errorHandler.HandleGrpcError(err)
+	}
+	st := status.Convert(err)
+
+	resp := et.ErrorResponse{Error: st.Message(), Code: st.Code().String()}
+	for _, d := range st.Details() {
+		if info, ok := d.(*errdetails.ErrorInfo); ok {
+			resp.Code = info.Reason
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(et.GrpcToHTTP(st.Code()))
+	_ = json.NewEncoder(w).Encode(resp)
+}
@@ -0,0 +1,154 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"remaster/services/auth/models"
+	oauth "remaster/services/auth/oauth"
+	"remaster/services/auth/repositories/memory"
+	"remaster/services/auth/services"
+	"remaster/services/auth/utils"
+	config "remaster/shared"
+	et "remaster/shared/errors"
+	"remaster/shared/events"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+)
+
+// newTestAuthHandler wires the handler to a real AuthService on the memory repository
+// and returns it with the signing keys and the access token of a registered user
+func newTestAuthHandler(t *testing.T) (*AuthHandler, *utils.JWTUtils, string) {
+	t.Helper()
+
+	security := &config.SecurityConfig{
+		BcryptCost:                   4,
+		PasswordHashAlgorithm:        utils.HashBcrypt,
+		PhoneDefaultRegion:           "US",
+		Lockout:                      config.LockoutConfig{MaxAttempts: 3, Schedule: []time.Duration{time.Minute}, MaxIPAttempts: 100, IPWindow: time.Minute},
+		AllowedSelfRegistrationTypes: []string{"client"},
+		RefreshBinding:               config.RefreshBindingConfig{Mode: "off"},
+	}
+	jwtUtils, err := utils.NewJWTUtils(&config.JWTConfig{
+		SecretKey:       "test-secret-key-that-is-long-enough",
+		Issuer:          "remaster-auth",
+		Audience:        "remaster",
+		AccessTokenTTL:  15 * time.Minute,
+		RefreshTokenTTL: 24 * time.Hour,
+	})
+	if err != nil {
+		t.Fatalf("jwt utils: %v", err)
+	}
+	passwords, err := utils.NewPasswordPolicy(&config.PasswordPolicyConfig{MinLength: 8, MaxLength: 72})
+	if err != nil {
+		t.Fatalf("password policy: %v", err)
+	}
+	hasher, err := utils.NewPasswordHasher(security)
+	if err != nil {
+		t.Fatalf("password hasher: %v", err)
+	}
+	oauthFactory, err := oauth.NewProviderFactory(&config.OAuthConfig{})
+	if err != nil {
+		t.Fatalf("oauth factory: %v", err)
+	}
+
+	mr := miniredis.RunT(t)
+	rdb := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() { _ = rdb.Close() })
+
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	svc := services.NewAuthService(memory.NewRepository(), oauthFactory, rdb, jwtUtils, security, passwords, hasher,
+		events.NoopPublisher{}, nil, logger)
+
+	auth, err := svc.CreateUser(context.Background(), &models.RegisterRequest{
+		Email:     "sidecar@example.com",
+		Password:  "Str0ng!Passw0rd",
+		FirstName: "Side",
+		LastName:  "Car",
+		Phone:     "+14155550123",
+		UserType:  models.UserTypeClient,
+	}, &models.RequestMetadata{IPAddress: "203.0.113.7"})
+	if err != nil {
+		t.Fatalf("CreateUser: %v", err)
+	}
+
+	return NewAuthHandler(svc, et.NewErrorHandler(logger), nil, nil, logger), jwtUtils, auth.AccessToken
+}
+
+func postValidate(h *AuthHandler, authorization, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodPost, "/v1/validate-token", strings.NewReader(body))
+	if authorization != "" {
+		req.Header.Set("Authorization", authorization)
+	}
+	rec := httptest.NewRecorder()
+	h.ValidateTokenHTTP(rec, req)
+	return rec
+}
+
+func TestValidateTokenHTTPAcceptsValidToken(t *testing.T) {
+	h, _, token := newTestAuthHandler(t)
+
+	for name, rec := range map[string]*httptest.ResponseRecorder{
+		"bearer header": postValidate(h, "Bearer "+token, ""),
+		"json body":     postValidate(h, "", `{"access_token":"`+token+`"}`),
+	} {
+		t.Run(name, func(t *testing.T) {
+			if rec.Code != http.StatusOK {
+				t.Fatalf("status = %d (%s), want 200", rec.Code, rec.Body)
+			}
+			var resp struct {
+				Valid    bool   `json:"valid"`
+				UserID   string `json:"user_id"`
+				Email    string `json:"email"`
+				UserType string `json:"user_type"`
+			}
+			if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+				t.Fatalf("decode %s: %v", rec.Body, err)
+			}
+			if !resp.Valid || resp.UserID == "" || resp.Email != "sidecar@example.com" || resp.UserType != "client" {
+				t.Fatalf("response = %+v, want the registered user", resp)
+			}
+		})
+	}
+}
+
+func TestValidateTokenHTTPRejects(t *testing.T) {
+	h, _, token := newTestAuthHandler(t)
+
+	tests := []struct {
+		name          string
+		authorization string
+		body          string
+		status        int
+		code          et.ErrorCode
+	}{
+		{"tampered token", "Bearer " + token + "x", "", http.StatusUnauthorized, et.CodeUnauthorized},
+		{"garbage token", "", `{"access_token":"not-a-jwt"}`, http.StatusUnauthorized, et.CodeUnauthorized},
+		{"empty body", "", "", http.StatusBadRequest, et.CodeValidation},
+		{"empty token", "", `{"access_token":""}`, http.StatusBadRequest, et.CodeValidation},
+		{"body is not json", "", "access_token=abc", http.StatusBadRequest, et.CodeBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := postValidate(h, tt.authorization, tt.body)
+			if rec.Code != tt.status {
+				t.Fatalf("status = %d (%s), want %d", rec.Code, rec.Body, tt.status)
+			}
+			var resp et.ErrorResponse
+			if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+				t.Fatalf("decode %s: %v", rec.Body, err)
+			}
+			if resp.Code != string(tt.code) {
+				t.Fatalf("code = %q, want %q", resp.Code, tt.code)
+			}
+		})
+	}
+}
@@ -0,0 +1,90 @@
+package handlers
+
+import (
+	"remaster/services/auth/models"
+	pb "remaster/shared/proto/auth"
+	"remaster/shared/validation"
+)
+
+// ValidateRequest checks a request against the validate tags of the model it maps to,
+// the server's ValidationUnary calls it before the handler runs. The tags stay the
+// only copy of the rules, messages without a model validate themselves
+func (h *AuthHandler) ValidateRequest(req any) error {
+	var model any
+	switch r := req.(type) {
+	case *pb.RegisterRequest:
+		model = registerRequest(r)
+	case *pb.LoginRequest:
+		model = loginRequest(r)
+	case *pb.OAuthLoginRequest:
+		model = oauthLoginRequest(r)
+	case *pb.RefreshTokenRequest:
+		model = refreshTokenRequest(r)
+	case *pb.ChangePasswordRequest:
+		model = changePasswordRequest(r)
+	case *pb.UpdateProfileImageRequest:
+		model = updateProfileImageRequest(r)
+	case *pb.AdminCreateUserRequest:
+		model = adminCreateUserRequest(r)
+	default:
+		return nil
+	}
+	return validation.Validate(model)
+}
+
+func registerRequest(req *pb.RegisterRequest) *models.RegisterRequest {
+	return &models.RegisterRequest{
+		Email:     req.Email,
+		Password:  req.Password,
+		FirstName: req.FirstName,
+		LastName:  req.LastName,
+		Phone:     req.Phone,
+		UserType:  models.UserType(req.UserType),
+	}
+}
+
+func loginRequest(req *pb.LoginRequest) *models.LoginRequest {
+	return &models.LoginRequest{
+		Email:    req.Email,
+		Password: req.Password,
+	}
+}
+
+func oauthLoginRequest(req *pb.OAuthLoginRequest) *models.OAuthLoginRequest {
+	return &models.OAuthLoginRequest{
+		Provider: req.Provider,
+		IDToken:  req.IdToken,
+	}
+}
+
+func refreshTokenRequest(req *pb.RefreshTokenRequest) *models.RefreshTokenRequest {
+	return &models.RefreshTokenRequest{
+		RefreshToken: req.RefreshToken,
+	}
+}
+
+func changePasswordRequest(req *pb.ChangePasswordRequest) *models.ChangePasswordRequest {
+	return &models.ChangePasswordRequest{
+		UserID:      req.UserId,
+		OldPassword: req.OldPassword,
+		NewPassword: req.NewPassword,
+	}
+}
+
+func updateProfileImageRequest(req *pb.UpdateProfileImageRequest) *models.UpdateProfileImageRequest {
+	return &models.UpdateProfileImageRequest{
+		UserID:   req.UserId,
+		MediaKey: req.MediaKey,
+	}
+}
+
+func adminCreateUserRequest(req *pb.AdminCreateUserRequest) *models.AdminCreateUserRequest {
+	return &models.AdminCreateUserRequest{
+		Email:     req.Email,
+		FirstName: req.FirstName,
+		LastName:  req.LastName,
+		Phone:     req.Phone,
+		UserType:  models.UserType(req.UserType),
+		Verified:  req.Verified,
+	}
+}
@@ -0,0 +1,58 @@
+package handlers
+
+import (
+	"testing"
+
+	et "remaster/shared/errors"
+	pb "remaster/shared/proto/auth"
+)
+
+func TestValidateRequestUsesModelRules(t *testing.T) {
+	h := &AuthHandler{}
+
+	err := h.ValidateRequest(&pb.RegisterRequest{
+		Email:     "not-an-email",
+		Password:  "short",
+		FirstName: "A",
+		LastName:  "User",
+		Phone:     "+14155550123",
+		UserType:  "admin",
+	})
+	appErr, ok := et.AsAppError(err)
+	if !ok {
+		t.Fatalf("error = %v, want a validation AppError", err)
+	}
+	for _, field := range []string{"email", "password", "first_name", "user_type"} {
+		if appErr.Details[field] == "" {
+			t.Errorf("no detail for %s in %v", field, appErr.Details)
+		}
+	}
+	if _, ok := appErr.Details["last_name"]; ok {
+		t.Errorf("valid last_name reported: %v", appErr.Details)
+	}
+}
+
+func TestValidateRequestAcceptsValidMessages(t *testing.T) {
+	h := &AuthHandler{}
+
+	valid := []any{
+		&pb.RegisterRequest{Email: "new@example.com", Password: "Str0ng!Passw0rd", FirstName: "New", LastName: "User", Phone: "+14155550123", UserType: "master"},
+		&pb.LoginRequest{Email: "new@example.com", Password: "x"},
+		&pb.OAuthLoginRequest{Provider: "facebook", IdToken: "token"},
+		// no model behind it, the message validates itself
+		&pb.UnlockAccountRequest{},
+	}
+	for _, req := range valid {
+		if err := h.ValidateRequest(req); err != nil {
+			t.Errorf("ValidateRequest(%T) = %v, want nil", req, err)
+		}
+	}
+}
+
+func TestValidateRequestRejectsUnknownOAuthProvider(t *testing.T) {
+	h := &AuthHandler{}
+	err := h.ValidateRequest(&pb.OAuthLoginRequest{Provider: "myspace", IdToken: "token"})
+	if appErr, ok := et.AsAppError(err); !ok || appErr.Details["provider"] == "" {
+		t.Fatalf("error = %v, want a provider violation", err)
+	}
+}
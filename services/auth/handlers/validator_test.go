@@ -0,0 +1,134 @@
+package handlers
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"math"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"remaster/services/auth/cache"
+	"remaster/services/auth/utils"
+	et "remaster/shared/errors"
+	pb "remaster/shared/proto/auth"
+	"remaster/shared/tokenauth"
+
+	"github.com/gin-gonic/gin"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+// serveAuth runs h on an in-memory listener and returns a client for it
+func serveAuth(t *testing.T, h *AuthHandler) pb.AuthServiceClient {
+	t.Helper()
+	lis := bufconn.Listen(1 << 20)
+	srv := grpc.NewServer()
+	pb.RegisterAuthServiceServer(srv, h)
+	go func() { _ = srv.Serve(lis) }()
+	t.Cleanup(srv.Stop)
+
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) { return lis.DialContext(ctx) }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	if err != nil {
+		t.Fatalf("dial: %v", err)
+	}
+	t.Cleanup(func() { _ = conn.Close() })
+	return pb.NewAuthServiceClient(conn)
+}
+
+func TestLocalAndRemoteValidatorsAgree(t *testing.T) {
+	h, jwtUtils, token := newTestAuthHandler(t)
+	validators := map[string]tokenauth.TokenValidator{
+		"local":  utils.NewLocalValidator(jwtUtils),
+		"remote": tokenauth.NewRemoteValidator(serveAuth(t, h)),
+	}
+
+	claims := make(map[string]*tokenauth.Claims, len(validators))
+	for name, v := range validators {
+		c, err := v.Validate(context.Background(), token)
+		if err != nil {
+			t.Fatalf("%s Validate: %v", name, err)
+		}
+		claims[name] = c
+	}
+
+	local, remote := claims["local"], claims["remote"]
+	if local.UserID == "" || local.Email != "sidecar@example.com" || local.UserType != "client" {
+		t.Fatalf("local claims = %+v, want the registered user", local)
+	}
+	if local.UserID != remote.UserID || local.Email != remote.Email || local.UserType != remote.UserType ||
+		local.OrgID != remote.OrgID || local.Scope != remote.Scope {
+		t.Fatalf("claims differ:\nlocal  %+v\nremote %+v", local, remote)
+	}
+	// the remote response carries unix seconds
+	if !local.ExpiresAt.Truncate(time.Second).Equal(remote.ExpiresAt) {
+		t.Fatalf("expiry differs: local %v, remote %v", local.ExpiresAt, remote.ExpiresAt)
+	}
+}
+
+func TestLocalAndRemoteValidatorsRejectTheSameToken(t *testing.T) {
+	h, jwtUtils, token := newTestAuthHandler(t)
+	validators := map[string]tokenauth.TokenValidator{
+		"local":  utils.NewLocalValidator(jwtUtils),
+		"remote": tokenauth.NewRemoteValidator(serveAuth(t, h)),
+	}
+
+	for name, v := range validators {
+		for _, bad := range []string{token + "x", "not-a-jwt"} {
+			_, err := v.Validate(context.Background(), bad)
+			if !tokenauth.IsInvalidToken(err) {
+				t.Fatalf("%s Validate(%q) = %v, want ErrInvalidToken", name, bad, err)
+			}
+		}
+	}
+}
+
+func TestLoginThrottleRetryHintReachesHTTPClients(t *testing.T) {
+	h, _, _ := newTestAuthHandler(t)
+	client := serveAuth(t, h)
+	ctx := context.Background()
+
+	var err error
+	for range cache.MaxLoginAttempts + 1 {
+		_, err = client.Login(ctx, &pb.LoginRequest{Email: "nobody@example.com", Password: "guess"})
+	}
+	st := status.Convert(err)
+	if st.Code() != codes.ResourceExhausted {
+		t.Fatalf("login after %d failures = %v, want ResourceExhausted", cache.MaxLoginAttempts, err)
+	}
+
+	var delay time.Duration
+	for _, d := range st.Details() {
+		if info, ok := d.(*errdetails.RetryInfo); ok {
+			delay = info.GetRetryDelay().AsDuration()
+		}
+	}
+	if delay <= 0 {
+		t.Fatalf("details = %v, want a RetryInfo with a positive delay", st.Details())
+	}
+
+	// the gateway side of the same error
+	gin.SetMode(gin.TestMode)
+	rec := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(rec)
+	c.Request = httptest.NewRequest(http.MethodPost, "/v1/auth/login", nil)
+	et.NewErrorHandler(slog.New(slog.NewTextHandler(io.Discard, nil))).HandleGrpcToHttp(c, err)
+
+	if rec.Code != http.StatusTooManyRequests {
+		t.Fatalf("status = %d, want 429", rec.Code)
+	}
+	if want := strconv.Itoa(int(math.Ceil(delay.Seconds()))); rec.Header().Get("Retry-After") != want {
+		t.Fatalf("Retry-After = %q, want %s", rec.Header().Get("Retry-After"), want)
+	}
+}
@@ -2,6 +2,7 @@ package main
 
 import (
 	"context"
+	"net/http"
 	"os"
 
 	"remaster/services/auth/handlers"
@@ -10,9 +11,16 @@ import (
 	"remaster/services/auth/services"
 	"remaster/services/auth/utils"
 	config "remaster/shared"
+	"remaster/shared/connection"
+	"remaster/shared/events"
 	"remaster/shared/logger"
 	auth_pb "remaster/shared/proto/auth"
+	media_pb "remaster/shared/proto/media"
 	"remaster/shared/server"
+	"remaster/shared/worker"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
 )
 
 func main() {
@@ -25,21 +33,28 @@ func main() {
 	// Logger
 	logger := logger.Get(cfg.Log)
 
+	dependencies := []server.ServerOption{
+		server.WithMongo(context.Background()),
+		server.WithRedis(context.Background()),
+	}
+	if cfg.Kafka.Enabled {
+		dependencies = append(dependencies, server.WithKafka(context.Background()))
+	}
+	if cfg.AWS.Enabled {
+		dependencies = append(dependencies, server.WithAWS(context.Background()))
+	}
+
 	// Build server
 	srv, err := server.NewServer(server.ServerConfig{
-		Name:              "auth",
-		Config:            cfg,
-		Logger:            logger,
-		EnableHealthCheck: true,
-		EnableReflection:  true,
+		Name:   "auth",
+		Config: cfg,
+		Logger: logger,
 		InterceptorConfig: server.InterceptorConfig{
 			EnableLogging:  true,
 			EnableRecovery: true,
+			EnableMetrics:  true,
 		},
-		Dependencies: []server.ServerOption{
-			server.WithMongo(context.Background()),
-			server.WithRedis(context.Background()),
-		},
+		Dependencies: dependencies,
 	})
 	if err != nil {
 		logger.Error("failed to initialize server", "error", err)
@@ -47,19 +62,88 @@ func main() {
 	}
 
 	// Dependencies
-	jwtUtils := utils.NewJWTUtils(&cfg.JWT)
-	oauthFactory := oauth.NewProviderFactory(&cfg.OAuth)
+	jwtUtils, err := utils.NewJWTUtils(&cfg.JWT)
+	if err != nil {
+		logger.Error("invalid jwt configuration", "error", err)
+		os.Exit(1)
+	}
+	oauthFactory, err := oauth.NewProviderFactory(&cfg.OAuth)
+	if err != nil {
+		logger.Error("invalid oauth configuration", "error", err)
+		os.Exit(1)
+	}
+	passwordPolicy, err := utils.NewPasswordPolicy(&cfg.Security.Password)
+	if err != nil {
+		logger.Error("failed to load password policy", "error", err)
+		os.Exit(1)
+	}
+	passwordHasher, err := utils.NewPasswordHasher(&cfg.Security)
+	if err != nil {
+		logger.Error("failed to create password hasher", "error", err)
+		os.Exit(1)
+	}
 	mongoMgr := srv.MongoMgr.GetDatabase()
 	redisClient := srv.RedisMgr.GetClient()
 
+	var publisher events.Publisher = events.NoopPublisher{}
+	if srv.KafkaMgr != nil {
+		asyncPublisher := events.NewAsyncPublisher(events.NewKafkaPublisher(srv.KafkaMgr, cfg.Kafka.UserEventsTopic),
+			cfg.Kafka.PublishQueueSize, cfg.Kafka.PublishTimeout, logger)
+		srv.RegisterWorker(worker.Func("event-publisher", asyncPublisher.Run))
+		publisher = asyncPublisher
+	}
+
+	// Media service client
+	mediaAddr, err := cfg.GetServiceGRPCAddr("media")
+	if err != nil {
+		logger.Error("failed to resolve media service address", "error", err)
+		os.Exit(1)
+	}
+	mediaConn, err := grpc.NewClient(mediaAddr, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		logger.Error("failed to create media service client", "error", err)
+		os.Exit(1)
+	}
+	defer mediaConn.Close()
+
 	// Business logic
-	authRepo := repositories.NewAuthRepository(mongoMgr, logger)
-	authService := services.NewAuthService(authRepo, oauthFactory, redisClient, jwtUtils, logger)
-	authHandler := handlers.NewAuthHandler(authService, srv.ErrorHandler, srv.Logger)
+	authRepo := repositories.NewAuthRepository(mongoMgr, &cfg.Mongo, logger)
+	if err := authRepo.EnsureIndexes(context.Background()); err != nil {
+		logger.Error("failed to ensure auth indexes", "error", err)
+		os.Exit(1)
+	}
+	authService := services.NewAuthService(authRepo, oauthFactory, redisClient, jwtUtils, &cfg.Security, passwordPolicy, passwordHasher, publisher, media_pb.NewMediaServiceClient(mediaConn), logger)
+	healthChecks := map[string]connection.HealthChecker{
+		"mongodb": srv.MongoMgr,
+		"redis":   srv.RedisMgr,
+	}
+	if srv.KafkaMgr != nil {
+		healthChecks["kafka"] = srv.KafkaMgr
+	}
+	if srv.AWSMgr != nil {
+		healthChecks["s3"] = srv.AWSMgr
+	}
+	trustedPeers, err := cfg.GRPC.TrustedPeerPrefixes()
+	if err != nil {
+		logger.Error("invalid grpc trusted peers", "error", err)
+		os.Exit(1)
+	}
+	authHandler := handlers.NewAuthHandler(authService, srv.ErrorHandler, healthChecks, trustedPeers, srv.Logger)
 
 	// Register gRPC service
 	auth_pb.RegisterAuthServiceServer(srv.GetGRPCServer(), authHandler)
 	logger.Info("auth service registered on gRPC server")
+	// token validation for sidecars that can't speak gRPC
+	if !srv.HandleHTTP("POST /v1/validate-token", http.HandlerFunc(authHandler.ValidateTokenHTTP)) {
+		logger.Info("HTTP validate endpoint disabled, enable_http is off for auth")
+	}
+	srv.MarkReady()
+
+	// Background jobs
+	srv.RegisterWorker(worker.Func("token-cleanup", func(ctx context.Context) error {
+		authService.RunTokenCleanup(ctx, srv.RedisMgr, cfg.JWT.TokenCleanupInterval, cfg.JWT.RevokedTokenRetention)
+		return nil
+	}))
 
 	// Start
 	if err := srv.Start(context.Background()); err != nil {
@@ -4,6 +4,8 @@ import (
 	"net/http"
 	"strings"
 
+	"remaster/shared/ctxkeys"
+
 	"github.com/gin-gonic/gin"
 	"github.com/golang-jwt/jwt/v5"
 )
@@ -29,8 +31,9 @@ func BearerMiddleware(jwtSecret string) gin.HandlerFunc {
 		}
 
 		if claims, ok := token.Claims.(jwt.MapClaims); ok {
-			c.Set("user_id", claims["user_id"])
-			c.Set("user_type", claims["user_type"])
+			userID, _ := claims["user_id"].(string)
+			userType, _ := claims["user_type"].(string)
+			c.Request = c.Request.WithContext(ctxkeys.WithUser(c.Request.Context(), userID, userType))
 		}
 
 		c.Next()
@@ -0,0 +1,29 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type AuditAction string
+
+const (
+	AuditPasswordChanged    AuditAction = "password_changed"
+	AuditOAuthAccountLinked AuditAction = "oauth_account_linked"
+	AuditAccountUnlocked    AuditAction = "account_unlocked"
+	AuditUserCreatedByAdmin AuditAction = "user_created_by_admin"
+)
+
+// AuditEntry is chained to the previous entry by hash so edits or deletions are detectable
+type AuditEntry struct {
+	ID           primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	ActorID      string             `bson:"actor_id" json:"actor_id"`
+	Action       AuditAction        `bson:"action" json:"action"`
+	TargetUserID string             `bson:"target_user_id" json:"target_user_id"`
+	IP           string             `bson:"ip,omitempty" json:"ip,omitempty"`
+	UserAgent    string             `bson:"user_agent,omitempty" json:"user_agent,omitempty"`
+	CreatedAt    time.Time          `bson:"created_at" json:"created_at"`
+	PrevHash     string             `bson:"prev_hash" json:"prev_hash"`
+	Hash         string             `bson:"hash" json:"hash"`
+}
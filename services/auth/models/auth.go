@@ -1,9 +1,6 @@
 package models
 
 import (
-	"errors"
-	"net/mail"
-	"strings"
 	"time"
 
 	"go.mongodb.org/mongo-driver/bson/primitive"
@@ -26,10 +23,11 @@ type User struct {
 	LastName  string             `bson:"last_name" json:"last_name" validate:"required,min=2,max=50"`
 	Phone     string             `bson:"phone" json:"phone" validate:"required"`
 	UserType  UserType           `bson:"user_type" json:"user_type" validate:"required,oneof=client master admin"`
+	OrgID     string             `bson:"org_id,omitempty" json:"org_id,omitempty"` // tenant, empty in single-tenant deployments
 
 	GoogleID     string `bson:"google_id,omitempty" json:"google_id,omitempty"`
 	GoogleEmail  string `bson:"google_email,omitempty" json:"google_email,omitempty"`
-	ProfileImage string `bson:"profile_image,omitempty" json:"profile_image,omitempty"`
+	ProfileImage string `bson:"profile_image,omitempty" json:"profile_image,omitempty"` // media key, or the provider's picture url for OAuth users
 
 	IsActive   bool `bson:"is_active" json:"is_active"`
 	IsVerified bool `bson:"is_verified" json:"is_verified"`
@@ -43,6 +41,8 @@ type User struct {
 	LastLoginIP      string     `bson:"last_login_ip,omitempty" json:"last_login_ip,omitempty"`
 	PasswordChangeAt time.Time  `bson:"password_changed_at" json:"password_changed_at"`
 	LockedUntil      *time.Time `bson:"locked_until,omitempty" json:"locked_until,omitempty"`
+	// lockouts since the last successful login, drives the lockout schedule
+	LockoutCount int `bson:"lockout_count" json:"lockout_count"`
 }
 
 type UserResponse struct {
@@ -59,10 +59,20 @@ type UserResponse struct {
 	LastLoginAt  *time.Time `json:"last_login_at,omitempty"`
 }
 
+// UserSearchCriteria filters the admin user search, zero values don't filter
+type UserSearchCriteria struct {
+	Email       string // case-insensitive substring
+	UserType    UserType
+	IsActive    *bool
+	IsVerified  *bool
+	CreatedFrom *time.Time
+	CreatedTo   *time.Time
+}
+
 type RefreshToken struct {
 	ID        primitive.ObjectID `bson:"_id,omitempty" json:"id"`
 	UserID    primitive.ObjectID `bson:"user_id" json:"user_id"`
-	Token     string             `bson:"token" json:"token"`
+	Token     string             `bson:"token" json:"token"` // sha-256 once stored, see utils.HashRefreshToken
 	ExpiresAt time.Time          `bson:"expires_at" json:"expires_at"`
 	CreatedAt time.Time          `bson:"created_at" json:"created_at"`
 	IsRevoked bool               `bson:"is_revoked" json:"is_revoked"`
@@ -84,6 +94,14 @@ type RefreshTokenRequest struct {
 	RefreshToken string `json:"refresh_token" validate:"required"`
 }
 
+// GuestTokenResponse is an anonymous session, UserID is a fresh id with no user behind it
+type GuestTokenResponse struct {
+	UserID      string `json:"user_id"`
+	AccessToken string `json:"access_token"`
+	ExpiresAt   int64  `json:"expires_at"`
+	TokenType   string `json:"token_type"`
+}
+
 type ValidateTokenRequest struct {
 	AccessToken string
 }
@@ -91,10 +109,19 @@ type ValidateTokenRequest struct {
 type ValidateTokenResponse struct {
 	Valid      bool
 	UserID     string
+	Email      string
 	UserType   UserType
 	IsActive   bool
 	IsVerified bool
 	ExpiresAt  int64
+	OrgID      string
+	// tokenauth.ScopePasswordChange when the token only allows changing the password
+	Scope string
+	// seconds left and whether that is under the refresh hint threshold
+	ExpiresIn     int64
+	ShouldRefresh bool
+	// set when Valid is false in batch results
+	Error string
 }
 
 type LoginAttempt struct {
@@ -112,10 +139,26 @@ type RegisterRequest struct {
 	Password  string   `json:"password" validate:"required,min=8"`
 	FirstName string   `json:"first_name" validate:"required,min=2,max=50"`
 	LastName  string   `json:"last_name" validate:"required,min=2,max=50"`
-	Phone     string   `json:"phone" validate:"required,min=6,max=10"`
+	Phone     string   `json:"phone" validate:"required,max=32"` // normalized to E.164 by the auth service
 	UserType  UserType `json:"user_type" validate:"required,oneof=client master"`
 }
 
+// AdminCreateUserRequest provisions an account on someone's behalf, the password is generated
+type AdminCreateUserRequest struct {
+	Email     string   `json:"email" validate:"required,email"`
+	FirstName string   `json:"first_name" validate:"required,min=2,max=50"`
+	LastName  string   `json:"last_name" validate:"required,min=2,max=50"`
+	Phone     string   `json:"phone" validate:"required,max=32"`
+	UserType  UserType `json:"user_type" validate:"required,oneof=client master admin"`
+	Verified  bool     `json:"verified"`
+}
+
+// AdminCreateUserResponse carries the temporary password, it is not stored or shown again
+type AdminCreateUserResponse struct {
+	User              *UserResponse `json:"user"`
+	TemporaryPassword string        `json:"temporary_password"`
+}
+
 type RegisterResponse struct {
 	Success bool   `json:"success"`
 	Message string `json:"message"`
@@ -128,7 +171,7 @@ type LoginRequest struct {
 }
 
 type OAuthLoginRequest struct {
-	Provider string `json:"provider" validate:"required,oneof=google"`
+	Provider string `json:"provider" validate:"required,oneof=google facebook"`
 	IDToken  string `json:"id_token" validate:"required"`
 }
 
@@ -138,12 +181,22 @@ type ChangePasswordRequest struct {
 	NewPassword string `json:"new_password" validate:"required,min=8"`
 }
 
+type UpdateProfileImageRequest struct {
+	UserID   string `json:"user_id" validate:"required"`
+	MediaKey string `json:"media_key" validate:"required"`
+}
+
 type AuthResponse struct {
 	User         *UserResponse `json:"user"`
 	AccessToken  string        `json:"access_token"`
 	RefreshToken string        `json:"refresh_token"`
 	ExpiresAt    int64         `json:"expires_at"`
 	TokenType    string        `json:"token_type"`
+	// set when the password is older than security.max_password_age, no refresh
+	// token is issued until the password has been changed
+	PasswordExpired bool `json:"password_expired,omitempty"`
+	// neither the device id nor the IP was seen in the user's earlier sessions
+	NewDevice bool `json:"new_device,omitempty"`
 }
 
 type LogoutRequest struct {
@@ -186,7 +239,6 @@ func (u *User) BeforeCreate() {
 	u.UpdatedAt = now
 	u.PasswordChangeAt = now
 	u.IsActive = true
-	u.IsVerified = false
 	u.LoginAttempts = 0
 
 	if u.ID.IsZero() {
@@ -197,28 +249,3 @@ func (u *User) BeforeCreate() {
 func (u *User) BeforeUpdate() {
 	u.UpdatedAt = time.Now()
 }
-
-func (req *RegisterRequest) ValidateRegisterRequest() error {
-	var errs []string
-
-	if _, err := mail.ParseAddress(req.Email); err != nil {
-		errs = append(errs, "invalid email format")
-	}
-	if len(req.Password) < 8 {
-		errs = append(errs, "password must be at least 8 characters long")
-	}
-	if strings.TrimSpace(req.FirstName) == "" || len(req.FirstName) < 2 || len(req.FirstName) > 50 {
-		errs = append(errs, "first name must be between 2 and 50 characters")
-	}
-	if strings.TrimSpace(req.LastName) == "" || len(req.LastName) < 2 || len(req.LastName) > 50 {
-		errs = append(errs, "last name must be between 2 and 50 characters")
-	}
-	if req.UserType != UserTypeMaster && req.UserType != UserTypeClient {
-		errs = append(errs, "invalid user type, must be 'client' or 'master'")
-	}
-
-	if len(errs) > 0 {
-		return errors.New(strings.Join(errs, ", "))
-	}
-	return nil
-}
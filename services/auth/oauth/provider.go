@@ -3,9 +3,11 @@ package oauth
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
 	"strings"
+	"time"
 
 	config "remaster/shared"
 
@@ -20,6 +22,7 @@ const (
 )
 
 type Claims struct {
+	Subject   string // provider's stable user id
 	Email     string
 	FirstName string
 	LastName  string
@@ -30,18 +33,30 @@ type OAuthProvider interface {
 	VerifyIDToken(ctx context.Context, token string) (*Claims, error)
 }
 
+// upper bound for a single provider call, the caller's ctx may cut it shorter
+const providerTimeout = 5 * time.Second
+
+// shared by all providers so connections are reused
+var httpClient = &http.Client{Timeout: providerTimeout}
+
 // ==== Google ====
 
 type GoogleProvider struct {
-	clientID string
+	clientID  string
+	validator *idtoken.Validator
 }
 
 func NewGoogleProvider(clientID string) *GoogleProvider {
-	return &GoogleProvider{clientID: clientID}
+	// NewValidator only fails on invalid options
+	validator, _ := idtoken.NewValidator(&idtoken.ValidatorOptions{Client: httpClient})
+	return &GoogleProvider{clientID: clientID, validator: validator}
 }
 
 func (p *GoogleProvider) VerifyIDToken(ctx context.Context, idToken string) (*Claims, error) {
-	payload, err := idtoken.Validate(ctx, idToken, p.clientID)
+	ctx, cancel := context.WithTimeout(ctx, providerTimeout)
+	defer cancel()
+
+	payload, err := p.validator.Validate(ctx, idToken, p.clientID)
 	if err != nil {
 		return nil, fmt.Errorf("google: token validation failed: %w", err)
 	}
@@ -59,6 +74,7 @@ func (p *GoogleProvider) VerifyIDToken(ctx context.Context, idToken string) (*Cl
 	}
 
 	return &Claims{
+		Subject:   payload.Subject,
 		Email:     email,
 		FirstName: firstName,
 		LastName:  lastName,
@@ -79,28 +95,21 @@ func NewFacebookProvider(appID, appSecret string) *FacebookProvider {
 }
 
 func (p *FacebookProvider) VerifyIDToken(ctx context.Context, accessToken string) (*Claims, error) {
+	ctx, cancel := context.WithTimeout(ctx, providerTimeout)
+	defer cancel()
+
 	debugURL := fmt.Sprintf(
 		"https://graph.facebook.com/debug_token?input_token=%s&access_token=%s|%s",
 		accessToken, p.appID, p.appSecret,
 	)
 
-	resp, err := http.Get(debugURL)
-	if err != nil {
-		return nil, fmt.Errorf("facebook: debug request failed: %w", err)
-	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("facebook: debug request failed with status %d", resp.StatusCode)
-	}
-
 	var debugResp struct {
 		Data struct {
 			IsValid bool `json:"is_valid"`
 		} `json:"data"`
 	}
-	if err := json.NewDecoder(resp.Body).Decode(&debugResp); err != nil {
-		return nil, fmt.Errorf("facebook: failed to decode debug response: %w", err)
+	if err := getJSON(ctx, debugURL, &debugResp); err != nil {
+		return nil, fmt.Errorf("facebook: debug request failed: %w", err)
 	}
 
 	if !debugResp.Data.IsValid {
@@ -112,17 +121,8 @@ func (p *FacebookProvider) VerifyIDToken(ctx context.Context, accessToken string
 		accessToken,
 	)
 
-	resp, err = http.Get(userURL)
-	if err != nil {
-		return nil, fmt.Errorf("facebook: user request failed: %w", err)
-	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("facebook: user request failed with status %d", resp.StatusCode)
-	}
-
 	var userResp struct {
+		ID        string `json:"id"`
 		Email     string `json:"email"`
 		FirstName string `json:"first_name"`
 		LastName  string `json:"last_name"`
@@ -132,11 +132,12 @@ func (p *FacebookProvider) VerifyIDToken(ctx context.Context, accessToken string
 			} `json:"data"`
 		} `json:"picture"`
 	}
-	if err := json.NewDecoder(resp.Body).Decode(&userResp); err != nil {
-		return nil, fmt.Errorf("facebook: failed to decode user response: %w", err)
+	if err := getJSON(ctx, userURL, &userResp); err != nil {
+		return nil, fmt.Errorf("facebook: user request failed: %w", err)
 	}
 
 	return &Claims{
+		Subject:   userResp.ID,
 		Email:     userResp.Email,
 		FirstName: userResp.FirstName,
 		LastName:  userResp.LastName,
@@ -144,25 +145,82 @@ func (p *FacebookProvider) VerifyIDToken(ctx context.Context, accessToken string
 	}, nil
 }
 
+// getJSON performs a GET bound to ctx and decodes the JSON body into dst
+func getJSON(ctx context.Context, url string, dst any) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return err
+	}
+
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("unexpected status %d", resp.StatusCode)
+	}
+
+	if err := json.NewDecoder(resp.Body).Decode(dst); err != nil {
+		return fmt.Errorf("failed to decode response: %w", err)
+	}
+	return nil
+}
+
 // ==== Factory ====
 
+// ErrProviderNotEnabled is returned by GetProvider for a known provider missing from oauth.enabled_providers
+var ErrProviderNotEnabled = errors.New("oauth provider not enabled")
+
 type ProviderFactory struct {
 	providers map[ProviderType]OAuthProvider
 }
 
-func NewProviderFactory(cfg *config.OAuthConfig) *ProviderFactory {
-	return &ProviderFactory{
-		providers: map[ProviderType]OAuthProvider{
-			Google:   NewGoogleProvider(cfg.GoogleClientID),
-			Facebook: NewFacebookProvider(cfg.FacebookAppID, cfg.FacebookAppSecret), // not tested
-		},
+// NewProviderFactory builds only the providers in oauth.enabled_providers and fails when
+// one of them has no credentials, instead of failing every login at runtime
+func NewProviderFactory(cfg *config.OAuthConfig) (*ProviderFactory, error) {
+	f := &ProviderFactory{providers: make(map[ProviderType]OAuthProvider, len(cfg.EnabledProviders))}
+	for _, name := range cfg.EnabledProviders {
+		p, err := newProvider(ProviderType(name), cfg)
+		if err != nil {
+			return nil, err
+		}
+		f.providers[ProviderType(name)] = p
+	}
+	return f, nil
+}
+
+func newProvider(provider ProviderType, cfg *config.OAuthConfig) (OAuthProvider, error) {
+	switch provider {
+	case Google:
+		if cfg.GoogleClientID == "" {
+			return nil, fmt.Errorf("oauth provider google is enabled but google_client_id is empty")
+		}
+		return NewGoogleProvider(cfg.GoogleClientID), nil
+	case Facebook:
+		if cfg.FacebookAppID == "" || cfg.FacebookAppSecret == "" {
+			return nil, fmt.Errorf("oauth provider facebook is enabled but facebook_app_id or facebook_app_secret is empty")
+		}
+		return NewFacebookProvider(cfg.FacebookAppID, cfg.FacebookAppSecret), nil // not tested
+	default:
+		return nil, fmt.Errorf("unknown oauth provider %q", provider)
 	}
 }
 
+// Register sets the provider for a type, replacing the configured one
+func (f *ProviderFactory) Register(provider ProviderType, p OAuthProvider) {
+	f.providers[provider] = p
+}
+
 func (f *ProviderFactory) GetProvider(provider ProviderType) (OAuthProvider, error) {
 	if p, ok := f.providers[provider]; ok {
 		return p, nil
 	}
+	switch provider {
+	case Google, Facebook:
+		return nil, fmt.Errorf("%w: %s", ErrProviderNotEnabled, provider)
+	}
 	return nil, fmt.Errorf("provider %s not supported", provider)
 }
 
@@ -0,0 +1,126 @@
+package oauth
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	config "remaster/shared"
+)
+
+// slowServer answers after delay unless the client gives up first
+func slowServer(t *testing.T, delay time.Duration) *httptest.Server {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-time.After(delay):
+			w.Header().Set("Content-Type", "application/json")
+			_, _ = w.Write([]byte(`{"id":"123"}`))
+		case <-r.Context().Done():
+		}
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func TestGetJSONReturnsPromptlyOnCancel(t *testing.T) {
+	srv := slowServer(t, 10*time.Second)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(50*time.Millisecond, cancel)
+
+	start := time.Now()
+	var dst struct{ ID string }
+	err := getJSON(ctx, srv.URL, &dst)
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("getJSON = %v, want context.Canceled", err)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Fatalf("getJSON took %v after cancel, want it to return promptly", elapsed)
+	}
+}
+
+func TestGetJSONHonorsDeadline(t *testing.T) {
+	srv := slowServer(t, 10*time.Second)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	var dst struct{ ID string }
+	if err := getJSON(ctx, srv.URL, &dst); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("getJSON = %v, want context.DeadlineExceeded", err)
+	}
+}
+
+func TestGetJSONDecodesResponse(t *testing.T) {
+	srv := slowServer(t, 0)
+
+	var dst struct{ ID string }
+	if err := getJSON(context.Background(), srv.URL, &dst); err != nil {
+		t.Fatalf("getJSON: %v", err)
+	}
+	if dst.ID != "123" {
+		t.Fatalf("decoded id = %q, want 123", dst.ID)
+	}
+}
+
+func TestProviderClientHasTimeout(t *testing.T) {
+	if httpClient.Timeout != providerTimeout || providerTimeout <= 0 {
+		t.Fatalf("shared client timeout = %v, want %v", httpClient.Timeout, providerTimeout)
+	}
+}
+
+func TestProviderFactoryResolvesOnlyEnabledProviders(t *testing.T) {
+	f, err := NewProviderFactory(&config.OAuthConfig{
+		EnabledProviders: []string{"google"},
+		GoogleClientID:   "client-id",
+	})
+	if err != nil {
+		t.Fatalf("NewProviderFactory: %v", err)
+	}
+
+	if p, err := f.GetProvider(Google); err != nil || p == nil {
+		t.Fatalf("GetProvider(google) = %v, %v, want the provider", p, err)
+	}
+	if _, err := f.GetProvider(Facebook); !errors.Is(err, ErrProviderNotEnabled) {
+		t.Fatalf("GetProvider(facebook) = %v, want ErrProviderNotEnabled", err)
+	}
+	if _, err := f.GetProvider("myspace"); err == nil || errors.Is(err, ErrProviderNotEnabled) {
+		t.Fatalf("GetProvider(myspace) = %v, want an unsupported provider error", err)
+	}
+}
+
+func TestProviderFactoryWithNoProviders(t *testing.T) {
+	f, err := NewProviderFactory(&config.OAuthConfig{GoogleClientID: "client-id"})
+	if err != nil {
+		t.Fatalf("NewProviderFactory: %v", err)
+	}
+	for _, p := range []ProviderType{Google, Facebook} {
+		if _, err := f.GetProvider(p); !errors.Is(err, ErrProviderNotEnabled) {
+			t.Fatalf("GetProvider(%s) = %v, want ErrProviderNotEnabled", p, err)
+		}
+	}
+}
+
+func TestProviderFactoryRejectsMisconfiguredProviders(t *testing.T) {
+	tests := []struct {
+		name string
+		cfg  config.OAuthConfig
+		want string
+	}{
+		{"google without client id", config.OAuthConfig{EnabledProviders: []string{"google"}}, "google_client_id"},
+		{"facebook without secret", config.OAuthConfig{EnabledProviders: []string{"facebook"}, FacebookAppID: "app"}, "facebook_app_secret"},
+		{"unknown provider", config.OAuthConfig{EnabledProviders: []string{"myspace"}}, "myspace"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := NewProviderFactory(&tt.cfg); err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Fatalf("NewProviderFactory = %v, want an error naming %s", err, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,475 @@
+package memory
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	models "remaster/services/auth/models"
+	repo "remaster/services/auth/repositories"
+	"remaster/services/auth/utils"
+	"remaster/shared/db"
+	et "remaster/shared/errors"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+var _ repo.AuthRepositoryInterface = (*Repository)(nil)
+
+// errDuplicateKey is what Create and LinkGoogleAccount wrap on unique violations
+var errDuplicateKey = errors.New("duplicate key")
+
+// Repository is a map-backed AuthRepositoryInterface for running the auth
+// service without mongo, errors mirror the mongo repository
+type Repository struct {
+	mu            sync.RWMutex
+	users         map[primitive.ObjectID]*models.User
+	refreshTokens map[primitive.ObjectID]*models.RefreshToken
+	audit         []*models.AuditEntry
+}
+
+func NewRepository() *Repository {
+	return &Repository{
+		users:         make(map[primitive.ObjectID]*models.User),
+		refreshTokens: make(map[primitive.ObjectID]*models.RefreshToken),
+	}
+}
+
+func (r *Repository) EnsureIndexes(ctx context.Context) error {
+	return nil
+}
+
+func (r *Repository) IsUniqueConstraintError(err error) bool {
+	return errors.Is(err, errDuplicateKey)
+}
+
+func (r *Repository) Create(ctx context.Context, user *models.User) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, u := range r.users {
+		if u.Email == user.Email {
+			return et.NewConflictError(fmt.Sprintf("user with email %s already exists", user.Email), errDuplicateKey)
+		}
+		if user.GoogleID != "" && u.GoogleID == user.GoogleID {
+			return et.NewConflictError("google account is linked to another user", errDuplicateKey)
+		}
+	}
+
+	user.BeforeCreate()
+	r.users[user.ID] = cloneUser(user)
+	return nil
+}
+
+func (r *Repository) GetByEmail(ctx context.Context, email string) (*models.User, error) {
+	return r.findUser(func(u *models.User) bool { return u.Email == email })
+}
+
+func (r *Repository) GetByID(ctx context.Context, id primitive.ObjectID) (*models.User, error) {
+	return r.findUser(func(u *models.User) bool { return u.ID == id })
+}
+
+func (r *Repository) GetByGoogleID(ctx context.Context, googleID string) (*models.User, error) {
+	return r.findUser(func(u *models.User) bool { return u.GoogleID == googleID })
+}
+
+func (r *Repository) GetUsersByIDs(ctx context.Context, ids []primitive.ObjectID) ([]*models.User, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	users := make([]*models.User, 0, len(ids))
+	for _, id := range ids {
+		if u, ok := r.users[id]; ok {
+			users = append(users, cloneUser(u))
+		}
+	}
+	return users, nil
+}
+
+func (r *Repository) SearchUsers(ctx context.Context, criteria models.UserSearchCriteria, page db.PageRequest) (*db.PageResponse[*models.User], error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	page = page.Normalize()
+	email := strings.ToLower(criteria.Email)
+
+	matched := make([]*models.User, 0)
+	for _, u := range r.users {
+		switch {
+		case email != "" && !strings.Contains(strings.ToLower(u.Email), email),
+			criteria.UserType != "" && u.UserType != criteria.UserType,
+			criteria.IsActive != nil && u.IsActive != *criteria.IsActive,
+			criteria.IsVerified != nil && u.IsVerified != *criteria.IsVerified,
+			criteria.CreatedFrom != nil && u.CreatedAt.Before(*criteria.CreatedFrom),
+			criteria.CreatedTo != nil && u.CreatedAt.After(*criteria.CreatedTo):
+			continue
+		}
+		matched = append(matched, cloneUser(u))
+	}
+
+	// newest first, ties broken by id like the mongo sort
+	sort.Slice(matched, func(i, j int) bool {
+		if !matched[i].CreatedAt.Equal(matched[j].CreatedAt) {
+			return matched[i].CreatedAt.After(matched[j].CreatedAt)
+		}
+		return bytes.Compare(matched[i].ID[:], matched[j].ID[:]) > 0
+	})
+
+	total := int64(len(matched))
+	start := min(page.Skip(), total)
+	end := min(start+page.PageSize, total)
+
+	return &db.PageResponse[*models.User]{
+		Items:      matched[start:end],
+		Total:      total,
+		Page:       page.Page,
+		PageSize:   page.PageSize,
+		TotalPages: (total + page.PageSize - 1) / page.PageSize,
+	}, nil
+}
+
+func (r *Repository) LinkGoogleAccount(ctx context.Context, userID primitive.ObjectID, googleID, googleEmail string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for id, u := range r.users {
+		if id != userID && u.GoogleID == googleID {
+			return et.NewConflictError("google account is linked to another user", errDuplicateKey)
+		}
+	}
+	if u, ok := r.users[userID]; ok {
+		u.GoogleID = googleID
+		u.GoogleEmail = googleEmail
+		u.UpdatedAt = time.Now()
+	}
+	return nil
+}
+
+func (r *Repository) UpdateLoginInfo(ctx context.Context, userID primitive.ObjectID, ipAddress string) error {
+	return r.updateUser(userID, func(u *models.User) {
+		now := time.Now()
+		u.LastLoginAt = &now
+		u.LastLoginIP = ipAddress
+	})
+}
+
+func (r *Repository) LockUserAccount(ctx context.Context, userID primitive.ObjectID, duration time.Duration) error {
+	return r.updateUser(userID, func(u *models.User) {
+		lockedUntil := time.Now().Add(duration)
+		u.LockedUntil = &lockedUntil
+		u.LoginAttempts = 0
+		u.LockoutCount++
+	})
+}
+
+func (r *Repository) UpdatePassword(ctx context.Context, userID primitive.ObjectID, hashedPassword string) error {
+	return r.updateUser(userID, func(u *models.User) {
+		u.Password = hashedPassword
+	})
+}
+
+func (r *Repository) ChangePassword(ctx context.Context, userID primitive.ObjectID, hashedPassword string) error {
+	return r.updateUser(userID, func(u *models.User) {
+		u.Password = hashedPassword
+		u.PasswordChangeAt = time.Now()
+	})
+}
+
+func (r *Repository) UpdateProfileImage(ctx context.Context, userID primitive.ObjectID, image string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	u, ok := r.users[userID]
+	if !ok {
+		return et.NewNotFoundError("user not found", nil)
+	}
+	u.ProfileImage = image
+	u.UpdatedAt = time.Now()
+	return nil
+}
+
+func (r *Repository) IncrementLoginAttempts(ctx context.Context, userID primitive.ObjectID) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	u, ok := r.users[userID]
+	if !ok {
+		return 0, mongo.ErrNoDocuments
+	}
+	u.LoginAttempts++
+	return u.LoginAttempts, nil
+}
+
+func (r *Repository) ResetLoginAttempts(ctx context.Context, userID primitive.ObjectID) error {
+	return r.updateUser(userID, func(u *models.User) {
+		u.LoginAttempts = 0
+		u.LockoutCount = 0
+		u.LockedUntil = nil
+	})
+}
+
+func (r *Repository) SaveRefreshToken(ctx context.Context, token *models.RefreshToken) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	token.ID = primitive.NewObjectID()
+	t := *token
+	t.Token = utils.HashRefreshToken(token.Token)
+	r.refreshTokens[t.ID] = &t
+	return nil
+}
+
+func (r *Repository) FindRefreshToken(ctx context.Context, token string) (*models.RefreshToken, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	hash := utils.HashRefreshToken(token)
+	for _, t := range r.refreshTokens {
+		if t.Token == hash {
+			found := *t
+			return &found, nil
+		}
+	}
+	return nil, et.NewUnauthorizedError("refresh token not found")
+}
+
+func (r *Repository) RevokeRefreshToken(ctx context.Context, tokenID primitive.ObjectID) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if t, ok := r.refreshTokens[tokenID]; ok {
+		t.IsRevoked = true
+	}
+	return nil
+}
+
+func (r *Repository) RevokeAllUserRefreshTokens(ctx context.Context, userID primitive.ObjectID) (int64, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var revoked int64
+	for _, t := range r.refreshTokens {
+		if t.UserID == userID && !t.IsRevoked {
+			t.IsRevoked = true
+			revoked++
+		}
+	}
+	return revoked, nil
+}
+
+// ListSessions returns the user's active refresh tokens, oldest first
+func (r *Repository) ListSessions(ctx context.Context, userID primitive.ObjectID) ([]*models.RefreshToken, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	now := time.Now()
+	sessions := make([]*models.RefreshToken, 0)
+	for _, t := range r.refreshTokens {
+		if t.UserID == userID && !t.IsRevoked && t.ExpiresAt.After(now) {
+			s := *t
+			sessions = append(sessions, &s)
+		}
+	}
+	sort.Slice(sessions, func(i, j int) bool {
+		return sessions[i].CreatedAt.Before(sessions[j].CreatedAt)
+	})
+	return sessions, nil
+}
+
+func (r *Repository) LoginHistory(ctx context.Context, userID primitive.ObjectID, deviceID, ip string) (bool, bool, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	hasHistory := false
+	for _, t := range r.refreshTokens {
+		if t.UserID != userID {
+			continue
+		}
+		hasHistory = true
+		if (deviceID != "" && t.DeviceID == deviceID) || (ip != "" && t.IP == ip) {
+			return true, true, nil
+		}
+	}
+	return hasHistory, false, nil
+}
+
+func (r *Repository) ListSessionsPage(ctx context.Context, userID primitive.ObjectID, page db.CursorRequest) (*db.CursorResponse[*models.RefreshToken], error) {
+	r.mu.RLock()
+	now := time.Now()
+	sessions := make([]*models.RefreshToken, 0)
+	for _, t := range r.refreshTokens {
+		if t.UserID == userID && !t.IsRevoked && t.ExpiresAt.After(now) {
+			s := *t
+			sessions = append(sessions, &s)
+		}
+	}
+	r.mu.RUnlock()
+
+	return cursorPage(sessions, page, false, func(t *models.RefreshToken) primitive.ObjectID { return t.ID })
+}
+
+func (r *Repository) CleanExpiredTokens(ctx context.Context, revokedRetention time.Duration) (int64, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	now := time.Now()
+	var deleted int64
+	for id, t := range r.refreshTokens {
+		if t.ExpiresAt.Before(now) || (t.IsRevoked && t.CreatedAt.Before(now.Add(-revokedRetention))) {
+			delete(r.refreshTokens, id)
+			deleted++
+		}
+	}
+	return deleted, nil
+}
+
+func (r *Repository) InsertAuditEntry(ctx context.Context, entry *models.AuditEntry) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if entry.ID.IsZero() {
+		entry.ID = primitive.NewObjectID()
+	}
+	e := *entry
+	r.audit = append(r.audit, &e)
+	return nil
+}
+
+// GetLastAuditEntry returns nil when the audit log is empty
+func (r *Repository) GetLastAuditEntry(ctx context.Context) (*models.AuditEntry, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	if len(r.audit) == 0 {
+		return nil, nil
+	}
+	e := *r.audit[len(r.audit)-1]
+	return &e, nil
+}
+
+func (r *Repository) ListAuditEntries(ctx context.Context, targetUserID string, page db.PageRequest) (*db.PageResponse[*models.AuditEntry], error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	page = page.Normalize()
+
+	// newest first, like the created_at sort in mongo
+	matched := make([]*models.AuditEntry, 0)
+	for i := len(r.audit) - 1; i >= 0; i-- {
+		if targetUserID == "" || r.audit[i].TargetUserID == targetUserID {
+			e := *r.audit[i]
+			matched = append(matched, &e)
+		}
+	}
+
+	total := int64(len(matched))
+	start := min(page.Skip(), total)
+	end := min(start+page.PageSize, total)
+
+	return &db.PageResponse[*models.AuditEntry]{
+		Items:      matched[start:end],
+		Total:      total,
+		Page:       page.Page,
+		PageSize:   page.PageSize,
+		TotalPages: (total + page.PageSize - 1) / page.PageSize,
+	}, nil
+}
+
+func (r *Repository) ListAuditEntriesByCursor(ctx context.Context, targetUserID string, page db.CursorRequest) (*db.CursorResponse[*models.AuditEntry], error) {
+	r.mu.RLock()
+	matched := make([]*models.AuditEntry, 0)
+	for _, e := range r.audit {
+		if targetUserID == "" || e.TargetUserID == targetUserID {
+			c := *e
+			matched = append(matched, &c)
+		}
+	}
+	r.mu.RUnlock()
+
+	return cursorPage(matched, page, true, func(e *models.AuditEntry) primitive.ObjectID { return e.ID })
+}
+
+// cursorPage applies db.CursorPaginate's keyset rules to an in-memory slice
+func cursorPage[T any](items []T, page db.CursorRequest, descending bool, idOf func(T) primitive.ObjectID) (*db.CursorResponse[T], error) {
+	less := func(a, b primitive.ObjectID) bool { return bytes.Compare(a[:], b[:]) < 0 }
+	sort.Slice(items, func(i, j int) bool {
+		if descending {
+			return less(idOf(items[j]), idOf(items[i]))
+		}
+		return less(idOf(items[i]), idOf(items[j]))
+	})
+
+	if page.Cursor != "" {
+		after, err := db.DecodeCursor(page.Cursor)
+		if err != nil {
+			return nil, et.NewValidationError("invalid cursor", map[string]string{"cursor": "is malformed"})
+		}
+		start := sort.Search(len(items), func(i int) bool {
+			if descending {
+				return less(idOf(items[i]), after)
+			}
+			return less(after, idOf(items[i]))
+		})
+		items = items[start:]
+	}
+
+	limit := page.Limit
+	if limit < 1 {
+		limit = db.DefaultPageSize
+	}
+	limit = min(limit, db.MaxPageSize)
+
+	resp := &db.CursorResponse[T]{Items: items}
+	if int64(len(items)) > limit {
+		resp.Items = items[:limit]
+		resp.NextCursor = db.EncodeCursor(idOf(resp.Items[limit-1]))
+	}
+	return resp, nil
+}
+
+// findUser returns a copy so callers can't mutate stored state
+func (r *Repository) findUser(match func(*models.User) bool) (*models.User, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	for _, u := range r.users {
+		if match(u) {
+			return cloneUser(u), nil
+		}
+	}
+	return nil, mongo.ErrNoDocuments
+}
+
+// updateUser is a no-op for unknown ids, matching UpdateByID without upsert
+func (r *Repository) updateUser(userID primitive.ObjectID, apply func(*models.User)) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if u, ok := r.users[userID]; ok {
+		apply(u)
+	}
+	return nil
+}
+
+func cloneUser(u *models.User) *models.User {
+	c := *u
+	if u.LastLoginAt != nil {
+		t := *u.LastLoginAt
+		c.LastLoginAt = &t
+	}
+	if u.EmailVerifiedAt != nil {
+		t := *u.EmailVerifiedAt
+		c.EmailVerifiedAt = &t
+	}
+	if u.LockedUntil != nil {
+		t := *u.LockedUntil
+		c.LockedUntil = &t
+	}
+	return &c
+}
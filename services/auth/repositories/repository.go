@@ -5,29 +5,46 @@ import (
 	"errors"
 	"fmt"
 	"log/slog"
+	"regexp"
 	"time"
 
 	models "remaster/services/auth/models"
+	"remaster/services/auth/utils"
+	config "remaster/shared"
+	"remaster/shared/db"
 	et "remaster/shared/errors"
 
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 	"go.mongodb.org/mongo-driver/mongo"
 	"go.mongodb.org/mongo-driver/mongo/options"
+	"go.mongodb.org/mongo-driver/mongo/readpref"
+	"go.mongodb.org/mongo-driver/mongo/writeconcern"
 )
 
 type authRepositoryImpl struct {
 	usersCol         *mongo.Collection
 	refreshTokensCol *mongo.Collection
 	loginAttemptsCol *mongo.Collection
+	auditCol         *mongo.Collection
 	logger           *slog.Logger
 }
 
-func NewAuthRepository(db *mongo.Database, logger *slog.Logger) *authRepositoryImpl {
+func NewAuthRepository(database *mongo.Database, mongoCfg *config.MongoConfig, logger *slog.Logger) *authRepositoryImpl {
+	// a refresh right after a revoke must not see the token as still valid
+	tokenOpts := options.Collection()
+	if mongoCfg.TokenWriteMajority {
+		tokenOpts.SetWriteConcern(writeconcern.Majority())
+	}
+	if mongoCfg.TokenReadPrimary {
+		tokenOpts.SetReadPreference(readpref.Primary())
+	}
+
 	repo := &authRepositoryImpl{
-		usersCol:         db.Collection("users"),
-		refreshTokensCol: db.Collection("refresh_tokens"),
-		loginAttemptsCol: db.Collection("login_attempts"),
+		usersCol:         database.Collection("users"),
+		refreshTokensCol: database.Collection("refresh_tokens", tokenOpts),
+		loginAttemptsCol: database.Collection("login_attempts"),
+		auditCol:         database.Collection("audit"),
 		logger:           logger.With(slog.String("auth", "repository")),
 	}
 	return repo
@@ -38,14 +55,34 @@ type AuthRepositoryInterface interface {
 	Create(ctx context.Context, user *models.User) error
 	GetByEmail(ctx context.Context, email string) (*models.User, error)
 	GetByID(ctx context.Context, id primitive.ObjectID) (*models.User, error)
+	GetByGoogleID(ctx context.Context, googleID string) (*models.User, error)
+	GetUsersByIDs(ctx context.Context, ids []primitive.ObjectID) ([]*models.User, error)
+	LinkGoogleAccount(ctx context.Context, userID primitive.ObjectID, googleID, googleEmail string) error
 	UpdateLoginInfo(ctx context.Context, userID primitive.ObjectID, ipAddress string) error
 	LockUserAccount(ctx context.Context, userID primitive.ObjectID, duration time.Duration) error
 	UpdatePassword(ctx context.Context, userID primitive.ObjectID, hashedPassword string) error
+	// ChangePassword also restarts the password age, UpdatePassword is for rehashing the same one
+	ChangePassword(ctx context.Context, userID primitive.ObjectID, hashedPassword string) error
+	UpdateProfileImage(ctx context.Context, userID primitive.ObjectID, image string) error
 
 	// Refresh token operations
 	SaveRefreshToken(ctx context.Context, token *models.RefreshToken) error
 	FindRefreshToken(ctx context.Context, token string) (*models.RefreshToken, error)
 	RevokeRefreshToken(ctx context.Context, tokenID primitive.ObjectID) error
+	RevokeAllUserRefreshTokens(ctx context.Context, userID primitive.ObjectID) (int64, error)
+	ListSessions(ctx context.Context, userID primitive.ObjectID) ([]*models.RefreshToken, error)
+	// LoginHistory reports whether the user has any stored session, revoked ones included,
+	// and whether one came from deviceID or ip. Empty values never match
+	LoginHistory(ctx context.Context, userID primitive.ObjectID, deviceID, ip string) (hasHistory, known bool, err error)
+	SearchUsers(ctx context.Context, criteria models.UserSearchCriteria, page db.PageRequest) (*db.PageResponse[*models.User], error)
+	ListSessionsPage(ctx context.Context, userID primitive.ObjectID, page db.CursorRequest) (*db.CursorResponse[*models.RefreshToken], error)
+	CleanExpiredTokens(ctx context.Context, revokedRetention time.Duration) (int64, error)
+
+	// Audit
+	InsertAuditEntry(ctx context.Context, entry *models.AuditEntry) error
+	GetLastAuditEntry(ctx context.Context) (*models.AuditEntry, error)
+	ListAuditEntries(ctx context.Context, targetUserID string, page db.PageRequest) (*db.PageResponse[*models.AuditEntry], error)
+	ListAuditEntriesByCursor(ctx context.Context, targetUserID string, page db.CursorRequest) (*db.CursorResponse[*models.AuditEntry], error)
 
 	// Login attempts
 	IncrementLoginAttempts(ctx context.Context, userID primitive.ObjectID) (int, error)
@@ -69,6 +106,47 @@ func (r *authRepositoryImpl) EnsureIndexes(ctx context.Context) error {
 		return fmt.Errorf("create users.email index: %w", err)
 	}
 
+	// sparse - password users have no google_id
+	_, err = r.usersCol.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys:    bson.D{{Key: "google_id", Value: 1}},
+		Options: options.Index().SetUnique(true).SetSparse(true).SetName("idx_users_google_id_unique"),
+	})
+	if err != nil {
+		r.logger.Error("Failed to create users.google_id index", "error", err)
+		return fmt.Errorf("create users.google_id index: %w", err)
+	}
+
+	// admin search narrows by type and sorts by creation, the email substring match scans
+	// whatever is left since a regex without an anchored prefix can't use an index
+	_, err = r.usersCol.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys:    bson.D{{Key: "user_type", Value: 1}, {Key: "created_at", Value: -1}},
+		Options: options.Index().SetName("idx_users_type_created"),
+	})
+	if err != nil {
+		r.logger.Error("Failed to create users.user_type index", "error", err)
+		return fmt.Errorf("create users.user_type index: %w", err)
+	}
+
+	_, err = r.auditCol.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{
+			Keys:    bson.D{{Key: "created_at", Value: -1}},
+			Options: options.Index().SetName("idx_audit_created"),
+		},
+		{
+			Keys:    bson.D{{Key: "target_user_id", Value: 1}, {Key: "created_at", Value: -1}},
+			Options: options.Index().SetName("idx_audit_target_created"),
+		},
+		// listings page on _id, see ListAuditEntriesByCursor
+		{
+			Keys:    bson.D{{Key: "target_user_id", Value: 1}, {Key: "_id", Value: -1}},
+			Options: options.Index().SetName("idx_audit_target_id"),
+		},
+	})
+	if err != nil {
+		r.logger.Error("Failed to create audit indexes", "error", err)
+		return fmt.Errorf("create audit indexes: %w", err)
+	}
+
 	r.logger.Info("Database indexes created successfully")
 	return nil
 }
@@ -110,6 +188,46 @@ func (r *authRepositoryImpl) GetByEmail(ctx context.Context, email string) (*mod
 	return &u, nil
 }
 
+func (r *authRepositoryImpl) GetByGoogleID(ctx context.Context, googleID string) (*models.User, error) {
+	r.logger.Info("Fetching user by Google ID")
+
+	var u models.User
+	err := r.usersCol.FindOne(ctx, bson.M{"google_id": googleID}).Decode(&u)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			r.logger.Warn("User not found by Google ID")
+			return nil, mongo.ErrNoDocuments
+		}
+		r.logger.Error("Failed to fetch user by Google ID", "error", err)
+		return nil, fmt.Errorf("failed to get user by google id: %w", err)
+	}
+
+	r.logger.Info("User fetched successfully", "user_id", u.ID.Hex())
+	return &u, nil
+}
+
+func (r *authRepositoryImpl) LinkGoogleAccount(ctx context.Context, userID primitive.ObjectID, googleID, googleEmail string) error {
+	r.logger.Info("Linking Google account", "user_id", userID.Hex())
+
+	filter := bson.M{"_id": userID}
+	update := bson.M{"$set": bson.M{
+		"google_id":    googleID,
+		"google_email": googleEmail,
+		"updated_at":   time.Now(),
+	}}
+	if _, err := r.usersCol.UpdateOne(ctx, filter, update); err != nil {
+		if mongo.IsDuplicateKeyError(err) {
+			r.logger.Warn("Google account already linked to another user", "user_id", userID.Hex())
+			return et.NewConflictError("google account is linked to another user", err)
+		}
+		r.logger.Error("Failed to link Google account", "error", err)
+		return et.NewDatabaseError("failed to link google account", err)
+	}
+
+	r.logger.Info("Google account linked successfully", "user_id", userID.Hex())
+	return nil
+}
+
 func (r *authRepositoryImpl) GetByID(ctx context.Context, id primitive.ObjectID) (*models.User, error) {
 	r.logger.Info("Fetching user by ID", "user_id", id.Hex())
 
@@ -128,11 +246,75 @@ func (r *authRepositoryImpl) GetByID(ctx context.Context, id primitive.ObjectID)
 	return &u, nil
 }
 
+// GetUsersByIDs returns the users that exist, in no particular order
+func (r *authRepositoryImpl) GetUsersByIDs(ctx context.Context, ids []primitive.ObjectID) ([]*models.User, error) {
+	r.logger.Info("Fetching users by IDs", "count", len(ids))
+
+	users := make([]*models.User, 0, len(ids))
+	if len(ids) == 0 {
+		return users, nil
+	}
+
+	cursor, err := r.usersCol.Find(ctx, bson.M{"_id": bson.M{"$in": ids}})
+	if err != nil {
+		r.logger.Error("Failed to fetch users by IDs", "error", err)
+		return nil, et.NewDatabaseError("failed to fetch users", err)
+	}
+	defer cursor.Close(ctx)
+
+	if err := cursor.All(ctx, &users); err != nil {
+		r.logger.Error("Failed to decode users", "error", err)
+		return nil, et.NewDatabaseError("failed to decode users", err)
+	}
+
+	r.logger.Info("Users fetched successfully", "requested", len(ids), "found", len(users))
+	return users, nil
+}
+
+// SearchUsers pages users matching criteria, newest first
+func (r *authRepositoryImpl) SearchUsers(ctx context.Context, criteria models.UserSearchCriteria, page db.PageRequest) (*db.PageResponse[*models.User], error) {
+	r.logger.Info("Searching users", "user_type", criteria.UserType, "page", page.Page)
+
+	filter := bson.M{}
+	if criteria.Email != "" {
+		filter["email"] = bson.M{"$regex": regexp.QuoteMeta(criteria.Email), "$options": "i"}
+	}
+	if criteria.UserType != "" {
+		filter["user_type"] = criteria.UserType
+	}
+	if criteria.IsActive != nil {
+		filter["is_active"] = *criteria.IsActive
+	}
+	if criteria.IsVerified != nil {
+		filter["is_verified"] = *criteria.IsVerified
+	}
+	if criteria.CreatedFrom != nil || criteria.CreatedTo != nil {
+		created := bson.M{}
+		if criteria.CreatedFrom != nil {
+			created["$gte"] = *criteria.CreatedFrom
+		}
+		if criteria.CreatedTo != nil {
+			created["$lte"] = *criteria.CreatedTo
+		}
+		filter["created_at"] = created
+	}
+
+	result, err := db.Paginate[*models.User](ctx, r.usersCol, filter, page, bson.D{{Key: "created_at", Value: -1}, {Key: "_id", Value: -1}})
+	if err != nil {
+		r.logger.Error("Failed to search users", "error", err)
+		return nil, et.NewDatabaseError("failed to search users", err)
+	}
+	return result, nil
+}
+
 func (r *authRepositoryImpl) SaveRefreshToken(ctx context.Context, token *models.RefreshToken) error {
 	r.logger.Info("Saving refresh token", "user_id", token.UserID.Hex())
 
 	token.ID = primitive.NewObjectID()
-	_, err := r.refreshTokensCol.InsertOne(ctx, token)
+	// only the hash is persisted so a dump doesn't hand out usable tokens
+	doc := *token
+	doc.Token = utils.HashRefreshToken(token.Token)
+	_, err := r.refreshTokensCol.InsertOne(ctx, doc)
 	if err != nil {
 		r.logger.Error("Failed to save refresh token", "error", err)
 		return fmt.Errorf("failed to save refresh token: %w", err)
@@ -146,7 +328,7 @@ func (r *authRepositoryImpl) FindRefreshToken(ctx context.Context, token string)
 	r.logger.Info("Finding refresh token")
 
 	var rt models.RefreshToken
-	err := r.refreshTokensCol.FindOne(ctx, bson.M{"token": token}).Decode(&rt)
+	err := r.refreshTokensCol.FindOne(ctx, bson.M{"token": utils.HashRefreshToken(token)}).Decode(&rt)
 	if err != nil {
 		if errors.Is(err, mongo.ErrNoDocuments) {
 			r.logger.Warn("Refresh token not found")
@@ -175,6 +357,118 @@ func (r *authRepositoryImpl) RevokeRefreshToken(ctx context.Context, tokenID pri
 	return nil
 }
 
+func (r *authRepositoryImpl) RevokeAllUserRefreshTokens(ctx context.Context, userID primitive.ObjectID) (int64, error) {
+	r.logger.Info("Revoking all refresh tokens", "user_id", userID.Hex())
+
+	filter := bson.M{"user_id": userID, "is_revoked": false}
+	update := bson.M{"$set": bson.M{"is_revoked": true}}
+	res, err := r.refreshTokensCol.UpdateMany(ctx, filter, update)
+	if err != nil {
+		r.logger.Error("Failed to revoke user refresh tokens", "error", err)
+		return 0, et.NewDatabaseError("failed to revoke refresh tokens", err)
+	}
+
+	r.logger.Info("User refresh tokens revoked", "user_id", userID.Hex(), "revoked", res.ModifiedCount)
+	return res.ModifiedCount, nil
+}
+
+// ListSessions returns the user's active refresh tokens, oldest first
+func (r *authRepositoryImpl) ListSessions(ctx context.Context, userID primitive.ObjectID) ([]*models.RefreshToken, error) {
+	r.logger.Info("Listing sessions", "user_id", userID.Hex())
+
+	filter := bson.M{
+		"user_id":    userID,
+		"is_revoked": false,
+		"expires_at": bson.M{"$gt": time.Now()},
+	}
+	opts := options.Find().SetSort(bson.D{{Key: "created_at", Value: 1}})
+
+	cursor, err := r.refreshTokensCol.Find(ctx, filter, opts)
+	if err != nil {
+		r.logger.Error("Failed to list sessions", "error", err)
+		return nil, et.NewDatabaseError("failed to list sessions", err)
+	}
+	defer cursor.Close(ctx)
+
+	sessions := make([]*models.RefreshToken, 0)
+	if err := cursor.All(ctx, &sessions); err != nil {
+		r.logger.Error("Failed to decode sessions", "error", err)
+		return nil, et.NewDatabaseError("failed to decode sessions", err)
+	}
+
+	return sessions, nil
+}
+
+func (r *authRepositoryImpl) LoginHistory(ctx context.Context, userID primitive.ObjectID, deviceID, ip string) (bool, bool, error) {
+	var match bson.A
+	if deviceID != "" {
+		match = append(match, bson.M{"device_id": deviceID})
+	}
+	if ip != "" {
+		match = append(match, bson.M{"ip": ip})
+	}
+	limit := options.Count().SetLimit(1)
+
+	if len(match) > 0 {
+		n, err := r.refreshTokensCol.CountDocuments(ctx, bson.M{"user_id": userID, "$or": match}, limit)
+		if err != nil {
+			r.logger.Error("Failed to look up known devices", "error", err)
+			return false, false, et.NewDatabaseError("failed to look up known devices", err)
+		}
+		if n > 0 {
+			return true, true, nil
+		}
+	}
+
+	n, err := r.refreshTokensCol.CountDocuments(ctx, bson.M{"user_id": userID}, limit)
+	if err != nil {
+		r.logger.Error("Failed to look up login history", "error", err)
+		return false, false, et.NewDatabaseError("failed to look up login history", err)
+	}
+	return n > 0, false, nil
+}
+
+// ListSessionsPage is ListSessions one cursor page at a time, oldest first
+func (r *authRepositoryImpl) ListSessionsPage(ctx context.Context, userID primitive.ObjectID, page db.CursorRequest) (*db.CursorResponse[*models.RefreshToken], error) {
+	r.logger.Info("Listing sessions page", "user_id", userID.Hex())
+
+	filter := bson.M{
+		"user_id":    userID,
+		"is_revoked": false,
+		"expires_at": bson.M{"$gt": time.Now()},
+	}
+	result, err := db.CursorPaginate(ctx, r.refreshTokensCol, filter, page, false,
+		func(t *models.RefreshToken) primitive.ObjectID { return t.ID })
+	if err != nil {
+		return nil, cursorPageError(r.logger, "sessions", err)
+	}
+	return result, nil
+}
+
+// deletes expired tokens and revoked ones older than the retention period
+func (r *authRepositoryImpl) CleanExpiredTokens(ctx context.Context, revokedRetention time.Duration) (int64, error) {
+	r.logger.Info("Cleaning expired refresh tokens")
+
+	now := time.Now()
+	filter := bson.M{
+		"$or": bson.A{
+			bson.M{"expires_at": bson.M{"$lt": now}},
+			bson.M{
+				"is_revoked": true,
+				"created_at": bson.M{"$lt": now.Add(-revokedRetention)},
+			},
+		},
+	}
+	res, err := r.refreshTokensCol.DeleteMany(ctx, filter)
+	if err != nil {
+		r.logger.Error("Failed to clean expired refresh tokens", "error", err)
+		return 0, et.NewDatabaseError("failed to clean expired refresh tokens", err)
+	}
+
+	r.logger.Info("Expired refresh tokens cleaned", "deleted", res.DeletedCount)
+	return res.DeletedCount, nil
+}
+
 func (r *authRepositoryImpl) IsUniqueConstraintError(err error) bool {
 	r.logger.Debug("Checking if error is unique constraint violation")
 
@@ -222,8 +516,12 @@ func (r *authRepositoryImpl) UpdateLoginInfo(ctx context.Context, userID primiti
 func (r *authRepositoryImpl) LockUserAccount(ctx context.Context, userID primitive.ObjectID, duration time.Duration) error {
 	r.logger.Info("Locking user account", "user_id", userID.Hex(), "duration", duration)
 
+	// the attempt counter starts over for the next lockout cycle
 	lockedUntil := time.Now().Add(duration)
-	update := bson.M{"$set": bson.M{"locked_until": lockedUntil}}
+	update := bson.M{
+		"$set": bson.M{"locked_until": lockedUntil, "login_attempts": 0},
+		"$inc": bson.M{"lockout_count": 1},
+	}
 	_, err := r.usersCol.UpdateByID(ctx, userID, update)
 	if err != nil {
 		r.logger.Error("Failed to lock user account", "error", err)
@@ -249,6 +547,111 @@ func (r *authRepositoryImpl) UpdatePassword(ctx context.Context, userID primitiv
 	return nil
 }
 
+func (r *authRepositoryImpl) ChangePassword(ctx context.Context, userID primitive.ObjectID, hashedPassword string) error {
+	r.logger.Info("Changing password", "user_id", userID.Hex())
+
+	now := time.Now()
+	filter := bson.M{"_id": userID}
+	update := bson.M{"$set": bson.M{"password": hashedPassword, "password_changed_at": now, "updated_at": now}}
+	_, err := r.usersCol.UpdateOne(ctx, filter, update)
+	if err != nil {
+		r.logger.Error("Failed to change password", "error", err)
+		return et.NewDatabaseError("failed to change password", err)
+	}
+
+	r.logger.Info("Password changed successfully", "user_id", userID.Hex())
+	return nil
+}
+
+func (r *authRepositoryImpl) UpdateProfileImage(ctx context.Context, userID primitive.ObjectID, image string) error {
+	r.logger.Info("Updating profile image", "user_id", userID.Hex())
+
+	filter := bson.M{"_id": userID}
+	update := bson.M{"$set": bson.M{"profile_image": image, "updated_at": time.Now()}}
+	res, err := r.usersCol.UpdateOne(ctx, filter, update)
+	if err != nil {
+		r.logger.Error("Failed to update profile image", "error", err)
+		return et.NewDatabaseError("failed to update profile image", err)
+	}
+	if res.MatchedCount == 0 {
+		r.logger.Warn("User not found for profile image update", "user_id", userID.Hex())
+		return et.NewNotFoundError("user not found", nil)
+	}
+
+	r.logger.Info("Profile image updated successfully", "user_id", userID.Hex())
+	return nil
+}
+
+func (r *authRepositoryImpl) InsertAuditEntry(ctx context.Context, entry *models.AuditEntry) error {
+	r.logger.Info("Inserting audit entry", "action", entry.Action, "target_user_id", entry.TargetUserID)
+
+	if entry.ID.IsZero() {
+		entry.ID = primitive.NewObjectID()
+	}
+	if _, err := r.auditCol.InsertOne(ctx, entry); err != nil {
+		r.logger.Error("Failed to insert audit entry", "error", err)
+		return et.NewDatabaseError("failed to insert audit entry", err)
+	}
+	return nil
+}
+
+// GetLastAuditEntry returns nil when the audit log is empty
+func (r *authRepositoryImpl) GetLastAuditEntry(ctx context.Context) (*models.AuditEntry, error) {
+	opts := options.FindOne().SetSort(bson.D{{Key: "_id", Value: -1}})
+
+	var entry models.AuditEntry
+	if err := r.auditCol.FindOne(ctx, bson.M{}, opts).Decode(&entry); err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, nil
+		}
+		r.logger.Error("Failed to fetch last audit entry", "error", err)
+		return nil, et.NewDatabaseError("failed to fetch last audit entry", err)
+	}
+	return &entry, nil
+}
+
+func (r *authRepositoryImpl) ListAuditEntries(ctx context.Context, targetUserID string, page db.PageRequest) (*db.PageResponse[*models.AuditEntry], error) {
+	r.logger.Info("Listing audit entries", "target_user_id", targetUserID, "page", page.Page)
+
+	filter := bson.M{}
+	if targetUserID != "" {
+		filter["target_user_id"] = targetUserID
+	}
+
+	// _id order is insertion order, which keeps offset pages in line with cursor pages
+	result, err := db.Paginate[*models.AuditEntry](ctx, r.auditCol, filter, page, bson.D{{Key: "_id", Value: -1}})
+	if err != nil {
+		r.logger.Error("Failed to list audit entries", "error", err)
+		return nil, et.NewDatabaseError("failed to list audit entries", err)
+	}
+	return result, nil
+}
+
+// ListAuditEntriesByCursor pages the audit log newest first without counting the collection
+func (r *authRepositoryImpl) ListAuditEntriesByCursor(ctx context.Context, targetUserID string, page db.CursorRequest) (*db.CursorResponse[*models.AuditEntry], error) {
+	r.logger.Info("Listing audit entries by cursor", "target_user_id", targetUserID)
+
+	filter := bson.M{}
+	if targetUserID != "" {
+		filter["target_user_id"] = targetUserID
+	}
+
+	result, err := db.CursorPaginate(ctx, r.auditCol, filter, page, true,
+		func(e *models.AuditEntry) primitive.ObjectID { return e.ID })
+	if err != nil {
+		return nil, cursorPageError(r.logger, "audit entries", err)
+	}
+	return result, nil
+}
+
+func cursorPageError(logger *slog.Logger, what string, err error) error {
+	if errors.Is(err, db.ErrInvalidCursor) {
+		return et.NewValidationError("invalid cursor", map[string]string{"cursor": "is malformed"})
+	}
+	logger.Error("Failed to list "+what, "error", err)
+	return et.NewDatabaseError("failed to list "+what, err)
+}
+
 func (r *authRepositoryImpl) IncrementLoginAttempts(ctx context.Context, userID primitive.ObjectID) (int, error) {
 	r.logger.Info("Incrementing login attempts", "user_id", userID.Hex())
 
@@ -269,7 +672,7 @@ func (r *authRepositoryImpl) IncrementLoginAttempts(ctx context.Context, userID
 func (r *authRepositoryImpl) ResetLoginAttempts(ctx context.Context, userID primitive.ObjectID) error {
 	r.logger.Info("Resetting login attempts", "user_id", userID.Hex())
 
-	update := bson.M{"$set": bson.M{"login_attempts": 0}, "$unset": bson.M{"locked_until": ""}}
+	update := bson.M{"$set": bson.M{"login_attempts": 0, "lockout_count": 0}, "$unset": bson.M{"locked_until": ""}}
 	_, err := r.usersCol.UpdateByID(ctx, userID, update)
 	if err != nil {
 		r.logger.Error("Failed to reset login attempts", "error", err)
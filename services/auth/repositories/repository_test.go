@@ -0,0 +1,241 @@
+package repositories
+
+import (
+	"io"
+	"log/slog"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	models "remaster/services/auth/models"
+	"remaster/services/auth/utils"
+	config "remaster/shared"
+	"remaster/shared/db"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/integration/mtest"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"go.mongodb.org/mongo-driver/mongo/writeconcern"
+)
+
+func newMockRepo(mt *mtest.T) *authRepositoryImpl {
+	return NewAuthRepository(mt.DB, &config.MongoConfig{}, slog.New(slog.NewTextHandler(io.Discard, nil)))
+}
+
+func TestSaveRefreshTokenStoresOnlyTheHash(t *testing.T) {
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+
+	mt.Run("insert", func(mt *mtest.T) {
+		mt.AddMockResponses(mtest.CreateSuccessResponse())
+		const raw = "3f1c0d9e8b7a6f5e4d3c2b1a0f9e8d7c6b5a4f3e2d1c0b9a8f7e6d5c4b3a2f1e"
+		token := &models.RefreshToken{UserID: primitive.NewObjectID(), Token: raw, ExpiresAt: time.Now().Add(time.Hour)}
+
+		if err := newMockRepo(mt).SaveRefreshToken(mt.Context(), token); err != nil {
+			mt.Fatalf("SaveRefreshToken: %v", err)
+		}
+
+		cmd := mt.GetStartedEvent().Command
+		if strings.Contains(cmd.String(), raw) {
+			mt.Fatalf("insert command carries the raw token: %s", cmd)
+		}
+		doc := cmd.Lookup("documents").Array().Index(0).Value().Document()
+		if got := doc.Lookup("token").StringValue(); got != utils.HashRefreshToken(raw) {
+			mt.Fatalf("stored token = %q, want its sha-256", got)
+		}
+		if token.Token != raw {
+			mt.Fatal("SaveRefreshToken replaced the caller's raw token")
+		}
+	})
+
+	mt.Run("lookup", func(mt *mtest.T) {
+		const raw = "lookup-token"
+		ns := mt.Coll.Database().Name() + ".refresh_tokens"
+		mt.AddMockResponses(mtest.CreateCursorResponse(0, ns, mtest.FirstBatch, bson.D{
+			{Key: "_id", Value: primitive.NewObjectID()},
+			{Key: "token", Value: utils.HashRefreshToken(raw)},
+		}))
+
+		if _, err := newMockRepo(mt).FindRefreshToken(mt.Context(), raw); err != nil {
+			mt.Fatalf("FindRefreshToken: %v", err)
+		}
+
+		filter := mt.GetStartedEvent().Command.Lookup("filter").Document()
+		if got := filter.Lookup("token").StringValue(); got != utils.HashRefreshToken(raw) {
+			mt.Fatalf("lookup filter token = %q, want the hash", got)
+		}
+	})
+}
+
+func TestRevokeAllUserRefreshTokensTargetsOnlyTheUsersLiveTokens(t *testing.T) {
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+
+	mt.Run("update", func(mt *mtest.T) {
+		mt.AddMockResponses(mtest.CreateSuccessResponse(bson.E{Key: "n", Value: 3}, bson.E{Key: "nModified", Value: 3}))
+		userID := primitive.NewObjectID()
+
+		revoked, err := newMockRepo(mt).RevokeAllUserRefreshTokens(mt.Context(), userID)
+		if err != nil {
+			mt.Fatalf("RevokeAllUserRefreshTokens: %v", err)
+		}
+		if revoked != 3 {
+			mt.Fatalf("revoked = %d, want 3", revoked)
+		}
+
+		update := mt.GetStartedEvent().Command.Lookup("updates").Array().Index(0).Value().Document()
+		if !update.Lookup("multi").Boolean() {
+			mt.Fatal("revoke-all is not an update many")
+		}
+		q := update.Lookup("q").Document()
+		if got := q.Lookup("user_id").ObjectID(); got != userID {
+			mt.Fatalf("filter user_id = %s, want %s", got.Hex(), userID.Hex())
+		}
+		if q.Lookup("is_revoked").Boolean() {
+			mt.Fatal("filter matches revoked tokens, want only live ones")
+		}
+		if !update.Lookup("u", "$set", "is_revoked").Boolean() {
+			mt.Fatalf("update = %s, want is_revoked set", update.Lookup("u"))
+		}
+	})
+}
+
+func TestRefreshTokenRevokesUseMajorityWrites(t *testing.T) {
+	// mtest clients default to majority, start from w:1 like a plain client
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock).
+		ClientOptions(options.Client().SetWriteConcern(writeconcern.W1())))
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+
+	tests := []struct {
+		name     string
+		majority bool
+	}{
+		{"token_write_majority on", true},
+		{"token_write_majority off", false},
+	}
+	for _, tt := range tests {
+		mt.Run(tt.name, func(mt *mtest.T) {
+			repo := NewAuthRepository(mt.DB, &config.MongoConfig{TokenWriteMajority: tt.majority}, logger)
+			revokes := map[string]func() error{
+				"revoke": func() error { return repo.RevokeRefreshToken(mt.Context(), primitive.NewObjectID()) },
+				"revoke all": func() error {
+					_, err := repo.RevokeAllUserRefreshTokens(mt.Context(), primitive.NewObjectID())
+					return err
+				},
+			}
+			for name, revoke := range revokes {
+				mt.AddMockResponses(mtest.CreateSuccessResponse(bson.E{Key: "n", Value: 1}, bson.E{Key: "nModified", Value: 1}))
+				if err := revoke(); err != nil {
+					mt.Fatalf("%s: %v", name, err)
+				}
+				w, _ := mt.GetStartedEvent().Command.Lookup("writeConcern", "w").StringValueOK()
+				if got := w == "majority"; got != tt.majority {
+					mt.Fatalf("%s writes with majority = %v, want %v", name, got, tt.majority)
+				}
+			}
+		})
+	}
+}
+
+func TestGetUsersByIDs(t *testing.T) {
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+
+	mt.Run("partial match", func(mt *mtest.T) {
+		found, missing := primitive.NewObjectID(), primitive.NewObjectID()
+		ns := mt.Coll.Database().Name() + ".users"
+		mt.AddMockResponses(mtest.CreateCursorResponse(0, ns, mtest.FirstBatch, bson.D{
+			{Key: "_id", Value: found},
+			{Key: "email", Value: "found@example.com"},
+		}))
+
+		users, err := newMockRepo(mt).GetUsersByIDs(mt.Context(), []primitive.ObjectID{found, missing})
+		if err != nil {
+			mt.Fatalf("GetUsersByIDs: %v", err)
+		}
+		if len(users) != 1 || users[0].ID != found {
+			mt.Fatalf("users = %+v, want only the stored one", users)
+		}
+
+		in := mt.GetStartedEvent().Command.Lookup("filter", "_id", "$in").Array()
+		if vals, _ := in.Values(); len(vals) != 2 {
+			mt.Fatalf("$in = %s, want both requested ids", in)
+		}
+	})
+
+	mt.Run("empty input", func(mt *mtest.T) {
+		users, err := newMockRepo(mt).GetUsersByIDs(mt.Context(), nil)
+		if err != nil || users == nil || len(users) != 0 {
+			mt.Fatalf("GetUsersByIDs(nil) = %v, %v, want an empty slice", users, err)
+		}
+		if ev := mt.GetStartedEvent(); ev != nil {
+			mt.Fatalf("empty input sent %s", ev.CommandName)
+		}
+	})
+}
+
+func TestSearchUsersFilters(t *testing.T) {
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+	yes, no := true, false
+	from := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	to := from.AddDate(0, 1, 0)
+
+	tests := []struct {
+		name     string
+		criteria models.UserSearchCriteria
+		want     bson.M
+	}{
+		{"no criteria", models.UserSearchCriteria{}, bson.M{}},
+		{"email substring is escaped and case-insensitive", models.UserSearchCriteria{Email: "a.b+c"},
+			bson.M{"email": bson.M{"$regex": `a\.b\+c`, "$options": "i"}}},
+		{"user type", models.UserSearchCriteria{UserType: models.UserTypeMaster}, bson.M{"user_type": "master"}},
+		{"inactive", models.UserSearchCriteria{IsActive: &no}, bson.M{"is_active": false}},
+		{"verified", models.UserSearchCriteria{IsVerified: &yes}, bson.M{"is_verified": true}},
+		{"created from", models.UserSearchCriteria{CreatedFrom: &from}, bson.M{"created_at": bson.M{"$gte": from}}},
+		{"created range", models.UserSearchCriteria{CreatedFrom: &from, CreatedTo: &to},
+			bson.M{"created_at": bson.M{"$gte": from, "$lte": to}}},
+		{"combined", models.UserSearchCriteria{Email: "acme", UserType: models.UserTypeClient, IsActive: &yes, IsVerified: &no, CreatedTo: &to},
+			bson.M{
+				"email":       bson.M{"$regex": "acme", "$options": "i"},
+				"user_type":   "client",
+				"is_active":   true,
+				"is_verified": false,
+				"created_at":  bson.M{"$lte": to},
+			}},
+	}
+	for _, tt := range tests {
+		mt.Run(tt.name, func(mt *mtest.T) {
+			ns := mt.Coll.Database().Name() + ".users"
+			mt.AddMockResponses(
+				mtest.CreateCursorResponse(0, ns, mtest.FirstBatch, bson.D{{Key: "n", Value: 1}}),
+				mtest.CreateCursorResponse(0, ns, mtest.FirstBatch, bson.D{{Key: "_id", Value: primitive.NewObjectID()}}),
+			)
+
+			result, err := newMockRepo(mt).SearchUsers(mt.Context(), tt.criteria, db.PageRequest{Page: 1, PageSize: 10})
+			if err != nil {
+				mt.Fatalf("SearchUsers: %v", err)
+			}
+			if result.Total != 1 || len(result.Items) != 1 {
+				mt.Fatalf("result = %d of %d, want the one match", len(result.Items), result.Total)
+			}
+
+			mt.GetStartedEvent() // count
+			find := mt.GetStartedEvent().Command
+			want, _ := bson.Marshal(tt.want)
+			if got := find.Lookup("filter").Document(); !reflect.DeepEqual(asMap(mt, got), asMap(mt, want)) {
+				mt.Fatalf("filter = %s, want %s", got, bson.Raw(want))
+			}
+			if sort := find.Lookup("sort").Document().String(); sort != `{"created_at": {"$numberInt":"-1"},"_id": {"$numberInt":"-1"}}` {
+				mt.Fatalf("sort = %s, want newest first", sort)
+			}
+		})
+	}
+}
+
+// asMap decodes a document so filters compare regardless of key order
+func asMap(mt *mtest.T, raw bson.Raw) bson.M {
+	var m bson.M
+	if err := bson.Unmarshal(raw, &m); err != nil {
+		mt.Fatalf("unmarshal %s: %v", raw, err)
+	}
+	return m
+}
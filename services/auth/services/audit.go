@@ -0,0 +1,86 @@
+package services
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	"remaster/services/auth/models"
+	repo "remaster/services/auth/repositories"
+	"remaster/shared/db"
+)
+
+// AuditLogger writes hash-chained audit entries for sensitive operations.
+// Each entry hashes its own fields together with the previous entry's hash,
+// so rewriting or removing a record breaks the chain from that point on
+type AuditLogger struct {
+	repo   repo.AuthRepositoryInterface
+	logger *slog.Logger
+	mu     sync.Mutex
+}
+
+func NewAuditLogger(authRepo repo.AuthRepositoryInterface, logger *slog.Logger) *AuditLogger {
+	return &AuditLogger{
+		repo:   authRepo,
+		logger: logger.With(slog.String("auth", "audit")),
+	}
+}
+
+func (a *AuditLogger) Record(ctx context.Context, actorID string, action models.AuditAction, targetUserID string, metadata *models.RequestMetadata) error {
+	// serialize writers so the chain stays linear within this instance
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	last, err := a.repo.GetLastAuditEntry(ctx)
+	if err != nil {
+		return err
+	}
+
+	entry := &models.AuditEntry{
+		ActorID:      actorID,
+		Action:       action,
+		TargetUserID: targetUserID,
+		CreatedAt:    time.Now().UTC().Truncate(time.Millisecond), // mongo stores ms precision
+	}
+	if metadata != nil {
+		entry.IP = metadata.IPAddress
+		entry.UserAgent = metadata.UserAgent
+	}
+	if last != nil {
+		entry.PrevHash = last.Hash
+	}
+	entry.Hash = hashAuditEntry(entry)
+
+	if err := a.repo.InsertAuditEntry(ctx, entry); err != nil {
+		a.logger.Error("Failed to write audit entry", "action", action, "error", err)
+		return err
+	}
+
+	a.logger.Info("Audit entry recorded", "action", action, "actor_id", actorID, "target_user_id", targetUserID)
+	return nil
+}
+
+func (a *AuditLogger) List(ctx context.Context, targetUserID string, page db.PageRequest) (*db.PageResponse[*models.AuditEntry], error) {
+	return a.repo.ListAuditEntries(ctx, targetUserID, page)
+}
+
+func (a *AuditLogger) ListByCursor(ctx context.Context, targetUserID string, page db.CursorRequest) (*db.CursorResponse[*models.AuditEntry], error) {
+	return a.repo.ListAuditEntriesByCursor(ctx, targetUserID, page)
+}
+
+func hashAuditEntry(e *models.AuditEntry) string {
+	sum := sha256.Sum256(fmt.Appendf(nil, "%s|%s|%s|%s|%s|%s|%s",
+		e.PrevHash,
+		e.ActorID,
+		e.Action,
+		e.TargetUserID,
+		e.IP,
+		e.UserAgent,
+		e.CreatedAt.Format(time.RFC3339Nano),
+	))
+	return hex.EncodeToString(sum[:])
+}
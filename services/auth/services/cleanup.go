@@ -0,0 +1,64 @@
+package services
+
+import (
+	"context"
+	"time"
+)
+
+const tokenCleanupLockKey = "auth:token-cleanup"
+
+// locker is the distributed lock of connection.RedisManager
+type locker interface {
+	AcquireLock(ctx context.Context, key string, ttl time.Duration) (release func(ctx context.Context) error, ok bool, err error)
+}
+
+// RunTokenCleanup removes expired and revoked refresh tokens once at startup and then every
+// interval until ctx is done. Only one replica cleans at a time thanks to the redis lock.
+func (s *AuthService) RunTokenCleanup(ctx context.Context, locks locker, interval, revokedRetention time.Duration) {
+	if interval <= 0 {
+		s.logger.Warn("Token cleanup disabled", "interval", interval)
+		return
+	}
+
+	s.logger.Info("Token cleanup job started", "interval", interval, "revoked_retention", revokedRetention)
+
+	// a replica restarted more often than the interval would otherwise never clean
+	s.cleanExpiredTokens(ctx, locks, interval, revokedRetention)
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			s.logger.Info("Token cleanup job stopped")
+			return
+		case <-ticker.C:
+			s.cleanExpiredTokens(ctx, locks, interval, revokedRetention)
+		}
+	}
+}
+
+func (s *AuthService) cleanExpiredTokens(ctx context.Context, locks locker, lockTTL, revokedRetention time.Duration) {
+	release, ok, err := locks.AcquireLock(ctx, tokenCleanupLockKey, lockTTL)
+	if err != nil {
+		s.logger.Error("Failed to acquire token cleanup lock", "error", err)
+		return
+	}
+	if !ok {
+		s.logger.Debug("Token cleanup already running on another replica")
+		return
+	}
+	defer func() {
+		if err := release(context.Background()); err != nil {
+			s.logger.Warn("Failed to release token cleanup lock", "error", err)
+		}
+	}()
+
+	deleted, err := s.repo.CleanExpiredTokens(ctx, revokedRetention)
+	if err != nil {
+		s.logger.Error("Token cleanup failed", "error", err)
+		return
+	}
+	s.logger.Info("Token cleanup finished", "deleted", deleted)
+}
@@ -0,0 +1,149 @@
+package services
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"remaster/services/auth/models"
+	"remaster/services/auth/repositories/memory"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// redisLocker is a SET NX lock on a shared miniredis, like the one of RedisManager
+type redisLocker struct {
+	client *redis.Client
+}
+
+func newRedisLocker(t *testing.T, mr *miniredis.Miniredis) *redisLocker {
+	t.Helper()
+	client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() { _ = client.Close() })
+	return &redisLocker{client: client}
+}
+
+func (l *redisLocker) AcquireLock(ctx context.Context, key string, ttl time.Duration) (func(context.Context) error, bool, error) {
+	ok, err := l.client.SetNX(ctx, key, "held", ttl).Result()
+	if err != nil || !ok {
+		return nil, false, err
+	}
+	return func(ctx context.Context) error { return l.client.Del(ctx, key).Err() }, true, nil
+}
+
+// cleanupHookRepo counts cleanups and, when entered is set, blocks each one until release is closed
+type cleanupHookRepo struct {
+	*memory.Repository
+	cleanups atomic.Int32
+	entered  chan struct{}
+	release  chan struct{}
+}
+
+func (r *cleanupHookRepo) CleanExpiredTokens(ctx context.Context, revokedRetention time.Duration) (int64, error) {
+	r.cleanups.Add(1)
+	if r.entered != nil {
+		r.entered <- struct{}{}
+		<-r.release
+	}
+	return r.Repository.CleanExpiredTokens(ctx, revokedRetention)
+}
+
+func TestTokenCleanupRunsOnOneReplicaAtATime(t *testing.T) {
+	mr := miniredis.RunT(t)
+	repo := &cleanupHookRepo{
+		Repository: memory.NewRepository(),
+		entered:    make(chan struct{}, 1),
+		release:    make(chan struct{}),
+	}
+	replicaA, replicaB := newTestService(t, nil), newTestService(t, nil)
+	replicaA.svc.repo, replicaB.svc.repo = repo, repo
+	locksA, locksB := newRedisLocker(t, mr), newRedisLocker(t, mr)
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		replicaA.svc.cleanExpiredTokens(context.Background(), locksA, time.Minute, time.Hour)
+	}()
+	<-repo.entered
+
+	// A holds the lock while it cleans, B must skip instead of cleaning as well
+	replicaB.svc.cleanExpiredTokens(context.Background(), locksB, time.Minute, time.Hour)
+	if n := repo.cleanups.Load(); n != 1 {
+		t.Fatalf("cleanups while A holds the lock = %d, want 1", n)
+	}
+
+	close(repo.release)
+	wg.Wait()
+	if mr.Exists(tokenCleanupLockKey) {
+		t.Fatal("lock still held after A finished")
+	}
+
+	// once A released the lock the next tick of B runs
+	repo.entered = nil
+	replicaB.svc.cleanExpiredTokens(context.Background(), locksB, time.Minute, time.Hour)
+	if n := repo.cleanups.Load(); n != 2 {
+		t.Fatalf("cleanups after release = %d, want 2", n)
+	}
+}
+
+func TestRunTokenCleanupCleansAtStartup(t *testing.T) {
+	env := newTestService(t, nil)
+	repo := &cleanupHookRepo{
+		Repository: env.repo,
+		entered:    make(chan struct{}, 1),
+		release:    make(chan struct{}),
+	}
+	close(repo.release)
+	env.svc.repo = repo
+	locks := newRedisLocker(t, miniredis.RunT(t))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		env.svc.RunTokenCleanup(ctx, locks, time.Hour, time.Hour)
+	}()
+
+	select {
+	case <-repo.entered:
+	case <-time.After(time.Second):
+		t.Fatal("no cleanup at startup, the first one waited for the interval")
+	}
+	cancel()
+	<-done
+}
+
+func TestCleanExpiredTokensRemovesExpiredAndOldRevokedTokens(t *testing.T) {
+	env := newTestService(t, nil)
+	ctx := context.Background()
+	now := time.Now()
+	userID := primitive.NewObjectID()
+
+	tokens := map[string]*models.RefreshToken{
+		"valid":         {ExpiresAt: now.Add(time.Hour), CreatedAt: now.Add(-2 * time.Hour)},
+		"expired":       {ExpiresAt: now.Add(-time.Minute), CreatedAt: now.Add(-2 * time.Hour)},
+		"old revoked":   {ExpiresAt: now.Add(time.Hour), CreatedAt: now.Add(-2 * time.Hour), IsRevoked: true},
+		"fresh revoked": {ExpiresAt: now.Add(time.Hour), CreatedAt: now.Add(-time.Minute), IsRevoked: true},
+	}
+	for name, token := range tokens {
+		token.UserID = userID
+		token.Token = name
+		if err := env.repo.SaveRefreshToken(ctx, token); err != nil {
+			t.Fatalf("SaveRefreshToken(%s): %v", name, err)
+		}
+	}
+
+	env.svc.cleanExpiredTokens(ctx, newRedisLocker(t, miniredis.RunT(t)), time.Minute, time.Hour)
+
+	for name, kept := range map[string]bool{"valid": true, "expired": false, "old revoked": false, "fresh revoked": true} {
+		_, err := env.repo.FindRefreshToken(ctx, name)
+		if found := err == nil; found != kept {
+			t.Errorf("token %q kept = %v, want %v", name, found, kept)
+		}
+	}
+}
@@ -5,6 +5,8 @@ import (
 	"errors"
 	"fmt"
 	"log/slog"
+	"slices"
+	"strings"
 	"time"
 
 	"remaster/services/auth/cache"
@@ -12,100 +14,107 @@ import (
 	oauth "remaster/services/auth/oauth"
 	repo "remaster/services/auth/repositories"
 	"remaster/services/auth/utils"
+	config "remaster/shared"
+	"remaster/shared/db"
 	et "remaster/shared/errors"
+	"remaster/shared/events"
+	media_pb "remaster/shared/proto/media"
+	"remaster/shared/validation"
 
 	"github.com/cenkalti/backoff/v4"
 	"github.com/redis/go-redis/v9"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 	"go.mongodb.org/mongo-driver/mongo"
-	"golang.org/x/crypto/bcrypt"
-)
-
-const (
-	BcryptCost = 12
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 )
 
 type AuthService struct {
 	repo         repo.AuthRepositoryInterface
 	oauthFactory *oauth.ProviderFactory
 	jwtUtils     *utils.JWTUtils
+	security     *config.SecurityConfig
+	passwords    *utils.PasswordPolicy
+	hasher       utils.PasswordHasher
+	events       events.Publisher
+	media        media_pb.MediaServiceClient
+	audit        *AuditLogger
 	logger       *slog.Logger
 
 	rl *cache.RateLimiter
 	tb *cache.TokenBlacklist
 	ts *cache.RefreshTokenStore
+
+	// compared against for unknown emails so they take as long as a wrong password
+	dummyHash string
 }
 
 func NewAuthService(
 	userRepo repo.AuthRepositoryInterface,
 	oauthFactory *oauth.ProviderFactory,
-	redisClient *redis.Client,
+	redisClient redis.UniversalClient,
 	jwtUtils *utils.JWTUtils,
+	security *config.SecurityConfig,
+	passwords *utils.PasswordPolicy,
+	hasher utils.PasswordHasher,
+	publisher events.Publisher,
+	mediaClient media_pb.MediaServiceClient,
 	logger *slog.Logger,
 ) *AuthService {
+	dummyHash, _ := hasher.Hash("remaster-timing-equalizer")
+
 	return &AuthService{
 		repo:         userRepo,
 		oauthFactory: oauthFactory,
 		jwtUtils:     jwtUtils,
+		security:     security,
+		passwords:    passwords,
+		hasher:       hasher,
+		events:       publisher,
+		media:        mediaClient,
+		audit:        NewAuditLogger(userRepo, logger),
 		logger:       logger.With(slog.String("auth", "service")),
 		rl:           cache.NewRateLimiter(redisClient),
 		tb:           cache.NewTokenBlacklist(redisClient),
 		ts:           cache.NewRefreshTokenStore(redisClient),
+		dummyHash:    dummyHash,
 	}
 }
 
-type AuthServiceInterface interface {
-	CreateUser(ctx context.Context, req *models.RegisterRequest) (string, error)
-}
-
 func (s *AuthService) CreateUser(ctx context.Context, req *models.RegisterRequest, metadata *models.RequestMetadata) (*models.AuthResponse, error) {
 	s.logger.Info("Starting user creation", "email", req.Email)
 
-	if err := req.ValidateRegisterRequest(); err != nil {
+	if err := validation.Validate(req); err != nil {
 		s.logger.Warn("Validation failed for registration", "error", err)
-		return nil, et.NewValidationError("failed to validate register request",
-			map[string]string{"error": err.Error()}) // TODO: refactor after validation lib import
+		return nil, err
 	}
-
-	existingUser, err := s.repo.GetByEmail(ctx, req.Email)
-	if err != nil && !errors.Is(err, mongo.ErrNoDocuments) {
-		s.logger.Error("Failed to check existing user", "error", err)
-		return nil, et.NewDatabaseError("failed to check existing user", err)
+	if !slices.Contains(s.security.AllowedSelfRegistrationTypes, string(req.UserType)) {
+		s.logger.Warn("Self registration not allowed for user type", "email", req.Email, "user_type", req.UserType)
+		return nil, et.NewForbiddenError(fmt.Sprintf("registration as %s is not allowed", req.UserType))
 	}
-	if existingUser != nil {
-		s.logger.Warn("User already exists", "email", req.Email)
-		return nil, et.NewConflictError("user with this email already exists", nil)
+	if err := s.passwords.ValidatePassword(req.Password); err != nil {
+		s.logger.Warn("Password policy check failed for registration", "email", req.Email)
+		return nil, err
 	}
 
-	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(req.Password), BcryptCost)
+	phone, err := utils.NormalizePhone(req.Phone, s.security.PhoneDefaultRegion)
 	if err != nil {
-		s.logger.Error("Failed to hash password", "error", err)
-		return nil, et.NewInternalError("failed to hash password", err)
+		s.logger.Warn("Invalid phone number for registration", "email", req.Email)
+		return nil, err
 	}
 
 	user := &models.User{
 		Email:     req.Email,
-		Password:  string(hashedPassword),
 		FirstName: req.FirstName,
 		LastName:  req.LastName,
-		Phone:     req.Phone,
+		Phone:     phone,
 		UserType:  req.UserType,
 	}
-
-	err = backoff.Retry(func() error {
-		return s.repo.Create(ctx, user)
-	}, backoff.WithMaxRetries(backoff.NewExponentialBackOff(), 3))
-	if err != nil {
-		conflictErr := et.NewConflictError("", nil)
-		if errors.As(err, &conflictErr) {
-			s.logger.Warn("Conflict during user creation", "error", err)
-			return nil, err
-		}
-		s.logger.Error("Failed to create user with retry", "error", err)
-		return nil, fmt.Errorf("service: %w", err)
+	if err := s.insertUser(ctx, user, req.Password); err != nil {
+		return nil, err
 	}
 
-	accessToken, err := s.jwtUtils.GenerateAccessToken(user.ID.Hex(), user.Email, string(user.UserType))
+	accessToken, err := s.jwtUtils.GenerateAccessToken(user.ID.Hex(), user.Email, string(user.UserType), user.OrgID)
 	if err != nil {
 		s.logger.Error("Failed to generate access token", "error", err)
 		return nil, err
@@ -132,8 +141,10 @@ func (s *AuthService) CreateUser(ctx context.Context, req *models.RegisterReques
 		return nil, fmt.Errorf("failed to save refresh token: %w", err)
 	}
 
+	s.publishEvent(ctx, events.UserRegistered, user, metadata)
+
 	return &models.AuthResponse{
-		User:         user.ToResponse(),
+		User:         s.userResponse(ctx, user),
 		AccessToken:  accessToken,
 		RefreshToken: refreshToken,
 		ExpiresAt:    time.Now().Add(s.jwtUtils.AccessTokenTTL).Unix(),
@@ -141,54 +152,206 @@ func (s *AuthService) CreateUser(ctx context.Context, req *models.RegisterReques
 	}, nil
 }
 
+// AdminCreateUser provisions an account of any type, skipping the self-registration
+// allowlist. A temporary password is generated and returned once, actorID is the admin
+func (s *AuthService) AdminCreateUser(ctx context.Context, req *models.AdminCreateUserRequest, actorID string, metadata *models.RequestMetadata) (*models.AdminCreateUserResponse, error) {
+	s.logger.Info("Admin creating user", "email", req.Email, "user_type", req.UserType, "actor_id", actorID)
+
+	if err := validation.Validate(req); err != nil {
+		s.logger.Warn("Validation failed for admin user creation", "error", err)
+		return nil, err
+	}
+
+	phone, err := utils.NormalizePhone(req.Phone, s.security.PhoneDefaultRegion)
+	if err != nil {
+		s.logger.Warn("Invalid phone number for admin user creation", "email", req.Email)
+		return nil, err
+	}
+
+	password, err := s.passwords.GenerateTemporary()
+	if err != nil {
+		s.logger.Error("Failed to generate temporary password", "error", err)
+		return nil, et.NewInternalError("failed to generate temporary password", err)
+	}
+
+	user := &models.User{
+		Email:      req.Email,
+		FirstName:  req.FirstName,
+		LastName:   req.LastName,
+		Phone:      phone,
+		UserType:   req.UserType,
+		IsVerified: req.Verified,
+	}
+	if req.Verified {
+		now := time.Now()
+		user.EmailVerifiedAt = &now
+	}
+	if err := s.insertUser(ctx, user, password); err != nil {
+		return nil, err
+	}
+
+	s.recordAudit(ctx, actorID, models.AuditUserCreatedByAdmin, user.ID.Hex(), metadata)
+	s.publishEvent(ctx, events.UserRegistered, user, metadata)
+	s.logger.Info("User created by admin", "user_id", user.ID.Hex(), "user_type", user.UserType)
+
+	return &models.AdminCreateUserResponse{
+		User:              s.userResponse(ctx, user),
+		TemporaryPassword: password,
+	}, nil
+}
+
+// insertUser hashes password onto user and stores it, rejecting taken emails with a conflict
+func (s *AuthService) insertUser(ctx context.Context, user *models.User, password string) error {
+	existingUser, err := s.repo.GetByEmail(ctx, user.Email)
+	if err != nil && !errors.Is(err, mongo.ErrNoDocuments) {
+		s.logger.Error("Failed to check existing user", "error", err)
+		return et.NewDatabaseError("failed to check existing user", err)
+	}
+	if existingUser != nil {
+		s.logger.Warn("User already exists", "email", user.Email)
+		return et.NewConflictError("user with this email already exists", nil)
+	}
+
+	hashedPassword, err := s.hasher.Hash(password)
+	if err != nil {
+		s.logger.Error("Failed to hash password", "error", err)
+		return et.NewInternalError("failed to hash password", err)
+	}
+	user.Password = hashedPassword
+
+	// only transient failures (timeouts, network) are retried, a duplicate email won't go away.
+	// A timed out insert may still have been applied, which the retry then sees as a conflict
+	transientFailure := false
+	err = backoff.Retry(func() error {
+		err := s.repo.Create(ctx, user)
+		if err == nil {
+			return nil
+		}
+		var appErr *et.AppError
+		if errors.As(err, &appErr) && appErr.Retryable {
+			transientFailure = true
+			return err
+		}
+		return backoff.Permanent(err)
+	}, backoff.WithContext(backoff.WithMaxRetries(backoff.NewExponentialBackOff(), 3), ctx))
+	if err != nil {
+		var appErr *et.AppError
+		if errors.As(err, &appErr) && appErr.Type == et.ErrorTypeConflict {
+			// the id is kept across attempts, finding it means our own insert went through
+			if transientFailure {
+				if existing, lookupErr := s.repo.GetByEmail(ctx, user.Email); lookupErr == nil && existing.ID == user.ID {
+					s.logger.Warn("Timed out user insert had been applied", "user_id", user.ID.Hex())
+					return nil
+				}
+			}
+			s.logger.Warn("Conflict during user creation", "error", err)
+			return err
+		}
+		s.logger.Error("Failed to create user with retry", "error", err)
+		return fmt.Errorf("service: %w", err)
+	}
+	return nil
+}
+
 func (s *AuthService) AuthenticateUser(ctx context.Context, req *models.LoginRequest, metadata *models.RequestMetadata) (*models.AuthResponse, error) {
 	s.logger.Info("Authenticating user", "email", req.Email)
 
-	// Redis check attempts
-	ok, attempts, err := s.rl.CheckLoginAttempts(ctx, req.Email)
+	// Redis check attempts, the retry hint reaches clients as RetryInfo / Retry-After
+	ok, attempts, retryAfter, err := s.rl.CheckLoginAttempts(ctx, req.Email)
 	if err != nil {
-		s.logger.Warn("Login rate limit exceeded", "email", req.Email, "attempts", attempts)
-		return nil, et.NewTooManyRequestsError(err.Error())
-	}
-	if !ok {
+		s.logger.Error("Failed to check login attempts", "error", err)
+	} else if !ok {
 		s.logger.Warn("Too many login attempts", "email", req.Email, "attempts", attempts)
-		return nil, et.NewTooManyRequestsError("too many login attempts")
+		return nil, et.NewTooManyRequestsError("too many login attempts").WithRetryAfter(retryAfter)
+	}
+
+	ipLimit := cache.IPLimit{MaxAttempts: s.security.Lockout.MaxIPAttempts, Window: s.security.Lockout.IPWindow}
+	if metadata.IPAddress != "" {
+		allowed, retryAfter, err := s.rl.CheckIPLoginAttempts(ctx, metadata.IPAddress, ipLimit)
+		if err != nil {
+			s.logger.Error("Failed to check IP login attempts", "error", err)
+		} else if !allowed {
+			s.logger.Warn("Too many failed logins from IP", "ip", metadata.IPAddress)
+			return nil, et.NewTooManyRequestsError("too many login attempts").WithRetryAfter(retryAfter)
+		}
 	}
 
 	user, err := s.repo.GetByEmail(ctx, req.Email)
 	if err != nil {
 		if errors.Is(err, mongo.ErrNoDocuments) {
 			s.logger.Warn("Authentication failed: user not found", "email", req.Email)
+			_ = s.hasher.Compare(s.dummyHash, req.Password)
 			_ = s.rl.IncrementLoginAttempts(ctx, req.Email)
+			s.countFailedLoginFromIP(ctx, metadata.IPAddress, ipLimit)
 			return nil, et.NewUnauthorizedError("invalid email or password")
 		}
 		s.logger.Error("Failed to fetch user for authentication", "error", err)
 		return nil, et.NewDatabaseError("failed to fetch user", err)
 	}
 
-	if err := bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(req.Password)); err != nil {
-		if err := s.rl.IncrementLoginAttempts(ctx, req.Email); err != nil {
-			s.logger.Error("Failed to increment login attempts in Redis", "error", err)
-		}
-		s.logger.Warn("Invalid password", "email", req.Email, "attempts", attempts+1)
+	if user.LockedUntil != nil && time.Now().Before(*user.LockedUntil) {
+		s.logger.Warn("Login attempt on locked account", "user_id", user.ID.Hex(), "locked_until", user.LockedUntil)
+		return nil, et.NewTooManyRequestsError("account is temporarily locked").
+			WithRetryAfter(time.Until(*user.LockedUntil))
+	}
+
+	if err := s.hasher.Compare(user.Password, req.Password); err != nil {
+		s.registerFailedLogin(ctx, user)
+		s.countFailedLoginFromIP(ctx, metadata.IPAddress, ipLimit)
 		return nil, et.NewUnauthorizedError("invalid email or password")
 	}
 
-	if err := s.rl.ResetLoginAttempts(ctx, req.Email); err != nil {
-		s.logger.Error("Failed to reset login attempts in Redis", "error", err)
+	if user.LoginAttempts > 0 || user.LockoutCount > 0 || user.LockedUntil != nil {
+		if err := s.repo.ResetLoginAttempts(ctx, user.ID); err != nil {
+			s.logger.Error("Failed to reset login attempts", "error", err)
+		}
 	}
 
-	accessToken, err := s.jwtUtils.GenerateAccessToken(user.ID.Hex(), user.Email, string(user.UserType))
+	s.rehashPasswordIfNeeded(ctx, user, req.Password)
+
+	// before UpdateLoginInfo overwrites last_login_ip
+	newDevice := s.isNewDevice(ctx, user, metadata)
+	if newDevice {
+		s.logger.Warn("Login from new device", "user_id", user.ID.Hex(), "ip", metadata.IPAddress, "device_id", metadata.DeviceID)
+		s.publishEvent(ctx, events.UserNewDeviceLogin, user, metadata)
+	}
+
+	// the refresh token is withheld and the access token is scoped to the password change,
+	// RequireAuth refuses it everywhere else. ChangePassword itself still wants the old password
+	if s.passwordExpired(user) {
+		accessToken, err := s.jwtUtils.GeneratePasswordChangeToken(user.ID.Hex(), user.Email, string(user.UserType), user.OrgID)
+		if err != nil {
+			s.logger.Error("Failed to generate password change token", "error", err)
+			return nil, err
+		}
+		s.logger.Warn("Password expired, refresh token withheld", "user_id", user.ID.Hex())
+		_ = s.repo.UpdateLoginInfo(ctx, user.ID, metadata.IPAddress)
+		return &models.AuthResponse{
+			User:            s.userResponse(ctx, user),
+			AccessToken:     accessToken,
+			ExpiresAt:       time.Now().Add(s.jwtUtils.AccessTokenTTL).Unix(),
+			TokenType:       "Bearer",
+			PasswordExpired: true,
+			NewDevice:       newDevice,
+		}, nil
+	}
+
+	accessToken, err := s.jwtUtils.GenerateAccessToken(user.ID.Hex(), user.Email, string(user.UserType), user.OrgID)
 	if err != nil {
 		s.logger.Error("Failed to generate access token", "error", err)
 		return nil, err
 	}
+
 	refreshToken, err := s.jwtUtils.GenerateRefreshToken()
 	if err != nil {
 		s.logger.Error("Failed to generate refresh token", "error", err)
 		return nil, err
 	}
 
+	if err := s.enforceSessionLimit(ctx, user.ID); err != nil {
+		return nil, err
+	}
+
 	expiresAt := time.Now().Add(s.jwtUtils.RefreshTokenTTL)
 	if err := s.ts.SaveRefreshToken(ctx, user.ID, refreshToken, expiresAt); err != nil {
 		s.logger.Error("Failed to save refresh token", "error", err)
@@ -215,12 +378,14 @@ func (s *AuthService) AuthenticateUser(ctx context.Context, req *models.LoginReq
 	}
 
 	s.logger.Info("User authenticated successfully", "user_id", user.ID.Hex())
+	s.publishEvent(ctx, events.UserLoggedIn, user, metadata)
 	return &models.AuthResponse{
-		User:         user.ToResponse(),
+		User:         s.userResponse(ctx, user),
 		AccessToken:  accessToken,
 		RefreshToken: refreshToken,
 		ExpiresAt:    time.Now().Add(s.jwtUtils.AccessTokenTTL).Unix(),
 		TokenType:    "Bearer",
+		NewDevice:    newDevice,
 	}, nil
 }
 
@@ -229,8 +394,8 @@ func (s *AuthService) OAuthLogin(ctx context.Context, req *models.OAuthLoginRequ
 
 	provider, err := s.oauthFactory.GetProvider(oauth.ProviderType(req.Provider))
 	if err != nil {
-		s.logger.Error("Invalid OAuth provider", "error", err)
-		return nil, et.NewInternalError("invalid oauth provider", err)
+		s.logger.Warn("Invalid OAuth provider", "error", err)
+		return nil, et.NewBadRequestError(err.Error())
 	}
 
 	claims, err := provider.VerifyIDToken(ctx, req.IDToken)
@@ -239,13 +404,38 @@ func (s *AuthService) OAuthLogin(ctx context.Context, req *models.OAuthLoginRequ
 		return nil, et.NewUnauthorizedError(err.Error())
 	}
 
-	user, err := s.repo.GetByEmail(ctx, claims.Email)
-	if err != nil && !errors.Is(err, mongo.ErrNoDocuments) {
-		s.logger.Error("Failed to find user for OAuth", "error", err)
-		return nil, et.NewDatabaseError("failed to find user", err)
+	isGoogle := oauth.ProviderType(req.Provider) == oauth.Google
+
+	// a linked Google account wins over email, the address may have changed
+	var user *models.User
+	if isGoogle && claims.Subject != "" {
+		user, err = s.repo.GetByGoogleID(ctx, claims.Subject)
+		if err != nil && !errors.Is(err, mongo.ErrNoDocuments) {
+			s.logger.Error("Failed to find user by Google ID", "error", err)
+			return nil, et.NewDatabaseError("failed to find user", err)
+		}
+	}
+	if user == nil {
+		user, err = s.repo.GetByEmail(ctx, claims.Email)
+		if err != nil && !errors.Is(err, mongo.ErrNoDocuments) {
+			s.logger.Error("Failed to find user for OAuth", "error", err)
+			return nil, et.NewDatabaseError("failed to find user", err)
+		}
+		if user != nil && isGoogle && user.GoogleID == "" && claims.Subject != "" {
+			if err := s.repo.LinkGoogleAccount(ctx, user.ID, claims.Subject, claims.Email); err != nil {
+				s.logger.Error("Failed to link Google account", "error", err)
+				return nil, err
+			}
+			s.recordAudit(ctx, user.ID.Hex(), models.AuditOAuthAccountLinked, user.ID.Hex(), metadata)
+		}
 	}
 
 	if user == nil {
+		if !s.oauthDomainAllowed(claims.Email) {
+			s.logger.Warn("OAuth sign-up from disallowed email domain", "email", claims.Email)
+			return nil, et.NewForbiddenError("sign-up with this email domain is not allowed")
+		}
+
 		s.logger.Info("Creating new user from OAuth", "email", claims.Email)
 		user = &models.User{
 			Email:        claims.Email,
@@ -256,6 +446,10 @@ func (s *AuthService) OAuthLogin(ctx context.Context, req *models.OAuthLoginRequ
 			IsVerified:   true,
 			IsActive:     true,
 		}
+		if isGoogle {
+			user.GoogleID = claims.Subject
+			user.GoogleEmail = claims.Email
+		}
 
 		if err := s.repo.Create(ctx, user); err != nil {
 			s.logger.Error("Failed to create OAuth user", "error", err)
@@ -270,7 +464,7 @@ func (s *AuthService) OAuthLogin(ctx context.Context, req *models.OAuthLoginRequ
 		_ = s.repo.UpdateLoginInfo(ctx, user.ID, metadata.IPAddress)
 	}
 
-	accessToken, err := s.jwtUtils.GenerateAccessToken(user.ID.Hex(), user.Email, string(user.UserType))
+	accessToken, err := s.jwtUtils.GenerateAccessToken(user.ID.Hex(), user.Email, string(user.UserType), user.OrgID)
 	if err != nil {
 		s.logger.Error("Failed to generate access token for OAuth", "error", err)
 		return nil, err
@@ -281,11 +475,18 @@ func (s *AuthService) OAuthLogin(ctx context.Context, req *models.OAuthLoginRequ
 		return nil, err
 	}
 
+	if err := s.enforceSessionLimit(ctx, user.ID); err != nil {
+		return nil, err
+	}
+
 	if err := s.repo.SaveRefreshToken(ctx, &models.RefreshToken{
 		UserID:    user.ID,
 		Token:     refreshToken,
 		ExpiresAt: time.Now().Add(s.jwtUtils.RefreshTokenTTL),
 		CreatedAt: time.Now(),
+		DeviceID:  metadata.DeviceID,
+		UserAgent: metadata.UserAgent,
+		IP:        metadata.IPAddress,
 	}); err != nil {
 		s.logger.Error("Failed to save refresh token for OAuth", "error", err)
 		return nil, err
@@ -293,7 +494,7 @@ func (s *AuthService) OAuthLogin(ctx context.Context, req *models.OAuthLoginRequ
 
 	s.logger.Info("OAuth login successful", "user_id", user.ID.Hex())
 	return &models.AuthResponse{
-		User:         user.ToResponse(),
+		User:         s.userResponse(ctx, user),
 		AccessToken:  accessToken,
 		RefreshToken: refreshToken,
 		ExpiresAt:    time.Now().Add(s.jwtUtils.AccessTokenTTL).Unix(),
@@ -301,6 +502,26 @@ func (s *AuthService) OAuthLogin(ctx context.Context, req *models.OAuthLoginRequ
 	}, nil
 }
 
+// IssueGuestToken mints an anonymous access token for browsing before registration,
+// nothing is stored and the token can't be refreshed
+func (s *AuthService) IssueGuestToken(ctx context.Context) (*models.GuestTokenResponse, error) {
+	guestID := primitive.NewObjectID().Hex()
+	s.logger.Info("Issuing guest token", "user_id", guestID)
+
+	accessToken, err := s.jwtUtils.GenerateGuestToken(guestID, string(models.UserTypeAnonymous))
+	if err != nil {
+		s.logger.Error("Failed to generate guest token", "error", err)
+		return nil, et.NewInternalError("failed to generate guest token", err)
+	}
+
+	return &models.GuestTokenResponse{
+		UserID:      guestID,
+		AccessToken: accessToken,
+		ExpiresAt:   time.Now().Add(s.jwtUtils.GuestTokenTTL).Unix(),
+		TokenType:   "Bearer",
+	}, nil
+}
+
 func (s *AuthService) RefreshToken(ctx context.Context, req *models.RefreshTokenRequest, metadata *models.RequestMetadata) (*models.RefreshTokenResponse, error) {
 	s.logger.Info("Refreshing token")
 
@@ -319,6 +540,22 @@ func (s *AuthService) RefreshToken(ctx context.Context, req *models.RefreshToken
 		return nil, et.NewUnauthorizedError("refresh token has expired")
 	}
 
+	if mismatch := s.deviceMismatch(storedToken, metadata); mismatch != "" {
+		s.logger.Warn("Refresh token used from another device",
+			"token_id", storedToken.ID.Hex(), "user_id", storedToken.UserID.Hex(),
+			"mismatch", mismatch, "mode", s.security.RefreshBinding.Mode)
+		if s.security.RefreshBinding.Mode == "strict" {
+			// a stolen token is dead for the thief and the owner alike, the owner logs in again
+			if err := s.repo.RevokeRefreshToken(ctx, storedToken.ID); err != nil {
+				s.logger.Error("Failed to revoke mismatched refresh token", "error", err)
+			}
+			if err := s.ts.RevokeRefreshToken(ctx, req.RefreshToken); err != nil {
+				s.logger.Error("Failed to revoke cached refresh token", "error", err)
+			}
+			return nil, et.NewUnauthorizedError("refresh token was issued to another device")
+		}
+	}
+
 	if err := s.repo.RevokeRefreshToken(ctx, storedToken.ID); err != nil {
 		s.logger.Error("Failed to revoke old refresh token", "error", err)
 		return nil, err
@@ -330,7 +567,7 @@ func (s *AuthService) RefreshToken(ctx context.Context, req *models.RefreshToken
 		return nil, et.NewNotFoundError("user associated with token not found", err)
 	}
 
-	accessToken, err := s.jwtUtils.GenerateAccessToken(user.ID.Hex(), user.Email, string(user.UserType))
+	accessToken, err := s.jwtUtils.GenerateAccessToken(user.ID.Hex(), user.Email, string(user.UserType), user.OrgID)
 	if err != nil {
 		s.logger.Error("Failed to generate new access token", "error", err)
 		return nil, err
@@ -363,41 +600,115 @@ func (s *AuthService) RefreshToken(ctx context.Context, req *models.RefreshToken
 	}, nil
 }
 
+// MaxValidateBatch bounds the number of tokens in one ValidateTokens call
+const MaxValidateBatch = 100
+
 func (s *AuthService) ValidateToken(ctx context.Context, req *models.ValidateTokenRequest) (*models.ValidateTokenResponse, error) {
 	s.logger.Info("Validating access token")
+	return s.validateToken(ctx, req.AccessToken, nil)
+}
+
+// ValidateTokens validates every token independently, an invalid token does not fail the batch.
+// Users are looked up once per batch
+func (s *AuthService) ValidateTokens(ctx context.Context, tokens []string) ([]*models.ValidateTokenResponse, error) {
+	s.logger.Info("Validating access token batch", "count", len(tokens))
+
+	if len(tokens) == 0 {
+		return nil, et.NewValidationError("no tokens to validate", map[string]string{"access_tokens": "is required"})
+	}
+	if len(tokens) > MaxValidateBatch {
+		return nil, et.NewValidationError("too many tokens",
+			map[string]string{"access_tokens": fmt.Sprintf("must contain at most %d tokens", MaxValidateBatch)})
+	}
+
+	users := make(map[primitive.ObjectID]*models.User)
+	results := make([]*models.ValidateTokenResponse, 0, len(tokens))
+	for _, token := range tokens {
+		resp, err := s.validateToken(ctx, token, users)
+		if err != nil {
+			var appErr *et.AppError
+			if !errors.As(err, &appErr) || appErr.Type != et.ErrorTypeUnauthorized {
+				return nil, err
+			}
+			resp = &models.ValidateTokenResponse{Valid: false, Error: appErr.Message}
+		}
+		results = append(results, resp)
+	}
 
-	claims, err := s.jwtUtils.ValidateAccessToken(req.AccessToken)
+	return results, nil
+}
+
+// validateToken checks the token and loads its user, users caches lookups when non-nil
+func (s *AuthService) validateToken(ctx context.Context, token string, users map[primitive.ObjectID]*models.User) (*models.ValidateTokenResponse, error) {
+	claims, err := s.jwtUtils.ValidateAccessToken(token)
 	if err != nil {
 		s.logger.Warn("Token validation failed", "error", err)
 		return nil, et.NewUnauthorizedError("invalid or expired token")
 	}
 
+	expiresAt := claims.RegisteredClaims.ExpiresAt.Time
+	remaining := max(time.Until(expiresAt), 0)
+
+	// guests have no user document, the signed claims are all there is
+	if models.UserType(claims.UserType) == models.UserTypeAnonymous {
+		return &models.ValidateTokenResponse{
+			Valid:         true,
+			UserID:        claims.UserID,
+			UserType:      models.UserTypeAnonymous,
+			IsActive:      true,
+			ExpiresAt:     expiresAt.Unix(),
+			ExpiresIn:     int64(remaining.Seconds()),
+			ShouldRefresh: remaining < s.jwtUtils.RefreshHintThreshold,
+		}, nil
+	}
+
 	userID, err := primitive.ObjectIDFromHex(claims.UserID)
 	if err != nil {
 		s.logger.Error("Failed to parse user ID from claims", "error", err)
 		return nil, et.NewInternalError("failed to parse user id", err)
 	}
 
-	user, err := s.repo.GetByID(ctx, userID)
-	if err != nil {
-		s.logger.Error("Failed to fetch user for token validation", "error", err)
-		return nil, et.NewDatabaseError("failed to fetch user", err)
+	user, cached := users[userID]
+	if !cached {
+		user, err = s.repo.GetByID(ctx, userID)
+		if err != nil {
+			s.logger.Error("Failed to fetch user for token validation", "error", err)
+			return nil, et.NewDatabaseError("failed to fetch user", err)
+		}
+		if users != nil {
+			users[userID] = user
+		}
 	}
 
 	s.logger.Info("Token validated successfully", "user_id", userID.Hex())
 	return &models.ValidateTokenResponse{
 		Valid:      true,
 		UserID:     user.ID.Hex(),
+		Email:      user.Email,
 		UserType:   user.UserType,
 		IsActive:   user.IsActive,
 		IsVerified: user.IsVerified,
-		ExpiresAt:  claims.ExpiresAt.Time.Unix(),
+		ExpiresAt:  expiresAt.Unix(),
+		OrgID:      claims.OrgID,
+		Scope:      claims.Scope,
+
+		ExpiresIn:     int64(remaining.Seconds()),
+		ShouldRefresh: remaining < s.jwtUtils.RefreshHintThreshold,
 	}, nil
 }
 
-func (s *AuthService) ChangePassword(ctx context.Context, req *models.ChangePasswordRequest) error {
+func (s *AuthService) ChangePassword(ctx context.Context, req *models.ChangePasswordRequest, metadata *models.RequestMetadata) error {
 	s.logger.Info("Changing password", "user_id", req.UserID)
 
+	if err := validation.Validate(req); err != nil {
+		s.logger.Warn("Validation failed for password change", "error", err)
+		return err
+	}
+	if err := s.passwords.ValidatePassword(req.NewPassword); err != nil {
+		s.logger.Warn("Password policy check failed for password change", "user_id", req.UserID)
+		return err
+	}
+
 	userID, err := primitive.ObjectIDFromHex(req.UserID)
 	if err != nil {
 		s.logger.Warn("Invalid user ID", "user_id", req.UserID, "error", err)
@@ -410,33 +721,116 @@ func (s *AuthService) ChangePassword(ctx context.Context, req *models.ChangePass
 		return et.NewDatabaseError("failed to fetch user", err)
 	}
 
-	if err := bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(req.OldPassword)); err != nil {
+	if err := s.hasher.Compare(user.Password, req.OldPassword); err != nil {
 		s.logger.Warn("Old password mismatch", "user_id", userID.Hex())
 		return et.NewUnauthorizedError("old password is incorrect")
 	}
 
-	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(req.NewPassword), BcryptCost)
+	hashedPassword, err := s.hasher.Hash(req.NewPassword)
 	if err != nil {
 		s.logger.Error("Failed to hash new password", "error", err)
 		return et.NewInternalError("failed to hash new password", err)
 	}
 
-	err = s.repo.UpdatePassword(ctx, user.ID, string(hashedPassword))
+	err = s.repo.ChangePassword(ctx, user.ID, hashedPassword)
 	if err != nil {
 		s.logger.Error("Failed to update password in DB", "error", err)
 		return et.NewDatabaseError("failed to update password", err)
 	}
 
+	// log out every other device
+	if _, err := s.repo.RevokeAllUserRefreshTokens(ctx, user.ID); err != nil {
+		s.logger.Error("Failed to revoke refresh tokens after password change", "error", err)
+		return err
+	}
+	if err := s.ts.RevokeAllUserTokens(ctx, user.ID); err != nil {
+		s.logger.Error("Failed to revoke cached refresh tokens", "error", err)
+	}
+
+	s.recordAudit(ctx, user.ID.Hex(), models.AuditPasswordChanged, user.ID.Hex(), metadata)
 	s.logger.Info("Password changed successfully", "user_id", userID.Hex())
+	s.publishEvent(ctx, events.UserPasswordChanged, user, nil)
 	return nil
 }
 
+// mediaStatusUploaded is the media service status of a confirmed upload
+const mediaStatusUploaded = "uploaded"
+
+// UpdateProfileImage points the user's profile image at an uploaded media object they own,
+// the media key is stored and presigned again on every read
+func (s *AuthService) UpdateProfileImage(ctx context.Context, req *models.UpdateProfileImageRequest) (string, error) {
+	s.logger.Info("Updating profile image", "user_id", req.UserID)
+
+	if err := validation.Validate(req); err != nil {
+		s.logger.Warn("Validation failed for profile image update", "error", err)
+		return "", err
+	}
+
+	userID, err := primitive.ObjectIDFromHex(req.UserID)
+	if err != nil {
+		s.logger.Warn("Invalid user ID", "user_id", req.UserID, "error", err)
+		return "", et.NewValidationError("invalid user id", map[string]string{"user_id": req.UserID})
+	}
+
+	resp, err := s.media.GetMedia(ctx, &media_pb.GetMediaRequest{Key: req.MediaKey})
+	if err != nil {
+		if status.Code(err) == codes.NotFound {
+			s.logger.Warn("Media not found", "media_key", req.MediaKey)
+			return "", et.NewNotFoundError("media not found", err)
+		}
+		s.logger.Error("Failed to fetch media", "error", err)
+		return "", et.NewInternalError("failed to fetch media", err)
+	}
+
+	media := resp.GetMedia()
+	if media.GetOwnerId() != userID.Hex() {
+		s.logger.Warn("Media owner mismatch", "user_id", userID.Hex(), "media_key", req.MediaKey)
+		return "", et.NewForbiddenError("media belongs to another user")
+	}
+	if media.GetStatus() != mediaStatusUploaded {
+		s.logger.Warn("Media not uploaded", "user_id", userID.Hex(), "media_key", req.MediaKey, "status", media.GetStatus())
+		return "", et.NewConflictError("media has not been uploaded yet", nil)
+	}
+
+	if err := s.repo.UpdateProfileImage(ctx, userID, media.GetKey()); err != nil {
+		return "", err
+	}
+
+	s.logger.Info("Profile image updated", "user_id", userID.Hex())
+	return media.GetUrl(), nil
+}
+
+// userResponse is ToResponse with the profile image turned into a url the client can load
+func (s *AuthService) userResponse(ctx context.Context, user *models.User) *models.UserResponse {
+	resp := user.ToResponse()
+	resp.ProfileImage = s.profileImageURL(ctx, user.ProfileImage)
+	return resp
+}
+
+// profileImageURL presigns a stored media key, OAuth picture urls are returned as they are,
+// a key that can not be presigned is dropped rather than failing the whole response
+func (s *AuthService) profileImageURL(ctx context.Context, image string) string {
+	if image == "" || strings.HasPrefix(image, "https://") || strings.HasPrefix(image, "http://") {
+		return image
+	}
+	if s.media == nil {
+		return ""
+	}
+
+	resp, err := s.media.GetMedia(ctx, &media_pb.GetMediaRequest{Key: image})
+	if err != nil {
+		s.logger.Warn("Failed to presign profile image", "media_key", image, "error", err)
+		return ""
+	}
+	return resp.GetMedia().GetUrl()
+}
+
 func (s *AuthService) Logout(ctx context.Context, req *models.LogoutRequest) error {
 	s.logger.Info("Logging out user", "user_id", req.UserID)
 
 	tokenID, err := primitive.ObjectIDFromHex(req.RefreshToken)
 	if err != nil {
-		s.logger.Warn("Invalid refresh token ID", "refresh_token", req.RefreshToken, "error", err)
+		s.logger.Warn("Invalid refresh token ID", "user_id", req.UserID, "error", err)
 		return et.NewValidationError("invalid refresh token id", map[string]string{"refresh_token": req.RefreshToken})
 	}
 
@@ -449,3 +843,309 @@ func (s *AuthService) Logout(ctx context.Context, req *models.LogoutRequest) err
 	s.logger.Info("Logout successful", "user_id", req.UserID)
 	return nil
 }
+
+// UnlockAccount clears a lockout and the failed attempt counters right away,
+// actorID is the admin doing it and is recorded in the audit log
+func (s *AuthService) UnlockAccount(ctx context.Context, userID, actorID string, metadata *models.RequestMetadata) error {
+	s.logger.Info("Unlocking account", "user_id", userID, "actor_id", actorID)
+
+	oid, err := primitive.ObjectIDFromHex(userID)
+	if err != nil {
+		return et.NewValidationError("invalid user id", map[string]string{"user_id": userID})
+	}
+
+	user, err := s.repo.GetByID(ctx, oid)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return et.NewNotFoundError("user not found", err)
+		}
+		s.logger.Error("Failed to fetch user for unlock", "error", err)
+		return et.NewDatabaseError("failed to fetch user", err)
+	}
+
+	if err := s.repo.ResetLoginAttempts(ctx, user.ID); err != nil {
+		s.logger.Error("Failed to unlock account", "user_id", userID, "error", err)
+		return et.NewDatabaseError("failed to unlock account", err)
+	}
+
+	s.recordAudit(ctx, actorID, models.AuditAccountUnlocked, userID, metadata)
+	s.logger.Info("Account unlocked", "user_id", userID)
+	return nil
+}
+
+// MaxUsersBatch bounds the number of ids in one GetUsers call
+const MaxUsersBatch = 100
+
+// GetUsers resolves ids to users in request order, unknown ids are skipped and
+// duplicates collapse to one result
+func (s *AuthService) GetUsers(ctx context.Context, ids []string) ([]*models.UserResponse, error) {
+	s.logger.Info("Fetching users", "count", len(ids))
+
+	if len(ids) > MaxUsersBatch {
+		return nil, et.NewValidationError("too many user ids",
+			map[string]string{"user_ids": fmt.Sprintf("must contain at most %d ids", MaxUsersBatch)})
+	}
+
+	objectIDs := make([]primitive.ObjectID, 0, len(ids))
+	seen := make(map[primitive.ObjectID]bool, len(ids))
+	for _, id := range ids {
+		oid, err := primitive.ObjectIDFromHex(id)
+		if err != nil {
+			return nil, et.NewValidationError("invalid user id", map[string]string{"user_ids": fmt.Sprintf("%q is not a valid id", id)})
+		}
+		if !seen[oid] {
+			seen[oid] = true
+			objectIDs = append(objectIDs, oid)
+		}
+	}
+
+	users, err := s.repo.GetUsersByIDs(ctx, objectIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	byID := make(map[primitive.ObjectID]*models.User, len(users))
+	for _, u := range users {
+		byID[u.ID] = u
+	}
+	result := make([]*models.UserResponse, 0, len(users))
+	for _, oid := range objectIDs {
+		if u, ok := byID[oid]; ok {
+			result = append(result, s.userResponse(ctx, u))
+		}
+	}
+	return result, nil
+}
+
+func (s *AuthService) ListAuditEntries(ctx context.Context, targetUserID string, page db.PageRequest) (*db.PageResponse[*models.AuditEntry], error) {
+	s.logger.Info("Listing audit entries", "target_user_id", targetUserID)
+	return s.audit.List(ctx, targetUserID, page)
+}
+
+func (s *AuthService) ListAuditEntriesByCursor(ctx context.Context, targetUserID string, page db.CursorRequest) (*db.CursorResponse[*models.AuditEntry], error) {
+	s.logger.Info("Listing audit entries by cursor", "target_user_id", targetUserID)
+	return s.audit.ListByCursor(ctx, targetUserID, page)
+}
+
+// SearchUsers backs the admin user search, results are newest first
+func (s *AuthService) SearchUsers(ctx context.Context, criteria models.UserSearchCriteria, page db.PageRequest) (*db.PageResponse[*models.UserResponse], error) {
+	s.logger.Info("Searching users", "user_type", criteria.UserType, "page", page.Page)
+
+	switch criteria.UserType {
+	case "", models.UserTypeClient, models.UserTypeMaster, models.UserTypeAdmin:
+	default:
+		return nil, et.NewValidationError("invalid user type", map[string]string{"user_type": "must be one of client, master, admin"})
+	}
+	if criteria.CreatedFrom != nil && criteria.CreatedTo != nil && criteria.CreatedFrom.After(*criteria.CreatedTo) {
+		return nil, et.NewValidationError("invalid creation date range", map[string]string{"created_from": "must not be after created_to"})
+	}
+
+	result, err := s.repo.SearchUsers(ctx, criteria, page)
+	if err != nil {
+		s.logger.Error("Failed to search users", "error", err)
+		return nil, err
+	}
+
+	users := make([]*models.UserResponse, 0, len(result.Items))
+	for _, u := range result.Items {
+		users = append(users, s.userResponse(ctx, u))
+	}
+	return &db.PageResponse[*models.UserResponse]{
+		Items:      users,
+		Total:      result.Total,
+		Page:       result.Page,
+		PageSize:   result.PageSize,
+		TotalPages: result.TotalPages,
+	}, nil
+}
+
+// ListSessions pages the user's active sessions, oldest first
+func (s *AuthService) ListSessions(ctx context.Context, userID string, page db.CursorRequest) (*db.CursorResponse[*models.RefreshToken], error) {
+	s.logger.Info("Listing sessions", "user_id", userID)
+
+	oid, err := primitive.ObjectIDFromHex(userID)
+	if err != nil {
+		return nil, et.NewValidationError("invalid user id", map[string]string{"user_id": userID})
+	}
+	return s.repo.ListSessionsPage(ctx, oid, page)
+}
+
+// the operation already happened, a failed audit write is logged loudly but not returned
+func (s *AuthService) recordAudit(ctx context.Context, actorID string, action models.AuditAction, targetUserID string, metadata *models.RequestMetadata) {
+	if err := s.audit.Record(ctx, actorID, action, targetUserID, metadata); err != nil {
+		s.logger.Error("Failed to record audit entry", "action", action, "target_user_id", targetUserID, "error", err)
+	}
+}
+
+// registerFailedLogin counts a bad password and locks the account once the
+// limit is hit, each further lockout takes the next, longer, schedule step
+func (s *AuthService) registerFailedLogin(ctx context.Context, user *models.User) {
+	attempts, err := s.repo.IncrementLoginAttempts(ctx, user.ID)
+	if err != nil {
+		s.logger.Error("Failed to increment login attempts", "error", err)
+		return
+	}
+	s.logger.Warn("Invalid password", "user_id", user.ID.Hex(), "attempts", attempts)
+
+	lockout := s.security.Lockout
+	if attempts < lockout.MaxAttempts {
+		return
+	}
+
+	duration := lockout.Schedule[min(user.LockoutCount, len(lockout.Schedule)-1)]
+	if err := s.repo.LockUserAccount(ctx, user.ID, duration); err != nil {
+		s.logger.Error("Failed to lock user account", "error", err)
+		return
+	}
+	s.logger.Warn("User account locked", "user_id", user.ID.Hex(), "duration", duration, "lockout_count", user.LockoutCount+1)
+}
+
+func (s *AuthService) countFailedLoginFromIP(ctx context.Context, ip string, limit cache.IPLimit) {
+	if ip == "" {
+		return
+	}
+	if err := s.rl.IncrementIPLoginAttempts(ctx, ip, limit); err != nil {
+		s.logger.Error("Failed to increment IP login attempts", "error", err)
+	}
+}
+
+// enforceSessionLimit revokes the oldest sessions so a new one fits under MaxActiveSessions
+func (s *AuthService) enforceSessionLimit(ctx context.Context, userID primitive.ObjectID) error {
+	limit := s.security.MaxActiveSessions
+	if limit <= 0 {
+		return nil
+	}
+
+	sessions, err := s.repo.ListSessions(ctx, userID)
+	if err != nil {
+		s.logger.Error("Failed to list sessions", "user_id", userID.Hex(), "error", err)
+		return err
+	}
+
+	for i := 0; i <= len(sessions)-limit; i++ {
+		oldest := sessions[i]
+		if err := s.repo.RevokeRefreshToken(ctx, oldest.ID); err != nil {
+			s.logger.Error("Failed to evict session", "token_id", oldest.ID.Hex(), "error", err)
+			return err
+		}
+		if err := s.ts.RevokeRefreshTokenHash(ctx, oldest.Token); err != nil {
+			s.logger.Warn("Failed to evict cached session", "token_id", oldest.ID.Hex(), "error", err)
+		}
+		s.logger.Info("Evicted oldest session", "user_id", userID.Hex(), "token_id", oldest.ID.Hex())
+	}
+
+	return nil
+}
+
+// rehashPasswordIfNeeded upgrades hashes made with an older, lower cost or another
+// algorithm. Failures are only logged - the user has already been authenticated
+func (s *AuthService) rehashPasswordIfNeeded(ctx context.Context, user *models.User, password string) {
+	if !s.hasher.NeedsRehash(user.Password) {
+		return
+	}
+
+	hashedPassword, err := s.hasher.Hash(password)
+	if err != nil {
+		s.logger.Error("Failed to rehash password", "user_id", user.ID.Hex(), "error", err)
+		return
+	}
+	if err := s.repo.UpdatePassword(ctx, user.ID, hashedPassword); err != nil {
+		s.logger.Error("Failed to store rehashed password", "user_id", user.ID.Hex(), "error", err)
+		return
+	}
+
+	user.Password = hashedPassword
+	s.logger.Info("Password rehashed", "user_id", user.ID.Hex(), "algorithm", s.security.PasswordHashAlgorithm)
+}
+
+// isNewDevice flags a login whose device id and IP were never seen for the user. Users
+// without any login history aren't flagged, there is nothing to compare against yet.
+// Lookup failures are logged and don't flag, the login itself already succeeded
+func (s *AuthService) isNewDevice(ctx context.Context, user *models.User, metadata *models.RequestMetadata) bool {
+	if metadata.DeviceID == "" && metadata.IPAddress == "" {
+		return false
+	}
+	if metadata.IPAddress != "" && metadata.IPAddress == user.LastLoginIP {
+		return false
+	}
+
+	hasHistory, known, err := s.repo.LoginHistory(ctx, user.ID, metadata.DeviceID, metadata.IPAddress)
+	if err != nil {
+		s.logger.Error("Failed to check login history", "user_id", user.ID.Hex(), "error", err)
+		return false
+	}
+	if !hasHistory && user.LastLoginAt == nil {
+		return false
+	}
+	return !known
+}
+
+// deviceMismatch names the part of the device fingerprint that differs from the one the
+// token was issued to, "" when it matches or binding is off
+func (s *AuthService) deviceMismatch(token *models.RefreshToken, metadata *models.RequestMetadata) string {
+	binding := s.security.RefreshBinding
+	if binding.Mode == "off" {
+		return ""
+	}
+	if token.DeviceID != "" && token.DeviceID != metadata.DeviceID {
+		return "device_id"
+	}
+	if binding.UserAgent && token.UserAgent != "" && token.UserAgent != metadata.UserAgent {
+		return "user_agent"
+	}
+	return ""
+}
+
+// oauthDomainAllowed checks the email domain against security.allowed_oauth_domains,
+// case-insensitively and without subdomains. An empty list allows every domain
+func (s *AuthService) oauthDomainAllowed(email string) bool {
+	if len(s.security.AllowedOAuthDomains) == 0 {
+		return true
+	}
+	at := strings.LastIndexByte(email, '@')
+	if at < 0 {
+		return false
+	}
+	domain := email[at+1:]
+	for _, allowed := range s.security.AllowedOAuthDomains {
+		if strings.EqualFold(domain, strings.TrimPrefix(allowed, "@")) {
+			return true
+		}
+	}
+	return false
+}
+
+// passwordExpired reports passwords older than security.max_password_age, users
+// from before password_changed_at was tracked count from their creation
+func (s *AuthService) passwordExpired(user *models.User) bool {
+	if s.security.MaxPasswordAge <= 0 {
+		return false
+	}
+	changedAt := user.PasswordChangeAt
+	if changedAt.IsZero() {
+		changedAt = user.CreatedAt
+	}
+	return time.Since(changedAt) > s.security.MaxPasswordAge
+}
+
+// events are best effort - a broker outage must not fail auth flows
+func (s *AuthService) publishEvent(ctx context.Context, eventType string, user *models.User, metadata *models.RequestMetadata) {
+	event := events.UserEvent{
+		Type:       eventType,
+		UserID:     user.ID.Hex(),
+		Email:      user.Email,
+		UserType:   string(user.UserType),
+		OccurredAt: time.Now().UTC(),
+	}
+	if metadata != nil {
+		event.Metadata = map[string]string{
+			"ip":         metadata.IPAddress,
+			"user_agent": metadata.UserAgent,
+			"device_id":  metadata.DeviceID,
+		}
+	}
+
+	if err := s.events.Publish(ctx, event); err != nil {
+		s.logger.Error("Failed to publish user event", "type", eventType, "user_id", event.UserID, "error", err)
+	}
+}
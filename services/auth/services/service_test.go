@@ -0,0 +1,1545 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"remaster/services/auth/cache"
+	"remaster/services/auth/models"
+	oauth "remaster/services/auth/oauth"
+	"remaster/services/auth/repositories/memory"
+	"remaster/services/auth/utils"
+	config "remaster/shared"
+	"remaster/shared/db"
+	et "remaster/shared/errors"
+	"remaster/shared/events"
+	media_pb "remaster/shared/proto/media"
+	"remaster/shared/tokenauth"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"golang.org/x/crypto/bcrypt"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+)
+
+const testPassword = "Str0ng!Passw0rd"
+
+type testEnv struct {
+	svc      *AuthService
+	repo     *memory.Repository
+	security *config.SecurityConfig
+}
+
+// newTestService wires an AuthService to the memory repository and an in-process redis,
+// configure may adjust the security settings before the service is built
+func newTestService(t *testing.T, configure func(*config.SecurityConfig)) *testEnv {
+	t.Helper()
+
+	security := &config.SecurityConfig{
+		BcryptCost:            4,
+		PasswordHashAlgorithm: "bcrypt",
+		PhoneDefaultRegion:    "US",
+		Lockout: config.LockoutConfig{
+			MaxAttempts:   3,
+			Schedule:      []time.Duration{15 * time.Minute, time.Hour},
+			MaxIPAttempts: 100,
+			IPWindow:      time.Minute,
+		},
+		AllowedSelfRegistrationTypes: []string{"client", "master"},
+		RefreshBinding:               config.RefreshBindingConfig{Mode: "off"},
+	}
+	if configure != nil {
+		configure(security)
+	}
+
+	mr := miniredis.RunT(t)
+	rdb := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() { _ = rdb.Close() })
+
+	jwtUtils, err := utils.NewJWTUtils(&config.JWTConfig{
+		SecretKey:       "test-secret-key-that-is-long-enough",
+		Issuer:          "remaster-auth",
+		Audience:        "remaster",
+		AccessTokenTTL:  15 * time.Minute,
+		RefreshTokenTTL: 24 * time.Hour,
+		GuestTokenTTL:   time.Hour,
+	})
+	if err != nil {
+		t.Fatalf("jwt utils: %v", err)
+	}
+	passwords, err := utils.NewPasswordPolicy(&config.PasswordPolicyConfig{MinLength: 8, MaxLength: 72})
+	if err != nil {
+		t.Fatalf("password policy: %v", err)
+	}
+	hasher, err := utils.NewPasswordHasher(security)
+	if err != nil {
+		t.Fatalf("password hasher: %v", err)
+	}
+	oauthFactory, err := oauth.NewProviderFactory(&config.OAuthConfig{})
+	if err != nil {
+		t.Fatalf("oauth factory: %v", err)
+	}
+
+	repository := memory.NewRepository()
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	svc := NewAuthService(repository, oauthFactory, rdb, jwtUtils, security, passwords, hasher,
+		events.NoopPublisher{}, nil, logger)
+
+	return &testEnv{svc: svc, repo: repository, security: security}
+}
+
+func (e *testEnv) register(t *testing.T, email string) *models.AuthResponse {
+	t.Helper()
+	resp, err := e.svc.CreateUser(context.Background(), &models.RegisterRequest{
+		Email:     email,
+		Password:  testPassword,
+		FirstName: "Test",
+		LastName:  "User",
+		Phone:     "+14155550123",
+		UserType:  models.UserTypeClient,
+	}, &models.RequestMetadata{IPAddress: "203.0.113.7"})
+	if err != nil {
+		t.Fatalf("CreateUser(%s): %v", email, err)
+	}
+	return resp
+}
+
+func (e *testEnv) login(email, password string, metadata *models.RequestMetadata) (*models.AuthResponse, error) {
+	if metadata == nil {
+		metadata = &models.RequestMetadata{IPAddress: "203.0.113.7"}
+	}
+	return e.svc.AuthenticateUser(context.Background(),
+		&models.LoginRequest{Email: email, Password: password}, metadata)
+}
+
+func assertErrorCode(t *testing.T, err error, want et.ErrorCode) *et.AppError {
+	t.Helper()
+	var appErr *et.AppError
+	if !errors.As(err, &appErr) {
+		t.Fatalf("error = %v, want an AppError with code %s", err, want)
+	}
+	if appErr.Code != want {
+		t.Fatalf("error code = %s (%v), want %s", appErr.Code, err, want)
+	}
+	return appErr
+}
+
+func TestCreateUserRejectsDuplicateEmail(t *testing.T) {
+	env := newTestService(t, nil)
+	env.register(t, "taken@example.com")
+
+	_, err := env.svc.CreateUser(context.Background(), &models.RegisterRequest{
+		Email:     "taken@example.com",
+		Password:  testPassword,
+		FirstName: "Other",
+		LastName:  "User",
+		Phone:     "+14155550124",
+		UserType:  models.UserTypeMaster,
+	}, &models.RequestMetadata{})
+	assertErrorCode(t, err, et.CodeConflict)
+}
+
+func TestAuthenticateUserLocksAccountAfterMaxAttempts(t *testing.T) {
+	env := newTestService(t, nil)
+	env.register(t, "lock@example.com")
+
+	for i := range env.security.Lockout.MaxAttempts {
+		_, err := env.login("lock@example.com", "wrong-password", nil)
+		if err == nil {
+			t.Fatalf("attempt %d: wrong password was accepted", i+1)
+		}
+		assertErrorCode(t, err, et.CodeUnauthorized)
+	}
+
+	// even the right password is refused while the lock lasts
+	_, err := env.login("lock@example.com", testPassword, nil)
+	appErr := assertErrorCode(t, err, et.CodeTooManyRequests)
+	if appErr.RetryAfter <= 0 || appErr.RetryAfter > env.security.Lockout.Schedule[0] {
+		t.Fatalf("RetryAfter = %s, want within the first lockout step %s", appErr.RetryAfter, env.security.Lockout.Schedule[0])
+	}
+
+	user, err := env.repo.GetByEmail(context.Background(), "lock@example.com")
+	if err != nil {
+		t.Fatalf("GetByEmail: %v", err)
+	}
+	if user.LockedUntil == nil || !user.LockedUntil.After(time.Now()) {
+		t.Fatalf("LockedUntil = %v, want a time in the future", user.LockedUntil)
+	}
+}
+
+func TestRefreshTokenRotates(t *testing.T) {
+	env := newTestService(t, nil)
+	auth := env.register(t, "rotate@example.com")
+
+	resp, err := env.svc.RefreshToken(context.Background(),
+		&models.RefreshTokenRequest{RefreshToken: auth.RefreshToken}, &models.RequestMetadata{})
+	if err != nil {
+		t.Fatalf("RefreshToken: %v", err)
+	}
+	if resp.RefreshToken == "" || resp.RefreshToken == auth.RefreshToken {
+		t.Fatalf("refresh token was not rotated")
+	}
+
+	// the old token was revoked by the rotation
+	_, err = env.svc.RefreshToken(context.Background(),
+		&models.RefreshTokenRequest{RefreshToken: auth.RefreshToken}, &models.RequestMetadata{})
+	assertErrorCode(t, err, et.CodeUnauthorized)
+}
+
+func TestRefreshTokenRejectsRevokedToken(t *testing.T) {
+	env := newTestService(t, nil)
+	auth := env.register(t, "revoked@example.com")
+
+	stored, err := env.repo.FindRefreshToken(context.Background(), auth.RefreshToken)
+	if err != nil {
+		t.Fatalf("FindRefreshToken: %v", err)
+	}
+	if err := env.repo.RevokeRefreshToken(context.Background(), stored.ID); err != nil {
+		t.Fatalf("RevokeRefreshToken: %v", err)
+	}
+
+	_, err = env.svc.RefreshToken(context.Background(),
+		&models.RefreshTokenRequest{RefreshToken: auth.RefreshToken}, &models.RequestMetadata{})
+	if appErr := assertErrorCode(t, err, et.CodeUnauthorized); appErr.Message != "refresh token has been revoked" {
+		t.Fatalf("message = %q, want the revoked token rejection", appErr.Message)
+	}
+}
+
+func TestRefreshTokenRejectsExpiredToken(t *testing.T) {
+	env := newTestService(t, nil)
+	auth := env.register(t, "expired@example.com")
+
+	token := "expired-refresh-token"
+	if err := env.repo.SaveRefreshToken(context.Background(), &models.RefreshToken{
+		UserID:    mustUserID(t, auth),
+		Token:     token,
+		ExpiresAt: time.Now().Add(-time.Minute),
+		CreatedAt: time.Now().Add(-25 * time.Hour),
+	}); err != nil {
+		t.Fatalf("SaveRefreshToken: %v", err)
+	}
+
+	_, err := env.svc.RefreshToken(context.Background(),
+		&models.RefreshTokenRequest{RefreshToken: token}, &models.RequestMetadata{})
+	if appErr := assertErrorCode(t, err, et.CodeUnauthorized); appErr.Message != "refresh token has expired" {
+		t.Fatalf("message = %q, want the expired token rejection", appErr.Message)
+	}
+}
+
+func mustUserID(t *testing.T, auth *models.AuthResponse) primitive.ObjectID {
+	t.Helper()
+	id, err := primitive.ObjectIDFromHex(auth.User.ID)
+	if err != nil {
+		t.Fatalf("user id %q: %v", auth.User.ID, err)
+	}
+	return id
+}
+
+// createHookRepo lets a test script Create, hideExisting makes GetByEmail miss so
+// insertUser's pre-check passes as it would when a concurrent sign-up races it
+type createHookRepo struct {
+	*memory.Repository
+	hideExisting bool
+	creates      int
+	create       func(attempt int, user *models.User) error
+}
+
+func (r *createHookRepo) GetByEmail(ctx context.Context, email string) (*models.User, error) {
+	if r.hideExisting {
+		return nil, mongo.ErrNoDocuments
+	}
+	return r.Repository.GetByEmail(ctx, email)
+}
+
+func (r *createHookRepo) Create(ctx context.Context, user *models.User) error {
+	r.creates++
+	if r.create != nil {
+		return r.create(r.creates, user)
+	}
+	return r.Repository.Create(ctx, user)
+}
+
+func newUser(email string) *models.User {
+	return &models.User{Email: email, FirstName: "Test", LastName: "User", UserType: models.UserTypeClient}
+}
+
+func TestInsertUserReturnsConflictWithoutRetry(t *testing.T) {
+	env := newTestService(t, nil)
+	env.register(t, "race@example.com")
+	hook := &createHookRepo{Repository: env.repo, hideExisting: true}
+	env.svc.repo = hook
+
+	start := time.Now()
+	err := env.svc.insertUser(context.Background(), newUser("race@example.com"), testPassword)
+	assertErrorCode(t, err, et.CodeConflict)
+	if hook.creates != 1 {
+		t.Fatalf("Create called %d times, want 1", hook.creates)
+	}
+	// the first backoff step is 500ms give or take half
+	if elapsed := time.Since(start); elapsed > 200*time.Millisecond {
+		t.Fatalf("conflict took %s, want no backoff delay", elapsed)
+	}
+}
+
+func TestInsertUserDoesNotRetryPermanentDatabaseErrors(t *testing.T) {
+	env := newTestService(t, nil)
+	hook := &createHookRepo{Repository: env.repo, create: func(int, *models.User) error {
+		return et.NewDatabaseError("failed to create user", errors.New("document failed validation"))
+	}}
+	env.svc.repo = hook
+
+	err := env.svc.insertUser(context.Background(), newUser("invalid@example.com"), testPassword)
+	if err == nil {
+		t.Fatal("insertUser succeeded, want the database error")
+	}
+	if hook.creates != 1 {
+		t.Fatalf("Create called %d times, want 1", hook.creates)
+	}
+}
+
+func TestInsertUserRetriesTransientErrors(t *testing.T) {
+	env := newTestService(t, nil)
+	hook := &createHookRepo{Repository: env.repo}
+	hook.create = func(attempt int, user *models.User) error {
+		if attempt == 1 {
+			return et.NewDatabaseError("failed to create user", context.DeadlineExceeded)
+		}
+		return env.repo.Create(context.Background(), user)
+	}
+	env.svc.repo = hook
+
+	if err := env.svc.insertUser(context.Background(), newUser("retry@example.com"), testPassword); err != nil {
+		t.Fatalf("insertUser: %v", err)
+	}
+	if hook.creates != 2 {
+		t.Fatalf("Create called %d times, want 2", hook.creates)
+	}
+}
+
+func TestInsertUserTreatsAppliedTimedOutInsertAsSuccess(t *testing.T) {
+	env := newTestService(t, nil)
+	hook := &createHookRepo{Repository: env.repo}
+	hook.create = func(attempt int, user *models.User) error {
+		err := env.repo.Create(context.Background(), user)
+		if attempt == 1 && err == nil {
+			// the write landed but the acknowledgement never arrived
+			return et.NewDatabaseError("failed to create user", context.DeadlineExceeded)
+		}
+		return err
+	}
+	env.svc.repo = hook
+
+	user := newUser("applied@example.com")
+	if err := env.svc.insertUser(context.Background(), user, testPassword); err != nil {
+		t.Fatalf("insertUser: %v, want the applied insert to count as success", err)
+	}
+	if hook.creates != 2 {
+		t.Fatalf("Create called %d times, want 2", hook.creates)
+	}
+	stored, err := env.repo.GetByEmail(context.Background(), "applied@example.com")
+	if err != nil || stored.ID != user.ID {
+		t.Fatalf("stored user = %v, %v, want id %s", stored, err, user.ID.Hex())
+	}
+}
+
+type fakeOAuthProvider struct {
+	claims *oauth.Claims
+}
+
+func (p fakeOAuthProvider) VerifyIDToken(context.Context, string) (*oauth.Claims, error) {
+	return p.claims, nil
+}
+
+// oauthLogin signs a new Google user in from the device in metadata
+func (e *testEnv) oauthLogin(t *testing.T, metadata *models.RequestMetadata) *models.AuthResponse {
+	t.Helper()
+	e.svc.oauthFactory.Register(oauth.Google, fakeOAuthProvider{claims: &oauth.Claims{
+		Subject:   "google-subject-1",
+		Email:     "oauth@example.com",
+		FirstName: "OAuth",
+		LastName:  "User",
+	}})
+	resp, err := e.svc.OAuthLogin(context.Background(),
+		&models.OAuthLoginRequest{Provider: string(oauth.Google), IDToken: "id-token"}, metadata)
+	if err != nil {
+		t.Fatalf("OAuthLogin: %v", err)
+	}
+	return resp
+}
+
+func TestOAuthLoginBindsRefreshTokenToDevice(t *testing.T) {
+	phone := &models.RequestMetadata{DeviceID: "phone", UserAgent: "app/1.0", IPAddress: "203.0.113.7"}
+	laptop := &models.RequestMetadata{DeviceID: "laptop", UserAgent: "browser/2.0", IPAddress: "198.51.100.4"}
+
+	t.Run("match", func(t *testing.T) {
+		env := newTestService(t, func(s *config.SecurityConfig) { s.RefreshBinding.Mode = "strict" })
+		auth := env.oauthLogin(t, phone)
+
+		stored, err := env.repo.FindRefreshToken(context.Background(), auth.RefreshToken)
+		if err != nil {
+			t.Fatalf("FindRefreshToken: %v", err)
+		}
+		if stored.DeviceID != phone.DeviceID || stored.UserAgent != phone.UserAgent || stored.IP != phone.IPAddress {
+			t.Fatalf("stored binding = %q/%q/%q, want the login device", stored.DeviceID, stored.UserAgent, stored.IP)
+		}
+
+		if _, err := env.svc.RefreshToken(context.Background(),
+			&models.RefreshTokenRequest{RefreshToken: auth.RefreshToken}, phone); err != nil {
+			t.Fatalf("RefreshToken from the same device: %v", err)
+		}
+	})
+
+	t.Run("mismatch revokes in strict mode", func(t *testing.T) {
+		env := newTestService(t, func(s *config.SecurityConfig) { s.RefreshBinding.Mode = "strict" })
+		auth := env.oauthLogin(t, phone)
+
+		_, err := env.svc.RefreshToken(context.Background(),
+			&models.RefreshTokenRequest{RefreshToken: auth.RefreshToken}, laptop)
+		if appErr := assertErrorCode(t, err, et.CodeUnauthorized); appErr.Message != "refresh token was issued to another device" {
+			t.Fatalf("message = %q, want the device mismatch rejection", appErr.Message)
+		}
+
+		stored, err := env.repo.FindRefreshToken(context.Background(), auth.RefreshToken)
+		if err != nil {
+			t.Fatalf("FindRefreshToken: %v", err)
+		}
+		if !stored.IsRevoked {
+			t.Fatal("mismatched refresh token was not revoked")
+		}
+	})
+
+	t.Run("mismatch is only logged in log mode", func(t *testing.T) {
+		env := newTestService(t, func(s *config.SecurityConfig) { s.RefreshBinding.Mode = "log" })
+		auth := env.oauthLogin(t, phone)
+
+		resp, err := env.svc.RefreshToken(context.Background(),
+			&models.RefreshTokenRequest{RefreshToken: auth.RefreshToken}, laptop)
+		if err != nil {
+			t.Fatalf("RefreshToken from another device: %v", err)
+		}
+		if resp.RefreshToken == "" {
+			t.Fatal("no refresh token issued")
+		}
+	})
+}
+
+// agedPasswordRepo reports every password as changed age ago
+type agedPasswordRepo struct {
+	*memory.Repository
+	age time.Duration
+}
+
+func (r *agedPasswordRepo) GetByEmail(ctx context.Context, email string) (*models.User, error) {
+	user, err := r.Repository.GetByEmail(ctx, email)
+	if err == nil {
+		user.PasswordChangeAt = time.Now().Add(-r.age)
+	}
+	return user, err
+}
+
+func TestAuthenticateUserPasswordExpiry(t *testing.T) {
+	const maxAge = 90 * 24 * time.Hour
+
+	tests := []struct {
+		name        string
+		maxAge      time.Duration
+		passwordAge time.Duration
+		wantExpired bool
+	}{
+		{name: "policy off, old password", maxAge: 0, passwordAge: 2 * maxAge},
+		{name: "policy on, recent password", maxAge: maxAge, passwordAge: time.Hour},
+		{name: "policy on, expired password", maxAge: maxAge, passwordAge: maxAge + time.Hour, wantExpired: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			env := newTestService(t, func(s *config.SecurityConfig) { s.MaxPasswordAge = tt.maxAge })
+			env.register(t, "rotation@example.com")
+			env.svc.repo = &agedPasswordRepo{Repository: env.repo, age: tt.passwordAge}
+
+			resp, err := env.login("rotation@example.com", testPassword, nil)
+			if err != nil {
+				t.Fatalf("AuthenticateUser: %v", err)
+			}
+			if resp.PasswordExpired != tt.wantExpired {
+				t.Fatalf("PasswordExpired = %v, want %v", resp.PasswordExpired, tt.wantExpired)
+			}
+			if gotRefresh := resp.RefreshToken != ""; gotRefresh == tt.wantExpired {
+				t.Fatalf("refresh token issued = %v, want %v", gotRefresh, !tt.wantExpired)
+			}
+
+			validated, err := env.svc.ValidateToken(context.Background(), &models.ValidateTokenRequest{AccessToken: resp.AccessToken})
+			if err != nil {
+				t.Fatalf("ValidateToken: %v", err)
+			}
+			wantScope := ""
+			if tt.wantExpired {
+				wantScope = tokenauth.ScopePasswordChange
+			}
+			if validated.Scope != wantScope {
+				t.Fatalf("token scope = %q, want %q", validated.Scope, wantScope)
+			}
+		})
+	}
+}
+
+// fakeMediaClient serves GetMedia from a map keyed by object key
+type fakeMediaClient struct {
+	media_pb.MediaServiceClient
+	media map[string]*media_pb.Media
+}
+
+func (c *fakeMediaClient) GetMedia(_ context.Context, req *media_pb.GetMediaRequest, _ ...grpc.CallOption) (*media_pb.GetMediaResponse, error) {
+	m, ok := c.media[req.GetKey()]
+	if !ok {
+		return nil, status.Error(codes.NotFound, "media not found")
+	}
+	signed := proto.Clone(m).(*media_pb.Media)
+	signed.Url = "https://bucket.example.com/" + m.GetKey() + "?X-Amz-Signature=fresh"
+	return &media_pb.GetMediaResponse{Media: signed}, nil
+}
+
+func TestUpdateProfileImage(t *testing.T) {
+	env := newTestService(t, nil)
+	owner := mustUserID(t, env.register(t, "owner@example.com"))
+	other := mustUserID(t, env.register(t, "other@example.com"))
+
+	const (
+		uploadedKey = "user/owner/uploaded.jpg"
+		pendingKey  = "user/owner/pending.jpg"
+		foreignKey  = "user/other/uploaded.jpg"
+	)
+	env.svc.media = &fakeMediaClient{media: map[string]*media_pb.Media{
+		uploadedKey: {Key: uploadedKey, OwnerId: owner.Hex(), Status: "uploaded"},
+		pendingKey:  {Key: pendingKey, OwnerId: owner.Hex(), Status: "pending"},
+		foreignKey:  {Key: foreignKey, OwnerId: other.Hex(), Status: "uploaded"},
+	}}
+
+	update := func(key string) (string, error) {
+		return env.svc.UpdateProfileImage(context.Background(),
+			&models.UpdateProfileImageRequest{UserID: owner.Hex(), MediaKey: key})
+	}
+
+	t.Run("owner match stores the key", func(t *testing.T) {
+		url, err := update(uploadedKey)
+		if err != nil {
+			t.Fatalf("UpdateProfileImage: %v", err)
+		}
+		if url == "" {
+			t.Fatal("no image url returned")
+		}
+		user, err := env.repo.GetByID(context.Background(), owner)
+		if err != nil {
+			t.Fatalf("GetByID: %v", err)
+		}
+		if user.ProfileImage != uploadedKey {
+			t.Fatalf("stored ProfileImage = %q, want the media key %q", user.ProfileImage, uploadedKey)
+		}
+	})
+
+	t.Run("owner mismatch", func(t *testing.T) {
+		_, err := update(foreignKey)
+		assertErrorCode(t, err, et.CodeForbidden)
+	})
+
+	t.Run("not found", func(t *testing.T) {
+		_, err := update("user/owner/missing.jpg")
+		assertErrorCode(t, err, et.CodeNotFound)
+	})
+
+	t.Run("not uploaded", func(t *testing.T) {
+		_, err := update(pendingKey)
+		assertErrorCode(t, err, et.CodeConflict)
+	})
+}
+
+func TestUserResponsePresignsProfileImageOnRead(t *testing.T) {
+	env := newTestService(t, nil)
+	env.svc.media = &fakeMediaClient{media: map[string]*media_pb.Media{
+		"user/u1/avatar.jpg": {Key: "user/u1/avatar.jpg", Status: "uploaded"},
+	}}
+
+	tests := []struct {
+		name  string
+		image string
+		want  string
+	}{
+		{name: "media key", image: "user/u1/avatar.jpg", want: "https://bucket.example.com/user/u1/avatar.jpg?X-Amz-Signature=fresh"},
+		{name: "oauth picture", image: "https://lh3.googleusercontent.com/a/photo", want: "https://lh3.googleusercontent.com/a/photo"},
+		{name: "missing media", image: "user/u1/deleted.jpg", want: ""},
+		{name: "no image", image: "", want: ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp := env.svc.userResponse(context.Background(), &models.User{ID: primitive.NewObjectID(), ProfileImage: tt.image})
+			if resp.ProfileImage != tt.want {
+				t.Fatalf("ProfileImage = %q, want %q", resp.ProfileImage, tt.want)
+			}
+		})
+	}
+}
+
+func TestUserLifecycleEmitsEvents(t *testing.T) {
+	env := newTestService(t, nil)
+	rec := &events.RecordingPublisher{}
+	env.svc.events = rec
+
+	metadata := &models.RequestMetadata{IPAddress: "203.0.113.7", DeviceID: "phone", UserAgent: "app/1.0"}
+	auth := env.register(t, "events@example.com")
+	if _, err := env.login("events@example.com", testPassword, metadata); err != nil {
+		t.Fatalf("AuthenticateUser: %v", err)
+	}
+	if err := env.svc.ChangePassword(context.Background(), &models.ChangePasswordRequest{
+		UserID:      auth.User.ID,
+		OldPassword: testPassword,
+		NewPassword: "N3w!Passw0rd",
+	}, metadata); err != nil {
+		t.Fatalf("ChangePassword: %v", err)
+	}
+
+	byType := make(map[string]events.UserEvent)
+	for _, e := range rec.Events() {
+		byType[e.Type] = e
+	}
+	for _, typ := range []string{events.UserRegistered, events.UserLoggedIn, events.UserPasswordChanged} {
+		e, ok := byType[typ]
+		if !ok {
+			t.Fatalf("no %s event in %v", typ, rec.Types())
+		}
+		if e.UserID != auth.User.ID || e.Email != "events@example.com" {
+			t.Fatalf("%s event = %+v, want it about the registered user", typ, e)
+		}
+	}
+	if got := byType[events.UserLoggedIn].Metadata["device_id"]; got != "phone" {
+		t.Fatalf("login event device_id = %q, want phone", got)
+	}
+}
+
+func TestFailedLoginEmitsNoEvent(t *testing.T) {
+	env := newTestService(t, nil)
+	env.register(t, "quiet@example.com")
+	rec := &events.RecordingPublisher{}
+	env.svc.events = rec
+
+	if _, err := env.login("quiet@example.com", "wrong-password", nil); err == nil {
+		t.Fatal("wrong password was accepted")
+	}
+	if types := rec.Types(); len(types) != 0 {
+		t.Fatalf("published %v, want nothing for a failed login", types)
+	}
+}
+
+func TestIssuedRefreshTokenIsStoredHashed(t *testing.T) {
+	env := newTestService(t, nil)
+	auth := env.register(t, "hashed@example.com")
+
+	stored, err := env.repo.FindRefreshToken(context.Background(), auth.RefreshToken)
+	if err != nil {
+		t.Fatalf("FindRefreshToken by the raw token: %v", err)
+	}
+	if stored.Token == auth.RefreshToken || stored.Token != utils.HashRefreshToken(auth.RefreshToken) {
+		t.Fatalf("stored token = %q, want the sha-256 of the issued token", stored.Token)
+	}
+
+	// the stored hash does not work as a refresh token
+	_, err = env.svc.RefreshToken(context.Background(),
+		&models.RefreshTokenRequest{RefreshToken: stored.Token}, &models.RequestMetadata{})
+	assertErrorCode(t, err, et.CodeUnauthorized)
+}
+
+func TestAdminCreateUserProvisionsMaster(t *testing.T) {
+	env := newTestService(t, nil)
+	ctx := context.Background()
+	adminID := primitive.NewObjectID().Hex()
+
+	resp, err := env.svc.AdminCreateUser(ctx, &models.AdminCreateUserRequest{
+		Email:     "master@example.com",
+		FirstName: "Master",
+		LastName:  "User",
+		Phone:     "+14155550123",
+		UserType:  models.UserTypeMaster,
+		Verified:  true,
+	}, adminID, &models.RequestMetadata{IPAddress: "203.0.113.7"})
+	if err != nil {
+		t.Fatalf("AdminCreateUser: %v", err)
+	}
+	if resp.TemporaryPassword == "" {
+		t.Fatal("no temporary password returned")
+	}
+
+	user, err := env.repo.GetByEmail(ctx, "master@example.com")
+	if err != nil {
+		t.Fatalf("user not stored: %v", err)
+	}
+	if user.UserType != models.UserTypeMaster || !user.IsVerified || user.EmailVerifiedAt == nil {
+		t.Fatalf("stored user = %+v, want a verified master", user)
+	}
+	if user.Password == resp.TemporaryPassword {
+		t.Fatal("temporary password stored in plain text")
+	}
+	if _, err := env.login("master@example.com", resp.TemporaryPassword, nil); err != nil {
+		t.Fatalf("login with the temporary password: %v", err)
+	}
+
+	entry, err := env.repo.GetLastAuditEntry(ctx)
+	if err != nil || entry == nil {
+		t.Fatalf("audit entry = %v, %v", entry, err)
+	}
+	// logins are not audited, so the last entry is still the creation
+	if entry.Action != models.AuditUserCreatedByAdmin || entry.ActorID != adminID || entry.TargetUserID != user.ID.Hex() {
+		t.Fatalf("audit entry = %+v, want the admin creating the user", entry)
+	}
+}
+
+func TestAdminCreateUserRejectsTakenEmail(t *testing.T) {
+	env := newTestService(t, nil)
+	env.register(t, "taken@example.com")
+
+	_, err := env.svc.AdminCreateUser(context.Background(), &models.AdminCreateUserRequest{
+		Email:     "taken@example.com",
+		FirstName: "Admin",
+		LastName:  "User",
+		Phone:     "+14155550123",
+		UserType:  models.UserTypeAdmin,
+	}, primitive.NewObjectID().Hex(), nil)
+	assertErrorCode(t, err, et.CodeConflict)
+}
+
+func TestLoginMigratesBcryptHashToArgon2id(t *testing.T) {
+	env := newTestService(t, nil)
+	env.register(t, "migrate@example.com")
+
+	argon, err := utils.NewPasswordHasher(&config.SecurityConfig{
+		PasswordHashAlgorithm: utils.HashArgon2id,
+		Argon2:                config.Argon2Config{Time: 1, MemoryKiB: 1024, Threads: 1},
+	})
+	if err != nil {
+		t.Fatalf("argon2id hasher: %v", err)
+	}
+	env.svc.hasher = argon
+
+	if _, err := env.login("migrate@example.com", testPassword, nil); err != nil {
+		t.Fatalf("login with a bcrypt hash under argon2id: %v", err)
+	}
+	user, _ := env.repo.GetByEmail(context.Background(), "migrate@example.com")
+	if !strings.HasPrefix(user.Password, "$argon2id$") {
+		t.Fatalf("stored hash = %q, want it rehashed with argon2id", user.Password)
+	}
+	if _, err := env.login("migrate@example.com", testPassword, nil); err != nil {
+		t.Fatalf("login after the migration: %v", err)
+	}
+}
+
+func TestCreateUserReportsFieldErrors(t *testing.T) {
+	env := newTestService(t, nil)
+
+	_, err := env.svc.CreateUser(context.Background(), &models.RegisterRequest{
+		Email:     "not-an-email",
+		Password:  testPassword,
+		FirstName: "T",
+		LastName:  "User",
+		Phone:     "+14155550123",
+		UserType:  models.UserTypeClient,
+	}, &models.RequestMetadata{IPAddress: "203.0.113.7"})
+
+	appErr := assertErrorCode(t, err, et.CodeValidation)
+	if appErr.Details["email"] == "" || appErr.Details["first_name"] == "" {
+		t.Fatalf("details = %v, want email and first_name violations", appErr.Details)
+	}
+	if _, ok := appErr.Details["last_name"]; ok {
+		t.Fatalf("details = %v, valid last_name reported", appErr.Details)
+	}
+}
+
+func TestLoginRehashesLowCostBcryptHash(t *testing.T) {
+	env := newTestService(t, nil)
+	env.register(t, "cost@example.com")
+	before, _ := env.repo.GetByEmail(context.Background(), "cost@example.com")
+
+	env.security.BcryptCost = 5
+	hasher, err := utils.NewPasswordHasher(env.security)
+	if err != nil {
+		t.Fatalf("password hasher: %v", err)
+	}
+	env.svc.hasher = hasher
+
+	if _, err := env.login("cost@example.com", testPassword, nil); err != nil {
+		t.Fatalf("login with a low cost hash: %v", err)
+	}
+	after, _ := env.repo.GetByEmail(context.Background(), "cost@example.com")
+	if after.Password == before.Password {
+		t.Fatal("hash unchanged after login with a raised cost")
+	}
+	if cost, err := bcrypt.Cost([]byte(after.Password)); err != nil || cost != 5 {
+		t.Fatalf("stored cost = %d, %v, want 5", cost, err)
+	}
+	if _, err := env.login("cost@example.com", testPassword, nil); err != nil {
+		t.Fatalf("login with the rehashed password: %v", err)
+	}
+
+	// an up to date hash is left alone
+	again, _ := env.repo.GetByEmail(context.Background(), "cost@example.com")
+	if again.Password != after.Password {
+		t.Fatal("hash rewritten although the cost already matched")
+	}
+}
+
+func TestPasswordPolicyAppliesToRegistrationAndChange(t *testing.T) {
+	env := newTestService(t, nil)
+	auth := env.register(t, "policy@example.com")
+
+	strict, err := utils.NewPasswordPolicy(&config.PasswordPolicyConfig{MinLength: 8, MaxLength: 72, RequireSymbol: true})
+	if err != nil {
+		t.Fatalf("password policy: %v", err)
+	}
+	env.svc.passwords = strict
+
+	_, err = env.svc.CreateUser(context.Background(), &models.RegisterRequest{
+		Email:     "weak@example.com",
+		Password:  "NoSymbol123",
+		FirstName: "Weak",
+		LastName:  "User",
+		Phone:     "+14155550123",
+		UserType:  models.UserTypeClient,
+	}, &models.RequestMetadata{IPAddress: "203.0.113.7"})
+	if appErr := assertErrorCode(t, err, et.CodeValidation); appErr.Details["require_symbol"] == "" {
+		t.Fatalf("register details = %v, want require_symbol", appErr.Details)
+	}
+
+	err = env.svc.ChangePassword(context.Background(), &models.ChangePasswordRequest{
+		UserID:      mustUserID(t, auth).Hex(),
+		OldPassword: testPassword,
+		NewPassword: "NoSymbol123",
+	}, &models.RequestMetadata{IPAddress: "203.0.113.7"})
+	if appErr := assertErrorCode(t, err, et.CodeValidation); appErr.Details["require_symbol"] == "" {
+		t.Fatalf("change details = %v, want require_symbol", appErr.Details)
+	}
+}
+
+func TestChangePasswordRevokesEverySession(t *testing.T) {
+	env := newTestService(t, nil)
+	ctx := context.Background()
+	first := env.register(t, "everywhere@example.com")
+	second, err := env.login("everywhere@example.com", testPassword, &models.RequestMetadata{IPAddress: "198.51.100.4", DeviceID: "laptop"})
+	if err != nil {
+		t.Fatalf("second login: %v", err)
+	}
+
+	if err := env.svc.ChangePassword(ctx, &models.ChangePasswordRequest{
+		UserID:      mustUserID(t, first).Hex(),
+		OldPassword: testPassword,
+		NewPassword: "N3w!Passw0rd-value",
+	}, &models.RequestMetadata{IPAddress: "203.0.113.7"}); err != nil {
+		t.Fatalf("ChangePassword: %v", err)
+	}
+
+	for name, token := range map[string]string{"first device": first.RefreshToken, "second device": second.RefreshToken} {
+		stored, err := env.repo.FindRefreshToken(ctx, token)
+		if err != nil {
+			t.Fatalf("%s token gone: %v", name, err)
+		}
+		if !stored.IsRevoked {
+			t.Errorf("%s token still live after the password change", name)
+		}
+		_, err = env.svc.RefreshToken(ctx, &models.RefreshTokenRequest{RefreshToken: token}, &models.RequestMetadata{})
+		assertErrorCode(t, err, et.CodeUnauthorized)
+	}
+
+	if _, err := env.login("everywhere@example.com", "N3w!Passw0rd-value", nil); err != nil {
+		t.Fatalf("login with the new password: %v", err)
+	}
+}
+
+func TestSessionLimitEvictsTheOldestSession(t *testing.T) {
+	const limit = 3
+	env := newTestService(t, func(s *config.SecurityConfig) { s.MaxActiveSessions = limit })
+	ctx := context.Background()
+
+	tokens := []string{env.register(t, "busy@example.com").RefreshToken}
+	for i := 0; i < limit; i++ {
+		// distinct created_at, the eviction order depends on it
+		time.Sleep(time.Millisecond)
+		auth, err := env.login("busy@example.com", testPassword, nil)
+		if err != nil {
+			t.Fatalf("login %d: %v", i+1, err)
+		}
+		tokens = append(tokens, auth.RefreshToken)
+	}
+
+	first, err := env.repo.FindRefreshToken(ctx, tokens[0])
+	if err != nil {
+		t.Fatalf("first session gone: %v", err)
+	}
+	sessions, err := env.repo.ListSessions(ctx, first.UserID)
+	if err != nil || len(sessions) != limit {
+		t.Fatalf("live sessions = %d, %v, want %d", len(sessions), err, limit)
+	}
+
+	for i, token := range tokens {
+		stored, err := env.repo.FindRefreshToken(ctx, token)
+		if err != nil {
+			t.Fatalf("session %d gone: %v", i, err)
+		}
+		if evicted := i == 0; stored.IsRevoked != evicted {
+			t.Errorf("session %d revoked = %v, want %v", i, stored.IsRevoked, evicted)
+		}
+	}
+	_, err = env.svc.RefreshToken(ctx, &models.RefreshTokenRequest{RefreshToken: tokens[0]}, &models.RequestMetadata{})
+	assertErrorCode(t, err, et.CodeUnauthorized)
+}
+
+func TestValidateTokensReportsEachTokenInOrder(t *testing.T) {
+	env := newTestService(t, nil)
+	ctx := context.Background()
+	auth := env.register(t, "batch@example.com")
+	valid := auth.AccessToken
+
+	// same key, already past its expiry
+	expiredSigner, err := utils.NewJWTUtils(&config.JWTConfig{
+		SecretKey:      "test-secret-key-that-is-long-enough",
+		Issuer:         "remaster-auth",
+		Audience:       "remaster",
+		AccessTokenTTL: -time.Minute,
+	})
+	if err != nil {
+		t.Fatalf("jwt utils: %v", err)
+	}
+	expired, err := expiredSigner.GenerateAccessToken(mustUserID(t, auth).Hex(), "batch@example.com", "client", "")
+	if err != nil {
+		t.Fatalf("sign expired token: %v", err)
+	}
+	guest, err := env.svc.jwtUtils.GenerateGuestToken("guest-1", string(models.UserTypeAnonymous))
+	if err != nil {
+		t.Fatalf("sign guest token: %v", err)
+	}
+
+	results, err := env.svc.ValidateTokens(ctx, []string{valid, valid + "x", expired, "not-a-jwt", guest})
+	if err != nil {
+		t.Fatalf("ValidateTokens: %v", err)
+	}
+	want := []bool{true, false, false, false, true}
+	if len(results) != len(want) {
+		t.Fatalf("results = %d, want one per token", len(results))
+	}
+	for i, r := range results {
+		if r.Valid != want[i] {
+			t.Errorf("result %d valid = %v (%s), want %v", i, r.Valid, r.Error, want[i])
+		}
+		if !r.Valid && r.Error == "" {
+			t.Errorf("result %d has no error", i)
+		}
+	}
+	if results[0].Email != "batch@example.com" || results[4].UserType != models.UserTypeAnonymous {
+		t.Fatalf("valid results = %+v, %+v", results[0], results[4])
+	}
+}
+
+func TestValidateTokensRejectsEmptyAndOversizedBatches(t *testing.T) {
+	env := newTestService(t, nil)
+
+	_, err := env.svc.ValidateTokens(context.Background(), nil)
+	assertErrorCode(t, err, et.CodeValidation)
+
+	_, err = env.svc.ValidateTokens(context.Background(), make([]string, MaxValidateBatch+1))
+	assertErrorCode(t, err, et.CodeValidation)
+}
+
+func TestSensitiveOperationsWriteOneAuditEntry(t *testing.T) {
+	adminID := primitive.NewObjectID().Hex()
+	metadata := &models.RequestMetadata{IPAddress: "203.0.113.7", UserAgent: "admin-console/1.0"}
+
+	tests := []struct {
+		name   string
+		action models.AuditAction
+		actor  func(userID string) string
+		run    func(t *testing.T, env *testEnv, userID string) error
+	}{
+		{
+			name:   "admin creates a user",
+			action: models.AuditUserCreatedByAdmin,
+			actor:  func(string) string { return adminID },
+			run: func(t *testing.T, env *testEnv, _ string) error {
+				_, err := env.svc.AdminCreateUser(context.Background(), &models.AdminCreateUserRequest{
+					Email:     "provisioned@example.com",
+					FirstName: "Pro",
+					LastName:  "Visioned",
+					Phone:     "+14155550123",
+					UserType:  models.UserTypeMaster,
+				}, adminID, metadata)
+				return err
+			},
+		},
+		{
+			name:   "password change",
+			action: models.AuditPasswordChanged,
+			actor:  func(userID string) string { return userID },
+			run: func(t *testing.T, env *testEnv, userID string) error {
+				return env.svc.ChangePassword(context.Background(), &models.ChangePasswordRequest{
+					UserID:      userID,
+					OldPassword: testPassword,
+					NewPassword: "N3w!Passw0rd-value",
+				}, metadata)
+			},
+		},
+		{
+			name:   "admin unlocks an account",
+			action: models.AuditAccountUnlocked,
+			actor:  func(string) string { return adminID },
+			run: func(t *testing.T, env *testEnv, userID string) error {
+				return env.svc.UnlockAccount(context.Background(), userID, adminID, metadata)
+			},
+		},
+		{
+			name:   "google account linked",
+			action: models.AuditOAuthAccountLinked,
+			actor:  func(userID string) string { return userID },
+			run: func(t *testing.T, env *testEnv, _ string) error {
+				env.oauthLogin(t, metadata)
+				return nil
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			env := newTestService(t, nil)
+			// the oauth helper signs in oauth@example.com, registering it first makes it a link
+			userID := mustUserID(t, env.register(t, "oauth@example.com")).Hex()
+
+			before := auditEntries(t, env)
+			if len(before) != 0 {
+				t.Fatalf("registration wrote %d audit entries, want none", len(before))
+			}
+			if err := tt.run(t, env, userID); err != nil {
+				t.Fatalf("operation: %v", err)
+			}
+
+			after := auditEntries(t, env)
+			if len(after) != 1 {
+				t.Fatalf("audit entries = %d, want exactly one", len(after))
+			}
+			entry := after[0]
+			if entry.Action != tt.action || entry.ActorID != tt.actor(userID) || entry.IP != metadata.IPAddress {
+				t.Fatalf("audit entry = %+v, want %s by %s", entry, tt.action, tt.actor(userID))
+			}
+			if entry.TargetUserID == "" || entry.Hash == "" {
+				t.Fatalf("audit entry = %+v, want a target and a hash", entry)
+			}
+		})
+	}
+}
+
+func auditEntries(t *testing.T, env *testEnv) []*models.AuditEntry {
+	t.Helper()
+	page, err := env.svc.ListAuditEntries(context.Background(), "", db.PageRequest{PageSize: db.MaxPageSize})
+	if err != nil {
+		t.Fatalf("ListAuditEntries: %v", err)
+	}
+	return page.Items
+}
+
+func TestCreateUserStoresPhoneInE164(t *testing.T) {
+	env := newTestService(t, nil)
+	ctx := context.Background()
+
+	_, err := env.svc.CreateUser(ctx, &models.RegisterRequest{
+		Email:     "local@example.com",
+		Password:  testPassword,
+		FirstName: "Local",
+		LastName:  "Number",
+		Phone:     "(415) 555-0123",
+		UserType:  models.UserTypeClient,
+	}, &models.RequestMetadata{IPAddress: "203.0.113.7"})
+	if err != nil {
+		t.Fatalf("CreateUser: %v", err)
+	}
+	user, err := env.repo.GetByEmail(ctx, "local@example.com")
+	if err != nil {
+		t.Fatalf("user not stored: %v", err)
+	}
+	if user.Phone != "+14155550123" {
+		t.Fatalf("stored phone = %q, want the E.164 form", user.Phone)
+	}
+
+	_, err = env.svc.CreateUser(ctx, &models.RegisterRequest{
+		Email:     "garbage@example.com",
+		Password:  testPassword,
+		FirstName: "Garbage",
+		LastName:  "Number",
+		Phone:     "not a phone",
+		UserType:  models.UserTypeClient,
+	}, &models.RequestMetadata{IPAddress: "203.0.113.7"})
+	if appErr := assertErrorCode(t, err, et.CodeValidation); appErr.Details["phone"] == "" {
+		t.Fatalf("details = %v, want the phone field", appErr.Details)
+	}
+}
+
+// elapsedLockRepo records each lock it is asked for and reports every lock as
+// already over, so a test can drive one lockout cycle after another
+type elapsedLockRepo struct {
+	*memory.Repository
+	locks []time.Duration
+}
+
+func (r *elapsedLockRepo) LockUserAccount(ctx context.Context, userID primitive.ObjectID, duration time.Duration) error {
+	r.locks = append(r.locks, duration)
+	return r.Repository.LockUserAccount(ctx, userID, duration)
+}
+
+func (r *elapsedLockRepo) GetByEmail(ctx context.Context, email string) (*models.User, error) {
+	user, err := r.Repository.GetByEmail(ctx, email)
+	if err == nil && user.LockedUntil != nil {
+		past := time.Now().Add(-time.Second)
+		user.LockedUntil = &past
+	}
+	return user, err
+}
+
+func TestLockoutDurationGrowsWithEachLockout(t *testing.T) {
+	schedule := []time.Duration{time.Minute, 5 * time.Minute, 30 * time.Minute}
+	env := newTestService(t, func(s *config.SecurityConfig) { s.Lockout.Schedule = schedule })
+	env.register(t, "stubborn@example.com")
+	repo := &elapsedLockRepo{Repository: env.repo}
+	env.svc.repo = repo
+
+	lockOut := func() {
+		t.Helper()
+		for range env.security.Lockout.MaxAttempts {
+			if _, err := env.login("stubborn@example.com", "wrong-password", nil); err == nil {
+				t.Fatal("wrong password accepted")
+			}
+		}
+	}
+
+	for range len(schedule) + 1 {
+		lockOut()
+	}
+	// the last step repeats once the schedule runs out
+	want := []time.Duration{time.Minute, 5 * time.Minute, 30 * time.Minute, 30 * time.Minute}
+	if !slices.Equal(repo.locks, want) {
+		t.Fatalf("lock durations = %v, want %v", repo.locks, want)
+	}
+	user, err := env.repo.GetByEmail(context.Background(), "stubborn@example.com")
+	if err != nil {
+		t.Fatalf("GetByEmail: %v", err)
+	}
+	if user.LockoutCount != len(want) {
+		t.Fatalf("LockoutCount = %d, want %d", user.LockoutCount, len(want))
+	}
+
+	// a successful login starts the schedule over
+	if _, err := env.login("stubborn@example.com", testPassword, nil); err != nil {
+		t.Fatalf("login after the lock: %v", err)
+	}
+	repo.locks = nil
+	lockOut()
+	if !slices.Equal(repo.locks, schedule[:1]) {
+		t.Fatalf("lock after a successful login = %v, want %v", repo.locks, schedule[:1])
+	}
+}
+
+func TestLoginThrottlesUnknownEmails(t *testing.T) {
+	env := newTestService(t, nil)
+	env.register(t, "somebody@example.com")
+
+	// no different from a wrong password on a real account
+	_, wrongPassword := env.login("somebody@example.com", "guess", &models.RequestMetadata{IPAddress: "198.51.100.9"})
+	for range cache.MaxLoginAttempts {
+		_, err := env.login("nobody@example.com", "guess", nil)
+		assertErrorCode(t, err, et.CodeUnauthorized)
+		if err.Error() != wrongPassword.Error() {
+			t.Fatalf("unknown email error %q differs from wrong password %q", err, wrongPassword)
+		}
+	}
+
+	_, err := env.login("nobody@example.com", "guess", nil)
+	appErr := assertErrorCode(t, err, et.CodeTooManyRequests)
+	if appErr.RetryAfter <= 0 || appErr.RetryAfter > cache.LockoutDuration {
+		t.Fatalf("RetryAfter = %s, want within %s", appErr.RetryAfter, cache.LockoutDuration)
+	}
+}
+
+func TestLoginThrottlesAnIPTryingManyUnknownEmails(t *testing.T) {
+	env := newTestService(t, func(s *config.SecurityConfig) { s.Lockout.MaxIPAttempts = 3 })
+	attacker := &models.RequestMetadata{IPAddress: "192.0.2.66"}
+
+	for i := range 3 {
+		_, err := env.login(fmt.Sprintf("user%d@example.com", i), "guess", attacker)
+		assertErrorCode(t, err, et.CodeUnauthorized)
+	}
+	_, err := env.login("user9@example.com", "guess", attacker)
+	assertErrorCode(t, err, et.CodeTooManyRequests)
+
+	// other clients are not affected
+	_, err = env.login("user9@example.com", "guess", &models.RequestMetadata{IPAddress: "198.51.100.4"})
+	assertErrorCode(t, err, et.CodeUnauthorized)
+}
+
+func TestGuestTokenValidatesAsAnonymous(t *testing.T) {
+	env := newTestService(t, nil)
+	ctx := context.Background()
+
+	guest, err := env.svc.IssueGuestToken(ctx)
+	if err != nil {
+		t.Fatalf("IssueGuestToken: %v", err)
+	}
+	if time.Until(time.Unix(guest.ExpiresAt, 0)) > time.Hour {
+		t.Fatalf("guest token expires at %v, past the 1h guest ttl", time.Unix(guest.ExpiresAt, 0))
+	}
+
+	resp, err := env.svc.ValidateToken(ctx, &models.ValidateTokenRequest{AccessToken: guest.AccessToken})
+	if err != nil {
+		t.Fatalf("ValidateToken: %v", err)
+	}
+	if !resp.Valid || resp.UserID != guest.UserID || resp.UserType != models.UserTypeAnonymous {
+		t.Fatalf("validated = %+v, want the anonymous guest %s", resp, guest.UserID)
+	}
+
+	// no user document backs the token
+	oid, _ := primitive.ObjectIDFromHex(guest.UserID)
+	if users, err := env.repo.GetUsersByIDs(ctx, []primitive.ObjectID{oid}); err != nil || len(users) != 0 {
+		t.Fatalf("guest lookup = %v, %v, want no stored user", users, err)
+	}
+}
+
+func TestUnlockAccountLetsALockedUserLogInRightAway(t *testing.T) {
+	env := newTestService(t, nil)
+	userID := mustUserID(t, env.register(t, "locked@example.com")).Hex()
+	admin := mustUserID(t, env.register(t, "admin@example.com")).Hex()
+
+	for range env.security.Lockout.MaxAttempts {
+		if _, err := env.login("locked@example.com", "wrong-password", nil); err == nil {
+			t.Fatal("wrong password accepted")
+		}
+	}
+	if _, err := env.login("locked@example.com", testPassword, nil); err == nil {
+		t.Fatal("locked account logged in with the right password")
+	}
+
+	if err := env.svc.UnlockAccount(context.Background(), userID, admin, nil); err != nil {
+		t.Fatalf("UnlockAccount: %v", err)
+	}
+	if _, err := env.login("locked@example.com", testPassword, nil); err != nil {
+		t.Fatalf("login after unlock: %v", err)
+	}
+
+	user, err := env.repo.GetByEmail(context.Background(), "locked@example.com")
+	if err != nil {
+		t.Fatalf("GetByEmail: %v", err)
+	}
+	if user.LockedUntil != nil || user.LoginAttempts != 0 {
+		t.Fatalf("locked_until = %v, attempts = %d, want both cleared", user.LockedUntil, user.LoginAttempts)
+	}
+}
+
+func TestUnlockAccountRejectsUnknownUsers(t *testing.T) {
+	env := newTestService(t, nil)
+	for id, code := range map[string]et.ErrorCode{
+		"not-an-id":                   et.CodeValidation,
+		primitive.NewObjectID().Hex(): et.CodeNotFound,
+	} {
+		err := env.svc.UnlockAccount(context.Background(), id, "admin", nil)
+		if appErr, ok := et.AsAppError(err); !ok || appErr.Code != code {
+			t.Fatalf("UnlockAccount(%q) = %v, want %s", id, err, code)
+		}
+	}
+}
+
+func TestListSessionsCursorWalksEverySession(t *testing.T) {
+	env := newTestService(t, nil)
+	userID := mustUserID(t, env.register(t, "walker@example.com")).Hex()
+	for i := range 4 {
+		if _, err := env.login("walker@example.com", testPassword, nil); err != nil {
+			t.Fatalf("login %d: %v", i+1, err)
+		}
+	}
+
+	seen := make(map[primitive.ObjectID]bool)
+	var last primitive.ObjectID
+	page := db.CursorRequest{Limit: 2}
+	for pages := 1; ; pages++ {
+		resp, err := env.svc.ListSessions(context.Background(), userID, page)
+		if err != nil {
+			t.Fatalf("ListSessions page %d: %v", pages, err)
+		}
+		for _, s := range resp.Items {
+			if seen[s.ID] {
+				t.Fatalf("session %s listed twice", s.ID.Hex())
+			}
+			if s.ID.Hex() <= last.Hex() {
+				t.Fatalf("session %s listed after %s, want oldest first", s.ID.Hex(), last.Hex())
+			}
+			seen[s.ID], last = true, s.ID
+		}
+		if resp.NextCursor == "" {
+			if pages != 3 {
+				t.Fatalf("walk took %d pages, want 3", pages)
+			}
+			break
+		}
+		page.Cursor = resp.NextCursor
+	}
+	if len(seen) != 5 {
+		t.Fatalf("walked %d sessions, want all 5", len(seen))
+	}
+
+	if _, err := env.svc.ListSessions(context.Background(), userID, db.CursorRequest{Cursor: "bogus"}); err == nil {
+		t.Fatal("ListSessions accepted a forged cursor")
+	}
+}
+
+func TestValidateTokenReportsRemainingLifetime(t *testing.T) {
+	env := newTestService(t, nil)
+	env.svc.jwtUtils.RefreshHintThreshold = 2 * time.Minute
+	userID := mustUserID(t, env.register(t, "ttl@example.com")).Hex()
+
+	tests := []struct {
+		name          string
+		ttl           time.Duration
+		shouldRefresh bool
+	}{
+		{"far from expiry", 15 * time.Minute, false},
+		{"near expiry", 90 * time.Second, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			signer, err := utils.NewJWTUtils(&config.JWTConfig{
+				SecretKey:      "test-secret-key-that-is-long-enough",
+				Issuer:         "remaster-auth",
+				Audience:       "remaster",
+				AccessTokenTTL: tt.ttl,
+			})
+			if err != nil {
+				t.Fatalf("jwt utils: %v", err)
+			}
+			token, err := signer.GenerateAccessToken(userID, "ttl@example.com", "client", "")
+			if err != nil {
+				t.Fatalf("sign token: %v", err)
+			}
+
+			resp, err := env.svc.ValidateToken(context.Background(), &models.ValidateTokenRequest{AccessToken: token})
+			if err != nil {
+				t.Fatalf("ValidateToken: %v", err)
+			}
+			if remaining := time.Duration(resp.ExpiresIn) * time.Second; remaining > tt.ttl || remaining < tt.ttl-5*time.Second {
+				t.Fatalf("ExpiresIn = %ds, want about %s", resp.ExpiresIn, tt.ttl)
+			}
+			if resp.ShouldRefresh != tt.shouldRefresh {
+				t.Fatalf("ShouldRefresh = %v, want %v", resp.ShouldRefresh, tt.shouldRefresh)
+			}
+		})
+	}
+}
+
+func TestCreateUserEnforcesSelfRegistrationAllowlist(t *testing.T) {
+	tests := []struct {
+		name    string
+		allowed []string
+		// registration outcome per user type, true = allowed
+		want map[models.UserType]bool
+	}{
+		{"clients only", []string{"client"}, map[models.UserType]bool{models.UserTypeClient: true, models.UserTypeMaster: false}},
+		{"clients and masters", []string{"client", "master"}, map[models.UserType]bool{models.UserTypeClient: true, models.UserTypeMaster: true}},
+		{"closed", nil, map[models.UserType]bool{models.UserTypeClient: false, models.UserTypeMaster: false}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			env := newTestService(t, func(s *config.SecurityConfig) { s.AllowedSelfRegistrationTypes = tt.allowed })
+
+			for userType, allowed := range tt.want {
+				email := string(userType) + "@example.com"
+				_, err := env.svc.CreateUser(context.Background(), &models.RegisterRequest{
+					Email:     email,
+					Password:  testPassword,
+					FirstName: "Self",
+					LastName:  "Registered",
+					Phone:     "+14155550123",
+					UserType:  userType,
+				}, &models.RequestMetadata{})
+				if allowed {
+					if err != nil {
+						t.Fatalf("registering as %s: %v", userType, err)
+					}
+					continue
+				}
+				assertErrorCode(t, err, et.CodeForbidden)
+				if _, err := env.repo.GetByEmail(context.Background(), email); err == nil {
+					t.Fatalf("rejected %s registration still stored the user", userType)
+				}
+			}
+		})
+	}
+}
+
+func TestSearchUsersCombinesFilters(t *testing.T) {
+	env := newTestService(t, nil)
+	ctx := context.Background()
+	env.register(t, "alice@acme.example")
+	env.register(t, "bob@acme.example")
+	env.register(t, "carol@other.example")
+	if _, err := env.svc.AdminCreateUser(ctx, &models.AdminCreateUserRequest{
+		Email: "dave@acme.example", FirstName: "Dave", LastName: "Master", Phone: "+14155550123", UserType: models.UserTypeMaster,
+	}, primitive.NewObjectID().Hex(), &models.RequestMetadata{}); err != nil {
+		t.Fatalf("AdminCreateUser: %v", err)
+	}
+
+	search := func(c models.UserSearchCriteria) []string {
+		t.Helper()
+		page, err := env.svc.SearchUsers(ctx, c, db.PageRequest{Page: 1, PageSize: 10})
+		if err != nil {
+			t.Fatalf("SearchUsers(%+v): %v", c, err)
+		}
+		emails := make([]string, 0, len(page.Items))
+		for _, u := range page.Items {
+			emails = append(emails, u.Email)
+		}
+		slices.Sort(emails)
+		return emails
+	}
+
+	if got := search(models.UserSearchCriteria{Email: "ACME"}); !slices.Equal(got, []string{"alice@acme.example", "bob@acme.example", "dave@acme.example"}) {
+		t.Fatalf("email search = %v", got)
+	}
+	if got := search(models.UserSearchCriteria{Email: "acme", UserType: models.UserTypeMaster}); !slices.Equal(got, []string{"dave@acme.example"}) {
+		t.Fatalf("email and type search = %v", got)
+	}
+	future := time.Now().Add(time.Hour)
+	if got := search(models.UserSearchCriteria{CreatedFrom: &future}); len(got) != 0 {
+		t.Fatalf("search from the future = %v, want none", got)
+	}
+
+	past := time.Now().Add(-time.Hour)
+	for name, c := range map[string]models.UserSearchCriteria{
+		"unknown type":   {UserType: "superuser"},
+		"guests":         {UserType: models.UserTypeAnonymous},
+		"inverted range": {CreatedFrom: &future, CreatedTo: &past},
+	} {
+		_, err := env.svc.SearchUsers(ctx, c, db.PageRequest{})
+		if appErr, ok := et.AsAppError(err); !ok || appErr.Code != et.CodeValidation {
+			t.Fatalf("%s: SearchUsers = %v, want a validation error", name, err)
+		}
+	}
+}
+
+func TestLoginFlagsNewDevices(t *testing.T) {
+	phone := &models.RequestMetadata{DeviceID: "phone", IPAddress: "203.0.113.7"}
+	laptop := &models.RequestMetadata{DeviceID: "laptop", IPAddress: "198.51.100.4"}
+
+	// loginFrom logs the user in and reports the flag and whether the event went out
+	loginFrom := func(t *testing.T, env *testEnv, email, password string, metadata *models.RequestMetadata) (bool, bool) {
+		t.Helper()
+		rec := &events.RecordingPublisher{}
+		env.svc.events = rec
+		resp, err := env.login(email, password, metadata)
+		if err != nil {
+			t.Fatalf("AuthenticateUser: %v", err)
+		}
+		return resp.NewDevice, slices.Contains(rec.Types(), events.UserNewDeviceLogin)
+	}
+
+	t.Run("first login", func(t *testing.T) {
+		env := newTestService(t, nil)
+		// admin created users have no sessions and have never logged in
+		created, err := env.svc.AdminCreateUser(context.Background(), &models.AdminCreateUserRequest{
+			Email:     "fresh@example.com",
+			FirstName: "Fresh",
+			LastName:  "User",
+			Phone:     "+14155550123",
+			UserType:  models.UserTypeClient,
+		}, primitive.NewObjectID().Hex(), &models.RequestMetadata{IPAddress: "192.0.2.1"})
+		if err != nil {
+			t.Fatalf("AdminCreateUser: %v", err)
+		}
+
+		if flagged, published := loginFrom(t, env, "fresh@example.com", created.TemporaryPassword, laptop); flagged || published {
+			t.Fatalf("first login flagged = %v, event = %v, want neither", flagged, published)
+		}
+	})
+
+	t.Run("known device", func(t *testing.T) {
+		env := newTestService(t, nil)
+		env.register(t, "known@example.com")
+		loginFrom(t, env, "known@example.com", testPassword, phone)
+
+		// same device from another network
+		moved := &models.RequestMetadata{DeviceID: "phone", IPAddress: "192.0.2.50"}
+		if flagged, published := loginFrom(t, env, "known@example.com", testPassword, moved); flagged || published {
+			t.Fatalf("known device flagged = %v, event = %v, want neither", flagged, published)
+		}
+	})
+
+	t.Run("new device", func(t *testing.T) {
+		env := newTestService(t, nil)
+		env.register(t, "moved@example.com")
+		loginFrom(t, env, "moved@example.com", testPassword, phone)
+
+		if flagged, published := loginFrom(t, env, "moved@example.com", testPassword, laptop); !flagged || !published {
+			t.Fatalf("new device flagged = %v, event = %v, want both", flagged, published)
+		}
+		// the laptop is known from now on
+		if flagged, _ := loginFrom(t, env, "moved@example.com", testPassword, laptop); flagged {
+			t.Fatal("second login from the laptop flagged again")
+		}
+	})
+}
+
+func TestOAuthSignUpDomainAllowlist(t *testing.T) {
+	// signUp logs a Google user in with email, creating the account if it doesn't exist
+	signUp := func(env *testEnv, email string) (*models.AuthResponse, error) {
+		env.svc.oauthFactory.Register(oauth.Google, fakeOAuthProvider{claims: &oauth.Claims{
+			Subject: "google-" + email, Email: email, FirstName: "OAuth", LastName: "User",
+		}})
+		return env.svc.OAuthLogin(context.Background(),
+			&models.OAuthLoginRequest{Provider: string(oauth.Google), IDToken: "id-token"},
+			&models.RequestMetadata{IPAddress: "203.0.113.7"})
+	}
+
+	tests := []struct {
+		name    string
+		allowed []string
+		email   string
+		wantErr bool
+	}{
+		{"allowed domain", []string{"company.com"}, "dev@company.com", false},
+		{"allowed with @ and another case", []string{"@Company.com"}, "dev@COMPANY.COM", false},
+		{"disallowed domain", []string{"company.com"}, "dev@gmail.com", true},
+		{"subdomain", []string{"company.com"}, "dev@eu.company.com", true},
+		{"empty allowlist", nil, "dev@gmail.com", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			env := newTestService(t, func(s *config.SecurityConfig) { s.AllowedOAuthDomains = tt.allowed })
+
+			_, err := signUp(env, tt.email)
+			if tt.wantErr {
+				assertErrorCode(t, err, et.CodeForbidden)
+				if _, err := env.repo.GetByEmail(context.Background(), tt.email); err == nil {
+					t.Fatal("user created from a disallowed domain")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("OAuthLogin: %v", err)
+			}
+		})
+	}
+
+	t.Run("existing users are not affected", func(t *testing.T) {
+		env := newTestService(t, func(s *config.SecurityConfig) { s.AllowedOAuthDomains = []string{"company.com"} })
+		env.register(t, "dev@gmail.com")
+
+		if _, err := signUp(env, "dev@gmail.com"); err != nil {
+			t.Fatalf("OAuthLogin for an existing user: %v", err)
+		}
+	})
+}
@@ -0,0 +1,146 @@
+package utils
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+
+	config "remaster/shared"
+
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/bcrypt"
+)
+
+const (
+	HashBcrypt   = "bcrypt"
+	HashArgon2id = "argon2id"
+
+	argon2SaltLen = 16
+	argon2KeyLen  = 32
+)
+
+// ErrPasswordMismatch is returned by Compare when the password is wrong
+var ErrPasswordMismatch = errors.New("password does not match hash")
+
+// PasswordHasher hashes with one algorithm but can verify any supported one
+type PasswordHasher interface {
+	Hash(password string) (string, error)
+	// Compare returns ErrPasswordMismatch for a wrong password, other errors mean a malformed hash
+	Compare(hash, password string) error
+	// NeedsRehash reports hashes made with another algorithm or weaker parameters
+	NeedsRehash(hash string) bool
+}
+
+type passwordHasher struct {
+	algorithm  string
+	bcryptCost int
+	argon2     config.Argon2Config
+}
+
+// NewPasswordHasher picks the algorithm from security.password_hash_algorithm. Stored hashes
+// are matched by prefix, so users keep logging in while they migrate on their next login
+func NewPasswordHasher(cfg *config.SecurityConfig) (PasswordHasher, error) {
+	switch cfg.PasswordHashAlgorithm {
+	case HashBcrypt, HashArgon2id:
+	default:
+		return nil, fmt.Errorf("unsupported password hash algorithm: %q", cfg.PasswordHashAlgorithm)
+	}
+	return &passwordHasher{
+		algorithm:  cfg.PasswordHashAlgorithm,
+		bcryptCost: cfg.BcryptCost,
+		argon2:     cfg.Argon2,
+	}, nil
+}
+
+func (h *passwordHasher) Hash(password string) (string, error) {
+	if h.algorithm == HashArgon2id {
+		return h.hashArgon2id(password)
+	}
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), h.bcryptCost)
+	if err != nil {
+		return "", err
+	}
+	return string(hash), nil
+}
+
+func (h *passwordHasher) Compare(hash, password string) error {
+	if strings.HasPrefix(hash, "$argon2id$") {
+		return compareArgon2id(hash, password)
+	}
+	err := bcrypt.CompareHashAndPassword([]byte(hash), []byte(password))
+	if errors.Is(err, bcrypt.ErrMismatchedHashAndPassword) {
+		return ErrPasswordMismatch
+	}
+	return err
+}
+
+func (h *passwordHasher) NeedsRehash(hash string) bool {
+	if strings.HasPrefix(hash, "$argon2id$") {
+		if h.algorithm != HashArgon2id {
+			return true
+		}
+		p, _, _, err := decodeArgon2id(hash)
+		return err != nil || p.Time < h.argon2.Time || p.MemoryKiB < h.argon2.MemoryKiB || p.Threads < h.argon2.Threads
+	}
+	if h.algorithm != HashBcrypt {
+		return true
+	}
+	cost, err := bcrypt.Cost([]byte(hash))
+	return err != nil || cost < h.bcryptCost
+}
+
+// $argon2id$v=19$m=<kib>,t=<time>,p=<threads>$<salt>$<key>, the PHC format other libraries read
+func (h *passwordHasher) hashArgon2id(password string) (string, error) {
+	salt := make([]byte, argon2SaltLen)
+	if _, err := rand.Read(salt); err != nil {
+		return "", err
+	}
+	p := h.argon2
+	key := argon2.IDKey([]byte(password), salt, p.Time, p.MemoryKiB, p.Threads, argon2KeyLen)
+	return fmt.Sprintf("$argon2id$v=%d$m=%d,t=%d,p=%d$%s$%s",
+		argon2.Version, p.MemoryKiB, p.Time, p.Threads,
+		base64.RawStdEncoding.EncodeToString(salt),
+		base64.RawStdEncoding.EncodeToString(key),
+	), nil
+}
+
+func compareArgon2id(hash, password string) error {
+	p, salt, key, err := decodeArgon2id(hash)
+	if err != nil {
+		return err
+	}
+	got := argon2.IDKey([]byte(password), salt, p.Time, p.MemoryKiB, p.Threads, uint32(len(key)))
+	if subtle.ConstantTimeCompare(got, key) != 1 {
+		return ErrPasswordMismatch
+	}
+	return nil
+}
+
+func decodeArgon2id(hash string) (config.Argon2Config, []byte, []byte, error) {
+	var p config.Argon2Config
+	parts := strings.Split(hash, "$")
+	if len(parts) != 6 || parts[1] != HashArgon2id {
+		return p, nil, nil, errors.New("malformed argon2id hash")
+	}
+
+	var version int
+	if _, err := fmt.Sscanf(parts[2], "v=%d", &version); err != nil || version != argon2.Version {
+		return p, nil, nil, fmt.Errorf("unsupported argon2 version: %s", parts[2])
+	}
+	if _, err := fmt.Sscanf(parts[3], "m=%d,t=%d,p=%d", &p.MemoryKiB, &p.Time, &p.Threads); err != nil {
+		return p, nil, nil, fmt.Errorf("malformed argon2id parameters: %w", err)
+	}
+
+	salt, err := base64.RawStdEncoding.DecodeString(parts[4])
+	if err != nil {
+		return p, nil, nil, fmt.Errorf("malformed argon2id salt: %w", err)
+	}
+	key, err := base64.RawStdEncoding.DecodeString(parts[5])
+	if err != nil || len(key) == 0 {
+		return p, nil, nil, errors.New("malformed argon2id key")
+	}
+	return p, salt, key, nil
+}
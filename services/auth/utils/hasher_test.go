@@ -0,0 +1,144 @@
+package utils
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	config "remaster/shared"
+)
+
+const hasherPassword = "Str0ng!Passw0rd"
+
+// cheap parameters, the tests check behaviour not strength
+var testArgon2 = config.Argon2Config{Time: 1, MemoryKiB: 1024, Threads: 1}
+
+func newTestHasher(t *testing.T, algorithm string) PasswordHasher {
+	t.Helper()
+	h, err := NewPasswordHasher(&config.SecurityConfig{
+		PasswordHashAlgorithm: algorithm,
+		BcryptCost:            4,
+		Argon2:                testArgon2,
+	})
+	if err != nil {
+		t.Fatalf("NewPasswordHasher(%s): %v", algorithm, err)
+	}
+	return h
+}
+
+func TestPasswordHasherRoundTrip(t *testing.T) {
+	for _, tt := range []struct {
+		algorithm string
+		prefix    string
+	}{
+		{HashBcrypt, "$2a$"},
+		{HashArgon2id, "$argon2id$v=19$m=1024,t=1,p=1$"},
+	} {
+		t.Run(tt.algorithm, func(t *testing.T) {
+			h := newTestHasher(t, tt.algorithm)
+
+			hash, err := h.Hash(hasherPassword)
+			if err != nil {
+				t.Fatalf("Hash: %v", err)
+			}
+			if !strings.HasPrefix(hash, tt.prefix) {
+				t.Fatalf("hash = %q, want prefix %q", hash, tt.prefix)
+			}
+			if err := h.Compare(hash, hasherPassword); err != nil {
+				t.Fatalf("Compare with the right password: %v", err)
+			}
+			if err := h.Compare(hash, "wrong-password"); !errors.Is(err, ErrPasswordMismatch) {
+				t.Fatalf("Compare with a wrong password = %v, want ErrPasswordMismatch", err)
+			}
+			if again, _ := h.Hash(hasherPassword); again == hash {
+				t.Fatal("two hashes of the same password are equal, salt is not random")
+			}
+		})
+	}
+}
+
+func TestPasswordHasherVerifiesAcrossAlgorithms(t *testing.T) {
+	bcryptHasher := newTestHasher(t, HashBcrypt)
+	argonHasher := newTestHasher(t, HashArgon2id)
+
+	bcryptHash, _ := bcryptHasher.Hash(hasherPassword)
+	argonHash, _ := argonHasher.Hash(hasherPassword)
+
+	// mid-migration either hasher must accept both kinds of stored hash
+	for name, tt := range map[string]struct {
+		h    PasswordHasher
+		hash string
+	}{
+		"argon2id hasher, bcrypt hash": {argonHasher, bcryptHash},
+		"bcrypt hasher, argon2id hash": {bcryptHasher, argonHash},
+	} {
+		t.Run(name, func(t *testing.T) {
+			if err := tt.h.Compare(tt.hash, hasherPassword); err != nil {
+				t.Fatalf("Compare: %v", err)
+			}
+			if err := tt.h.Compare(tt.hash, "wrong-password"); !errors.Is(err, ErrPasswordMismatch) {
+				t.Fatalf("Compare with a wrong password = %v, want ErrPasswordMismatch", err)
+			}
+		})
+	}
+}
+
+func TestPasswordHasherNeedsRehash(t *testing.T) {
+	bcryptHasher := newTestHasher(t, HashBcrypt)
+	argonHasher := newTestHasher(t, HashArgon2id)
+	bcryptHash, _ := bcryptHasher.Hash(hasherPassword)
+	argonHash, _ := argonHasher.Hash(hasherPassword)
+
+	stronger, err := NewPasswordHasher(&config.SecurityConfig{
+		PasswordHashAlgorithm: HashArgon2id,
+		Argon2:                config.Argon2Config{Time: 2, MemoryKiB: 1024, Threads: 1},
+	})
+	if err != nil {
+		t.Fatalf("NewPasswordHasher: %v", err)
+	}
+	costlier, err := NewPasswordHasher(&config.SecurityConfig{PasswordHashAlgorithm: HashBcrypt, BcryptCost: 5})
+	if err != nil {
+		t.Fatalf("NewPasswordHasher: %v", err)
+	}
+
+	tests := []struct {
+		name string
+		h    PasswordHasher
+		hash string
+		want bool
+	}{
+		{"bcrypt hash, bcrypt hasher", bcryptHasher, bcryptHash, false},
+		{"argon2id hash, argon2id hasher", argonHasher, argonHash, false},
+		{"bcrypt hash, argon2id hasher", argonHasher, bcryptHash, true},
+		{"argon2id hash, bcrypt hasher", bcryptHasher, argonHash, true},
+		{"weaker argon2id parameters", stronger, argonHash, true},
+		{"lower bcrypt cost", costlier, bcryptHash, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.h.NeedsRehash(tt.hash); got != tt.want {
+				t.Fatalf("NeedsRehash = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPasswordHasherRejectsMalformedHashes(t *testing.T) {
+	h := newTestHasher(t, HashArgon2id)
+	for _, hash := range []string{
+		"$argon2id$v=19$m=1024,t=1,p=1$c2FsdA",
+		"$argon2id$v=18$m=1024,t=1,p=1$c2FsdA$a2V5",
+		"not-a-hash",
+	} {
+		err := h.Compare(hash, hasherPassword)
+		if err == nil || errors.Is(err, ErrPasswordMismatch) {
+			t.Errorf("Compare(%q) = %v, want a malformed hash error", hash, err)
+		}
+	}
+}
+
+func TestNewPasswordHasherRejectsUnknownAlgorithm(t *testing.T) {
+	if _, err := NewPasswordHasher(&config.SecurityConfig{PasswordHashAlgorithm: "md5"}); err == nil {
+		t.Fatal("NewPasswordHasher accepted md5")
+	}
+}
@@ -2,64 +2,162 @@ package utils
 
 import (
 	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
 	"encoding/hex"
 	"fmt"
 	"time"
 
 	config "remaster/shared"
+	"remaster/shared/tokenauth"
 
 	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
 )
 
+// UserID duplicates the standard sub claim, kept for one release so older
+// consumers that read user_id keep working
 type CustomClaims struct {
 	UserID    string          `json:"user_id"`
 	Email     string          `json:"email"`
 	UserType  string          `json:"user_type"`
+	OrgID     string          `json:"org_id,omitempty"` // only for users of an organization
+	Scope     string          `json:"scope,omitempty"`  // empty for full access, see tokenauth.ScopePasswordChange
 	ExpiresAt jwt.NumericDate `json:"expires_at"`
 	jwt.RegisteredClaims
 }
 
+// signingKey is an HMAC secret with the kid written into token headers
+type signingKey struct {
+	id     string
+	secret []byte
+}
+
+// kid is derived from the secret, so rotating the secret rotates the kid
+func newSigningKey(secret string) *signingKey {
+	sum := sha256.Sum256([]byte(secret))
+	return &signingKey{
+		id:     hex.EncodeToString(sum[:8]),
+		secret: []byte(secret),
+	}
+}
+
 type JWTUtils struct {
-	secretKey       string
+	current         *signingKey
+	previous        *signingKey // nil unless a rotation is in progress
+	previousUntil   time.Time
+	issuer          string
+	audience        string
+	leeway          time.Duration
 	AccessTokenTTL  time.Duration
 	RefreshTokenTTL time.Duration
+	GuestTokenTTL   time.Duration
+	// access tokens with less time left should be refreshed
+	RefreshHintThreshold time.Duration
+
+	refreshTokenBytes    int
+	refreshTokenEncoding string
 }
 
-func NewJWTUtils(jwtConfig *config.JWTConfig) *JWTUtils {
-	return &JWTUtils{
-		secretKey:       jwtConfig.SecretKey,
+// MinSecretKeyLength is the HS256 minimum, RFC 7518 wants a key at least as long as the hash
+const MinSecretKeyLength = 32
+
+// NewJWTUtils rejects secrets too short for HS256, config validation only enforces
+// this in production so it is checked here for every environment
+func NewJWTUtils(jwtConfig *config.JWTConfig) (*JWTUtils, error) {
+	if len(jwtConfig.SecretKey) < MinSecretKeyLength {
+		return nil, fmt.Errorf("jwt secret key must be at least %d bytes, got %d", MinSecretKeyLength, len(jwtConfig.SecretKey))
+	}
+	if jwtConfig.PreviousSecretKey != "" && len(jwtConfig.PreviousSecretKey) < MinSecretKeyLength {
+		return nil, fmt.Errorf("jwt previous secret key must be at least %d bytes, got %d", MinSecretKeyLength, len(jwtConfig.PreviousSecretKey))
+	}
+
+	j := &JWTUtils{
+		current:         newSigningKey(jwtConfig.SecretKey),
+		previousUntil:   jwtConfig.PreviousKeyValidUntil,
+		issuer:          jwtConfig.Issuer,
+		audience:        jwtConfig.Audience,
+		leeway:          time.Duration(jwtConfig.LeewaySeconds) * time.Second,
 		AccessTokenTTL:  jwtConfig.AccessTokenTTL,
 		RefreshTokenTTL: jwtConfig.RefreshTokenTTL,
+		GuestTokenTTL:   jwtConfig.GuestTokenTTL,
+
+		RefreshHintThreshold: jwtConfig.RefreshHintThreshold,
+
+		refreshTokenBytes:    jwtConfig.RefreshTokenBytes,
+		refreshTokenEncoding: jwtConfig.RefreshTokenEncoding,
+	}
+	if jwtConfig.PreviousSecretKey != "" {
+		j.previous = newSigningKey(jwtConfig.PreviousSecretKey)
 	}
+	return j, nil
 }
 
-func (j *JWTUtils) GenerateAccessToken(userID, email, userType string) (string, error) {
+// GenerateAccessToken adds the org_id claim only when orgID is set
+func (j *JWTUtils) GenerateAccessToken(userID, email, userType, orgID string) (string, error) {
+	return j.signAccessToken(userID, email, userType, orgID, "", j.AccessTokenTTL)
+}
+
+// GeneratePasswordChangeToken mints an access token scoped to changing an expired
+// password, RequireAuth refuses it on every other route
+func (j *JWTUtils) GeneratePasswordChangeToken(userID, email, userType, orgID string) (string, error) {
+	return j.signAccessToken(userID, email, userType, orgID, tokenauth.ScopePasswordChange, j.AccessTokenTTL)
+}
+
+// GenerateGuestToken mints a short lived anonymous token for a user that does not exist in the database
+func (j *JWTUtils) GenerateGuestToken(guestID, userType string) (string, error) {
+	return j.signAccessToken(guestID, "", userType, "", "", j.GuestTokenTTL)
+}
+
+func (j *JWTUtils) signAccessToken(userID, email, userType, orgID, scope string, ttl time.Duration) (string, error) {
 	claims := CustomClaims{
 		UserID:   userID,
 		Email:    email,
 		UserType: userType,
+		OrgID:    orgID,
+		Scope:    scope,
 		RegisteredClaims: jwt.RegisteredClaims{
-			ExpiresAt: jwt.NewNumericDate(time.Now().Add(j.AccessTokenTTL)),
+			Subject:   userID,
+			ID:        uuid.NewString(),
+			Issuer:    j.issuer,
+			Audience:  jwt.ClaimStrings{j.audience},
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(ttl)),
 			IssuedAt:  jwt.NewNumericDate(time.Now()),
 		},
 	}
 
 	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	return token.SignedString([]byte(j.secretKey))
+	token.Header["kid"] = j.current.id
+	return token.SignedString(j.current.secret)
 }
 
+// GenerateRefreshToken returns refresh_token_bytes of randomness, hex encoded
+// unless base64url is configured. Lookups compare the whole string, so tokens
+// issued before an encoding change keep working
 func (j *JWTUtils) GenerateRefreshToken() (string, error) {
-	bytes := make([]byte, 32)
+	n := j.refreshTokenBytes
+	if n == 0 {
+		n = 32
+	}
+	bytes := make([]byte, n)
 	if _, err := rand.Read(bytes); err != nil {
 		return "", err
 	}
+	if j.refreshTokenEncoding == "base64url" {
+		return base64.RawURLEncoding.EncodeToString(bytes), nil
+	}
 	return hex.EncodeToString(bytes), nil
 }
 
+// HashRefreshToken is the at-rest form of a refresh token. Tokens are random,
+// so a plain sha-256 is enough and keeps lookups a single equality match
+func HashRefreshToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
 func (j *JWTUtils) ParseRefreshToken(token string) (*CustomClaims, error) {
-	tokenClaims, err := jwt.ParseWithClaims(token, &CustomClaims{}, func(t *jwt.Token) (interface{}, error) {
-		return []byte(j.secretKey), nil
-	})
+	tokenClaims, err := jwt.ParseWithClaims(token, &CustomClaims{}, j.keyFunc)
 	if err != nil {
 		return nil, err
 	}
@@ -69,10 +167,16 @@ func (j *JWTUtils) ParseRefreshToken(token string) (*CustomClaims, error) {
 	return nil, err
 }
 
+// ValidateAccessToken checks the signature, exp and nbf with the configured leeway for
+// clock skew between the issuing and validating hosts, and rejects tokens minted for
+// another issuer or audience
 func (j *JWTUtils) ValidateAccessToken(tokenStr string) (*CustomClaims, error) {
-	token, err := jwt.ParseWithClaims(tokenStr, &CustomClaims{}, func(token *jwt.Token) (interface{}, error) {
-		return []byte(j.secretKey), nil
-	})
+	token, err := jwt.ParseWithClaims(tokenStr, &CustomClaims{}, j.keyFunc,
+		jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg()}),
+		jwt.WithLeeway(j.leeway),
+		jwt.WithIssuer(j.issuer),
+		jwt.WithAudience(j.audience),
+	)
 	if err != nil {
 		return nil, err
 	}
@@ -81,6 +185,28 @@ func (j *JWTUtils) ValidateAccessToken(tokenStr string) (*CustomClaims, error) {
 	if !ok || !token.Valid {
 		return nil, fmt.Errorf("invalid token")
 	}
+	// tokens issued before sub was set have none, the rest must agree with user_id
+	if claims.Subject != "" && claims.Subject != claims.UserID {
+		return nil, fmt.Errorf("token subject does not match user_id")
+	}
 
 	return claims, nil
 }
+
+// keyFunc picks the key by kid. Tokens without a kid predate rotation support
+// and are checked against the current key. Previous-key tokens only pass until
+// the configured end of the rotation, whatever iat they claim
+func (j *JWTUtils) keyFunc(token *jwt.Token) (interface{}, error) {
+	kid, _ := token.Header["kid"].(string)
+	switch {
+	case kid == "" || kid == j.current.id:
+		return j.current.secret, nil
+	case j.previous != nil && kid == j.previous.id:
+		if !time.Now().Before(j.previousUntil) {
+			return nil, fmt.Errorf("token signed with retired key")
+		}
+		return j.previous.secret, nil
+	default:
+		return nil, fmt.Errorf("unknown signing key %q", kid)
+	}
+}
@@ -0,0 +1,345 @@
+package utils
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/hex"
+	"strings"
+	"testing"
+	"time"
+
+	config "remaster/shared"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const (
+	oldSecret = "old-secret-key-0123456789abcdefghij"
+	newSecret = "new-secret-key-0123456789abcdefghij"
+)
+
+func newTestJWT(t *testing.T, secret, previous string, previousUntil time.Time) *JWTUtils {
+	t.Helper()
+	j, err := NewJWTUtils(&config.JWTConfig{
+		SecretKey:             secret,
+		PreviousSecretKey:     previous,
+		PreviousKeyValidUntil: previousUntil,
+		AccessTokenTTL:        15 * time.Minute,
+		Issuer:                "remaster-auth",
+		Audience:              "remaster-users",
+	})
+	if err != nil {
+		t.Fatalf("NewJWTUtils: %v", err)
+	}
+	return j
+}
+
+func TestValidateAccessTokenAcceptsPreviousKeyInsideOverlap(t *testing.T) {
+	before := newTestJWT(t, oldSecret, "", time.Time{})
+	token, err := before.GenerateAccessToken("u1", "u1@example.com", "client", "")
+	if err != nil {
+		t.Fatalf("GenerateAccessToken: %v", err)
+	}
+
+	rotated := newTestJWT(t, newSecret, oldSecret, time.Now().Add(10*time.Minute))
+	claims, err := rotated.ValidateAccessToken(token)
+	if err != nil {
+		t.Fatalf("old-key token rejected inside the overlap: %v", err)
+	}
+	if claims.UserID != "u1" {
+		t.Fatalf("UserID = %q, want u1", claims.UserID)
+	}
+}
+
+func TestValidateAccessTokenRejectsPreviousKeyAfterOverlap(t *testing.T) {
+	// a freshly issued token, as someone holding the leaked old key would mint it
+	before := newTestJWT(t, oldSecret, "", time.Time{})
+	token, err := before.GenerateAccessToken("u1", "u1@example.com", "client", "")
+	if err != nil {
+		t.Fatalf("GenerateAccessToken: %v", err)
+	}
+
+	rotated := newTestJWT(t, newSecret, oldSecret, time.Now().Add(-time.Second))
+	if _, err := rotated.ValidateAccessToken(token); err == nil {
+		t.Fatal("old-key token accepted after the overlap ended")
+	}
+}
+
+func TestValidateAccessTokenAcceptsCurrentKeyDuringRotation(t *testing.T) {
+	rotated := newTestJWT(t, newSecret, oldSecret, time.Now().Add(-time.Second))
+	token, err := rotated.GenerateAccessToken("u1", "u1@example.com", "client", "")
+	if err != nil {
+		t.Fatalf("GenerateAccessToken: %v", err)
+	}
+	if _, err := rotated.ValidateAccessToken(token); err != nil {
+		t.Fatalf("current-key token rejected: %v", err)
+	}
+}
+
+// signClaims signs claims with j's current key, for tokens GenerateAccessToken would not mint
+func signClaims(t *testing.T, j *JWTUtils, claims CustomClaims) string {
+	t.Helper()
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+	token.Header["kid"] = j.current.id
+	signed, err := token.SignedString(j.current.secret)
+	if err != nil {
+		t.Fatalf("SignedString: %v", err)
+	}
+	return signed
+}
+
+func claimsFor(j *JWTUtils, userID string) CustomClaims {
+	return CustomClaims{
+		UserID:   userID,
+		UserType: "client",
+		RegisteredClaims: jwt.RegisteredClaims{
+			Subject:   userID,
+			Issuer:    j.issuer,
+			Audience:  jwt.ClaimStrings{j.audience},
+			IssuedAt:  jwt.NewNumericDate(time.Now()),
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Minute)),
+		},
+	}
+}
+
+func TestValidateAccessTokenLeeway(t *testing.T) {
+	j, err := NewJWTUtils(&config.JWTConfig{
+		SecretKey:      newSecret,
+		AccessTokenTTL: 15 * time.Minute,
+		Issuer:         "remaster-auth",
+		Audience:       "remaster-users",
+		LeewaySeconds:  30,
+	})
+	if err != nil {
+		t.Fatalf("NewJWTUtils: %v", err)
+	}
+
+	tests := []struct {
+		name    string
+		exp     time.Duration
+		nbf     time.Duration
+		wantErr bool
+	}{
+		{name: "expired inside the leeway", exp: -10 * time.Second},
+		{name: "expired outside the leeway", exp: -time.Minute, wantErr: true},
+		{name: "not yet valid inside the leeway", exp: time.Minute, nbf: 10 * time.Second},
+		{name: "not yet valid outside the leeway", exp: 5 * time.Minute, nbf: time.Minute, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			claims := claimsFor(j, "u1")
+			claims.RegisteredClaims.ExpiresAt = jwt.NewNumericDate(time.Now().Add(tt.exp))
+			if tt.nbf != 0 {
+				claims.NotBefore = jwt.NewNumericDate(time.Now().Add(tt.nbf))
+			}
+
+			_, err := j.ValidateAccessToken(signClaims(t, j, claims))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ValidateAccessToken error = %v, want error %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestValidateAccessTokenIssuerAndAudience(t *testing.T) {
+	j := newTestJWT(t, newSecret, "", time.Time{})
+
+	tests := []struct {
+		name     string
+		issuer   string
+		audience []string
+		wantErr  bool
+	}{
+		{name: "matching", issuer: "remaster-auth", audience: []string{"remaster-users"}},
+		{name: "one of several audiences", issuer: "remaster-auth", audience: []string{"billing", "remaster-users"}},
+		{name: "other issuer", issuer: "someone-else", audience: []string{"remaster-users"}, wantErr: true},
+		{name: "missing issuer", audience: []string{"remaster-users"}, wantErr: true},
+		{name: "other audience", issuer: "remaster-auth", audience: []string{"billing"}, wantErr: true},
+		{name: "missing audience", issuer: "remaster-auth", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			claims := claimsFor(j, "u1")
+			claims.Issuer = tt.issuer
+			claims.Audience = tt.audience
+
+			_, err := j.ValidateAccessToken(signClaims(t, j, claims))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ValidateAccessToken error = %v, want error %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestAccessTokenCarriesSubjectAndID(t *testing.T) {
+	j := newTestJWT(t, newSecret, "", time.Time{})
+
+	first, err := j.GenerateAccessToken("u1", "u1@example.com", "client", "")
+	if err != nil {
+		t.Fatalf("GenerateAccessToken: %v", err)
+	}
+	second, err := j.GenerateAccessToken("u1", "u1@example.com", "client", "")
+	if err != nil {
+		t.Fatalf("GenerateAccessToken: %v", err)
+	}
+
+	a, err := j.ValidateAccessToken(first)
+	if err != nil {
+		t.Fatalf("ValidateAccessToken: %v", err)
+	}
+	b, err := j.ValidateAccessToken(second)
+	if err != nil {
+		t.Fatalf("ValidateAccessToken: %v", err)
+	}
+	if a.Subject != "u1" || a.UserID != a.Subject {
+		t.Fatalf("sub = %q, user_id = %q, want both u1", a.Subject, a.UserID)
+	}
+	if a.ID == "" || a.ID == b.ID {
+		t.Fatalf("jti = %q and %q, want a unique id per token", a.ID, b.ID)
+	}
+}
+
+func TestAccessTokenOrgIDClaim(t *testing.T) {
+	j := newTestJWT(t, newSecret, "", time.Time{})
+
+	for _, orgID := range []string{"", "org-42"} {
+		token, err := j.GenerateAccessToken("u1", "u1@example.com", "client", orgID)
+		if err != nil {
+			t.Fatalf("GenerateAccessToken: %v", err)
+		}
+
+		// single-tenant tokens stay exactly as they were, without the claim
+		payload, err := base64.RawURLEncoding.DecodeString(strings.Split(token, ".")[1])
+		if err != nil {
+			t.Fatalf("decode payload: %v", err)
+		}
+		if got := strings.Contains(string(payload), `"org_id"`); got != (orgID != "") {
+			t.Fatalf("org %q: payload %s, org_id claim present = %v", orgID, payload, got)
+		}
+
+		claims, err := j.ValidateAccessToken(token)
+		if err != nil {
+			t.Fatalf("ValidateAccessToken: %v", err)
+		}
+		if claims.OrgID != orgID {
+			t.Fatalf("OrgID = %q, want %q", claims.OrgID, orgID)
+		}
+		validated, err := NewLocalValidator(j).Validate(context.Background(), token)
+		if err != nil {
+			t.Fatalf("local Validate: %v", err)
+		}
+		if validated.OrgID != orgID {
+			t.Fatalf("local validator OrgID = %q, want %q", validated.OrgID, orgID)
+		}
+	}
+}
+
+func TestValidateAccessTokenSubject(t *testing.T) {
+	j := newTestJWT(t, newSecret, "", time.Time{})
+
+	t.Run("mismatched sub is rejected", func(t *testing.T) {
+		claims := claimsFor(j, "u1")
+		claims.Subject = "u2"
+		if _, err := j.ValidateAccessToken(signClaims(t, j, claims)); err == nil {
+			t.Fatal("token whose sub disagrees with user_id was accepted")
+		}
+	})
+
+	t.Run("token without sub predates the claim", func(t *testing.T) {
+		claims := claimsFor(j, "u1")
+		claims.Subject = ""
+		if _, err := j.ValidateAccessToken(signClaims(t, j, claims)); err != nil {
+			t.Fatalf("legacy token without sub rejected: %v", err)
+		}
+	})
+}
+
+func TestGenerateRefreshTokenEncodings(t *testing.T) {
+	tests := []struct {
+		name     string
+		bytes    int
+		encoding string
+		decode   func(string) ([]byte, error)
+		wantLen  int
+	}{
+		{"default", 0, "", hex.DecodeString, 32},
+		{"hex", 48, "hex", hex.DecodeString, 48},
+		{"base64url", 32, "base64url", base64.RawURLEncoding.DecodeString, 32},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			j, err := NewJWTUtils(&config.JWTConfig{
+				SecretKey:            newSecret,
+				RefreshTokenBytes:    tt.bytes,
+				RefreshTokenEncoding: tt.encoding,
+			})
+			if err != nil {
+				t.Fatalf("NewJWTUtils: %v", err)
+			}
+
+			seen := make(map[string]bool)
+			for range 100 {
+				token, err := j.GenerateRefreshToken()
+				if err != nil {
+					t.Fatalf("GenerateRefreshToken: %v", err)
+				}
+				raw, err := tt.decode(token)
+				if err != nil || len(raw) != tt.wantLen {
+					t.Fatalf("token %q decodes to %d bytes (%v), want %d", token, len(raw), err, tt.wantLen)
+				}
+				if seen[token] {
+					t.Fatalf("token %q generated twice", token)
+				}
+				seen[token] = true
+			}
+		})
+	}
+}
+
+func TestHashRefreshTokenIsExactMatch(t *testing.T) {
+	j, err := NewJWTUtils(&config.JWTConfig{SecretKey: newSecret, RefreshTokenEncoding: "base64url"})
+	if err != nil {
+		t.Fatalf("NewJWTUtils: %v", err)
+	}
+	token, err := j.GenerateRefreshToken()
+	if err != nil {
+		t.Fatalf("GenerateRefreshToken: %v", err)
+	}
+
+	if HashRefreshToken(strings.Clone(token)) != HashRefreshToken(token) {
+		t.Fatal("the same token hashed differently")
+	}
+	// base64url is case sensitive, a lookup must not fold case or trim
+	for _, variant := range []string{strings.ToUpper(token), strings.ToLower(token), token + "=", token[1:]} {
+		if variant != token && HashRefreshToken(variant) == HashRefreshToken(token) {
+			t.Fatalf("variant %q matches token %q", variant, token)
+		}
+	}
+}
+
+func TestNewJWTUtilsRejectsWeakSecrets(t *testing.T) {
+	short := strings.Repeat("k", MinSecretKeyLength-1)
+	tests := []struct {
+		name     string
+		secret   string
+		previous string
+		wantErr  bool
+	}{
+		{"empty secret", "", "", true},
+		{"short secret", short, "", true},
+		{"minimum length", strings.Repeat("k", MinSecretKeyLength), "", false},
+		{"short previous secret", newSecret, short, true},
+		{"long previous secret", newSecret, oldSecret, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			j, err := NewJWTUtils(&config.JWTConfig{SecretKey: tt.secret, PreviousSecretKey: tt.previous})
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("NewJWTUtils = %v, want error %v", err, tt.wantErr)
+			}
+			if err != nil && j != nil {
+				t.Fatal("NewJWTUtils returned utils along with an error")
+			}
+		})
+	}
+}
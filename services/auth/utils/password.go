@@ -0,0 +1,147 @@
+package utils
+
+import (
+	"bufio"
+	"crypto/rand"
+	"fmt"
+	"math/big"
+	"os"
+	"strings"
+	"unicode"
+
+	config "remaster/shared"
+	et "remaster/shared/errors"
+)
+
+type PasswordPolicy struct {
+	cfg    config.PasswordPolicyConfig
+	common map[string]struct{}
+}
+
+// NewPasswordPolicy loads the common password list if one is configured
+func NewPasswordPolicy(cfg *config.PasswordPolicyConfig) (*PasswordPolicy, error) {
+	p := &PasswordPolicy{
+		cfg:    *cfg,
+		common: make(map[string]struct{}),
+	}
+
+	if cfg.CommonPasswordsFile == "" {
+		return p, nil
+	}
+
+	f, err := os.Open(cfg.CommonPasswordsFile)
+	if err != nil {
+		return nil, fmt.Errorf("open common passwords file: %w", err)
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		if line := strings.TrimSpace(scanner.Text()); line != "" {
+			p.common[strings.ToLower(line)] = struct{}{}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("read common passwords file: %w", err)
+	}
+
+	return p, nil
+}
+
+// ValidatePassword returns a validation error listing every rule the password breaks
+func (p *PasswordPolicy) ValidatePassword(password string) error {
+	var hasUpper, hasLower, hasDigit, hasSymbol bool
+	for _, r := range password {
+		switch {
+		case unicode.IsUpper(r):
+			hasUpper = true
+		case unicode.IsLower(r):
+			hasLower = true
+		case unicode.IsDigit(r):
+			hasDigit = true
+		case unicode.IsPunct(r) || unicode.IsSymbol(r):
+			hasSymbol = true
+		}
+	}
+
+	failed := make(map[string]string)
+	if len([]rune(password)) < p.cfg.MinLength {
+		failed["min_length"] = fmt.Sprintf("must be at least %d characters", p.cfg.MinLength)
+	}
+	// bytes, not runes - that is what bcrypt truncates on
+	if len(password) > p.cfg.MaxLength {
+		failed["max_length"] = fmt.Sprintf("must be at most %d bytes", p.cfg.MaxLength)
+	}
+	if p.cfg.RequireUpper && !hasUpper {
+		failed["require_upper"] = "must contain an uppercase letter"
+	}
+	if p.cfg.RequireLower && !hasLower {
+		failed["require_lower"] = "must contain a lowercase letter"
+	}
+	if p.cfg.RequireDigit && !hasDigit {
+		failed["require_digit"] = "must contain a digit"
+	}
+	if p.cfg.RequireSymbol && !hasSymbol {
+		failed["require_symbol"] = "must contain a symbol"
+	}
+	if _, ok := p.common[strings.ToLower(password)]; ok {
+		failed["common"] = "is too common"
+	}
+
+	if len(failed) > 0 {
+		return et.NewValidationError("password does not meet policy", failed)
+	}
+	return nil
+}
+
+const (
+	tempUpper  = "ABCDEFGHJKLMNPQRSTUVWXYZ"
+	tempLower  = "abcdefghijkmnopqrstuvwxyz"
+	tempDigits = "23456789"
+	tempSymbol = "!@#$%^&*-_=+"
+)
+
+// GenerateTemporary returns a random password that satisfies the policy, for accounts
+// provisioned by an admin. Look-alike characters are left out so it can be read out
+func (p *PasswordPolicy) GenerateTemporary() (string, error) {
+	length := max(p.cfg.MinLength, 16)
+	if p.cfg.MaxLength > 0 {
+		length = min(length, p.cfg.MaxLength)
+	}
+
+	// one of each class up front so every rule holds, then shuffle
+	classes := []string{tempUpper, tempLower, tempDigits, tempSymbol}
+	all := strings.Join(classes, "")
+	out := make([]byte, 0, length)
+	for _, set := range classes {
+		c, err := randomChar(set)
+		if err != nil {
+			return "", err
+		}
+		out = append(out, c)
+	}
+	for len(out) < length {
+		c, err := randomChar(all)
+		if err != nil {
+			return "", err
+		}
+		out = append(out, c)
+	}
+
+	for i := len(out) - 1; i > 0; i-- {
+		j, err := rand.Int(rand.Reader, big.NewInt(int64(i+1)))
+		if err != nil {
+			return "", err
+		}
+		out[i], out[j.Int64()] = out[j.Int64()], out[i]
+	}
+	return string(out), nil
+}
+
+func randomChar(set string) (byte, error) {
+	n, err := rand.Int(rand.Reader, big.NewInt(int64(len(set))))
+	if err != nil {
+		return 0, err
+	}
+	return set[n.Int64()], nil
+}
@@ -0,0 +1,109 @@
+package utils
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	config "remaster/shared"
+	et "remaster/shared/errors"
+)
+
+func newTestPolicy(t *testing.T, common ...string) *PasswordPolicy {
+	t.Helper()
+	cfg := &config.PasswordPolicyConfig{
+		MinLength:     10,
+		MaxLength:     72,
+		RequireUpper:  true,
+		RequireLower:  true,
+		RequireDigit:  true,
+		RequireSymbol: true,
+	}
+	if len(common) > 0 {
+		cfg.CommonPasswordsFile = filepath.Join(t.TempDir(), "common.txt")
+		if err := os.WriteFile(cfg.CommonPasswordsFile, []byte(strings.Join(common, "\n")+"\n\n"), 0o600); err != nil {
+			t.Fatalf("write common passwords: %v", err)
+		}
+	}
+	p, err := NewPasswordPolicy(cfg)
+	if err != nil {
+		t.Fatalf("NewPasswordPolicy: %v", err)
+	}
+	return p
+}
+
+func TestValidatePasswordRules(t *testing.T) {
+	p := newTestPolicy(t, "Summer2024!!")
+
+	tests := []struct {
+		name     string
+		password string
+		failed   []string
+	}{
+		{"valid", "Str0ng!Passw0rd", nil},
+		{"too short", "Sh0rt!", []string{"min_length"}},
+		{"too long", "Aa1!" + strings.Repeat("x", 69), []string{"max_length"}},
+		{"no uppercase", "str0ng!passw0rd", []string{"require_upper"}},
+		{"no lowercase", "STR0NG!PASSW0RD", []string{"require_lower"}},
+		{"no digit", "Strong!Password", []string{"require_digit"}},
+		{"no symbol", "Str0ngPassw0rd", []string{"require_symbol"}},
+		{"common, any case", "summer2024!!", []string{"common", "require_upper"}},
+		{"several rules", "password", []string{"min_length", "require_upper", "require_digit", "require_symbol"}},
+		// length counts runes, the byte limit is what bcrypt truncates on
+		{"multibyte within limits", "Пароль1!Пароль", nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := p.ValidatePassword(tt.password)
+			if tt.failed == nil {
+				if err != nil {
+					t.Fatalf("ValidatePassword = %v, want nil", err)
+				}
+				return
+			}
+
+			appErr, ok := et.AsAppError(err)
+			if !ok || appErr.Code != et.CodeValidation {
+				t.Fatalf("error = %v, want a validation AppError", err)
+			}
+			if len(appErr.Details) != len(tt.failed) {
+				t.Fatalf("failed rules = %v, want %v", appErr.Details, tt.failed)
+			}
+			for _, rule := range tt.failed {
+				if appErr.Details[rule] == "" {
+					t.Errorf("rule %q not reported in %v", rule, appErr.Details)
+				}
+			}
+		})
+	}
+}
+
+func TestNewPasswordPolicyFailsOnMissingCommonList(t *testing.T) {
+	_, err := NewPasswordPolicy(&config.PasswordPolicyConfig{
+		MinLength:           8,
+		MaxLength:           72,
+		CommonPasswordsFile: filepath.Join(t.TempDir(), "missing.txt"),
+	})
+	if err == nil {
+		t.Fatal("NewPasswordPolicy accepted a missing common password file")
+	}
+}
+
+func TestGenerateTemporarySatisfiesPolicy(t *testing.T) {
+	p := newTestPolicy(t)
+	seen := make(map[string]bool)
+	for i := 0; i < 20; i++ {
+		password, err := p.GenerateTemporary()
+		if err != nil {
+			t.Fatalf("GenerateTemporary: %v", err)
+		}
+		if err := p.ValidatePassword(password); err != nil {
+			t.Fatalf("temporary password %q breaks the policy: %v", password, err)
+		}
+		if seen[password] {
+			t.Fatalf("temporary password %q generated twice", password)
+		}
+		seen[password] = true
+	}
+}
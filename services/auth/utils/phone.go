@@ -0,0 +1,35 @@
+package utils
+
+import (
+	"strings"
+
+	et "remaster/shared/errors"
+
+	"github.com/nyaruka/phonenumbers"
+)
+
+// NormalizePhone parses a phone number and returns it in E.164 form,
+// numbers without a +country prefix are read in defaultRegion
+func NormalizePhone(raw, defaultRegion string) (string, error) {
+	raw = strings.TrimSpace(raw)
+	if raw == "" {
+		return "", et.NewValidationError("Phone number is required", map[string]string{
+			"phone": "required",
+		})
+	}
+
+	num, err := phonenumbers.Parse(raw, strings.ToUpper(defaultRegion))
+	if err != nil {
+		return "", et.NewValidationError("Phone number could not be parsed", map[string]string{
+			"phone": err.Error(),
+		})
+	}
+
+	if !phonenumbers.IsValidNumber(num) {
+		return "", et.NewValidationError("Phone number is not valid", map[string]string{
+			"phone": "not a valid number for its region",
+		})
+	}
+
+	return phonenumbers.Format(num, phonenumbers.E164), nil
+}
@@ -0,0 +1,62 @@
+package utils
+
+import (
+	"errors"
+	"testing"
+
+	et "remaster/shared/errors"
+)
+
+func TestNormalizePhone(t *testing.T) {
+	tests := []struct {
+		name   string
+		raw    string
+		region string
+		want   string
+	}{
+		{"us national", "(415) 555-0123", "US", "+14155550123"},
+		{"us e164", "+1 415 555 0123", "US", "+14155550123"},
+		{"uk national", "020 7946 0958", "GB", "+442079460958"},
+		{"german international", "+49 30 901820", "US", "+4930901820"},
+		{"french with 00 prefix", "0033 1 42 68 53 00", "FR", "+33142685300"},
+		{"indian mobile", "98765 43210", "in", "+919876543210"},
+		{"surrounding spaces", "  +14155550123  ", "US", "+14155550123"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := NormalizePhone(tt.raw, tt.region)
+			if err != nil {
+				t.Fatalf("NormalizePhone(%q, %s): %v", tt.raw, tt.region, err)
+			}
+			if got != tt.want {
+				t.Fatalf("NormalizePhone(%q, %s) = %q, want %q", tt.raw, tt.region, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNormalizePhoneRejectsInvalidNumbers(t *testing.T) {
+	tests := []struct {
+		name   string
+		raw    string
+		region string
+	}{
+		{"empty", "   ", "US"},
+		{"letters", "call me maybe", "US"},
+		{"too short", "12345", "US"},
+		{"unassigned us area code", "(055) 555-0123", "US"},
+		{"national number without a region", "4155550123", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NormalizePhone(tt.raw, tt.region)
+			var appErr *et.AppError
+			if !errors.As(err, &appErr) || appErr.Code != et.CodeValidation {
+				t.Fatalf("NormalizePhone(%q) = %v, want a validation error", tt.raw, err)
+			}
+			if appErr.Details["phone"] == "" {
+				t.Fatalf("details = %v, want the phone field", appErr.Details)
+			}
+		})
+	}
+}
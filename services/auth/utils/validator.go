@@ -0,0 +1,39 @@
+package utils
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"remaster/shared/tokenauth"
+)
+
+// LocalValidator checks access tokens against the signing keys without a network call.
+// Unlike the auth service it can't see deactivated users, only the signed claims
+type LocalValidator struct {
+	jwt *JWTUtils
+}
+
+var _ tokenauth.TokenValidator = (*LocalValidator)(nil)
+
+func NewLocalValidator(jwt *JWTUtils) *LocalValidator {
+	return &LocalValidator{jwt: jwt}
+}
+
+func (v *LocalValidator) Validate(_ context.Context, token string) (*tokenauth.Claims, error) {
+	claims, err := v.jwt.ValidateAccessToken(token)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", tokenauth.ErrInvalidToken, err)
+	}
+
+	expiresAt := claims.RegisteredClaims.ExpiresAt.Time
+	return &tokenauth.Claims{
+		UserID:        claims.UserID,
+		UserType:      claims.UserType,
+		Email:         claims.Email,
+		OrgID:         claims.OrgID,
+		Scope:         claims.Scope,
+		ExpiresAt:     expiresAt,
+		ShouldRefresh: time.Until(expiresAt) < v.jwt.RefreshHintThreshold,
+	}, nil
+}
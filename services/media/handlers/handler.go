@@ -0,0 +1,123 @@
+package handlers
+
+import (
+	"context"
+	"log/slog"
+
+	"remaster/shared/errors"
+	pb "remaster/shared/proto/media"
+
+	"remaster/services/media/models"
+	"remaster/services/media/services"
+
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+type MediaHandler struct {
+	pb.UnimplementedMediaServiceServer
+	errorHandler *errors.ErrorHandler
+	mediaService *services.MediaService
+	logger       *slog.Logger
+}
+
+func NewMediaHandler(mediaService *services.MediaService, errorHandler *errors.ErrorHandler, logger *slog.Logger) *MediaHandler {
+	return &MediaHandler{
+		mediaService: mediaService,
+		logger:       logger.With(slog.String("media", "handler")),
+		errorHandler: errorHandler,
+	}
+}
+
+func (h *MediaHandler) CreateUploadURL(ctx context.Context, req *pb.CreateUploadURLRequest) (*pb.CreateUploadURLResponse, error) {
+	h.logger.Info("Upload URL request", "owner_id", req.OwnerId, "content_type", req.ContentType, "size", req.Size)
+
+	upload, err := h.mediaService.CreateUploadURL(ctx, &models.CreateUploadURLRequest{
+		OwnerID:     req.OwnerId,
+		EntityType:  req.EntityType,
+		EntityID:    req.EntityId,
+		ContentType: req.ContentType,
+		Size:        req.Size,
+	})
+	if err != nil {
+		h.logger.Error("Upload URL creation failed", "error", err)
+		return nil, h.errorHandler.HandleGrpcError(err)
+	}
+
+	return &pb.CreateUploadURLResponse{
+		Success:   true,
+		Message:   "Upload URL created",
+		MediaId:   upload.Media.ID.Hex(),
+		Key:       upload.Media.Key,
+		UploadUrl: upload.URL,
+		ExpiresAt: upload.ExpiresAt.Unix(),
+	}, nil
+}
+
+func (h *MediaHandler) ConfirmUpload(ctx context.Context, req *pb.ConfirmUploadRequest) (*pb.ConfirmUploadResponse, error) {
+	h.logger.Info("Confirm upload request", "media_id", req.MediaId)
+
+	media, err := h.mediaService.ConfirmUpload(ctx, req.MediaId, req.OwnerId)
+	if err != nil {
+		h.logger.Error("Confirm upload failed", "error", err)
+		return nil, h.errorHandler.HandleGrpcError(err)
+	}
+
+	return &pb.ConfirmUploadResponse{
+		Success: true,
+		Message: "Upload confirmed",
+		Media:   toProto(media, ""),
+	}, nil
+}
+
+func (h *MediaHandler) GetMedia(ctx context.Context, req *pb.GetMediaRequest) (*pb.GetMediaResponse, error) {
+	h.logger.Info("Get media request", "media_id", req.MediaId, "key", req.Key)
+
+	media, url, err := h.mediaService.GetMedia(ctx, req.MediaId, req.Key)
+	if err != nil {
+		h.logger.Error("Get media failed", "error", err)
+		return nil, h.errorHandler.HandleGrpcError(err)
+	}
+
+	return &pb.GetMediaResponse{
+		Success: true,
+		Message: "Media fetched successfully",
+		Media:   toProto(media, url),
+	}, nil
+}
+
+func (h *MediaHandler) DeleteMedia(ctx context.Context, req *pb.DeleteMediaRequest) (*pb.DeleteMediaResponse, error) {
+	h.logger.Info("Delete media request", "media_id", req.MediaId)
+
+	if err := h.mediaService.DeleteMedia(ctx, req.MediaId, req.OwnerId); err != nil {
+		h.logger.Error("Delete media failed", "error", err)
+		return nil, h.errorHandler.HandleGrpcError(err)
+	}
+
+	return &pb.DeleteMediaResponse{
+		Success: true,
+		Message: "Media deleted successfully",
+	}, nil
+}
+
+func (h *MediaHandler) Health(ctx context.Context, _ *pb.HealthRequest) (*pb.HealthResponse, error) {
+	return &pb.HealthResponse{
+		Status:    "ok",
+		Timestamp: timestamppb.Now(),
+		Checks:    map[string]string{},
+	}, nil
+}
+
+func toProto(media *models.Media, url string) *pb.Media {
+	return &pb.Media{
+		Id:          media.ID.Hex(),
+		OwnerId:     media.OwnerID.Hex(),
+		EntityType:  media.EntityType,
+		EntityId:    media.EntityID,
+		Key:         media.Key,
+		ContentType: media.ContentType,
+		Size:        media.Size,
+		Url:         url,
+		CreatedAt:   timestamppb.New(media.CreatedAt),
+		Status:      string(media.Status),
+	}
+}
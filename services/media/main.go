@@ -1,17 +1,63 @@
 package main
 
 import (
-	"net/http"
+	"context"
+	"os"
 
-	"github.com/gin-gonic/gin"
+	"remaster/services/media/handlers"
+	"remaster/services/media/repositories"
+	"remaster/services/media/services"
+	config "remaster/shared"
+	"remaster/shared/logger"
+	media_pb "remaster/shared/proto/media"
+	"remaster/shared/server"
 )
 
 func main() {
-	router := gin.Default()
+	// Load config
+	cfg, err := config.LoadConfig("config.yaml")
+	if err != nil {
+		panic("failed to load config: " + err.Error())
+	}
 
-	router.GET("/health", func(c *gin.Context) {
-		c.JSON(http.StatusOK, gin.H{"status": "UP"})
+	// Logger
+	logger := logger.Get(cfg.Log)
+
+	// Build server
+	srv, err := server.NewServer(server.ServerConfig{
+		Name:   "media",
+		Config: cfg,
+		Logger: logger,
+		InterceptorConfig: server.InterceptorConfig{
+			EnableLogging:  true,
+			EnableRecovery: true,
+		},
+		Dependencies: []server.ServerOption{
+			server.WithMongo(context.Background()),
+			server.WithAWS(context.Background()),
+		},
 	})
+	if err != nil {
+		logger.Error("failed to initialize server", "error", err)
+		os.Exit(1)
+	}
+
+	// Business logic
+	mediaRepo := repositories.NewMediaRepository(srv.MongoMgr.GetDatabase(), logger)
+	if err := mediaRepo.EnsureIndexes(context.Background()); err != nil {
+		logger.Error("failed to ensure media indexes", "error", err)
+		os.Exit(1)
+	}
+	mediaService := services.NewMediaService(mediaRepo, srv.AWSMgr, &cfg.Media, logger)
+	mediaHandler := handlers.NewMediaHandler(mediaService, srv.ErrorHandler, srv.Logger)
+
+	// Register gRPC service
+	media_pb.RegisterMediaServiceServer(srv.GetGRPCServer(), mediaHandler)
+	logger.Info("media service registered on gRPC server")
+	srv.MarkReady()
 
-	router.Run(":8081")
+	// Start
+	if err := srv.Start(context.Background()); err != nil {
+		logger.Error("server exited with error", "error", err)
+	}
 }
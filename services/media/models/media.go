@@ -0,0 +1,54 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type MediaStatus string
+
+const (
+	MediaStatusPending  MediaStatus = "pending"
+	MediaStatusUploaded MediaStatus = "uploaded"
+)
+
+type Media struct {
+	ID          primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	OwnerID     primitive.ObjectID `bson:"owner_id" json:"owner_id"`
+	EntityType  string             `bson:"entity_type" json:"entity_type"`
+	EntityID    string             `bson:"entity_id,omitempty" json:"entity_id,omitempty"`
+	Key         string             `bson:"key" json:"key"`
+	ContentType string             `bson:"content_type" json:"content_type"`
+	Size        int64              `bson:"size" json:"size"`
+	Status      MediaStatus        `bson:"status" json:"status"`
+	CreatedAt   time.Time          `bson:"created_at" json:"created_at"`
+	UpdatedAt   time.Time          `bson:"updated_at" json:"updated_at"`
+}
+
+type CreateUploadURLRequest struct {
+	OwnerID     string `json:"owner_id" validate:"required"`
+	EntityType  string `json:"entity_type" validate:"required,oneof=user master order review"`
+	EntityID    string `json:"entity_id"`
+	ContentType string `json:"content_type" validate:"required"`
+	Size        int64  `json:"size" validate:"required,min=1"`
+}
+
+type UploadURL struct {
+	Media     *Media
+	URL       string
+	ExpiresAt time.Time
+}
+
+func (m *Media) BeforeCreate() {
+	now := time.Now()
+	m.CreatedAt = now
+	m.UpdatedAt = now
+
+	if m.ID.IsZero() {
+		m.ID = primitive.NewObjectID()
+	}
+	if m.Status == "" {
+		m.Status = MediaStatusPending
+	}
+}
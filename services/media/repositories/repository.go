@@ -0,0 +1,140 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	models "remaster/services/media/models"
+	"remaster/shared/connection"
+	et "remaster/shared/errors"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type mediaRepositoryImpl struct {
+	mediaCol *mongo.Collection
+	logger   *slog.Logger
+}
+
+func NewMediaRepository(db *mongo.Database, logger *slog.Logger) *mediaRepositoryImpl {
+	return &mediaRepositoryImpl{
+		mediaCol: db.Collection(connection.MediaCollection),
+		logger:   logger.With(slog.String("media", "repository")),
+	}
+}
+
+type MediaRepositoryInterface interface {
+	Create(ctx context.Context, media *models.Media) error
+	GetByID(ctx context.Context, id primitive.ObjectID) (*models.Media, error)
+	GetByKey(ctx context.Context, key string) (*models.Media, error)
+	MarkUploaded(ctx context.Context, id primitive.ObjectID) error
+	Delete(ctx context.Context, id primitive.ObjectID) error
+
+	// Utility
+	EnsureIndexes(ctx context.Context) error
+}
+
+func (r *mediaRepositoryImpl) EnsureIndexes(ctx context.Context) error {
+	r.logger.Info("Creating database indexes")
+
+	_, err := r.mediaCol.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{
+			Keys:    bson.D{{Key: "key", Value: 1}},
+			Options: options.Index().SetUnique(true).SetName("idx_media_key_unique"),
+		},
+		{
+			Keys:    bson.D{{Key: "owner_id", Value: 1}},
+			Options: options.Index().SetName("idx_media_owner"),
+		},
+		{
+			Keys:    bson.D{{Key: "entity_type", Value: 1}, {Key: "entity_id", Value: 1}},
+			Options: options.Index().SetName("idx_media_entity"),
+		},
+		{
+			Keys:    bson.D{{Key: "created_at", Value: -1}},
+			Options: options.Index().SetName("idx_media_created"),
+		},
+	})
+	if err != nil {
+		r.logger.Error("Failed to create media indexes", "error", err)
+		return fmt.Errorf("create media indexes: %w", err)
+	}
+
+	r.logger.Info("Database indexes created successfully")
+	return nil
+}
+
+func (r *mediaRepositoryImpl) Create(ctx context.Context, media *models.Media) error {
+	r.logger.Info("Creating media", "owner_id", media.OwnerID.Hex(), "key", media.Key)
+
+	media.BeforeCreate()
+
+	if _, err := r.mediaCol.InsertOne(ctx, media); err != nil {
+		if mongo.IsDuplicateKeyError(err) {
+			r.logger.Warn("Media key already exists", "key", media.Key)
+			return et.NewConflictError("media with this key already exists", err)
+		}
+		r.logger.Error("Failed to insert media", "error", err)
+		return et.NewDatabaseError("failed to create media", err)
+	}
+
+	r.logger.Info("Media created successfully", "media_id", media.ID.Hex())
+	return nil
+}
+
+func (r *mediaRepositoryImpl) GetByID(ctx context.Context, id primitive.ObjectID) (*models.Media, error) {
+	r.logger.Info("Fetching media by ID", "media_id", id.Hex())
+	return r.findOne(ctx, bson.M{"_id": id})
+}
+
+func (r *mediaRepositoryImpl) GetByKey(ctx context.Context, key string) (*models.Media, error) {
+	r.logger.Info("Fetching media by key", "key", key)
+	return r.findOne(ctx, bson.M{"key": key})
+}
+
+func (r *mediaRepositoryImpl) findOne(ctx context.Context, filter bson.M) (*models.Media, error) {
+	var m models.Media
+	if err := r.mediaCol.FindOne(ctx, filter).Decode(&m); err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			r.logger.Warn("Media not found")
+			return nil, et.NewNotFoundError("media not found", err)
+		}
+		r.logger.Error("Failed to fetch media", "error", err)
+		return nil, et.NewDatabaseError("failed to fetch media", err)
+	}
+	return &m, nil
+}
+
+func (r *mediaRepositoryImpl) MarkUploaded(ctx context.Context, id primitive.ObjectID) error {
+	r.logger.Info("Marking media uploaded", "media_id", id.Hex())
+
+	result, err := r.mediaCol.UpdateOne(ctx, bson.M{"_id": id}, bson.M{
+		"$set": bson.M{"status": models.MediaStatusUploaded, "updated_at": time.Now()},
+	})
+	if err != nil {
+		r.logger.Error("Failed to mark media uploaded", "error", err)
+		return et.NewDatabaseError("failed to update media", err)
+	}
+	if result.MatchedCount == 0 {
+		return et.NewNotFoundError("media not found", nil)
+	}
+	return nil
+}
+
+func (r *mediaRepositoryImpl) Delete(ctx context.Context, id primitive.ObjectID) error {
+	r.logger.Info("Deleting media", "media_id", id.Hex())
+
+	if _, err := r.mediaCol.DeleteOne(ctx, bson.M{"_id": id}); err != nil {
+		r.logger.Error("Failed to delete media", "error", err)
+		return et.NewDatabaseError("failed to delete media", err)
+	}
+
+	r.logger.Info("Media deleted successfully", "media_id", id.Hex())
+	return nil
+}
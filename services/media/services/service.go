@@ -0,0 +1,196 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"slices"
+	"time"
+
+	"remaster/services/media/models"
+	repo "remaster/services/media/repositories"
+	config "remaster/shared"
+	"remaster/shared/connection"
+	et "remaster/shared/errors"
+	"remaster/shared/validation"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// file extensions for allowed content types
+var extensions = map[string]string{
+	"image/jpeg": ".jpg",
+	"image/png":  ".png",
+	"image/webp": ".webp",
+	"image/gif":  ".gif",
+	"video/mp4":  ".mp4",
+}
+
+type MediaService struct {
+	repo   repo.MediaRepositoryInterface
+	s3     connection.S3API
+	config *config.MediaConfig
+	logger *slog.Logger
+}
+
+func NewMediaService(mediaRepo repo.MediaRepositoryInterface, s3 connection.S3API, cfg *config.MediaConfig, logger *slog.Logger) *MediaService {
+	return &MediaService{
+		repo:   mediaRepo,
+		s3:     s3,
+		config: cfg,
+		logger: logger.With(slog.String("media", "service")),
+	}
+}
+
+// CreateUploadURL validates the upload, stores pending metadata and returns a presigned PUT url
+func (s *MediaService) CreateUploadURL(ctx context.Context, req *models.CreateUploadURLRequest) (*models.UploadURL, error) {
+	s.logger.Info("Creating upload URL", "owner_id", req.OwnerID, "entity_type", req.EntityType)
+
+	if err := validation.Validate(req); err != nil {
+		s.logger.Warn("Validation failed for upload", "error", err)
+		return nil, err
+	}
+
+	ownerID, err := primitive.ObjectIDFromHex(req.OwnerID)
+	if err != nil {
+		return nil, et.NewValidationError("invalid owner id", map[string]string{"owner_id": "must be a valid id"})
+	}
+	if !slices.Contains(s.config.AllowedContentTypes, req.ContentType) {
+		return nil, et.NewValidationError("unsupported content type",
+			map[string]string{"content_type": fmt.Sprintf("must be one of: %v", s.config.AllowedContentTypes)})
+	}
+	if req.Size > s.config.MaxUploadSize {
+		return nil, et.NewValidationError("file is too large",
+			map[string]string{"size": fmt.Sprintf("must be at most %d bytes", s.config.MaxUploadSize)})
+	}
+
+	media := &models.Media{
+		ID:          primitive.NewObjectID(),
+		OwnerID:     ownerID,
+		EntityType:  req.EntityType,
+		EntityID:    req.EntityID,
+		ContentType: req.ContentType,
+		Size:        req.Size,
+	}
+	media.Key = fmt.Sprintf("%s/%s/%s%s", req.EntityType, ownerID.Hex(), media.ID.Hex(), extensions[req.ContentType])
+
+	url, err := s.s3.GetPresignedUploadURL(ctx, media.Key, media.ContentType, media.Size, s.config.UploadURLTTL)
+	if err != nil {
+		s.logger.Error("Failed to presign upload", "error", err)
+		return nil, et.NewInternalError("failed to create upload url", err)
+	}
+
+	if err := s.repo.Create(ctx, media); err != nil {
+		return nil, err
+	}
+
+	s.logger.Info("Upload URL created", "media_id", media.ID.Hex(), "key", media.Key)
+	return &models.UploadURL{
+		Media:     media,
+		URL:       url,
+		ExpiresAt: time.Now().Add(s.config.UploadURLTTL),
+	}, nil
+}
+
+// ConfirmUpload checks that the object was put to storage and marks the media uploaded,
+// only uploaded media may be referenced by other records
+func (s *MediaService) ConfirmUpload(ctx context.Context, mediaIDHex, ownerIDHex string) (*models.Media, error) {
+	s.logger.Info("Confirming upload", "media_id", mediaIDHex, "owner_id", ownerIDHex)
+
+	mediaID, err := primitive.ObjectIDFromHex(mediaIDHex)
+	if err != nil {
+		return nil, et.NewValidationError("invalid media id", map[string]string{"media_id": "must be a valid id"})
+	}
+
+	media, err := s.repo.GetByID(ctx, mediaID)
+	if err != nil {
+		return nil, err
+	}
+	if media.OwnerID.Hex() != ownerIDHex {
+		s.logger.Warn("Media owner mismatch", "media_id", mediaIDHex, "owner_id", ownerIDHex)
+		return nil, et.NewForbiddenError("media belongs to another user")
+	}
+	if media.Status == models.MediaStatusUploaded {
+		return media, nil
+	}
+
+	object, err := s.s3.HeadObject(ctx, media.Key)
+	if err != nil {
+		if errors.Is(err, connection.ErrObjectNotFound) {
+			s.logger.Warn("Upload not found in storage", "media_id", mediaIDHex, "key", media.Key)
+			return nil, et.NewConflictError("media has not been uploaded yet", err)
+		}
+		s.logger.Error("Failed to head object", "error", err)
+		return nil, et.NewInternalError("failed to confirm upload", err)
+	}
+	if object.Size != media.Size {
+		s.logger.Warn("Uploaded object size mismatch", "media_id", mediaIDHex, "want", media.Size, "got", object.Size)
+		return nil, et.NewConflictError("uploaded object does not match the requested upload", nil)
+	}
+
+	if err := s.repo.MarkUploaded(ctx, media.ID); err != nil {
+		return nil, err
+	}
+	media.Status = models.MediaStatusUploaded
+
+	s.logger.Info("Upload confirmed", "media_id", mediaIDHex)
+	return media, nil
+}
+
+// GetMedia looks media up by id, or by key when id is empty
+func (s *MediaService) GetMedia(ctx context.Context, mediaIDHex, key string) (*models.Media, string, error) {
+	var (
+		media *models.Media
+		err   error
+	)
+
+	switch {
+	case mediaIDHex != "":
+		mediaID, parseErr := primitive.ObjectIDFromHex(mediaIDHex)
+		if parseErr != nil {
+			return nil, "", et.NewValidationError("invalid media id", map[string]string{"media_id": "must be a valid id"})
+		}
+		media, err = s.repo.GetByID(ctx, mediaID)
+	case key != "":
+		media, err = s.repo.GetByKey(ctx, key)
+	default:
+		return nil, "", et.NewValidationError("media id or key is required", map[string]string{"media_id": "is required"})
+	}
+	if err != nil {
+		return nil, "", err
+	}
+
+	url, err := s.s3.GetPresignedURL(ctx, media.Key, s.config.DownloadURLTTL)
+	if err != nil {
+		s.logger.Error("Failed to presign download", "error", err)
+		return nil, "", et.NewInternalError("failed to create download url", err)
+	}
+
+	return media, url, nil
+}
+
+func (s *MediaService) DeleteMedia(ctx context.Context, mediaIDHex, ownerIDHex string) error {
+	s.logger.Info("Deleting media", "media_id", mediaIDHex, "owner_id", ownerIDHex)
+
+	mediaID, err := primitive.ObjectIDFromHex(mediaIDHex)
+	if err != nil {
+		return et.NewValidationError("invalid media id", map[string]string{"media_id": "must be a valid id"})
+	}
+
+	media, err := s.repo.GetByID(ctx, mediaID)
+	if err != nil {
+		return err
+	}
+	if media.OwnerID.Hex() != ownerIDHex {
+		s.logger.Warn("Media owner mismatch", "media_id", mediaIDHex, "owner_id", ownerIDHex)
+		return et.NewForbiddenError("media belongs to another user")
+	}
+
+	if err := s.s3.DeleteObject(ctx, media.Key); err != nil {
+		s.logger.Error("Failed to delete object", "error", err)
+		return et.NewInternalError("failed to delete media object", err)
+	}
+
+	return s.repo.Delete(ctx, media.ID)
+}
@@ -0,0 +1,354 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"remaster/services/media/models"
+	config "remaster/shared"
+	"remaster/shared/connection"
+	et "remaster/shared/errors"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// fakeRepo keeps media in memory
+type fakeRepo struct {
+	mu    sync.Mutex
+	media map[primitive.ObjectID]*models.Media
+}
+
+func newFakeRepo() *fakeRepo {
+	return &fakeRepo{media: make(map[primitive.ObjectID]*models.Media)}
+}
+
+func (r *fakeRepo) Create(_ context.Context, media *models.Media) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	media.BeforeCreate()
+	stored := *media
+	r.media[media.ID] = &stored
+	return nil
+}
+
+func (r *fakeRepo) GetByID(_ context.Context, id primitive.ObjectID) (*models.Media, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	m, ok := r.media[id]
+	if !ok {
+		return nil, et.NewNotFoundError("media not found", nil)
+	}
+	found := *m
+	return &found, nil
+}
+
+func (r *fakeRepo) GetByKey(_ context.Context, key string) (*models.Media, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, m := range r.media {
+		if m.Key == key {
+			found := *m
+			return &found, nil
+		}
+	}
+	return nil, et.NewNotFoundError("media not found", nil)
+}
+
+func (r *fakeRepo) MarkUploaded(_ context.Context, id primitive.ObjectID) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	m, ok := r.media[id]
+	if !ok {
+		return et.NewNotFoundError("media not found", nil)
+	}
+	m.Status = models.MediaStatusUploaded
+	return nil
+}
+
+func (r *fakeRepo) Delete(_ context.Context, id primitive.ObjectID) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delete(r.media, id)
+	return nil
+}
+
+func (r *fakeRepo) EnsureIndexes(context.Context) error { return nil }
+
+// presignCall records what a presign was asked to sign
+type presignCall struct {
+	key         string
+	contentType string
+	size        int64
+	ttl         time.Duration
+}
+
+// fakeS3 stands in for the bucket, objects maps keys that were put to their metadata
+type fakeS3 struct {
+	objects  map[string]*connection.ObjectInfo
+	uploads  []presignCall
+	deleted  []string
+	failHead error
+}
+
+func newFakeS3() *fakeS3 {
+	return &fakeS3{objects: make(map[string]*connection.ObjectInfo)}
+}
+
+func (s *fakeS3) PutObject(_ context.Context, key string, body io.Reader, contentType string) error {
+	data, err := io.ReadAll(body)
+	if err != nil {
+		return err
+	}
+	s.objects[key] = &connection.ObjectInfo{Size: int64(len(data)), ContentType: contentType}
+	return nil
+}
+
+func (s *fakeS3) GetPresignedURL(_ context.Context, key string, _ time.Duration) (string, error) {
+	return "https://bucket.example.com/" + key + "?X-Amz-Signature=get", nil
+}
+
+func (s *fakeS3) GetPresignedUploadURL(_ context.Context, key, contentType string, size int64, ttl time.Duration) (string, error) {
+	s.uploads = append(s.uploads, presignCall{key: key, contentType: contentType, size: size, ttl: ttl})
+	return "https://bucket.example.com/" + key + "?X-Amz-Signature=put", nil
+}
+
+func (s *fakeS3) HeadObject(_ context.Context, key string) (*connection.ObjectInfo, error) {
+	if s.failHead != nil {
+		return nil, s.failHead
+	}
+	info, ok := s.objects[key]
+	if !ok {
+		return nil, connection.ErrObjectNotFound
+	}
+	return info, nil
+}
+
+func (s *fakeS3) DeleteObject(_ context.Context, key string) error {
+	s.deleted = append(s.deleted, key)
+	delete(s.objects, key)
+	return nil
+}
+
+func newTestService(t *testing.T) (*MediaService, *fakeRepo, *fakeS3) {
+	t.Helper()
+	repo := newFakeRepo()
+	s3 := newFakeS3()
+	svc := NewMediaService(repo, s3, &config.MediaConfig{
+		MaxUploadSize:       5 << 20,
+		AllowedContentTypes: []string{"image/jpeg", "image/png"},
+		UploadURLTTL:        15 * time.Minute,
+		DownloadURLTTL:      time.Hour,
+	}, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	return svc, repo, s3
+}
+
+func assertErrorCode(t *testing.T, err error, want et.ErrorCode) *et.AppError {
+	t.Helper()
+	var appErr *et.AppError
+	if !errors.As(err, &appErr) {
+		t.Fatalf("error = %v, want an AppError with code %s", err, want)
+	}
+	if appErr.Code != want {
+		t.Fatalf("error code = %s (%v), want %s", appErr.Code, err, want)
+	}
+	return appErr
+}
+
+// reserve creates a pending upload for owner
+func reserve(t *testing.T, svc *MediaService, owner primitive.ObjectID, size int64) *models.Media {
+	t.Helper()
+	upload, err := svc.CreateUploadURL(context.Background(), &models.CreateUploadURLRequest{
+		OwnerID:     owner.Hex(),
+		EntityType:  "user",
+		ContentType: "image/jpeg",
+		Size:        size,
+	})
+	if err != nil {
+		t.Fatalf("CreateUploadURL: %v", err)
+	}
+	return upload.Media
+}
+
+func TestConfirmUploadMarksUploadedMedia(t *testing.T) {
+	svc, repo, s3 := newTestService(t)
+	owner := primitive.NewObjectID()
+	media := reserve(t, svc, owner, 1024)
+	if media.Status != models.MediaStatusPending {
+		t.Fatalf("reserved status = %q, want pending", media.Status)
+	}
+
+	s3.objects[media.Key] = &connection.ObjectInfo{Size: 1024, ContentType: "image/jpeg"}
+	confirmed, err := svc.ConfirmUpload(context.Background(), media.ID.Hex(), owner.Hex())
+	if err != nil {
+		t.Fatalf("ConfirmUpload: %v", err)
+	}
+	if confirmed.Status != models.MediaStatusUploaded {
+		t.Fatalf("confirmed status = %q, want uploaded", confirmed.Status)
+	}
+	stored, _ := repo.GetByID(context.Background(), media.ID)
+	if stored.Status != models.MediaStatusUploaded {
+		t.Fatalf("stored status = %q, want uploaded", stored.Status)
+	}
+}
+
+func TestConfirmUploadRejects(t *testing.T) {
+	owner := primitive.NewObjectID()
+
+	t.Run("object never put", func(t *testing.T) {
+		svc, repo, _ := newTestService(t)
+		media := reserve(t, svc, owner, 1024)
+
+		_, err := svc.ConfirmUpload(context.Background(), media.ID.Hex(), owner.Hex())
+		assertErrorCode(t, err, et.CodeConflict)
+		if stored, _ := repo.GetByID(context.Background(), media.ID); stored.Status != models.MediaStatusPending {
+			t.Fatalf("status = %q, want still pending", stored.Status)
+		}
+	})
+
+	t.Run("size differs from the reservation", func(t *testing.T) {
+		svc, _, s3 := newTestService(t)
+		media := reserve(t, svc, owner, 1024)
+		s3.objects[media.Key] = &connection.ObjectInfo{Size: 4096, ContentType: "image/jpeg"}
+
+		_, err := svc.ConfirmUpload(context.Background(), media.ID.Hex(), owner.Hex())
+		assertErrorCode(t, err, et.CodeConflict)
+	})
+
+	t.Run("another owner", func(t *testing.T) {
+		svc, _, s3 := newTestService(t)
+		media := reserve(t, svc, owner, 1024)
+		s3.objects[media.Key] = &connection.ObjectInfo{Size: 1024, ContentType: "image/jpeg"}
+
+		_, err := svc.ConfirmUpload(context.Background(), media.ID.Hex(), primitive.NewObjectID().Hex())
+		assertErrorCode(t, err, et.CodeForbidden)
+	})
+
+	t.Run("storage error", func(t *testing.T) {
+		svc, _, s3 := newTestService(t)
+		media := reserve(t, svc, owner, 1024)
+		s3.failHead = errors.New("connection reset")
+
+		_, err := svc.ConfirmUpload(context.Background(), media.ID.Hex(), owner.Hex())
+		assertErrorCode(t, err, et.CodeInternal)
+	})
+}
+
+func TestCreateUploadURLPresignsValidatedUpload(t *testing.T) {
+	svc, repo, s3 := newTestService(t)
+	owner := primitive.NewObjectID()
+
+	upload, err := svc.CreateUploadURL(context.Background(), &models.CreateUploadURLRequest{
+		OwnerID:     owner.Hex(),
+		EntityType:  "review",
+		ContentType: "image/png",
+		Size:        2048,
+	})
+	if err != nil {
+		t.Fatalf("CreateUploadURL: %v", err)
+	}
+
+	if len(s3.uploads) != 1 {
+		t.Fatalf("presigned %d uploads, want 1", len(s3.uploads))
+	}
+	call := s3.uploads[0]
+	want := presignCall{key: upload.Media.Key, contentType: "image/png", size: 2048, ttl: 15 * time.Minute}
+	if call != want {
+		t.Fatalf("presign = %+v, want %+v", call, want)
+	}
+	// keys are namespaced by entity and owner, and never taken from the request
+	if prefix := "review/" + owner.Hex() + "/"; !strings.HasPrefix(upload.Media.Key, prefix) || !strings.HasSuffix(upload.Media.Key, ".png") {
+		t.Fatalf("key = %q, want %s<id>.png", upload.Media.Key, prefix)
+	}
+
+	stored, err := repo.GetByID(context.Background(), upload.Media.ID)
+	if err != nil {
+		t.Fatalf("media not stored: %v", err)
+	}
+	if stored.OwnerID != owner || stored.Status != models.MediaStatusPending || stored.Key != upload.Media.Key {
+		t.Fatalf("stored media = %+v, want a pending record of the owner under the presigned key", stored)
+	}
+}
+
+func TestCreateUploadURLRejectsInvalidUploads(t *testing.T) {
+	owner := primitive.NewObjectID().Hex()
+	tests := []struct {
+		name  string
+		req   *models.CreateUploadURLRequest
+		field string
+	}{
+		{"content type not allowed", &models.CreateUploadURLRequest{OwnerID: owner, EntityType: "user", ContentType: "application/pdf", Size: 1024}, "content_type"},
+		{"allowed elsewhere but not configured", &models.CreateUploadURLRequest{OwnerID: owner, EntityType: "user", ContentType: "video/mp4", Size: 1024}, "content_type"},
+		{"too large", &models.CreateUploadURLRequest{OwnerID: owner, EntityType: "user", ContentType: "image/jpeg", Size: 5<<20 + 1}, "size"},
+		{"invalid owner", &models.CreateUploadURLRequest{OwnerID: "not-an-id", EntityType: "user", ContentType: "image/jpeg", Size: 1024}, "owner_id"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			svc, repo, s3 := newTestService(t)
+
+			_, err := svc.CreateUploadURL(context.Background(), tt.req)
+			appErr := assertErrorCode(t, err, et.CodeValidation)
+			if _, ok := appErr.Details[tt.field]; !ok {
+				t.Fatalf("details = %v, want a violation for %s", appErr.Details, tt.field)
+			}
+			if len(s3.uploads) != 0 || len(repo.media) != 0 {
+				t.Fatalf("presigned %d and stored %d for a rejected upload", len(s3.uploads), len(repo.media))
+			}
+		})
+	}
+}
+
+func TestDeleteMediaChecksOwnership(t *testing.T) {
+	owner := primitive.NewObjectID()
+
+	t.Run("owner", func(t *testing.T) {
+		svc, repo, s3 := newTestService(t)
+		media := reserve(t, svc, owner, 1024)
+
+		if err := svc.DeleteMedia(context.Background(), media.ID.Hex(), owner.Hex()); err != nil {
+			t.Fatalf("DeleteMedia: %v", err)
+		}
+		if len(s3.deleted) != 1 || s3.deleted[0] != media.Key {
+			t.Fatalf("deleted objects = %v, want %s", s3.deleted, media.Key)
+		}
+		if _, err := repo.GetByID(context.Background(), media.ID); err == nil {
+			t.Fatal("media record still stored")
+		}
+	})
+
+	t.Run("another owner", func(t *testing.T) {
+		svc, repo, s3 := newTestService(t)
+		media := reserve(t, svc, owner, 1024)
+
+		err := svc.DeleteMedia(context.Background(), media.ID.Hex(), primitive.NewObjectID().Hex())
+		assertErrorCode(t, err, et.CodeForbidden)
+		if len(s3.deleted) != 0 {
+			t.Fatalf("deleted objects = %v, want none", s3.deleted)
+		}
+		if _, err := repo.GetByID(context.Background(), media.ID); err != nil {
+			t.Fatalf("media record removed: %v", err)
+		}
+	})
+}
+
+func TestGetMediaPresignsDownloadByIDOrKey(t *testing.T) {
+	svc, _, _ := newTestService(t)
+	media := reserve(t, svc, primitive.NewObjectID(), 1024)
+
+	byID, url, err := svc.GetMedia(context.Background(), media.ID.Hex(), "")
+	if err != nil || byID.Key != media.Key || !strings.Contains(url, media.Key) {
+		t.Fatalf("GetMedia by id = %+v, %q, %v", byID, url, err)
+	}
+	byKey, _, err := svc.GetMedia(context.Background(), "", media.Key)
+	if err != nil || byKey.ID != media.ID {
+		t.Fatalf("GetMedia by key = %+v, %v", byKey, err)
+	}
+
+	_, _, err = svc.GetMedia(context.Background(), primitive.NewObjectID().Hex(), "")
+	assertErrorCode(t, err, et.CodeNotFound)
+}
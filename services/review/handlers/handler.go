@@ -0,0 +1,161 @@
+package handlers
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"remaster/shared/connection"
+	"remaster/shared/db"
+	"remaster/shared/errors"
+	pb "remaster/shared/proto/review"
+
+	"remaster/services/review/models"
+	"remaster/services/review/services"
+
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+type ReviewHandler struct {
+	pb.UnimplementedReviewServiceServer
+	errorHandler  *errors.ErrorHandler
+	reviewService *services.ReviewService
+	healthChecks  map[string]connection.HealthChecker
+	logger        *slog.Logger
+}
+
+// per dependency, checks run concurrently and have to answer within the gateway's 2s
+const healthCheckTimeout = 1500 * time.Millisecond
+
+func NewReviewHandler(
+	reviewService *services.ReviewService,
+	errorHandler *errors.ErrorHandler,
+	healthChecks map[string]connection.HealthChecker,
+	logger *slog.Logger,
+) *ReviewHandler {
+	return &ReviewHandler{
+		reviewService: reviewService,
+		healthChecks:  healthChecks,
+		logger:        logger.With(slog.String("review", "handler")),
+		errorHandler:  errorHandler,
+	}
+}
+
+func (h *ReviewHandler) CreateReview(ctx context.Context, req *pb.CreateReviewRequest) (*pb.CreateReviewResponse, error) {
+	h.logger.Info("Create review request", "order_id", req.OrderId, "master_id", req.MasterId)
+
+	review, err := h.reviewService.CreateReview(ctx, &models.CreateReviewRequest{
+		OrderID:    req.OrderId,
+		ReviewerID: req.ReviewerId,
+		MasterID:   req.MasterId,
+		Rating:     int(req.Rating),
+		Comment:    req.Comment,
+	})
+	if err != nil {
+		h.logger.Error("Create review failed", "error", err)
+		return nil, h.errorHandler.HandleGrpcError(err)
+	}
+
+	return &pb.CreateReviewResponse{
+		Success: true,
+		Message: "Review created successfully",
+		Review:  toProtoReview(review),
+	}, nil
+}
+
+func (h *ReviewHandler) GetReviewsByMaster(ctx context.Context, req *pb.GetReviewsByMasterRequest) (*pb.GetReviewsByMasterResponse, error) {
+	h.logger.Info("Get reviews request", "master_id", req.MasterId)
+
+	filter := models.ReviewFilter{
+		MinRating: int(req.MinRating),
+		MaxRating: int(req.MaxRating),
+		Sort:      req.Sort,
+	}
+	if req.CreatedFrom != nil {
+		from := req.CreatedFrom.AsTime()
+		filter.CreatedFrom = &from
+	}
+	if req.CreatedTo != nil {
+		to := req.CreatedTo.AsTime()
+		filter.CreatedTo = &to
+	}
+
+	result, err := h.reviewService.GetReviewsByMaster(ctx, req.MasterId, filter, db.PageRequest{
+		Page:     req.Page,
+		PageSize: req.PageSize,
+	})
+	if err != nil {
+		h.logger.Error("Get reviews failed", "error", err)
+		return nil, h.errorHandler.HandleGrpcError(err)
+	}
+
+	pbReviews := make([]*pb.Review, 0, len(result.Items))
+	for _, r := range result.Items {
+		pbReviews = append(pbReviews, toProtoReview(r))
+	}
+
+	return &pb.GetReviewsByMasterResponse{
+		Success:    true,
+		Message:    "Reviews fetched successfully",
+		Reviews:    pbReviews,
+		Total:      result.Total,
+		Page:       result.Page,
+		PageSize:   result.PageSize,
+		TotalPages: result.TotalPages,
+	}, nil
+}
+
+func (h *ReviewHandler) GetAverageRating(ctx context.Context, req *pb.GetAverageRatingRequest) (*pb.GetAverageRatingResponse, error) {
+	h.logger.Info("Get average rating request", "master_id", req.MasterId)
+
+	summary, err := h.reviewService.GetAverageRating(ctx, req.MasterId)
+	if err != nil {
+		h.logger.Error("Get average rating failed", "error", err)
+		return nil, h.errorHandler.HandleGrpcError(err)
+	}
+
+	distribution := make(map[int32]int64, len(summary.Distribution))
+	for star, count := range summary.Distribution {
+		distribution[int32(star)] = count
+	}
+
+	return &pb.GetAverageRatingResponse{
+		MasterId:     summary.MasterID,
+		Average:      summary.Average,
+		Count:        summary.Count,
+		Distribution: distribution,
+	}, nil
+}
+
+func (h *ReviewHandler) Health(ctx context.Context, _ *pb.HealthRequest) (*pb.HealthResponse, error) {
+	status := "ok"
+	checks := make(map[string]string, len(h.healthChecks))
+
+	for name, err := range connection.CheckAll(ctx, h.healthChecks, healthCheckTimeout) {
+		if err != nil {
+			h.logger.Warn("Dependency health check failed", "dependency", name, "error", err)
+			checks[name] = "unhealthy"
+			status = "unhealthy"
+			continue
+		}
+		checks[name] = "ok"
+	}
+
+	return &pb.HealthResponse{
+		Status:    status,
+		Timestamp: timestamppb.Now(),
+		Checks:    checks,
+	}, nil
+}
+
+func toProtoReview(r *models.Review) *pb.Review {
+	return &pb.Review{
+		Id:         r.ID.Hex(),
+		OrderId:    r.OrderID.Hex(),
+		ReviewerId: r.ReviewerID.Hex(),
+		MasterId:   r.MasterID.Hex(),
+		Rating:     int32(r.Rating),
+		Comment:    r.Comment,
+		CreatedAt:  timestamppb.New(r.CreatedAt),
+	}
+}
@@ -0,0 +1,44 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"testing"
+
+	"remaster/shared/connection"
+	pb "remaster/shared/proto/review"
+)
+
+type fakeChecker struct {
+	err error
+}
+
+func (c fakeChecker) HealthCheck(context.Context) error { return c.err }
+
+func TestHealthProbesMongo(t *testing.T) {
+	tests := []struct {
+		name       string
+		err        error
+		wantStatus string
+	}{
+		{name: "healthy", wantStatus: "ok"},
+		{name: "unhealthy", err: errors.New("server selection error: mongo-0.internal:27017"), wantStatus: "unhealthy"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := NewReviewHandler(nil, nil, map[string]connection.HealthChecker{
+				"mongodb": fakeChecker{err: tt.err},
+			}, slog.New(slog.NewTextHandler(io.Discard, nil)))
+
+			resp, err := h.Health(context.Background(), &pb.HealthRequest{})
+			if err != nil {
+				t.Fatalf("Health: %v", err)
+			}
+			if resp.Status != tt.wantStatus || resp.Checks["mongodb"] != tt.wantStatus {
+				t.Fatalf("status = %q, mongodb = %q, want %q", resp.Status, resp.Checks["mongodb"], tt.wantStatus)
+			}
+		})
+	}
+}
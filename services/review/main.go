@@ -1,17 +1,74 @@
 package main
 
 import (
-	"net/http"
+	"context"
+	"os"
 
-	"github.com/gin-gonic/gin"
+	"remaster/services/review/handlers"
+	"remaster/services/review/repositories"
+	"remaster/services/review/services"
+	config "remaster/shared"
+	"remaster/shared/connection"
+	"remaster/shared/events"
+	"remaster/shared/logger"
+	review_pb "remaster/shared/proto/review"
+	"remaster/shared/server"
 )
 
 func main() {
-	router := gin.Default()
+	// Load config
+	cfg, err := config.LoadConfig("config.yaml")
+	if err != nil {
+		panic("failed to load config: " + err.Error())
+	}
 
-	router.GET("/health", func(c *gin.Context) {
-		c.JSON(http.StatusOK, gin.H{"status": "UP"})
+	// Logger
+	logger := logger.Get(cfg.Log)
+
+	// Build server
+	srv, err := server.NewServer(server.ServerConfig{
+		Name:   "review",
+		Config: cfg,
+		Logger: logger,
+		InterceptorConfig: server.InterceptorConfig{
+			EnableLogging:  true,
+			EnableRecovery: true,
+		},
+		Dependencies: []server.ServerOption{
+			server.WithMongo(context.Background()),
+		},
 	})
+	if err != nil {
+		logger.Error("failed to initialize server", "error", err)
+		os.Exit(1)
+	}
+
+	// Business logic
+	reviewRepo := repositories.NewReviewRepository(srv.MongoMgr.GetDatabase(), logger)
+	if err := reviewRepo.EnsureIndexes(context.Background()); err != nil {
+		logger.Error("failed to ensure review indexes", "error", err)
+		os.Exit(1)
+	}
+	reviewService := services.NewReviewService(reviewRepo, logger)
+	healthChecks := map[string]connection.HealthChecker{
+		"mongodb": srv.MongoMgr,
+	}
+	reviewHandler := handlers.NewReviewHandler(reviewService, srv.ErrorHandler, healthChecks, srv.Logger)
+
+	// Register gRPC service
+	review_pb.RegisterReviewServiceServer(srv.GetGRPCServer(), reviewHandler)
+	logger.Info("review service registered on gRPC server")
+	srv.MarkReady()
+
+	// Background jobs
+	if cfg.Kafka.Enabled {
+		consumer := connection.NewKafkaConsumer(&cfg.Kafka, cfg.Kafka.GroupID+"-review", logger)
+		consumer.Handle(cfg.Kafka.UserEventsTopic, events.UserEventHandler(reviewService.HandleUserEvent))
+		srv.RegisterWorker(consumer)
+	}
 
-	router.Run(":8081")
+	// Start
+	if err := srv.Start(context.Background()); err != nil {
+		logger.Error("server exited with error", "error", err)
+	}
 }
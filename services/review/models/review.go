@@ -0,0 +1,106 @@
+package models
+
+import (
+	"strings"
+	"time"
+
+	et "remaster/shared/errors"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const (
+	MinRating        = 1
+	MaxRating        = 5
+	MaxCommentLength = 2000
+)
+
+type Review struct {
+	ID         primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	OrderID    primitive.ObjectID `bson:"order_id" json:"order_id"`
+	ReviewerID primitive.ObjectID `bson:"reviewer_id" json:"reviewer_id"`
+	MasterID   primitive.ObjectID `bson:"master_id" json:"master_id"`
+	Rating     int                `bson:"rating" json:"rating" validate:"required,min=1,max=5"`
+	Comment    string             `bson:"comment,omitempty" json:"comment,omitempty" validate:"max=2000"`
+	CreatedAt  time.Time          `bson:"created_at" json:"created_at"`
+	UpdatedAt  time.Time          `bson:"updated_at" json:"updated_at"`
+}
+
+type CreateReviewRequest struct {
+	OrderID    string `json:"order_id" validate:"required"`
+	ReviewerID string `json:"reviewer_id" validate:"required"`
+	MasterID   string `json:"master_id" validate:"required"`
+	Rating     int    `json:"rating" validate:"required,min=1,max=5"`
+	Comment    string `json:"comment" validate:"max=2000"`
+}
+
+type RatingSummary struct {
+	MasterID string  `bson:"_id" json:"master_id"`
+	Average  float64 `bson:"average" json:"average"`
+	Count    int64   `bson:"count" json:"count"`
+	// reviews per star, keyed 1-5
+	Distribution map[int]int64 `bson:"-" json:"distribution"`
+}
+
+// review list orders, SortNewest is the default
+const (
+	SortNewest     = "newest"
+	SortOldest     = "oldest"
+	SortRatingDesc = "rating_desc"
+	SortRatingAsc  = "rating_asc"
+)
+
+// ReviewFilter narrows a master's review list, zero values are ignored
+type ReviewFilter struct {
+	MinRating   int
+	MaxRating   int
+	CreatedFrom *time.Time
+	CreatedTo   *time.Time
+	Sort        string
+}
+
+func (r *Review) BeforeCreate() {
+	now := time.Now()
+	r.CreatedAt = now
+	r.UpdatedAt = now
+
+	if r.ID.IsZero() {
+		r.ID = primitive.NewObjectID()
+	}
+}
+
+func (f *ReviewFilter) Validate() error {
+	v := et.New()
+
+	validRating := func(r int) bool { return r == 0 || (r >= MinRating && r <= MaxRating) }
+	v.Check(validRating(f.MinRating), "min_rating", "must be between 1 and 5")
+	v.Check(validRating(f.MaxRating), "max_rating", "must be between 1 and 5")
+	v.Check(f.MinRating == 0 || f.MaxRating == 0 || f.MinRating <= f.MaxRating, "min_rating", "must not exceed max_rating")
+	v.Check(f.CreatedFrom == nil || f.CreatedTo == nil || !f.CreatedFrom.After(*f.CreatedTo), "created_from", "must not be after created_to")
+	switch f.Sort {
+	case "", SortNewest, SortOldest, SortRatingDesc, SortRatingAsc:
+	default:
+		v.AddError("sort", "must be one of newest, oldest, rating_desc, rating_asc")
+	}
+
+	if !v.Valid() {
+		return et.NewValidationError("invalid review filter", v.Errors)
+	}
+	return nil
+}
+
+func (req *CreateReviewRequest) Validate() error {
+	v := et.New()
+
+	v.Check(primitive.IsValidObjectID(req.OrderID), "order_id", "must be a valid id")
+	v.Check(primitive.IsValidObjectID(req.ReviewerID), "reviewer_id", "must be a valid id")
+	v.Check(primitive.IsValidObjectID(req.MasterID), "master_id", "must be a valid id")
+	v.Check(req.Rating >= MinRating && req.Rating <= MaxRating, "rating", "must be between 1 and 5")
+	v.Check(len(strings.TrimSpace(req.Comment)) <= MaxCommentLength, "comment", "must be at most 2000 characters")
+	v.Check(req.ReviewerID != req.MasterID, "reviewer_id", "master cannot review themselves")
+
+	if !v.Valid() {
+		return et.NewValidationError("invalid review request", v.Errors)
+	}
+	return nil
+}
@@ -0,0 +1,197 @@
+package repositories
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+
+	models "remaster/services/review/models"
+	"remaster/shared/connection"
+	"remaster/shared/db"
+	et "remaster/shared/errors"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type reviewRepositoryImpl struct {
+	reviewsCol *mongo.Collection
+	logger     *slog.Logger
+}
+
+func NewReviewRepository(db *mongo.Database, logger *slog.Logger) *reviewRepositoryImpl {
+	return &reviewRepositoryImpl{
+		reviewsCol: db.Collection(connection.ReviewsCollection),
+		logger:     logger.With(slog.String("review", "repository")),
+	}
+}
+
+type ReviewRepositoryInterface interface {
+	CreateReview(ctx context.Context, review *models.Review) error
+	GetReviewsByMaster(ctx context.Context, masterID primitive.ObjectID, filter models.ReviewFilter, page db.PageRequest) (*db.PageResponse[*models.Review], error)
+	GetAverageRating(ctx context.Context, masterID primitive.ObjectID) (*models.RatingSummary, error)
+
+	// Utility
+	EnsureIndexes(ctx context.Context) error
+}
+
+func (r *reviewRepositoryImpl) EnsureIndexes(ctx context.Context) error {
+	r.logger.Info("Creating database indexes")
+
+	_, err := r.reviewsCol.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{
+			// one review per order per reviewer
+			Keys:    bson.D{{Key: "order_id", Value: 1}, {Key: "reviewer_id", Value: 1}},
+			Options: options.Index().SetUnique(true).SetName("idx_reviews_order_reviewer_unique"),
+		},
+		{
+			Keys:    bson.D{{Key: "master_id", Value: 1}, {Key: "created_at", Value: -1}},
+			Options: options.Index().SetName("idx_reviews_master_created"),
+		},
+		{
+			// rating filters and rating sorts
+			Keys:    bson.D{{Key: "master_id", Value: 1}, {Key: "rating", Value: -1}, {Key: "created_at", Value: -1}},
+			Options: options.Index().SetName("idx_reviews_master_rating"),
+		},
+	})
+	if err != nil {
+		r.logger.Error("Failed to create reviews indexes", "error", err)
+		return fmt.Errorf("create reviews indexes: %w", err)
+	}
+
+	r.logger.Info("Database indexes created successfully")
+	return nil
+}
+
+func (r *reviewRepositoryImpl) CreateReview(ctx context.Context, review *models.Review) error {
+	r.logger.Info("Creating review", "order_id", review.OrderID.Hex(), "reviewer_id", review.ReviewerID.Hex())
+
+	review.BeforeCreate()
+
+	_, err := r.reviewsCol.InsertOne(ctx, review)
+	if err != nil {
+		if mongo.IsDuplicateKeyError(err) {
+			r.logger.Warn("Review already exists", "order_id", review.OrderID.Hex(), "reviewer_id", review.ReviewerID.Hex())
+			return et.NewConflictError("review for this order already exists", err)
+		}
+		r.logger.Error("Failed to insert review", "error", err)
+		return et.NewDatabaseError("failed to create review", err)
+	}
+
+	r.logger.Info("Review created successfully", "review_id", review.ID.Hex())
+	return nil
+}
+
+func (r *reviewRepositoryImpl) GetReviewsByMaster(ctx context.Context, masterID primitive.ObjectID, filter models.ReviewFilter, page db.PageRequest) (*db.PageResponse[*models.Review], error) {
+	r.logger.Info("Fetching reviews by master", "master_id", masterID.Hex(), "page", page.Page, "page_size", page.PageSize, "sort", filter.Sort)
+
+	result, err := db.Paginate[*models.Review](ctx, r.reviewsCol, reviewsQuery(masterID, filter), page, reviewsSort(filter.Sort))
+	if err != nil {
+		r.logger.Error("Failed to fetch reviews", "error", err)
+		return nil, et.NewDatabaseError("failed to fetch reviews", err)
+	}
+
+	r.logger.Info("Reviews fetched successfully", "master_id", masterID.Hex(), "count", len(result.Items), "total", result.Total)
+	return result, nil
+}
+
+func reviewsQuery(masterID primitive.ObjectID, filter models.ReviewFilter) bson.M {
+	query := bson.M{"master_id": masterID}
+
+	rating := bson.M{}
+	if filter.MinRating > 0 {
+		rating["$gte"] = filter.MinRating
+	}
+	if filter.MaxRating > 0 {
+		rating["$lte"] = filter.MaxRating
+	}
+	if len(rating) > 0 {
+		query["rating"] = rating
+	}
+
+	created := bson.M{}
+	if filter.CreatedFrom != nil {
+		created["$gte"] = *filter.CreatedFrom
+	}
+	if filter.CreatedTo != nil {
+		created["$lte"] = *filter.CreatedTo
+	}
+	if len(created) > 0 {
+		query["created_at"] = created
+	}
+	return query
+}
+
+// every order ends in _id so equal ratings or timestamps page deterministically
+func reviewsSort(sort string) bson.D {
+	switch sort {
+	case models.SortOldest:
+		return bson.D{{Key: "created_at", Value: 1}, {Key: "_id", Value: 1}}
+	case models.SortRatingDesc:
+		return bson.D{{Key: "rating", Value: -1}, {Key: "created_at", Value: -1}, {Key: "_id", Value: -1}}
+	case models.SortRatingAsc:
+		return bson.D{{Key: "rating", Value: 1}, {Key: "created_at", Value: -1}, {Key: "_id", Value: -1}}
+	default:
+		return bson.D{{Key: "created_at", Value: -1}, {Key: "_id", Value: -1}}
+	}
+}
+
+// GetAverageRating counts reviews per star in one pass, average and total follow from the distribution
+func (r *reviewRepositoryImpl) GetAverageRating(ctx context.Context, masterID primitive.ObjectID) (*models.RatingSummary, error) {
+	r.logger.Info("Calculating average rating", "master_id", masterID.Hex())
+
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: bson.M{"master_id": masterID}}},
+		{{Key: "$group", Value: bson.M{
+			"_id":   "$rating",
+			"count": bson.M{"$sum": 1},
+		}}},
+	}
+
+	cursor, err := r.reviewsCol.Aggregate(ctx, pipeline)
+	if err != nil {
+		r.logger.Error("Failed to aggregate rating", "error", err)
+		return nil, et.NewDatabaseError("failed to calculate rating", err)
+	}
+	defer cursor.Close(ctx)
+
+	var buckets []ratingBucket
+	if err := cursor.All(ctx, &buckets); err != nil {
+		r.logger.Error("Failed to decode rating aggregate", "error", err)
+		return nil, et.NewDatabaseError("failed to decode rating", err)
+	}
+
+	summary := summarizeRatings(masterID.Hex(), buckets)
+	r.logger.Info("Average rating calculated", "master_id", masterID.Hex(), "average", summary.Average, "count", summary.Count)
+	return summary, nil
+}
+
+type ratingBucket struct {
+	Rating int   `bson:"_id"`
+	Count  int64 `bson:"count"`
+}
+
+// summarizeRatings fills every star 1-5 so clients can draw the histogram without gaps,
+// a master without reviews has average 0
+func summarizeRatings(masterID string, buckets []ratingBucket) *models.RatingSummary {
+	summary := &models.RatingSummary{
+		MasterID:     masterID,
+		Distribution: make(map[int]int64, models.MaxRating),
+	}
+	for star := models.MinRating; star <= models.MaxRating; star++ {
+		summary.Distribution[star] = 0
+	}
+
+	var sum int64
+	for _, b := range buckets {
+		summary.Distribution[b.Rating] += b.Count
+		summary.Count += b.Count
+		sum += int64(b.Rating) * b.Count
+	}
+	if summary.Count > 0 {
+		summary.Average = float64(sum) / float64(summary.Count)
+	}
+	return summary
+}
@@ -0,0 +1,211 @@
+package repositories
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"log/slog"
+	"reflect"
+	"testing"
+	"time"
+
+	models "remaster/services/review/models"
+	"remaster/shared/db"
+	et "remaster/shared/errors"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/integration/mtest"
+)
+
+func newMockRepo(mt *mtest.T) *reviewRepositoryImpl {
+	return &reviewRepositoryImpl{
+		reviewsCol: mt.Coll,
+		logger:     slog.New(slog.NewTextHandler(io.Discard, nil)),
+	}
+}
+
+func newReview() *models.Review {
+	return &models.Review{
+		OrderID:    primitive.NewObjectID(),
+		ReviewerID: primitive.NewObjectID(),
+		MasterID:   primitive.NewObjectID(),
+		Rating:     4,
+	}
+}
+
+func TestCreateReview(t *testing.T) {
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+
+	mt.Run("stores the review", func(mt *mtest.T) {
+		mt.AddMockResponses(mtest.CreateSuccessResponse())
+		review := newReview()
+
+		if err := newMockRepo(mt).CreateReview(mt.Context(), review); err != nil {
+			mt.Fatalf("CreateReview: %v", err)
+		}
+		if review.ID.IsZero() || review.CreatedAt.IsZero() {
+			mt.Fatalf("review = %+v, want id and timestamps set", review)
+		}
+	})
+
+	mt.Run("duplicate order and reviewer is a conflict", func(mt *mtest.T) {
+		mt.AddMockResponses(mtest.CreateWriteErrorsResponse(mtest.WriteError{
+			Index:   0,
+			Code:    11000,
+			Message: "E11000 duplicate key error collection: remaster.reviews index: idx_reviews_order_reviewer_unique",
+		}))
+
+		err := newMockRepo(mt).CreateReview(mt.Context(), newReview())
+		var appErr *et.AppError
+		if !errors.As(err, &appErr) || appErr.Code != et.CodeConflict {
+			mt.Fatalf("error = %v, want a conflict", err)
+		}
+	})
+
+	mt.Run("other write errors are database errors", func(mt *mtest.T) {
+		mt.AddMockResponses(mtest.CreateWriteErrorsResponse(mtest.WriteError{Code: 121, Message: "Document failed validation"}))
+
+		err := newMockRepo(mt).CreateReview(mt.Context(), newReview())
+		var appErr *et.AppError
+		if !errors.As(err, &appErr) || appErr.Code != et.CodeDatabase {
+			mt.Fatalf("error = %v, want a database error", err)
+		}
+	})
+}
+
+func TestGetAverageRating(t *testing.T) {
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+
+	mt.Run("averages the star buckets", func(mt *mtest.T) {
+		ns := mt.Coll.Database().Name() + "." + mt.Coll.Name()
+		mt.AddMockResponses(mtest.CreateCursorResponse(0, ns, mtest.FirstBatch,
+			bson.D{{Key: "_id", Value: 5}, {Key: "count", Value: int64(3)}},
+			bson.D{{Key: "_id", Value: 4}, {Key: "count", Value: int64(1)}},
+			bson.D{{Key: "_id", Value: 1}, {Key: "count", Value: int64(1)}},
+		))
+		masterID := primitive.NewObjectID()
+
+		summary, err := newMockRepo(mt).GetAverageRating(mt.Context(), masterID)
+		if err != nil {
+			mt.Fatalf("GetAverageRating: %v", err)
+		}
+		// (5*3 + 4 + 1) / 5
+		if summary.Average != 4 || summary.Count != 5 {
+			mt.Fatalf("average = %v over %d, want 4 over 5", summary.Average, summary.Count)
+		}
+		if summary.MasterID != masterID.Hex() {
+			mt.Fatalf("MasterID = %q, want %q", summary.MasterID, masterID.Hex())
+		}
+		pipeline := mt.GetStartedEvent().Command.Lookup("pipeline").Array()
+		if got := pipeline.Index(0).Value().Document().Lookup("$match", "master_id").ObjectID(); got != masterID {
+			mt.Fatalf("pipeline matches master %s, want %s", got.Hex(), masterID.Hex())
+		}
+		if got := pipeline.Index(1).Value().Document().Lookup("$group", "_id").StringValue(); got != "$rating" {
+			mt.Fatalf("pipeline groups by %q, want one bucket per star", got)
+		}
+		want := map[int]int64{1: 1, 2: 0, 3: 0, 4: 1, 5: 3}
+		for star, count := range want {
+			if summary.Distribution[star] != count {
+				mt.Fatalf("distribution = %v, want %v", summary.Distribution, want)
+			}
+		}
+	})
+
+	mt.Run("master without reviews", func(mt *mtest.T) {
+		ns := mt.Coll.Database().Name() + "." + mt.Coll.Name()
+		mt.AddMockResponses(mtest.CreateCursorResponse(0, ns, mtest.FirstBatch))
+
+		summary, err := newMockRepo(mt).GetAverageRating(mt.Context(), primitive.NewObjectID())
+		if err != nil {
+			mt.Fatalf("GetAverageRating: %v", err)
+		}
+		if summary.Average != 0 || summary.Count != 0 || len(summary.Distribution) != models.MaxRating {
+			mt.Fatalf("summary = %+v, want zero average with every star present", summary)
+		}
+	})
+}
+
+func TestGetReviewsByMasterFilters(t *testing.T) {
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+	masterID := primitive.NewObjectID()
+	from := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	to := from.AddDate(0, 1, 0)
+
+	tests := []struct {
+		name   string
+		filter models.ReviewFilter
+		want   bson.M
+	}{
+		{"no filter", models.ReviewFilter{}, bson.M{"master_id": masterID}},
+		{"min rating", models.ReviewFilter{MinRating: 4}, bson.M{"master_id": masterID, "rating": bson.M{"$gte": 4}}},
+		{"rating range", models.ReviewFilter{MinRating: 2, MaxRating: 3},
+			bson.M{"master_id": masterID, "rating": bson.M{"$gte": 2, "$lte": 3}}},
+		{"created to", models.ReviewFilter{CreatedTo: &to}, bson.M{"master_id": masterID, "created_at": bson.M{"$lte": to}}},
+		{"combined", models.ReviewFilter{MaxRating: 2, CreatedFrom: &from, CreatedTo: &to},
+			bson.M{"master_id": masterID, "rating": bson.M{"$lte": 2}, "created_at": bson.M{"$gte": from, "$lte": to}}},
+	}
+	for _, tt := range tests {
+		mt.Run(tt.name, func(mt *mtest.T) {
+			find := listReviews(mt, masterID, tt.filter)
+			want, _ := bson.Marshal(tt.want)
+			if got := find.Lookup("filter").Document(); !reflect.DeepEqual(asMap(mt, got), asMap(mt, want)) {
+				mt.Fatalf("filter = %s, want %s", got, bson.Raw(want))
+			}
+		})
+	}
+}
+
+func TestGetReviewsByMasterSortOrders(t *testing.T) {
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+
+	tests := []struct {
+		sort string
+		want bson.D
+	}{
+		{"", bson.D{{Key: "created_at", Value: -1}, {Key: "_id", Value: -1}}},
+		{models.SortNewest, bson.D{{Key: "created_at", Value: -1}, {Key: "_id", Value: -1}}},
+		{models.SortOldest, bson.D{{Key: "created_at", Value: 1}, {Key: "_id", Value: 1}}},
+		{models.SortRatingDesc, bson.D{{Key: "rating", Value: -1}, {Key: "created_at", Value: -1}, {Key: "_id", Value: -1}}},
+		{models.SortRatingAsc, bson.D{{Key: "rating", Value: 1}, {Key: "created_at", Value: -1}, {Key: "_id", Value: -1}}},
+	}
+	for _, tt := range tests {
+		mt.Run("sort "+tt.sort, func(mt *mtest.T) {
+			find := listReviews(mt, primitive.NewObjectID(), models.ReviewFilter{Sort: tt.sort})
+			// key order matters here, so compare the encoded documents
+			want, _ := bson.Marshal(tt.want)
+			if got := find.Lookup("sort").Document(); !bytes.Equal(got, want) {
+				mt.Fatalf("sort = %s, want %s", got, bson.Raw(want))
+			}
+		})
+	}
+}
+
+// listReviews fetches one page through the repository and returns the find command it sent
+func listReviews(mt *mtest.T, masterID primitive.ObjectID, filter models.ReviewFilter) bson.Raw {
+	ns := mt.Coll.Database().Name() + "." + mt.Coll.Name()
+	mt.AddMockResponses(
+		mtest.CreateCursorResponse(0, ns, mtest.FirstBatch, bson.D{{Key: "n", Value: 1}}),
+		mtest.CreateCursorResponse(0, ns, mtest.FirstBatch, bson.D{{Key: "_id", Value: primitive.NewObjectID()}, {Key: "rating", Value: 5}}),
+	)
+
+	result, err := newMockRepo(mt).GetReviewsByMaster(mt.Context(), masterID, filter, db.PageRequest{Page: 1, PageSize: 10})
+	if err != nil {
+		mt.Fatalf("GetReviewsByMaster: %v", err)
+	}
+	if result.Total != 1 || len(result.Items) != 1 || result.Items[0].Rating != 5 {
+		mt.Fatalf("result = %d of %d, want the one review", len(result.Items), result.Total)
+	}
+
+	mt.GetStartedEvent() // count
+	return mt.GetStartedEvent().Command
+}
+
+// asMap decodes a document so filters compare regardless of key order
+func asMap(mt *mtest.T, raw bson.Raw) bson.M {
+	var m bson.M
+	if err := bson.Unmarshal(raw, &m); err != nil {
+		mt.Fatalf("unmarshal %s: %v", raw, err)
+	}
+	return m
+}
@@ -0,0 +1,84 @@
+package services
+
+import (
+	"context"
+	"log/slog"
+
+	"remaster/services/review/models"
+	repo "remaster/services/review/repositories"
+	"remaster/shared/db"
+	et "remaster/shared/errors"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type ReviewService struct {
+	repo   repo.ReviewRepositoryInterface
+	logger *slog.Logger
+}
+
+func NewReviewService(reviewRepo repo.ReviewRepositoryInterface, logger *slog.Logger) *ReviewService {
+	return &ReviewService{
+		repo:   reviewRepo,
+		logger: logger.With(slog.String("review", "service")),
+	}
+}
+
+func (s *ReviewService) CreateReview(ctx context.Context, req *models.CreateReviewRequest) (*models.Review, error) {
+	s.logger.Info("Creating review", "order_id", req.OrderID, "master_id", req.MasterID)
+
+	if err := req.Validate(); err != nil {
+		s.logger.Warn("Validation failed for review", "error", err)
+		return nil, err
+	}
+
+	// ids are validated above
+	orderID, _ := primitive.ObjectIDFromHex(req.OrderID)
+	reviewerID, _ := primitive.ObjectIDFromHex(req.ReviewerID)
+	masterID, _ := primitive.ObjectIDFromHex(req.MasterID)
+
+	review := &models.Review{
+		OrderID:    orderID,
+		ReviewerID: reviewerID,
+		MasterID:   masterID,
+		Rating:     req.Rating,
+		Comment:    req.Comment,
+	}
+
+	if err := s.repo.CreateReview(ctx, review); err != nil {
+		s.logger.Warn("Failed to create review", "error", err)
+		return nil, err
+	}
+
+	s.logger.Info("Review created successfully", "review_id", review.ID.Hex())
+	return review, nil
+}
+
+func (s *ReviewService) GetReviewsByMaster(ctx context.Context, masterIDHex string, filter models.ReviewFilter, page db.PageRequest) (*db.PageResponse[*models.Review], error) {
+	s.logger.Info("Listing reviews", "master_id", masterIDHex)
+
+	if err := filter.Validate(); err != nil {
+		s.logger.Warn("Invalid review filter", "error", err)
+		return nil, err
+	}
+
+	masterID, err := primitive.ObjectIDFromHex(masterIDHex)
+	if err != nil {
+		s.logger.Warn("Invalid master ID", "master_id", masterIDHex, "error", err)
+		return nil, et.NewValidationError("invalid master id", map[string]string{"master_id": "must be a valid id"})
+	}
+
+	return s.repo.GetReviewsByMaster(ctx, masterID, filter, page)
+}
+
+func (s *ReviewService) GetAverageRating(ctx context.Context, masterIDHex string) (*models.RatingSummary, error) {
+	s.logger.Info("Getting average rating", "master_id", masterIDHex)
+
+	masterID, err := primitive.ObjectIDFromHex(masterIDHex)
+	if err != nil {
+		s.logger.Warn("Invalid master ID", "master_id", masterIDHex, "error", err)
+		return nil, et.NewValidationError("invalid master id", map[string]string{"master_id": "must be a valid id"})
+	}
+
+	return s.repo.GetAverageRating(ctx, masterID)
+}
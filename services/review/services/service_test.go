@@ -0,0 +1,167 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"testing"
+	"time"
+
+	"remaster/services/review/models"
+	"remaster/shared/db"
+	et "remaster/shared/errors"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// fakeRepo enforces the unique (order_id, reviewer_id) index in memory
+type fakeRepo struct {
+	reviews []*models.Review
+}
+
+func (r *fakeRepo) CreateReview(_ context.Context, review *models.Review) error {
+	for _, existing := range r.reviews {
+		if existing.OrderID == review.OrderID && existing.ReviewerID == review.ReviewerID {
+			return et.NewConflictError("review for this order already exists", nil)
+		}
+	}
+	review.BeforeCreate()
+	r.reviews = append(r.reviews, review)
+	return nil
+}
+
+func (r *fakeRepo) GetReviewsByMaster(context.Context, primitive.ObjectID, models.ReviewFilter, db.PageRequest) (*db.PageResponse[*models.Review], error) {
+	return &db.PageResponse[*models.Review]{}, nil
+}
+
+func (r *fakeRepo) GetAverageRating(_ context.Context, masterID primitive.ObjectID) (*models.RatingSummary, error) {
+	return &models.RatingSummary{MasterID: masterID.Hex()}, nil
+}
+
+func (r *fakeRepo) EnsureIndexes(context.Context) error { return nil }
+
+func newTestService() (*ReviewService, *fakeRepo) {
+	repo := &fakeRepo{}
+	return NewReviewService(repo, slog.New(slog.NewTextHandler(io.Discard, nil))), repo
+}
+
+func assertErrorCode(t *testing.T, err error, want et.ErrorCode) *et.AppError {
+	t.Helper()
+	var appErr *et.AppError
+	if !errors.As(err, &appErr) {
+		t.Fatalf("error = %v, want an AppError with code %s", err, want)
+	}
+	if appErr.Code != want {
+		t.Fatalf("error code = %s (%v), want %s", appErr.Code, err, want)
+	}
+	return appErr
+}
+
+func newRequest() *models.CreateReviewRequest {
+	return &models.CreateReviewRequest{
+		OrderID:    primitive.NewObjectID().Hex(),
+		ReviewerID: primitive.NewObjectID().Hex(),
+		MasterID:   primitive.NewObjectID().Hex(),
+		Rating:     5,
+		Comment:    "Fixed the sink in twenty minutes",
+	}
+}
+
+func TestCreateReview(t *testing.T) {
+	svc, repo := newTestService()
+	req := newRequest()
+
+	review, err := svc.CreateReview(context.Background(), req)
+	if err != nil {
+		t.Fatalf("CreateReview: %v", err)
+	}
+	if review.MasterID.Hex() != req.MasterID || review.Rating != 5 || review.ID.IsZero() {
+		t.Fatalf("review = %+v, want it built from the request", review)
+	}
+	if len(repo.reviews) != 1 {
+		t.Fatalf("stored %d reviews, want 1", len(repo.reviews))
+	}
+}
+
+func TestCreateReviewRejectsDuplicate(t *testing.T) {
+	svc, repo := newTestService()
+	req := newRequest()
+	if _, err := svc.CreateReview(context.Background(), req); err != nil {
+		t.Fatalf("first CreateReview: %v", err)
+	}
+
+	again := *req
+	again.Rating = 1
+	_, err := svc.CreateReview(context.Background(), &again)
+	assertErrorCode(t, err, et.CodeConflict)
+	if len(repo.reviews) != 1 {
+		t.Fatalf("stored %d reviews, want the duplicate rejected", len(repo.reviews))
+	}
+}
+
+func TestCreateReviewValidates(t *testing.T) {
+	tests := []struct {
+		name   string
+		modify func(*models.CreateReviewRequest)
+		field  string
+	}{
+		{name: "rating too low", modify: func(r *models.CreateReviewRequest) { r.Rating = 0 }, field: "rating"},
+		{name: "rating too high", modify: func(r *models.CreateReviewRequest) { r.Rating = 6 }, field: "rating"},
+		{name: "invalid order id", modify: func(r *models.CreateReviewRequest) { r.OrderID = "order-1" }, field: "order_id"},
+		{name: "master reviews themselves", modify: func(r *models.CreateReviewRequest) { r.ReviewerID = r.MasterID }, field: "reviewer_id"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			svc, repo := newTestService()
+			req := newRequest()
+			tt.modify(req)
+
+			_, err := svc.CreateReview(context.Background(), req)
+			appErr := assertErrorCode(t, err, et.CodeValidation)
+			if _, ok := appErr.Details[tt.field]; !ok {
+				t.Fatalf("details = %v, want an error for %s", appErr.Details, tt.field)
+			}
+			if len(repo.reviews) != 0 {
+				t.Fatal("invalid review was stored")
+			}
+		})
+	}
+}
+
+func TestGetAverageRatingRejectsInvalidMasterID(t *testing.T) {
+	svc, _ := newTestService()
+	_, err := svc.GetAverageRating(context.Background(), "not-an-id")
+	assertErrorCode(t, err, et.CodeValidation)
+}
+
+func TestGetReviewsByMasterValidatesFilter(t *testing.T) {
+	svc, _ := newTestService()
+	masterID := primitive.NewObjectID().Hex()
+	from := time.Date(2026, 2, 1, 0, 0, 0, 0, time.UTC)
+	to := from.AddDate(0, -1, 0)
+
+	tests := []struct {
+		name   string
+		filter models.ReviewFilter
+		field  string
+	}{
+		{"rating out of range", models.ReviewFilter{MinRating: 6}, "min_rating"},
+		{"inverted rating range", models.ReviewFilter{MinRating: 4, MaxRating: 2}, "min_rating"},
+		{"inverted date range", models.ReviewFilter{CreatedFrom: &from, CreatedTo: &to}, "created_from"},
+		{"unknown sort", models.ReviewFilter{Sort: "helpful"}, "sort"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := svc.GetReviewsByMaster(context.Background(), masterID, tt.filter, db.PageRequest{})
+			if appErr := assertErrorCode(t, err, et.CodeValidation); appErr.Details[tt.field] == "" {
+				t.Fatalf("details = %v, want a violation for %s", appErr.Details, tt.field)
+			}
+		})
+	}
+
+	valid := models.ReviewFilter{MinRating: 3, MaxRating: 5, CreatedFrom: &to, CreatedTo: &from, Sort: models.SortRatingDesc}
+	if _, err := svc.GetReviewsByMaster(context.Background(), masterID, valid, db.PageRequest{}); err != nil {
+		t.Fatalf("valid filter rejected: %v", err)
+	}
+}
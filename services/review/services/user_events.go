@@ -0,0 +1,18 @@
+package services
+
+import (
+	"context"
+
+	"remaster/shared/events"
+)
+
+// HandleUserEvent is the sample consumer of auth user events, it only logs them for now.
+// Handlers must be idempotent since events can be delivered more than once
+func (s *ReviewService) HandleUserEvent(ctx context.Context, event events.UserEvent) error {
+	s.logger.InfoContext(ctx, "Received user event",
+		"type", event.Type,
+		"user_id", event.UserID,
+		"occurred_at", event.OccurredAt,
+	)
+	return nil
+}
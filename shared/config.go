@@ -3,24 +3,32 @@ package config
 import (
 	"fmt"
 	"log"
+	"net/netip"
 	"strings"
+	"sync/atomic"
 	"time"
 
+	"github.com/fsnotify/fsnotify"
+	"github.com/go-viper/mapstructure/v2"
 	"github.com/spf13/viper"
 )
 
 type Config struct {
-	App      AppConfig              `mapstructure:"app"`
-	HTTP     HTTPConfig             `mapstructure:"http"`
-	GRPC     GRPCConfig             `mapstructure:"grpc"`
-	Mongo    MongoConfig            `mapstructure:"mongo"`
-	Redis    RedisConfig            `mapstructure:"redis"`
-	JWT      JWTConfig              `mapstructure:"jwt"`
-	OAuth    OAuthConfig            `mapstructure:"oauth"`
-	AWS      AWSConfig              `mapstructure:"aws"`
-	Kafka    KafkaConfig            `mapstructure:"kafka"`
-	Log      LogConfig              `mapstructure:"log"`
-	Services map[string]ServiceAddr `mapstructure:"services"`
+	App       AppConfig              `mapstructure:"app"`
+	HTTP      HTTPConfig             `mapstructure:"http"`
+	GRPC      GRPCConfig             `mapstructure:"grpc"`
+	Retry     RetryConfig            `mapstructure:"retry"`
+	RateLimit RateLimitConfig        `mapstructure:"rate_limit"`
+	Mongo     MongoConfig            `mapstructure:"mongo"`
+	Redis     RedisConfig            `mapstructure:"redis"`
+	JWT       JWTConfig              `mapstructure:"jwt"`
+	Security  SecurityConfig         `mapstructure:"security"`
+	OAuth     OAuthConfig            `mapstructure:"oauth"`
+	AWS       AWSConfig              `mapstructure:"aws"`
+	Kafka     KafkaConfig            `mapstructure:"kafka"`
+	Media     MediaConfig            `mapstructure:"media"`
+	Log       LogConfig              `mapstructure:"log"`
+	Services  map[string]ServiceAddr `mapstructure:"services"`
 }
 
 type AppConfig struct {
@@ -36,6 +44,42 @@ type HTTPConfig struct {
 	ReadTimeout     time.Duration `mapstructure:"read_timeout"`
 	WriteTimeout    time.Duration `mapstructure:"write_timeout"`
 	ShutdownTimeout time.Duration `mapstructure:"shutdown_timeout"`
+	// deadline for the whole request including downstream calls, 0 = none
+	RequestTimeout time.Duration `mapstructure:"request_timeout"`
+
+	SecurityHeaders SecurityHeadersConfig `mapstructure:"security_headers"`
+	// proxies whose X-Forwarded-For is believed, IPs or CIDRs. The client IP
+	// feeds rate limiting, so anything broader than your load balancers lets
+	// callers pick their own rate limit key
+	TrustedProxies []string `mapstructure:"trusted_proxies"`
+
+	// versioned API groups live under it, e.g. /api -> /api/v1/auth/login
+	BasePath string `mapstructure:"base_path"`
+	// also serve the pre-versioning root paths (/auth/...), marked deprecated
+	LegacyRoutes bool `mapstructure:"legacy_routes"`
+	// how long a response is replayed for a repeated Idempotency-Key
+	IdempotencyTTL time.Duration `mapstructure:"idempotency_ttl"`
+
+	Compression CompressionConfig `mapstructure:"compression"`
+}
+
+// gzip for gateway responses, only for clients that send Accept-Encoding: gzip
+type CompressionConfig struct {
+	Enabled bool `mapstructure:"enabled"`
+	// smaller bodies cost more cpu than they save
+	MinBytes int `mapstructure:"min_bytes" validate:"min=0"`
+	// media types without parameters, e.g. application/json
+	ContentTypes []string `mapstructure:"content_types"`
+}
+
+// response headers set by the gateway, empty values are not sent
+type SecurityHeadersConfig struct {
+	ContentTypeOptions string `mapstructure:"content_type_options"`
+	FrameOptions       string `mapstructure:"frame_options"`
+	ReferrerPolicy     string `mapstructure:"referrer_policy"`
+	// Strict-Transport-Security is only sent in production, 0 = never
+	HSTSMaxAge            time.Duration `mapstructure:"hsts_max_age"`
+	HSTSIncludeSubdomains bool          `mapstructure:"hsts_include_subdomains"`
 }
 
 type GRPCConfig struct {
@@ -46,6 +90,57 @@ type GRPCConfig struct {
 	ConnectionTimeout time.Duration `mapstructure:"connection_timeout"`
 	EnableReflection  bool          `mapstructure:"enable_reflection"`
 	EnableHealthCheck bool          `mapstructure:"enable_health_check"`
+
+	// per connection stream cap and connection cycling for load balancing, 0 = unlimited
+	MaxConcurrentStreams  uint32        `mapstructure:"max_concurrent_streams"`
+	MaxConnectionIdle     time.Duration `mapstructure:"max_connection_idle"`
+	MaxConnectionAge      time.Duration `mapstructure:"max_connection_age"`
+	MaxConnectionAgeGrace time.Duration `mapstructure:"max_connection_age_grace"`
+
+	// applied when the caller sent no deadline, 0 = no server side deadline
+	DefaultTimeout time.Duration   `mapstructure:"default_timeout"`
+	MethodTimeouts []MethodTimeout `mapstructure:"method_timeouts"`
+
+	// total size of incoming request metadata (keys + values), 0 = no limit
+	MaxMetadataBytes int `mapstructure:"max_metadata_bytes"`
+
+	// gzip for messages of at least CompressionMinBytes, smaller ones aren't worth the cpu
+	Compression         bool `mapstructure:"compression"`
+	CompressionMinBytes int  `mapstructure:"compression_min_bytes"`
+
+	// debug logs of request bodies with passwords and tokens masked, needs log level debug
+	LogRequestBodies bool `mapstructure:"log_request_bodies"`
+
+	// IPs or CIDRs of the gateways, only they may pass the client address in
+	// x-forwarded-for / x-real-ip. Other callers are identified by their socket address
+	TrustedPeers []string `mapstructure:"trusted_peers"`
+
+	// gateway only: fail startup unless every downstream service connects and passes its
+	// health check within connection_timeout. Off = connect lazily on the first request
+	RequireDownstreamOnStart bool `mapstructure:"require_downstream_on_start"`
+}
+
+// per method override of grpc.default_timeout, method is the full gRPC method name
+type MethodTimeout struct {
+	Method  string        `mapstructure:"method"`
+	Timeout time.Duration `mapstructure:"timeout"`
+}
+
+// gateway per-IP rate limiting
+type RateLimitConfig struct {
+	Requests int           `mapstructure:"requests"`
+	Window   time.Duration `mapstructure:"window"`
+	// let traffic through when Redis is unreachable instead of rejecting it
+	FailOpen bool `mapstructure:"fail_open"`
+}
+
+// client side retries for gateway -> service calls
+type RetryConfig struct {
+	MaxAttempts    int           `mapstructure:"max_attempts"`
+	InitialBackoff time.Duration `mapstructure:"initial_backoff"`
+	MaxBackoff     time.Duration `mapstructure:"max_backoff"`
+	// full gRPC method names that are safe to retry, opt-in only
+	Methods []string `mapstructure:"methods"`
 }
 
 type MongoConfig struct {
@@ -55,9 +150,18 @@ type MongoConfig struct {
 	MinPoolSize     uint64        `mapstructure:"min_pool_size"`
 	ConnectTimeout  time.Duration `mapstructure:"connect_timeout"`
 	ServerSelection time.Duration `mapstructure:"server_selection_timeout"`
+	// keep retrying the initial connect with backoff for this long, 0 = single attempt
+	ConnectRetryMaxElapsed time.Duration `mapstructure:"connect_retry_max_elapsed"`
+	// run WithTransaction bodies without a transaction on a standalone mongod, dev only
+	AllowNonTxFallback bool `mapstructure:"allow_non_tx_fallback"`
+	// refresh token writes wait for a majority and reads go to the primary, so a
+	// revoked token can't be read back from a lagging secondary
+	TokenWriteMajority bool `mapstructure:"token_write_majority"`
+	TokenReadPrimary   bool `mapstructure:"token_read_primary"`
 }
 
 type RedisConfig struct {
+	Mode         string        `mapstructure:"mode" validate:"oneof=single sentinel cluster"`
 	Host         string        `mapstructure:"host" validate:"required"`
 	Port         string        `mapstructure:"port" validate:"required"`
 	Password     string        `mapstructure:"password"`
@@ -67,6 +171,14 @@ type RedisConfig struct {
 	DialTimeout  time.Duration `mapstructure:"dial_timeout"`
 	ReadTimeout  time.Duration `mapstructure:"read_timeout"`
 	WriteTimeout time.Duration `mapstructure:"write_timeout"`
+
+	// sentinel mode
+	MasterName       string   `mapstructure:"master_name"`
+	SentinelAddrs    []string `mapstructure:"sentinel_addrs"`
+	SentinelPassword string   `mapstructure:"sentinel_password"`
+
+	// cluster mode
+	ClusterAddrs []string `mapstructure:"cluster_addrs"`
 }
 
 type JWTConfig struct {
@@ -75,17 +187,100 @@ type JWTConfig struct {
 	RefreshTokenTTL time.Duration `mapstructure:"refresh_token_ttl"`
 	Issuer          string        `mapstructure:"issuer"`
 	Audience        string        `mapstructure:"audience"`
+	LeewaySeconds   int           `mapstructure:"leeway_seconds"`
+
+	// anonymous browse tokens, guests get no refresh token
+	GuestTokenTTL time.Duration `mapstructure:"guest_token_ttl"`
+	// ValidateToken recommends a refresh once less than this is left
+	RefreshHintThreshold time.Duration `mapstructure:"refresh_hint_threshold"`
+
+	// opaque refresh tokens: random bytes encoded as hex or base64url
+	RefreshTokenBytes    int    `mapstructure:"refresh_token_bytes"`
+	RefreshTokenEncoding string `mapstructure:"refresh_token_encoding"`
+
+	// key rotation: tokens signed with the previous key are accepted until
+	// PreviousKeyValidUntil (RFC 3339), new tokens always use SecretKey. Set it to
+	// the rotation time plus access_token_ttl so the old key can't mint tokens for long
+	PreviousSecretKey     string    `mapstructure:"previous_secret_key"`
+	PreviousKeyValidUntil time.Time `mapstructure:"previous_key_valid_until"`
+
+	// expired/revoked refresh token cleanup
+	TokenCleanupInterval  time.Duration `mapstructure:"token_cleanup_interval"`
+	RevokedTokenRetention time.Duration `mapstructure:"revoked_token_retention"`
+}
+
+type SecurityConfig struct {
+	BcryptCost int                  `mapstructure:"bcrypt_cost"`
+	Password   PasswordPolicyConfig `mapstructure:"password"`
+	// bcrypt or argon2id for new hashes, logins verify either and upgrade to this one
+	PasswordHashAlgorithm string       `mapstructure:"password_hash_algorithm"`
+	Argon2                Argon2Config `mapstructure:"argon2"`
+	// oldest sessions are revoked past this many, 0 = unlimited
+	MaxActiveSessions int `mapstructure:"max_active_sessions"`
+	// ISO 3166 region assumed for phone numbers without a +country prefix
+	PhoneDefaultRegion string        `mapstructure:"phone_default_region"`
+	Lockout            LockoutConfig `mapstructure:"lockout"`
+	// user types that may register themselves, the rest need an admin
+	AllowedSelfRegistrationTypes []string `mapstructure:"allowed_self_registration_types"`
+	// email domains OAuth may create new users for, empty = any. Existing users can always log in
+	AllowedOAuthDomains []string `mapstructure:"allowed_oauth_domains"`
+	// logins with an older password get a short lived token for changing it, 0 = never expires
+	MaxPasswordAge time.Duration `mapstructure:"max_password_age"`
+	// checks refreshes against the device the refresh token was issued to
+	RefreshBinding RefreshBindingConfig `mapstructure:"refresh_binding"`
+}
+
+// RefreshBindingConfig - mode off ignores the device, log only logs a mismatch, strict
+// revokes the token and rejects the refresh. Tokens issued without a device id are never bound
+type RefreshBindingConfig struct {
+	Mode string `mapstructure:"mode"`
+	// the User-Agent has to match too, browser updates change it
+	UserAgent bool `mapstructure:"user_agent"`
+}
+
+// argon2id cost parameters, see RFC 9106 section 4
+type Argon2Config struct {
+	Time      uint32 `mapstructure:"time"`
+	MemoryKiB uint32 `mapstructure:"memory_kib"`
+	Threads   uint8  `mapstructure:"threads"`
+}
+
+// progressive account lockout: the nth lockout lasts Schedule[n], the last
+// entry repeats once the schedule is exhausted
+type LockoutConfig struct {
+	MaxAttempts int             `mapstructure:"max_attempts"`
+	Schedule    []time.Duration `mapstructure:"schedule"`
+	// failed logins allowed per client IP within IPWindow, regardless of email
+	MaxIPAttempts int           `mapstructure:"max_ip_attempts"`
+	IPWindow      time.Duration `mapstructure:"ip_window"`
+}
+
+type PasswordPolicyConfig struct {
+	MinLength     int  `mapstructure:"min_length"`
+	MaxLength     int  `mapstructure:"max_length"` // bcrypt ignores bytes past 72
+	RequireUpper  bool `mapstructure:"require_upper"`
+	RequireLower  bool `mapstructure:"require_lower"`
+	RequireDigit  bool `mapstructure:"require_digit"`
+	RequireSymbol bool `mapstructure:"require_symbol"`
+
+	// optional newline separated list of banned passwords
+	CommonPasswordsFile string `mapstructure:"common_passwords_file"`
 }
 
 type OAuthConfig struct {
-	GoogleClientID     string `mapstructure:"google_client_id" validate:"required"`
-	GoogleClientSecret string `mapstructure:"google_client_secret" validate:"required"`
-	GoogleRedirectURL  string `mapstructure:"google_redirect_url" validate:"required,url"`
-	FacebookAppID      string `mapstructure:"facebook_app_id" validate:"required"` // NOTE: not tested
-	FacebookAppSecret  string `mapstructure:"facebook_app_secret" validate:"required"` // never used facebook :)
+	// providers the auth service accepts, each needs its credentials below
+	EnabledProviders   []string `mapstructure:"enabled_providers"`
+	GoogleClientID     string   `mapstructure:"google_client_id" validate:"required"`
+	GoogleClientSecret string   `mapstructure:"google_client_secret" validate:"required"`
+	GoogleRedirectURL  string   `mapstructure:"google_redirect_url" validate:"required,url"`
+	FacebookAppID      string   `mapstructure:"facebook_app_id" validate:"required"`     // NOTE: not tested
+	FacebookAppSecret  string   `mapstructure:"facebook_app_secret" validate:"required"` // never used facebook :)
 }
 
 type AWSConfig struct {
+	// services that only probe the bucket connect when enabled, media always needs S3
+	Enabled     bool   `mapstructure:"enabled"`
+	Endpoint    string `mapstructure:"endpoint"`
 	Region      string `mapstructure:"region" validate:"required"`
 	AccessKeyID string `mapstructure:"access_key_id" validate:"required"`
 	SecretKey   string `mapstructure:"secret_access_key" validate:"required"`
@@ -94,11 +289,26 @@ type AWSConfig struct {
 }
 
 type KafkaConfig struct {
+	Enabled        bool          `mapstructure:"enabled"`
 	Brokers        []string      `mapstructure:"brokers" validate:"required,min=1"`
 	GroupID        string        `mapstructure:"group_id" validate:"required"`
 	AutoOffset     string        `mapstructure:"auto_offset"`
 	SessionTimeout time.Duration `mapstructure:"session_timeout"`
 	RetryMax       int           `mapstructure:"retry_max"`
+
+	// producers queue events and publish them in the background, each write gets PublishTimeout
+	PublishTimeout   time.Duration `mapstructure:"publish_timeout"`
+	PublishQueueSize int           `mapstructure:"publish_queue_size"`
+
+	// topics
+	UserEventsTopic string `mapstructure:"user_events_topic"`
+}
+
+type MediaConfig struct {
+	MaxUploadSize       int64         `mapstructure:"max_upload_size"`
+	AllowedContentTypes []string      `mapstructure:"allowed_content_types"`
+	UploadURLTTL        time.Duration `mapstructure:"upload_url_ttl"`
+	DownloadURLTTL      time.Duration `mapstructure:"download_url_ttl"`
 }
 
 type LogConfig struct {
@@ -106,21 +316,30 @@ type LogConfig struct {
 	Format string `mapstructure:"format" validate:"oneof=pretty json"`
 	Output string `mapstructure:"output" validate:"oneof=stdout stderr file"`
 	File   string `mapstructure:"file"`
+	// attribute keys whose values are masked, matched case-insensitively
+	RedactKeys []string `mapstructure:"redact_keys"`
+	// HTTP and gRPC requests taking longer are logged at warn with slow_request=true, 0 = off
+	SlowRequestThreshold time.Duration `mapstructure:"slow_request_threshold"`
 }
 
+// GatewayServices are dialed by the api gateway at startup
+var GatewayServices = []string{"auth", "media"}
+
 type ServiceAddr struct {
 	Name     string `mapstructure:"name"`
 	Host     string `mapstructure:"host" validate:"required"`
 	GRPCPort string `mapstructure:"grpc_port" validate:"required"`
 	HTTPPort string `mapstructure:"http_port"`
+	// serves /health, /ready and /metrics on http_port next to grpc
+	EnableHTTP bool `mapstructure:"enable_http"`
+	// connections the gateway opens to this service, 0 = 1
+	PoolSize int `mapstructure:"pool_size"`
 }
 
 // Load config data from file
 func LoadConfig(configPath ...string) (*Config, error) {
 	// Viper configuration
 	viper.AddConfigPath(".")
-
-	viper.SetConfigName("config")
 	viper.SetConfigType("yaml")
 
 	viper.SetEnvKeyReplacer(strings.NewReplacer(".", "_", "-", "_", "grpc_port", "GRPCPort"))
@@ -131,21 +350,102 @@ func LoadConfig(configPath ...string) (*Config, error) {
 	setDefaults()
 	bindEnvVars()
 
+	if err := readConfigFiles(); err != nil {
+		return nil, err
+	}
+
+	bindEnvVars()
+
+	cfg, err := decodeConfig()
+	if err != nil {
+		return nil, err
+	}
+
+	log.Println("Configuration loaded and validated successfully")
+	return cfg, nil
+}
+
+// WatchConfig re-reads the config files when the watched one changes and hands
+// the new, validated config to onChange. Only call it after LoadConfig. Callers
+// decide which settings are safe to apply at runtime; an invalid edit is logged
+// and ignored. Only config.yaml is watched, the overlay is re-merged on reload
+func WatchConfig(onChange func(*Config)) {
+	viper.OnConfigChange(func(e fsnotify.Event) {
+		if err := readConfigFiles(); err != nil {
+			log.Printf("Config reload failed: %v", err)
+			return
+		}
+		cfg, err := decodeConfig()
+		if err != nil {
+			log.Printf("Config reload rejected: %v", err)
+			return
+		}
+		log.Printf("Configuration reloaded from %s", e.Name)
+		onChange(cfg)
+	})
+	// readConfigFiles leaves the overlay name set, point the watcher at the base file
+	viper.SetConfigName("config")
+	viper.WatchConfig()
+}
+
+// Live holds a config section that can be swapped while readers use it
+type Live[T any] struct {
+	p atomic.Pointer[T]
+}
+
+func NewLive[T any](v T) *Live[T] {
+	l := &Live[T]{}
+	l.Store(v)
+	return l
+}
+
+func (l *Live[T]) Load() T {
+	return *l.p.Load()
+}
+
+func (l *Live[T]) Store(v T) {
+	l.p.Store(&v)
+}
+
+// readConfigFiles reads config.yaml and merges the environment overlay on top
+func readConfigFiles() error {
+	viper.SetConfigName("config")
+
 	// try to read config file
 	if err := viper.ReadInConfig(); err != nil {
 		if _, ok := err.(viper.ConfigFileNotFoundError); !ok {
-			return nil, fmt.Errorf("error reading config file: %w", err)
+			return fmt.Errorf("error reading config file: %w", err)
 		}
 		log.Println("Config file not found. Using environment variables and defaults.")
 	} else {
 		log.Printf("Using config file: %s", viper.ConfigFileUsed())
 	}
 
-	bindEnvVars()
+	// environment overlay (config.{env}.yaml) merged on top of the base file,
+	// the environment comes from APP_ENV or app.environment in the base file
+	if env := viper.GetString("app.environment"); env != "" {
+		viper.SetConfigName("config." + env)
+		if err := viper.MergeInConfig(); err != nil {
+			if _, ok := err.(viper.ConfigFileNotFoundError); !ok {
+				return fmt.Errorf("error reading %s config overlay: %w", env, err)
+			}
+		} else {
+			log.Printf("Merged config overlay: %s", viper.ConfigFileUsed())
+		}
+	}
+	return nil
+}
 
+func decodeConfig() (*Config, error) {
 	// parse configuration data
 	var cfg Config
-	if err := viper.Unmarshal(&cfg); err != nil {
+	// the viper defaults plus RFC 3339 timestamps
+	hook := viper.DecodeHook(mapstructure.ComposeDecodeHookFunc(
+		mapstructure.StringToTimeDurationHookFunc(),
+		mapstructure.StringToSliceHookFunc(","),
+		mapstructure.StringToTimeHookFunc(time.RFC3339),
+	))
+	if err := viper.Unmarshal(&cfg, hook); err != nil {
 		return nil, fmt.Errorf("unable to decode config: %w", err)
 	}
 
@@ -153,8 +453,6 @@ func LoadConfig(configPath ...string) (*Config, error) {
 	if err := validateConfig(&cfg); err != nil {
 		return nil, fmt.Errorf("config validation failed: %w", err)
 	}
-
-	log.Println("Configuration loaded and validated successfully")
 	return &cfg, nil
 }
 
@@ -172,6 +470,19 @@ func setDefaults() {
 	viper.SetDefault("http.idle_timeout", "5s")
 	viper.SetDefault("http.write_timeout", "10s")
 	viper.SetDefault("http.shutdown_timeout", "5s")
+	viper.SetDefault("http.request_timeout", "30s")
+	viper.SetDefault("http.security_headers.content_type_options", "nosniff")
+	viper.SetDefault("http.security_headers.frame_options", "DENY")
+	viper.SetDefault("http.security_headers.referrer_policy", "strict-origin-when-cross-origin")
+	viper.SetDefault("http.security_headers.hsts_max_age", "8760h") // 1 year
+	viper.SetDefault("http.security_headers.hsts_include_subdomains", true)
+	viper.SetDefault("http.trusted_proxies", []string{"127.0.0.1", "::1"})
+	viper.SetDefault("http.base_path", "/api")
+	viper.SetDefault("http.legacy_routes", true)
+	viper.SetDefault("http.idempotency_ttl", "10m")
+	viper.SetDefault("http.compression.enabled", true)
+	viper.SetDefault("http.compression.min_bytes", 1024)
+	viper.SetDefault("http.compression.content_types", []string{"application/json", "application/problem+json", "text/plain"})
 
 	// gRPC defaults
 	viper.SetDefault("grpc.port", "9090")
@@ -179,8 +490,35 @@ func setDefaults() {
 	viper.SetDefault("grpc.max_receive_size", 4*1024*1024) // 4MB
 	viper.SetDefault("grpc.max_send_size", 4*1024*1024)    // 4MB
 	viper.SetDefault("grpc.connection_timeout", "10s")
-	viper.SetDefault("grpc.enable_reflection", true)
+	viper.SetDefault("grpc.require_downstream_on_start", false)
+	viper.SetDefault("grpc.enable_reflection", false) // config.yaml turns it on for development
 	viper.SetDefault("grpc.enable_health_check", true)
+	viper.SetDefault("grpc.max_concurrent_streams", 1000)
+	viper.SetDefault("grpc.max_connection_idle", "15m")
+	viper.SetDefault("grpc.max_connection_age", "30m")
+	viper.SetDefault("grpc.max_connection_age_grace", "5m")
+	viper.SetDefault("grpc.default_timeout", "10s")
+	viper.SetDefault("grpc.max_metadata_bytes", 8*1024) // 8KB
+	viper.SetDefault("grpc.compression", false)
+	viper.SetDefault("grpc.compression_min_bytes", 1024) // 1KB
+	viper.SetDefault("grpc.log_request_bodies", false)
+	viper.SetDefault("grpc.trusted_peers", []string{"127.0.0.1", "::1"})
+
+	// Rate limit defaults
+	viper.SetDefault("rate_limit.requests", 100)
+	viper.SetDefault("rate_limit.window", "1m")
+	viper.SetDefault("rate_limit.fail_open", true)
+
+	// Retry defaults
+	viper.SetDefault("retry.max_attempts", 3)
+	viper.SetDefault("retry.initial_backoff", "100ms")
+	viper.SetDefault("retry.max_backoff", "1s")
+	viper.SetDefault("retry.methods", []string{
+		"/auth.AuthService/ValidateToken",
+		"/auth.AuthService/Health",
+		"/media.MediaService/GetMedia",
+		"/media.MediaService/Health",
+	})
 
 	// MongoDB defaults
 	viper.SetDefault("mongo.uri", "mongodb://localhost:27017")
@@ -189,8 +527,13 @@ func setDefaults() {
 	viper.SetDefault("mongo.min_pool_size", 5)
 	viper.SetDefault("mongo.connect_timeout", "10s")
 	viper.SetDefault("mongo.server_selection_timeout", "5s")
+	viper.SetDefault("mongo.connect_retry_max_elapsed", "30s")
+	viper.SetDefault("mongo.allow_non_tx_fallback", false)
+	viper.SetDefault("mongo.token_write_majority", true)
+	viper.SetDefault("mongo.token_read_primary", true)
 
 	// Redis defaults
+	viper.SetDefault("redis.mode", "single")
 	viper.SetDefault("redis.host", "localhost")
 	viper.SetDefault("redis.port", "6379")
 	viper.SetDefault("redis.password", "")
@@ -205,27 +548,72 @@ func setDefaults() {
 	viper.SetDefault("jwt.secret_key", "change-this-in-production-min-32-characters")
 	viper.SetDefault("jwt.access_token_ttl", "15m")
 	viper.SetDefault("jwt.refresh_token_ttl", "24h")
+	viper.SetDefault("jwt.guest_token_ttl", "30m")
+	viper.SetDefault("jwt.refresh_hint_threshold", "2m")
+	viper.SetDefault("jwt.refresh_token_bytes", 32)
+	viper.SetDefault("jwt.refresh_token_encoding", "hex")
 	viper.SetDefault("jwt.issuer", "remaster")
 	viper.SetDefault("jwt.audience", "remaster-users")
+	viper.SetDefault("jwt.leeway_seconds", 30)
+	viper.SetDefault("jwt.token_cleanup_interval", "1h")
+	viper.SetDefault("jwt.revoked_token_retention", "24h")
+
+	// Security defaults
+	viper.SetDefault("security.bcrypt_cost", 12)
+	viper.SetDefault("security.password_hash_algorithm", "bcrypt")
+	viper.SetDefault("security.argon2.time", 2)
+	viper.SetDefault("security.argon2.memory_kib", 19456) // 19MiB, OWASP minimum
+	viper.SetDefault("security.argon2.threads", 1)
+	viper.SetDefault("security.max_active_sessions", 5)
+	viper.SetDefault("security.phone_default_region", "US")
+	viper.SetDefault("security.allowed_self_registration_types", []string{"client", "master"})
+	viper.SetDefault("security.max_password_age", 0)
+	viper.SetDefault("security.allowed_oauth_domains", []string{})
+	viper.SetDefault("security.refresh_binding.mode", "log")
+	viper.SetDefault("security.refresh_binding.user_agent", false)
+	viper.SetDefault("security.lockout.max_attempts", 5)
+	viper.SetDefault("security.lockout.schedule", []string{"1m", "5m", "30m", "24h"})
+	viper.SetDefault("security.lockout.max_ip_attempts", 20)
+	viper.SetDefault("security.lockout.ip_window", "15m")
+	viper.SetDefault("security.password.min_length", 8)
+	viper.SetDefault("security.password.max_length", 72)
+	viper.SetDefault("security.password.require_upper", true)
+	viper.SetDefault("security.password.require_lower", true)
+	viper.SetDefault("security.password.require_digit", true)
+	viper.SetDefault("security.password.require_symbol", false)
 
 	// OAuth defaults
+	viper.SetDefault("oauth.enabled_providers", []string{"google"})
 	viper.SetDefault("oauth.google_redirect_url", "http://localhost:8080/auth/google/callback")
 
 	// AWS defaults
+	viper.SetDefault("aws.enabled", false)
 	viper.SetDefault("aws.region", "us-east-1")
 	viper.SetDefault("aws.s3_region", "us-east-1")
 
 	// Kafka defaults
+	viper.SetDefault("kafka.enabled", false)
 	viper.SetDefault("kafka.brokers", []string{"localhost:9092"})
 	viper.SetDefault("kafka.group_id", "remaster")
 	viper.SetDefault("kafka.auto_offset", "latest")
 	viper.SetDefault("kafka.session_timeout", "10s")
 	viper.SetDefault("kafka.retry_max", 3)
+	viper.SetDefault("kafka.publish_timeout", "5s")
+	viper.SetDefault("kafka.publish_queue_size", 1024)
+	viper.SetDefault("kafka.user_events_topic", "user-events")
+
+	// Media defaults
+	viper.SetDefault("media.max_upload_size", 10*1024*1024) // 10MB
+	viper.SetDefault("media.allowed_content_types", []string{"image/jpeg", "image/png", "image/webp"})
+	viper.SetDefault("media.upload_url_ttl", "15m")
+	viper.SetDefault("media.download_url_ttl", "1h")
 
 	// Log defaults
 	viper.SetDefault("log.level", "info")
 	viper.SetDefault("log.format", "pretty")
 	viper.SetDefault("log.output", "stdout")
+	viper.SetDefault("log.slow_request_threshold", "1s")
+	viper.SetDefault("log.redact_keys", []string{"token", "password", "access_token", "refresh_token", "secret", "authorization"})
 }
 
 // bind Env variables to config fields
@@ -244,33 +632,57 @@ func bindEnvVars() {
 		"grpc.port": "GRPC_PORT",
 		"grpc.host": "GRPC_HOST",
 
+		"grpc.enable_reflection":           "GRPC_ENABLE_REFLECTION",
+		"grpc.enable_health_check":         "GRPC_ENABLE_HEALTH_CHECK",
+		"grpc.require_downstream_on_start": "GRPC_REQUIRE_DOWNSTREAM_ON_START",
+		"grpc.trusted_peers":               "GRPC_TRUSTED_PEERS",
+
 		// MongoDB
 		"mongo.uri":      "MONGO_URI",
 		"mongo.database": "MONGO_DATABASE",
 
+		"mongo.allow_non_tx_fallback": "MONGO_ALLOW_NON_TX_FALLBACK",
+
 		// Redis
 		"redis.host":     "REDIS_HOST",
 		"redis.port":     "REDIS_PORT",
 		"redis.password": "REDIS_PASSWORD",
 		"redis.db":       "REDIS_DB",
+		"redis.mode":     "REDIS_MODE",
 
 		// JWT
-		"jwt.secret_key":        "JWT_SECRET_KEY",
-		"jwt.access_token_ttl":  "JWT_ACCESS_TOKEN_TTL",
-		"jwt.refresh_token_ttl": "JWT_REFRESH_TOKEN_TTL",
+		"jwt.secret_key":               "JWT_SECRET_KEY",
+		"jwt.previous_secret_key":      "JWT_PREVIOUS_SECRET_KEY",
+		"jwt.previous_key_valid_until": "JWT_PREVIOUS_KEY_VALID_UNTIL",
+		"jwt.access_token_ttl":         "JWT_ACCESS_TOKEN_TTL",
+		"jwt.refresh_token_ttl":        "JWT_REFRESH_TOKEN_TTL",
+		"jwt.guest_token_ttl":          "JWT_GUEST_TOKEN_TTL",
+
+		// Security
+		"security.bcrypt_cost":                    "BCRYPT_COST",
+		"security.password_hash_algorithm":        "PASSWORD_HASH_ALGORITHM",
+		"security.phone_default_region":           "PHONE_DEFAULT_REGION",
+		"security.password.common_passwords_file": "PASSWORD_COMMON_LIST_FILE",
 
 		// OAuth
+		"oauth.enabled_providers":    "OAUTH_ENABLED_PROVIDERS",
 		"oauth.google_client_id":     "GOOGLE_CLIENT_ID",
 		"oauth.google_client_secret": "GOOGLE_CLIENT_SECRET",
 		"oauth.google_redirect_url":  "GOOGLE_REDIRECT_URL",
 
 		// AWS
+		"aws.enabled":           "AWS_ENABLED",
+		"aws.endpoint":          "AWS_ENDPOINT",
 		"aws.region":            "AWS_REGION",
 		"aws.access_key_id":     "AWS_ACCESS_KEY_ID",
 		"aws.secret_access_key": "AWS_SECRET_ACCESS_KEY",
 		"aws.s3_bucket":         "AWS_S3_BUCKET",
 
+		// Rate limit
+		"rate_limit.fail_open": "RATE_LIMIT_FAIL_OPEN",
+
 		// Kafka
+		"kafka.enabled":  "KAFKA_ENABLED",
 		"kafka.brokers":  "KAFKA_BROKERS",
 		"kafka.group_id": "KAFKA_GROUP_ID",
 
@@ -296,6 +708,41 @@ func validateConfig(cfg *Config) error {
 		if len(cfg.JWT.SecretKey) < 32 {
 			return fmt.Errorf("JWT secret key must be at least 32 characters")
 		}
+		if cfg.JWT.PreviousSecretKey != "" && len(cfg.JWT.PreviousSecretKey) < 32 {
+			return fmt.Errorf("JWT previous secret key must be at least 32 characters")
+		}
+	}
+
+	if _, err := cfg.GRPC.TrustedPeerPrefixes(); err != nil {
+		return err
+	}
+
+	// an open-ended overlap would let a leaked old key sign tokens forever
+	if cfg.JWT.PreviousSecretKey != "" && cfg.JWT.PreviousKeyValidUntil.IsZero() {
+		return fmt.Errorf("JWT previous_secret_key requires previous_key_valid_until")
+	}
+
+	// below 16 bytes a refresh token becomes guessable
+	if cfg.JWT.RefreshTokenBytes < 16 {
+		return fmt.Errorf("JWT refresh_token_bytes must be at least 16, got %d", cfg.JWT.RefreshTokenBytes)
+	}
+	switch cfg.JWT.RefreshTokenEncoding {
+	case "hex", "base64url":
+	default:
+		return fmt.Errorf("unsupported JWT refresh_token_encoding: %s", cfg.JWT.RefreshTokenEncoding)
+	}
+
+	if cfg.Log.SlowRequestThreshold < 0 {
+		return fmt.Errorf("log slow_request_threshold must not be negative, got %s", cfg.Log.SlowRequestThreshold)
+	}
+
+	if cfg.Kafka.PublishQueueSize < 1 || cfg.Kafka.PublishTimeout <= 0 {
+		return fmt.Errorf("kafka publish_queue_size and publish_timeout must be positive, got %d and %s",
+			cfg.Kafka.PublishQueueSize, cfg.Kafka.PublishTimeout)
+	}
+
+	if cfg.GRPC.CompressionMinBytes < 0 {
+		return fmt.Errorf("grpc compression_min_bytes must not be negative, got %d", cfg.GRPC.CompressionMinBytes)
 	}
 
 	// required MONGO fields
@@ -306,14 +753,122 @@ func validateConfig(cfg *Config) error {
 		return fmt.Errorf("MongoDB database name is required")
 	}
 
+	// redis HA settings
+	switch cfg.Redis.Mode {
+	case "", "single":
+	case "sentinel":
+		if cfg.Redis.MasterName == "" || len(cfg.Redis.SentinelAddrs) == 0 {
+			return fmt.Errorf("redis sentinel mode requires master_name and sentinel_addrs")
+		}
+	case "cluster":
+		if len(cfg.Redis.ClusterAddrs) == 0 {
+			return fmt.Errorf("redis cluster mode requires cluster_addrs")
+		}
+	default:
+		return fmt.Errorf("unsupported redis mode: %s", cfg.Redis.Mode)
+	}
+
+	// bcrypt accepts costs in [4, 31]
+	if cfg.Security.BcryptCost < 4 || cfg.Security.BcryptCost > 31 {
+		return fmt.Errorf("bcrypt cost must be between 4 and 31, got %d", cfg.Security.BcryptCost)
+	}
+
+	switch cfg.Security.PasswordHashAlgorithm {
+	case "bcrypt":
+	case "argon2id":
+		a := cfg.Security.Argon2
+		if a.Time < 1 || a.MemoryKiB < 8*uint32(a.Threads) || a.Threads < 1 {
+			return fmt.Errorf("argon2 needs time >= 1, threads >= 1 and memory_kib >= 8*threads")
+		}
+	default:
+		return fmt.Errorf("unsupported password hash algorithm: %s", cfg.Security.PasswordHashAlgorithm)
+	}
+
+	if cfg.Security.Lockout.MaxAttempts < 1 || len(cfg.Security.Lockout.Schedule) == 0 {
+		return fmt.Errorf("lockout needs max_attempts >= 1 and a non-empty schedule")
+	}
+	if cfg.Security.Lockout.MaxIPAttempts < 1 || cfg.Security.Lockout.IPWindow <= 0 {
+		return fmt.Errorf("lockout needs max_ip_attempts >= 1 and a positive ip_window")
+	}
+
+	if len(cfg.Security.AllowedSelfRegistrationTypes) == 0 {
+		return fmt.Errorf("allowed_self_registration_types must list at least one user type")
+	}
+	for _, t := range cfg.Security.AllowedSelfRegistrationTypes {
+		if t != "client" && t != "master" {
+			return fmt.Errorf("user type %q can't self-register, allowed: client, master", t)
+		}
+	}
+
+	switch cfg.Security.RefreshBinding.Mode {
+	case "off", "log", "strict":
+	default:
+		return fmt.Errorf("unsupported refresh_binding mode: %s", cfg.Security.RefreshBinding.Mode)
+	}
+
+	if cfg.Security.MaxPasswordAge < 0 {
+		return fmt.Errorf("max_password_age must not be negative, got %s", cfg.Security.MaxPasswordAge)
+	}
+
+	policy := cfg.Security.Password
+	if policy.MinLength < 1 || policy.MaxLength > 72 || policy.MinLength > policy.MaxLength {
+		return fmt.Errorf("password policy lengths must satisfy 1 <= min_length <= max_length <= 72")
+	}
+
 	// validate HTTP and gRPC ports
 	if cfg.HTTP.Port == cfg.GRPC.Port {
 		return fmt.Errorf("HTTP and gRPC ports must be different")
 	}
 
+	// a half configured entry would only fail when someone dials ":9091"
+	for _, name := range GatewayServices {
+		if _, ok := cfg.Services[name]; !ok {
+			return fmt.Errorf("services.%s is missing, the api gateway connects to it", name)
+		}
+	}
+	for name, svc := range cfg.Services {
+		if svc.Host == "" {
+			return fmt.Errorf("services.%s.host is required", name)
+		}
+		if svc.GRPCPort == "" {
+			return fmt.Errorf("services.%s.grpc_port is required", name)
+		}
+		if svc.PoolSize < 0 {
+			return fmt.Errorf("service %s pool_size must not be negative, got %d", name, svc.PoolSize)
+		}
+		if !svc.EnableHTTP {
+			continue
+		}
+		if svc.HTTPPort == "" || svc.HTTPPort == svc.GRPCPort {
+			return fmt.Errorf("service %s enables http but http_port is missing or equals grpc_port", name)
+		}
+	}
+
 	return nil
 }
 
+// TrustedPeerPrefixes parses grpc.trusted_peers, a plain IP is a single address prefix
+func (c GRPCConfig) TrustedPeerPrefixes() ([]netip.Prefix, error) {
+	prefixes := make([]netip.Prefix, 0, len(c.TrustedPeers))
+	for _, peer := range c.TrustedPeers {
+		if strings.Contains(peer, "/") {
+			prefix, err := netip.ParsePrefix(peer)
+			if err != nil {
+				return nil, fmt.Errorf("invalid grpc trusted peer %q: %w", peer, err)
+			}
+			prefixes = append(prefixes, prefix.Masked())
+			continue
+		}
+		addr, err := netip.ParseAddr(peer)
+		if err != nil {
+			return nil, fmt.Errorf("invalid grpc trusted peer %q: %w", peer, err)
+		}
+		addr = addr.Unmap()
+		prefixes = append(prefixes, netip.PrefixFrom(addr, addr.BitLen()))
+	}
+	return prefixes, nil
+}
+
 func (c *Config) GetServiceGRPCAddr(name string) (string, error) {
 	svc, ok := c.Services[name]
 	if !ok {
@@ -321,3 +876,15 @@ func (c *Config) GetServiceGRPCAddr(name string) (string, error) {
 	}
 	return fmt.Sprintf("%s:%s", svc.Host, svc.GRPCPort), nil
 }
+
+// GetServiceHTTPAddr returns the ops http address, ok is false when it is disabled
+func (c *Config) GetServiceHTTPAddr(name string) (string, bool, error) {
+	svc, ok := c.Services[name]
+	if !ok {
+		return "", false, fmt.Errorf("service %s not found in config", name)
+	}
+	if !svc.EnableHTTP {
+		return "", false, nil
+	}
+	return fmt.Sprintf("%s:%s", svc.Host, svc.HTTPPort), true, nil
+}
@@ -0,0 +1,152 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/spf13/viper"
+)
+
+// useConfigDir runs the test in a temp dir holding the repo's config.yaml plus
+// files, with viper's global state reset around it
+func useConfigDir(t *testing.T, files map[string]string) string {
+	t.Helper()
+	base, err := os.ReadFile(filepath.Join("..", "config.yaml"))
+	if err != nil {
+		t.Fatalf("read base config: %v", err)
+	}
+
+	dir := t.TempDir()
+	writeConfig(t, filepath.Join(dir, "config.yaml"), string(base))
+	for name, content := range files {
+		writeConfig(t, filepath.Join(dir, name), content)
+	}
+
+	t.Chdir(dir)
+	viper.Reset()
+	t.Cleanup(viper.Reset)
+	return dir
+}
+
+func writeConfig(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatalf("write %s: %v", path, err)
+	}
+}
+
+func TestLoadConfigMergesEnvironmentOverlay(t *testing.T) {
+	useConfigDir(t, map[string]string{
+		"config.staging.yaml": "log:\n  level: warn\nrate_limit:\n  requests: 7\n",
+	})
+	t.Setenv("APP_ENV", "staging")
+
+	cfg, err := LoadConfig()
+	if err != nil {
+		t.Fatalf("LoadConfig: %v", err)
+	}
+	if cfg.App.Environment != "staging" {
+		t.Fatalf("environment = %q, want staging from APP_ENV", cfg.App.Environment)
+	}
+	// overlapping keys come from the overlay, the rest from the base file
+	if cfg.Log.Level != "warn" || cfg.RateLimit.Requests != 7 {
+		t.Fatalf("log level %q, rate limit %d, want the overlay's warn and 7", cfg.Log.Level, cfg.RateLimit.Requests)
+	}
+	if cfg.RateLimit.Window != time.Minute || cfg.Log.Format != "pretty" {
+		t.Fatalf("window %s, format %q, want the base file's 1m and pretty", cfg.RateLimit.Window, cfg.Log.Format)
+	}
+}
+
+func TestLoadConfigWithoutOverlayUsesBaseFile(t *testing.T) {
+	useConfigDir(t, map[string]string{
+		"config.staging.yaml": "log:\n  level: warn\n",
+	})
+	t.Setenv("APP_ENV", "development")
+
+	cfg, err := LoadConfig()
+	if err != nil {
+		t.Fatalf("LoadConfig: %v", err)
+	}
+	if cfg.Log.Level != "debug" || cfg.RateLimit.Requests != 100 {
+		t.Fatalf("log level %q, rate limit %d, want the base file's debug and 100", cfg.Log.Level, cfg.RateLimit.Requests)
+	}
+}
+
+func TestLoadConfigRejectsBrokenOverlay(t *testing.T) {
+	useConfigDir(t, map[string]string{
+		"config.staging.yaml": "log: [unterminated\n",
+	})
+	t.Setenv("APP_ENV", "staging")
+
+	if _, err := LoadConfig(); err == nil {
+		t.Fatal("LoadConfig accepted a malformed overlay")
+	}
+}
+
+func TestProductionOverlayDisablesReflection(t *testing.T) {
+	overlay, err := os.ReadFile(filepath.Join("..", "config.production.yaml"))
+	if err != nil {
+		t.Fatalf("read production overlay: %v", err)
+	}
+	useConfigDir(t, map[string]string{"config.production.yaml": string(overlay)})
+	t.Setenv("APP_ENV", "production")
+	t.Setenv("JWT_SECRET_KEY", "a-production-secret-of-at-least-32-chars")
+
+	cfg, err := LoadConfig()
+	if err != nil {
+		t.Fatalf("LoadConfig: %v", err)
+	}
+	if cfg.GRPC.EnableReflection {
+		t.Fatal("reflection is on in production although the base config enables it")
+	}
+	if !cfg.GRPC.EnableHealthCheck {
+		t.Fatal("health check turned off by the production overlay")
+	}
+}
+
+func TestValidateConfigServicesMap(t *testing.T) {
+	useConfigDir(t, nil)
+	t.Setenv("APP_ENV", "development")
+
+	tests := []struct {
+		name   string
+		change func(services map[string]ServiceAddr)
+		want   string
+	}{
+		{"valid map", func(map[string]ServiceAddr) {}, ""},
+		{"missing gateway service", func(s map[string]ServiceAddr) { delete(s, "media") }, "services.media is missing"},
+		{"missing host", func(s map[string]ServiceAddr) {
+			auth := s["auth"]
+			auth.Host = ""
+			s["auth"] = auth
+		}, "services.auth.host is required"},
+		{"missing grpc port", func(s map[string]ServiceAddr) {
+			review := s["review"]
+			review.GRPCPort = ""
+			s["review"] = review
+		}, "services.review.grpc_port is required"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg, err := LoadConfig()
+			if err != nil {
+				t.Fatalf("LoadConfig: %v", err)
+			}
+			tt.change(cfg.Services)
+
+			err = validateConfig(cfg)
+			if tt.want == "" {
+				if err != nil {
+					t.Fatalf("validateConfig = %v, want the repo's services map accepted", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Fatalf("validateConfig = %v, want %q", err, tt.want)
+			}
+		})
+	}
+}
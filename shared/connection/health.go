@@ -0,0 +1,39 @@
+package connection
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// HealthChecker is implemented by every connection manager
+type HealthChecker interface {
+	HealthCheck(ctx context.Context) error
+}
+
+// CheckAll probes all checkers concurrently, each with its own timeout so one slow
+// dependency can't stall the rest. Healthy checkers map to a nil error
+func CheckAll(ctx context.Context, checkers map[string]HealthChecker, timeout time.Duration) map[string]error {
+	var (
+		mu      sync.Mutex
+		wg      sync.WaitGroup
+		results = make(map[string]error, len(checkers))
+	)
+
+	for name, checker := range checkers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			checkCtx, cancel := context.WithTimeout(ctx, timeout)
+			defer cancel()
+
+			err := checker.HealthCheck(checkCtx)
+			mu.Lock()
+			results[name] = err
+			mu.Unlock()
+		}()
+	}
+
+	wg.Wait()
+	return results
+}
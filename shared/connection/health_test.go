@@ -0,0 +1,61 @@
+package connection
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+type checkerFunc func(ctx context.Context) error
+
+func (f checkerFunc) HealthCheck(ctx context.Context) error { return f(ctx) }
+
+func TestCheckAllReportsEachDependency(t *testing.T) {
+	down := errors.New("connection refused")
+	checkers := map[string]HealthChecker{
+		"mongodb": checkerFunc(func(context.Context) error { return nil }),
+		"redis":   checkerFunc(func(context.Context) error { return down }),
+		// hangs until its own timeout fires
+		"s3": checkerFunc(func(ctx context.Context) error {
+			<-ctx.Done()
+			return ctx.Err()
+		}),
+	}
+
+	start := time.Now()
+	results := CheckAll(context.Background(), checkers, 50*time.Millisecond)
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Fatalf("CheckAll took %s, want the slow check cut off by its timeout", elapsed)
+	}
+
+	if len(results) != len(checkers) {
+		t.Fatalf("got %d results, want %d", len(results), len(checkers))
+	}
+	if err := results["mongodb"]; err != nil {
+		t.Fatalf("mongodb = %v, want healthy", err)
+	}
+	if err := results["redis"]; !errors.Is(err, down) {
+		t.Fatalf("redis = %v, want %v", err, down)
+	}
+	if err := results["s3"]; !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("s3 = %v, want a deadline error", err)
+	}
+}
+
+func TestCheckAllGivesEachCheckerItsOwnTimeout(t *testing.T) {
+	deadlines := make(chan time.Duration, 2)
+	record := checkerFunc(func(ctx context.Context) error {
+		deadline, _ := ctx.Deadline()
+		deadlines <- time.Until(deadline)
+		return nil
+	})
+
+	CheckAll(context.Background(), map[string]HealthChecker{"kafka": record, "redis": record}, time.Second)
+	close(deadlines)
+	for left := range deadlines {
+		if left <= 0 || left > time.Second {
+			t.Fatalf("checker deadline in %s, want within its own 1s timeout", left)
+		}
+	}
+}
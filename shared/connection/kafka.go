@@ -1 +1,139 @@
 package connection
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	cfg "remaster/shared"
+
+	"github.com/segmentio/kafka-go"
+)
+
+type KafkaManager struct {
+	writer *kafka.Writer
+	config *cfg.KafkaConfig
+	mu     sync.RWMutex
+}
+
+var (
+	kafkaInstance *KafkaManager
+	kafkaOnce     sync.Once
+)
+
+// singleton
+func NewKafkaManager(cfg *cfg.KafkaConfig) *KafkaManager {
+	kafkaOnce.Do(func() {
+		kafkaInstance = &KafkaManager{config: cfg}
+	})
+	return kafkaInstance
+}
+
+func (k *KafkaManager) Connect(ctx context.Context) error {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	if k.writer != nil {
+		log.Println("Kafka writer already initialized")
+		return nil
+	}
+
+	if err := k.ping(ctx); err != nil {
+		return err
+	}
+
+	k.writer = &kafka.Writer{
+		Addr:                   kafka.TCP(k.config.Brokers...),
+		Balancer:               &kafka.Hash{},
+		RequiredAcks:           kafka.RequireAll,
+		MaxAttempts:            k.config.RetryMax,
+		BatchTimeout:           10 * time.Millisecond,
+		AllowAutoTopicCreation: true,
+	}
+
+	log.Printf("Successfully connected to Kafka brokers: %v", k.config.Brokers)
+	return nil
+}
+
+func (k *KafkaManager) Disconnect() error {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	if k.writer == nil {
+		return nil
+	}
+	if err := k.writer.Close(); err != nil {
+		return fmt.Errorf("failed to close Kafka writer: %w", err)
+	}
+	k.writer = nil
+	log.Println("Kafka connection closed")
+	return nil
+}
+
+func (k *KafkaManager) HealthCheck(ctx context.Context) error {
+	k.mu.RLock()
+	writer := k.writer
+	k.mu.RUnlock()
+
+	if writer == nil {
+		return fmt.Errorf("kafka writer not initialized")
+	}
+	return k.ping(ctx)
+}
+
+// dial first reachable broker
+func (k *KafkaManager) ping(ctx context.Context) error {
+	if len(k.config.Brokers) == 0 {
+		return fmt.Errorf("no kafka brokers configured")
+	}
+
+	pingCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	var lastErr error
+	for _, broker := range k.config.Brokers {
+		conn, err := kafka.DialContext(pingCtx, "tcp", broker)
+		if err != nil {
+			lastErr = err
+			continue
+		}
+		_ = conn.Close()
+		return nil
+	}
+	return fmt.Errorf("failed to reach Kafka brokers: %w", lastErr)
+}
+
+// === HELPER FUNCTIONS ===
+
+// Publish writes a single message, key decides the partition
+func (k *KafkaManager) Publish(ctx context.Context, topic, key string, value []byte) error {
+	k.mu.RLock()
+	writer := k.writer
+	k.mu.RUnlock()
+
+	if writer == nil {
+		return fmt.Errorf("kafka writer not initialized")
+	}
+
+	err := writer.WriteMessages(ctx, kafka.Message{
+		Topic: topic,
+		Key:   []byte(key),
+		Value: value,
+		Time:  time.Now(),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to publish to %s: %w", topic, err)
+	}
+	return nil
+}
+
+func (k *KafkaManager) PublishJSON(ctx context.Context, topic, key string, v any) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Errorf("failed to marshal kafka message: %w", err)
+	}
+	return k.Publish(ctx, topic, key, data)
+}
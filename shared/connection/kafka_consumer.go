@@ -0,0 +1,163 @@
+package connection
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	cfg "remaster/shared"
+
+	"github.com/segmentio/kafka-go"
+)
+
+const consumerRetryBackoff = 500 * time.Millisecond
+
+// MessageHandler processes one message, an error makes the consumer retry it
+type MessageHandler func(ctx context.Context, msg kafka.Message) error
+
+// messageReader is the part of kafka.Reader the consumer uses
+type messageReader interface {
+	FetchMessage(ctx context.Context) (kafka.Message, error)
+	CommitMessages(ctx context.Context, msgs ...kafka.Message) error
+	Close() error
+}
+
+// KafkaConsumer reads topics as a consumer group and dispatches messages to the
+// handler registered for their topic. Offsets are committed only after the handler
+// succeeded, so a crash redelivers the message (at-least-once). A message that still
+// fails after retry_max retries is logged and committed so it cannot block its partition
+type KafkaConsumer struct {
+	config  *cfg.KafkaConfig
+	groupID string
+	logger  *slog.Logger
+
+	mu       sync.Mutex
+	handlers map[string]MessageHandler
+	reader   messageReader
+}
+
+// NewKafkaConsumer creates a consumer for groupID, every service needs its own group
+// to receive all messages of a topic
+func NewKafkaConsumer(config *cfg.KafkaConfig, groupID string, logger *slog.Logger) *KafkaConsumer {
+	return &KafkaConsumer{
+		config:   config,
+		groupID:  groupID,
+		logger:   logger.With(slog.String("consumer_group", groupID)),
+		handlers: make(map[string]MessageHandler),
+	}
+}
+
+// Handle registers handler for topic, it must be called before Run
+func (c *KafkaConsumer) Handle(topic string, handler MessageHandler) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.handlers[topic] = handler
+}
+
+func (c *KafkaConsumer) Name() string {
+	return "kafka-consumer:" + c.groupID
+}
+
+// Run consumes until ctx is done, it can be registered as a server worker
+func (c *KafkaConsumer) Run(ctx context.Context) error {
+	c.mu.Lock()
+	if len(c.handlers) == 0 {
+		c.mu.Unlock()
+		return errors.New("kafka consumer has no handlers")
+	}
+	if c.reader == nil {
+		c.reader = c.newReader()
+	}
+	reader := c.reader
+	c.mu.Unlock()
+
+	defer func() {
+		if err := reader.Close(); err != nil {
+			c.logger.Warn("Failed to close Kafka reader", "error", err)
+		}
+	}()
+
+	for {
+		msg, err := reader.FetchMessage(ctx)
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			return fmt.Errorf("failed to fetch kafka message: %w", err)
+		}
+
+		if !c.process(ctx, msg) {
+			// shutting down, the uncommitted message is redelivered to the next consumer
+			return nil
+		}
+		if err := reader.CommitMessages(ctx, msg); err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			return fmt.Errorf("failed to commit kafka offset: %w", err)
+		}
+	}
+}
+
+// process runs the handler with retries, false means ctx ended before the message was handled
+func (c *KafkaConsumer) process(ctx context.Context, msg kafka.Message) bool {
+	c.mu.Lock()
+	handler, ok := c.handlers[msg.Topic]
+	c.mu.Unlock()
+
+	log := c.logger.With(
+		slog.String("topic", msg.Topic),
+		slog.Int("partition", msg.Partition),
+		slog.Int64("offset", msg.Offset),
+	)
+	if !ok {
+		log.Warn("No handler for Kafka topic, skipping message")
+		return true
+	}
+
+	for attempt := 0; ; attempt++ {
+		err := handler(ctx, msg)
+		if err == nil {
+			return true
+		}
+		if ctx.Err() != nil {
+			return false
+		}
+		if attempt >= c.config.RetryMax {
+			log.Error("Kafka message failed after retries, skipping", "attempts", attempt+1, "error", err)
+			return true
+		}
+		log.Warn("Kafka message handler failed, retrying", "attempt", attempt+1, "error", err)
+
+		select {
+		case <-ctx.Done():
+			return false
+		case <-time.After(time.Duration(attempt+1) * consumerRetryBackoff):
+		}
+	}
+}
+
+func (c *KafkaConsumer) newReader() *kafka.Reader {
+	topics := make([]string, 0, len(c.handlers))
+	for topic := range c.handlers {
+		topics = append(topics, topic)
+	}
+
+	startOffset := kafka.LastOffset
+	if c.config.AutoOffset == "earliest" {
+		startOffset = kafka.FirstOffset
+	}
+
+	return kafka.NewReader(kafka.ReaderConfig{
+		Brokers:        c.config.Brokers,
+		GroupID:        c.groupID,
+		GroupTopics:    topics,
+		StartOffset:    startOffset,
+		SessionTimeout: c.config.SessionTimeout,
+		// commits are explicit, after the handler succeeded
+		CommitInterval: 0,
+	})
+}
@@ -0,0 +1,197 @@
+package connection
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"sync"
+	"testing"
+	"time"
+
+	cfg "remaster/shared"
+
+	"github.com/segmentio/kafka-go"
+)
+
+// fakeReader hands out queued messages, blocks once they run out and records commits
+type fakeReader struct {
+	mu        sync.Mutex
+	queue     []kafka.Message
+	committed []int64
+	drained   chan struct{}
+	closed    bool
+}
+
+func newFakeReader(msgs ...kafka.Message) *fakeReader {
+	return &fakeReader{queue: msgs, drained: make(chan struct{})}
+}
+
+func (r *fakeReader) FetchMessage(ctx context.Context) (kafka.Message, error) {
+	r.mu.Lock()
+	if len(r.queue) > 0 {
+		msg := r.queue[0]
+		r.queue = r.queue[1:]
+		r.mu.Unlock()
+		return msg, nil
+	}
+	r.mu.Unlock()
+
+	select {
+	case <-r.drained:
+	default:
+		close(r.drained)
+	}
+	<-ctx.Done()
+	return kafka.Message{}, ctx.Err()
+}
+
+func (r *fakeReader) CommitMessages(_ context.Context, msgs ...kafka.Message) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, m := range msgs {
+		r.committed = append(r.committed, m.Offset)
+	}
+	return nil
+}
+
+func (r *fakeReader) Close() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.closed = true
+	return nil
+}
+
+func (r *fakeReader) commits() []int64 {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]int64(nil), r.committed...)
+}
+
+func newTestConsumer(reader messageReader, retryMax int) *KafkaConsumer {
+	c := NewKafkaConsumer(&cfg.KafkaConfig{RetryMax: retryMax}, "test", slog.New(slog.NewTextHandler(io.Discard, nil)))
+	c.reader = reader
+	return c
+}
+
+// consumeAll runs c until reader has handed out every message, then stops it
+func consumeAll(t *testing.T, c *KafkaConsumer, reader *fakeReader) {
+	t.Helper()
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- c.Run(ctx) }()
+
+	select {
+	case <-reader.drained:
+	case <-time.After(5 * time.Second):
+		t.Fatal("consumer did not get through the queued messages")
+	}
+	cancel()
+	if err := <-done; err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+}
+
+func equalOffsets(a, b []int64) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func TestKafkaConsumerDispatchesAndCommits(t *testing.T) {
+	reader := newFakeReader(
+		kafka.Message{Topic: "users", Offset: 1, Value: []byte("a")},
+		kafka.Message{Topic: "orders", Offset: 2, Value: []byte("b")},
+		kafka.Message{Topic: "unknown", Offset: 3},
+		kafka.Message{Topic: "users", Offset: 4, Value: []byte("c")},
+	)
+	c := newTestConsumer(reader, 0)
+
+	got := map[string][]string{}
+	var mu sync.Mutex
+	for _, topic := range []string{"users", "orders"} {
+		c.Handle(topic, func(_ context.Context, msg kafka.Message) error {
+			mu.Lock()
+			defer mu.Unlock()
+			got[msg.Topic] = append(got[msg.Topic], string(msg.Value))
+			return nil
+		})
+	}
+
+	consumeAll(t, c, reader)
+
+	if len(got["users"]) != 2 || got["users"][1] != "c" || len(got["orders"]) != 1 {
+		t.Fatalf("handled %v, want each message by its topic's handler", got)
+	}
+	// messages without a handler are committed too, they would block the partition
+	if commits := reader.commits(); !equalOffsets(commits, []int64{1, 2, 3, 4}) {
+		t.Fatalf("committed %v, want every offset in order", commits)
+	}
+	if !reader.closed {
+		t.Fatal("reader not closed after Run")
+	}
+}
+
+func TestKafkaConsumerCommitsOnlyAfterHandling(t *testing.T) {
+	t.Run("retry then success", func(t *testing.T) {
+		reader := newFakeReader(kafka.Message{Topic: "users", Offset: 7})
+		c := newTestConsumer(reader, 3)
+
+		attempts := 0
+		c.Handle("users", func(context.Context, kafka.Message) error {
+			attempts++
+			if commits := reader.commits(); len(commits) != 0 {
+				t.Errorf("offset committed before the handler succeeded: %v", commits)
+			}
+			if attempts == 1 {
+				return errors.New("cache down")
+			}
+			return nil
+		})
+
+		consumeAll(t, c, reader)
+		if attempts != 2 || !equalOffsets(reader.commits(), []int64{7}) {
+			t.Fatalf("attempts %d, commits %v, want 2 and one commit", attempts, reader.commits())
+		}
+	})
+
+	t.Run("shutdown during retries", func(t *testing.T) {
+		reader := newFakeReader(kafka.Message{Topic: "users", Offset: 7})
+		c := newTestConsumer(reader, 3)
+		failed := make(chan struct{}, 1)
+		c.Handle("users", func(context.Context, kafka.Message) error {
+			select {
+			case failed <- struct{}{}:
+			default:
+			}
+			return errors.New("cache down")
+		})
+
+		ctx, cancel := context.WithCancel(context.Background())
+		done := make(chan error, 1)
+		go func() { done <- c.Run(ctx) }()
+		<-failed
+		cancel()
+
+		if err := <-done; err != nil {
+			t.Fatalf("Run = %v, want nil on shutdown", err)
+		}
+		// left uncommitted so the group redelivers it
+		if commits := reader.commits(); len(commits) != 0 {
+			t.Fatalf("committed %v, want nothing for an unhandled message", commits)
+		}
+	})
+}
+
+func TestKafkaConsumerNeedsHandlers(t *testing.T) {
+	c := newTestConsumer(newFakeReader(), 0)
+	if err := c.Run(context.Background()); err == nil {
+		t.Fatal("Run without handlers succeeded")
+	}
+}
@@ -2,11 +2,14 @@ package connection
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log"
+	"strings"
 	"sync"
 	"time"
 
+	"github.com/cenkalti/backoff/v4"
 	"go.mongodb.org/mongo-driver/mongo"
 	"go.mongodb.org/mongo-driver/mongo/options"
 	"go.mongodb.org/mongo-driver/mongo/readpref"
@@ -60,6 +63,35 @@ func (m *MongoManager) Connect(ctx context.Context) error {
 		m.client.Disconnect(ctx)
 	}
 
+	// retry connect+ping so a service started just before mongo doesn't crash
+	var client *mongo.Client
+	b := backoff.NewExponentialBackOff()
+	b.MaxElapsedTime = m.config.ConnectRetryMaxElapsed
+
+	var policy backoff.BackOff = b
+	if m.config.ConnectRetryMaxElapsed <= 0 {
+		policy = &backoff.StopBackOff{}
+	}
+
+	err := backoff.RetryNotify(func() (err error) {
+		client, err = m.dial(ctx)
+		return err
+	}, backoff.WithContext(policy, ctx), func(err error, wait time.Duration) {
+		log.Printf("MongoDB not reachable, retrying in %s: %v", wait, err)
+	})
+	if err != nil {
+		return err
+	}
+
+	m.client = client
+	m.database = client.Database(m.config.Database)
+
+	log.Printf("Successfully connected to MongoDB database: %s", m.config.Database)
+	return nil
+}
+
+// dial creates a client and pings the primary once
+func (m *MongoManager) dial(ctx context.Context) (*mongo.Client, error) {
 	connectCtx, cancel := context.WithTimeout(ctx, m.config.ConnectTimeout)
 	defer cancel()
 
@@ -74,7 +106,7 @@ func (m *MongoManager) Connect(ctx context.Context) error {
 	// client creation
 	client, err := mongo.Connect(connectCtx, clientOptions)
 	if err != nil {
-		return fmt.Errorf("failed to create MongoDB client: %w", err)
+		return nil, fmt.Errorf("failed to create MongoDB client: %w", err)
 	}
 
 	// check conn
@@ -83,14 +115,10 @@ func (m *MongoManager) Connect(ctx context.Context) error {
 
 	if err := client.Ping(pingCtx, readpref.Primary()); err != nil {
 		client.Disconnect(ctx)
-		return fmt.Errorf("failed to ping MongoDB: %w", err)
+		return nil, fmt.Errorf("failed to ping MongoDB: %w", err)
 	}
 
-	m.client = client
-	m.database = client.Database(m.config.Database)
-
-	log.Printf("Successfully connected to MongoDB database: %s", m.config.Database)
-	return nil
+	return client, nil
 }
 
 // Disconnection from MongoDB
@@ -180,6 +208,14 @@ func (m *MongoManager) WithTransaction(ctx context.Context, fn func(sessCtx mong
 	}
 
 	_, err = session.WithTransaction(ctx, callback)
+	if err != nil && m.config.AllowNonTxFallback && isTransactionNotSupported(err) {
+		// fn may have failed partway through its first attempt, it has to be safe to rerun
+		log.Printf("WARNING: MongoDB does not support transactions (standalone?), running without one: %v", err)
+		if err := fn(mongo.NewSessionContext(ctx, session)); err != nil {
+			return fmt.Errorf("non-transactional fallback failed: %w", err)
+		}
+		return nil
+	}
 	if err != nil {
 		return fmt.Errorf("transaction failed: %w", err)
 	}
@@ -187,6 +223,16 @@ func (m *MongoManager) WithTransaction(ctx context.Context, fn func(sessCtx mong
 	return nil
 }
 
+// standalone servers reject transactions with IllegalOperation (20), the code
+// alone is too broad so the message has to match as well
+func isTransactionNotSupported(err error) bool {
+	var ce mongo.CommandError
+	if errors.As(err, &ce) && ce.Code != 20 {
+		return false
+	}
+	return strings.Contains(err.Error(), "Transaction numbers are only allowed on a replica set")
+}
+
 // // create indexes
 // func (m *MongoManager) CreateIndexes(ctx context.Context) error {
 // 	if m.database == nil {
@@ -0,0 +1,204 @@
+package connection
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"io"
+	"net"
+	"testing"
+	"time"
+
+	cfg "remaster/shared"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/integration/mtest"
+)
+
+const (
+	opReply = 1
+	opQuery = 2004
+	opMsg   = 2013
+)
+
+// fakeMongod answers every command on addr as a healthy standalone server,
+// enough for the driver's handshake, monitoring and ping
+func fakeMongod(t *testing.T, addr string) {
+	t.Helper()
+	lis, err := net.Listen("tcp", addr)
+	if err != nil {
+		t.Errorf("fake mongod listen: %v", err)
+		return
+	}
+	t.Cleanup(func() { _ = lis.Close() })
+
+	go func() {
+		for {
+			conn, err := lis.Accept()
+			if err != nil {
+				return
+			}
+			go serveFakeMongo(conn)
+		}
+	}()
+}
+
+func serveFakeMongo(conn net.Conn) {
+	defer conn.Close()
+
+	reply, _ := bson.Marshal(bson.D{
+		{Key: "ok", Value: 1},
+		{Key: "ismaster", Value: true},
+		{Key: "isWritablePrimary", Value: true},
+		{Key: "helloOk", Value: true},
+		{Key: "minWireVersion", Value: 0},
+		{Key: "maxWireVersion", Value: 21},
+		{Key: "maxBsonObjectSize", Value: 16 * 1024 * 1024},
+		{Key: "maxMessageSizeBytes", Value: 48 * 1000 * 1000},
+		{Key: "maxWriteBatchSize", Value: 100000},
+		{Key: "logicalSessionTimeoutMinutes", Value: 30},
+		{Key: "connectionId", Value: 1},
+	})
+
+	header := make([]byte, 16)
+	for {
+		if _, err := io.ReadFull(conn, header); err != nil {
+			return
+		}
+		length := binary.LittleEndian.Uint32(header[0:])
+		requestID := binary.LittleEndian.Uint32(header[4:])
+		opCode := binary.LittleEndian.Uint32(header[12:])
+		if _, err := io.CopyN(io.Discard, conn, int64(length)-16); err != nil {
+			return
+		}
+
+		var body []byte
+		switch opCode {
+		case opQuery:
+			// responseFlags, cursorID, startingFrom, numberReturned
+			body = make([]byte, 20)
+			binary.LittleEndian.PutUint32(body[16:], 1)
+			body = append(body, reply...)
+			opCode = opReply
+		case opMsg:
+			// flagBits, then one body section
+			body = append(make([]byte, 5), reply...)
+		default:
+			return
+		}
+
+		msg := make([]byte, 16, 16+len(body))
+		binary.LittleEndian.PutUint32(msg[0:], uint32(16+len(body)))
+		binary.LittleEndian.PutUint32(msg[4:], requestID+1)
+		binary.LittleEndian.PutUint32(msg[8:], requestID)
+		binary.LittleEndian.PutUint32(msg[12:], opCode)
+		if _, err := conn.Write(append(msg, body...)); err != nil {
+			return
+		}
+	}
+}
+
+// unusedAddr returns a local address with nothing listening on it yet
+func unusedAddr(t *testing.T) string {
+	t.Helper()
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	addr := lis.Addr().String()
+	_ = lis.Close()
+	return addr
+}
+
+func newTestMongoManager(addr string, retryMaxElapsed time.Duration) *MongoManager {
+	return &MongoManager{config: &cfg.MongoConfig{
+		URI:                    "mongodb://" + addr + "/?directConnection=true",
+		Database:               "test",
+		ConnectTimeout:         time.Second,
+		ServerSelection:        200 * time.Millisecond,
+		ConnectRetryMaxElapsed: retryMaxElapsed,
+	}}
+}
+
+func TestMongoConnectRetriesUntilServerIsUp(t *testing.T) {
+	addr := unusedAddr(t)
+	mgr := newTestMongoManager(addr, 10*time.Second)
+	t.Cleanup(func() { _ = mgr.Disconnect(context.Background()) })
+
+	const delay = 500 * time.Millisecond
+	time.AfterFunc(delay, func() { fakeMongod(t, addr) })
+
+	start := time.Now()
+	if err := mgr.Connect(context.Background()); err != nil {
+		t.Fatalf("Connect: %v", err)
+	}
+	if elapsed := time.Since(start); elapsed < delay {
+		t.Fatalf("connected after %v, before the server was up", elapsed)
+	}
+	if err := mgr.HealthCheck(context.Background()); err != nil {
+		t.Fatalf("HealthCheck after connecting: %v", err)
+	}
+}
+
+func TestMongoConnectGivesUp(t *testing.T) {
+	tests := []struct {
+		name            string
+		retryMaxElapsed time.Duration
+		maxTime         time.Duration
+	}{
+		{"retries disabled", 0, time.Second},
+		{"after max elapsed", 700 * time.Millisecond, 3 * time.Second},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mgr := newTestMongoManager(unusedAddr(t), tt.retryMaxElapsed)
+
+			start := time.Now()
+			if err := mgr.Connect(context.Background()); err == nil {
+				t.Fatal("Connect succeeded with no server")
+			}
+			if elapsed := time.Since(start); elapsed > tt.maxTime {
+				t.Fatalf("gave up after %v, want under %v", elapsed, tt.maxTime)
+			}
+		})
+	}
+}
+
+// standaloneErr is what a standalone mongod answers to the first command of a transaction
+var standaloneErr = mongo.CommandError{Code: 20, Name: "IllegalOperation", Message: "Transaction numbers are only allowed on a replica set member or mongos"}
+
+func TestWithTransactionFallback(t *testing.T) {
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+
+	tests := []struct {
+		name     string
+		fallback bool
+		// what each call of fn returns, in order
+		results []error
+		calls   int
+		wantErr bool
+	}{
+		{"falls back on standalone", true, []error{standaloneErr, nil}, 2, false},
+		{"fallback disabled", false, []error{standaloneErr}, 1, true},
+		{"other command error", true, []error{mongo.CommandError{Code: 20, Message: "something else"}}, 1, true},
+		{"fallback run fails", true, []error{standaloneErr, errors.New("insert failed")}, 2, true},
+	}
+	for _, tt := range tests {
+		mt.Run(tt.name, func(mt *mtest.T) {
+			mgr := &MongoManager{client: mt.Client, config: &cfg.MongoConfig{AllowNonTxFallback: tt.fallback}}
+
+			calls := 0
+			err := mgr.WithTransaction(context.Background(), func(mongo.SessionContext) error {
+				calls++
+				return tt.results[calls-1]
+			})
+			if (err != nil) != tt.wantErr {
+				mt.Fatalf("WithTransaction = %v, want error %v", err, tt.wantErr)
+			}
+			if calls != tt.calls {
+				mt.Fatalf("fn ran %d times, want %d", calls, tt.calls)
+			}
+		})
+	}
+}
@@ -2,6 +2,10 @@ package connection
 
 import (
 	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
 	"sync"
@@ -12,8 +16,15 @@ import (
 	"github.com/redis/go-redis/v9"
 )
 
+// supported RedisConfig.Mode values
+const (
+	RedisModeSingle   = "single"
+	RedisModeSentinel = "sentinel"
+	RedisModeCluster  = "cluster"
+)
+
 type RedisManager struct {
-	client *redis.Client
+	client redis.UniversalClient
 	config *cfg.RedisConfig
 	mu     sync.RWMutex
 }
@@ -44,16 +55,11 @@ func (r *RedisManager) Connect(ctx context.Context) error {
 		r.client = nil
 	}
 
-	r.client = redis.NewClient(&redis.Options{
-		Addr:         fmt.Sprintf("%s:%s", r.config.Host, r.config.Port),
-		Password:     r.config.Password,
-		DB:           r.config.DB,
-		PoolSize:     r.config.PoolSize,
-		MinIdleConns: r.config.MinIdleConns,
-		DialTimeout:  r.config.DialTimeout,
-		ReadTimeout:  r.config.ReadTimeout,
-		WriteTimeout: r.config.WriteTimeout,
-	})
+	client, err := newRedisClient(r.config)
+	if err != nil {
+		return err
+	}
+	r.client = client
 
 	pingCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
 	defer cancel()
@@ -64,10 +70,65 @@ func (r *RedisManager) Connect(ctx context.Context) error {
 		return fmt.Errorf("failed to ping Redis: %w", err)
 	}
 
-	log.Println("Successfully connected to Redis")
+	log.Printf("Successfully connected to Redis (mode: %s)", redisMode(r.config))
 	return nil
 }
 
+// build client for configured deployment mode
+func newRedisClient(c *cfg.RedisConfig) (redis.UniversalClient, error) {
+	switch redisMode(c) {
+	case RedisModeSingle:
+		return redis.NewClient(&redis.Options{
+			Addr:         fmt.Sprintf("%s:%s", c.Host, c.Port),
+			Password:     c.Password,
+			DB:           c.DB,
+			PoolSize:     c.PoolSize,
+			MinIdleConns: c.MinIdleConns,
+			DialTimeout:  c.DialTimeout,
+			ReadTimeout:  c.ReadTimeout,
+			WriteTimeout: c.WriteTimeout,
+		}), nil
+	case RedisModeSentinel:
+		if c.MasterName == "" || len(c.SentinelAddrs) == 0 {
+			return nil, fmt.Errorf("redis sentinel mode requires master_name and sentinel_addrs")
+		}
+		return redis.NewFailoverClient(&redis.FailoverOptions{
+			MasterName:       c.MasterName,
+			SentinelAddrs:    c.SentinelAddrs,
+			SentinelPassword: c.SentinelPassword,
+			Password:         c.Password,
+			DB:               c.DB,
+			PoolSize:         c.PoolSize,
+			MinIdleConns:     c.MinIdleConns,
+			DialTimeout:      c.DialTimeout,
+			ReadTimeout:      c.ReadTimeout,
+			WriteTimeout:     c.WriteTimeout,
+		}), nil
+	case RedisModeCluster:
+		if len(c.ClusterAddrs) == 0 {
+			return nil, fmt.Errorf("redis cluster mode requires cluster_addrs")
+		}
+		return redis.NewClusterClient(&redis.ClusterOptions{
+			Addrs:        c.ClusterAddrs,
+			Password:     c.Password,
+			PoolSize:     c.PoolSize,
+			MinIdleConns: c.MinIdleConns,
+			DialTimeout:  c.DialTimeout,
+			ReadTimeout:  c.ReadTimeout,
+			WriteTimeout: c.WriteTimeout,
+		}), nil
+	default:
+		return nil, fmt.Errorf("unsupported redis mode: %s", c.Mode)
+	}
+}
+
+func redisMode(c *cfg.RedisConfig) string {
+	if c.Mode == "" {
+		return RedisModeSingle
+	}
+	return c.Mode
+}
+
 func (r *RedisManager) Disconnect() error {
 	r.mu.Lock()
 	defer r.mu.Unlock()
@@ -94,7 +155,7 @@ func (r *RedisManager) HealthCheck(ctx context.Context) error {
 	return client.Ping(ctx).Err()
 }
 
-func (r *RedisManager) GetClient() *redis.Client {
+func (r *RedisManager) GetClient() redis.UniversalClient {
 	r.mu.RLock()
 	defer r.mu.RUnlock()
 	return r.client
@@ -121,3 +182,72 @@ func (r *RedisManager) Stats(ctx context.Context) (map[string]any, error) {
 	}
 	return map[string]any{"info": info}, nil
 }
+
+// === JSON VALUES ===
+// Go has no generic methods, pass the client, e.g. SetJSON(ctx, mgr.GetClient(), ...)
+
+// SetJSON stores val as JSON under key, ttl 0 means no expiry
+func SetJSON[T any](ctx context.Context, client redis.Cmdable, key string, val T, ttl time.Duration) error {
+	data, err := json.Marshal(val)
+	if err != nil {
+		return fmt.Errorf("failed to marshal %s: %w", key, err)
+	}
+	return client.Set(ctx, key, data, ttl).Err()
+}
+
+// GetJSON decodes the JSON stored under key, a missing key is ok=false with no error
+func GetJSON[T any](ctx context.Context, client redis.Cmdable, key string) (val T, ok bool, err error) {
+	data, err := client.Get(ctx, key).Bytes()
+	if errors.Is(err, redis.Nil) {
+		return val, false, nil
+	}
+	if err != nil {
+		return val, false, err
+	}
+	if err := json.Unmarshal(data, &val); err != nil {
+		return val, false, fmt.Errorf("failed to unmarshal %s: %w", key, err)
+	}
+	return val, true, nil
+}
+
+// === DISTRIBUTED LOCK ===
+
+// deletes the lock only if it is still held by the caller's token
+var releaseLockScript = redis.NewScript(`
+if redis.call("GET", KEYS[1]) == ARGV[1] then
+	return redis.call("DEL", KEYS[1])
+end
+return 0
+`)
+
+// AcquireLock tries to take a lock with SET NX PX. ok=false means the lock is held by someone else.
+// release is safe to call after the ttl expired - it never removes a lock taken over by another owner.
+func (r *RedisManager) AcquireLock(ctx context.Context, key string, ttl time.Duration) (release func(ctx context.Context) error, ok bool, err error) {
+	client := r.GetClient()
+	if client == nil {
+		return nil, false, fmt.Errorf("redis client not initialized")
+	}
+
+	buf := make([]byte, 16)
+	if _, err := rand.Read(buf); err != nil {
+		return nil, false, fmt.Errorf("failed to generate lock token: %w", err)
+	}
+	token := hex.EncodeToString(buf)
+	lockKey := "lock:" + key
+
+	ok, err = client.SetNX(ctx, lockKey, token, ttl).Result()
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to acquire lock %s: %w", key, err)
+	}
+	if !ok {
+		return nil, false, nil
+	}
+
+	release = func(ctx context.Context) error {
+		if err := releaseLockScript.Run(ctx, client, []string{lockKey}, token).Err(); err != nil {
+			return fmt.Errorf("failed to release lock %s: %w", key, err)
+		}
+		return nil
+	}
+	return release, true, nil
+}
@@ -0,0 +1,215 @@
+package connection
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	cfg "remaster/shared"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+)
+
+func newTestRedisManager(t *testing.T) (*RedisManager, *miniredis.Miniredis) {
+	t.Helper()
+	mr := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() { _ = client.Close() })
+	return &RedisManager{client: client, config: &cfg.RedisConfig{}}, mr
+}
+
+func TestAcquireLockContention(t *testing.T) {
+	mgr, _ := newTestRedisManager(t)
+	ctx := context.Background()
+
+	release, ok, err := mgr.AcquireLock(ctx, "token-cleanup", time.Minute)
+	if err != nil || !ok {
+		t.Fatalf("first AcquireLock = %v, %v, want the lock", ok, err)
+	}
+
+	if _, ok, err := mgr.AcquireLock(ctx, "token-cleanup", time.Minute); err != nil || ok {
+		t.Fatalf("second AcquireLock = %v, %v, want it refused while held", ok, err)
+	}
+	if _, ok, err := mgr.AcquireLock(ctx, "other-job", time.Minute); err != nil || !ok {
+		t.Fatalf("AcquireLock of another key = %v, %v, want it granted", ok, err)
+	}
+
+	if err := release(ctx); err != nil {
+		t.Fatalf("release: %v", err)
+	}
+	if _, ok, err := mgr.AcquireLock(ctx, "token-cleanup", time.Minute); err != nil || !ok {
+		t.Fatalf("AcquireLock after release = %v, %v, want the lock", ok, err)
+	}
+}
+
+func TestAcquireLockExpiresWithTTL(t *testing.T) {
+	mgr, mr := newTestRedisManager(t)
+	ctx := context.Background()
+
+	if _, ok, err := mgr.AcquireLock(ctx, "token-cleanup", time.Minute); err != nil || !ok {
+		t.Fatalf("AcquireLock = %v, %v, want the lock", ok, err)
+	}
+	if ttl := mr.TTL("lock:token-cleanup"); ttl != time.Minute {
+		t.Fatalf("lock ttl = %s, want 1m", ttl)
+	}
+
+	// the holder crashed without releasing
+	mr.FastForward(time.Minute + time.Second)
+	if _, ok, err := mgr.AcquireLock(ctx, "token-cleanup", time.Minute); err != nil || !ok {
+		t.Fatalf("AcquireLock after the ttl = %v, %v, want the lock", ok, err)
+	}
+}
+
+func TestReleaseOnlyRemovesOwnLock(t *testing.T) {
+	mgr, mr := newTestRedisManager(t)
+	ctx := context.Background()
+
+	staleRelease, ok, err := mgr.AcquireLock(ctx, "token-cleanup", time.Second)
+	if err != nil || !ok {
+		t.Fatalf("AcquireLock = %v, %v, want the lock", ok, err)
+	}
+	mr.FastForward(2 * time.Second)
+
+	// another replica takes the lock over after the first one's ttl ran out
+	if _, ok, err := mgr.AcquireLock(ctx, "token-cleanup", time.Minute); err != nil || !ok {
+		t.Fatalf("takeover AcquireLock = %v, %v, want the lock", ok, err)
+	}
+	holder, _ := mr.Get("lock:token-cleanup")
+
+	if err := staleRelease(ctx); err != nil {
+		t.Fatalf("stale release: %v", err)
+	}
+	if got, _ := mr.Get("lock:token-cleanup"); got != holder {
+		t.Fatalf("lock = %q after the stale release, want the new holder's %q", got, holder)
+	}
+}
+
+func TestNewRedisClientBuildsClientForMode(t *testing.T) {
+	tests := []struct {
+		name    string
+		config  cfg.RedisConfig
+		check   func(redis.UniversalClient) bool
+		wantErr bool
+	}{
+		{
+			name:   "empty mode is single",
+			config: cfg.RedisConfig{Host: "localhost", Port: "6379"},
+			check:  func(c redis.UniversalClient) bool { _, ok := c.(*redis.Client); return ok },
+		},
+		{
+			name:   "single",
+			config: cfg.RedisConfig{Mode: RedisModeSingle, Host: "localhost", Port: "6379"},
+			check:  func(c redis.UniversalClient) bool { _, ok := c.(*redis.Client); return ok },
+		},
+		{
+			// a failover client is a *redis.Client that asks the sentinels for the master
+			name: "sentinel",
+			config: cfg.RedisConfig{
+				Mode:          RedisModeSentinel,
+				MasterName:    "mymaster",
+				SentinelAddrs: []string{"sentinel-0:26379", "sentinel-1:26379"},
+			},
+			check: func(c redis.UniversalClient) bool { _, ok := c.(*redis.Client); return ok },
+		},
+		{
+			name:   "cluster",
+			config: cfg.RedisConfig{Mode: RedisModeCluster, ClusterAddrs: []string{"node-0:6379", "node-1:6379"}},
+			check:  func(c redis.UniversalClient) bool { _, ok := c.(*redis.ClusterClient); return ok },
+		},
+		{name: "sentinel without master", config: cfg.RedisConfig{Mode: RedisModeSentinel, SentinelAddrs: []string{"s:26379"}}, wantErr: true},
+		{name: "sentinel without addresses", config: cfg.RedisConfig{Mode: RedisModeSentinel, MasterName: "mymaster"}, wantErr: true},
+		{name: "cluster without addresses", config: cfg.RedisConfig{Mode: RedisModeCluster}, wantErr: true},
+		{name: "unknown mode", config: cfg.RedisConfig{Mode: "ring"}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client, err := newRedisClient(&tt.config)
+			if tt.wantErr {
+				if err == nil {
+					_ = client.Close()
+					t.Fatal("newRedisClient succeeded, want a config error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("newRedisClient: %v", err)
+			}
+			defer client.Close()
+			if !tt.check(client) {
+				t.Fatalf("client is %T, wrong type for mode %q", client, tt.config.Mode)
+			}
+		})
+	}
+}
+
+func TestNewRedisClientUsesSentinelMaster(t *testing.T) {
+	client, err := newRedisClient(&cfg.RedisConfig{
+		Mode:          RedisModeSentinel,
+		MasterName:    "mymaster",
+		SentinelAddrs: []string{"sentinel-0:26379"},
+		DB:            2,
+	})
+	if err != nil {
+		t.Fatalf("newRedisClient: %v", err)
+	}
+	defer client.Close()
+
+	opts := client.(*redis.Client).Options()
+	// failover clients dial through the sentinel, the address is a placeholder
+	if opts.Addr != "FailoverClient" || opts.DB != 2 {
+		t.Fatalf("options addr = %q db = %d, want a failover client on db 2", opts.Addr, opts.DB)
+	}
+}
+
+type cachedUser struct {
+	ID    string   `json:"id"`
+	Roles []string `json:"roles"`
+}
+
+func TestJSONRoundTrip(t *testing.T) {
+	mgr, _ := newTestRedisManager(t)
+	ctx := context.Background()
+	want := cachedUser{ID: "u-1", Roles: []string{"client", "admin"}}
+
+	if err := SetJSON(ctx, mgr.GetClient(), "user:u-1", want, 0); err != nil {
+		t.Fatalf("SetJSON: %v", err)
+	}
+	got, ok, err := GetJSON[cachedUser](ctx, mgr.GetClient(), "user:u-1")
+	if err != nil || !ok {
+		t.Fatalf("GetJSON = %v, %v, want a hit", ok, err)
+	}
+	if got.ID != want.ID || len(got.Roles) != 2 || got.Roles[1] != "admin" {
+		t.Fatalf("GetJSON = %+v, want %+v", got, want)
+	}
+}
+
+func TestJSONMissAndExpiry(t *testing.T) {
+	mgr, mr := newTestRedisManager(t)
+	ctx := context.Background()
+
+	if _, ok, err := GetJSON[cachedUser](ctx, mgr.GetClient(), "user:missing"); err != nil || ok {
+		t.Fatalf("GetJSON of a missing key = %v, %v, want a clean miss", ok, err)
+	}
+
+	if err := SetJSON(ctx, mgr.GetClient(), "user:u-1", cachedUser{ID: "u-1"}, time.Minute); err != nil {
+		t.Fatalf("SetJSON: %v", err)
+	}
+	if ttl := mr.TTL("user:u-1"); ttl != time.Minute {
+		t.Fatalf("ttl = %s, want 1m", ttl)
+	}
+	mr.FastForward(time.Minute + time.Second)
+	if _, ok, err := GetJSON[cachedUser](ctx, mgr.GetClient(), "user:u-1"); err != nil || ok {
+		t.Fatalf("GetJSON after the ttl = %v, %v, want a clean miss", ok, err)
+	}
+}
+
+func TestGetJSONReportsUndecodableValue(t *testing.T) {
+	mgr, mr := newTestRedisManager(t)
+	if err := mr.Set("user:u-1", "not json"); err != nil {
+		t.Fatalf("seed: %v", err)
+	}
+	if _, ok, err := GetJSON[cachedUser](context.Background(), mgr.GetClient(), "user:u-1"); err == nil || ok {
+		t.Fatalf("GetJSON of garbage = %v, %v, want an error", ok, err)
+	}
+}
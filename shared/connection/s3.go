@@ -0,0 +1,240 @@
+package connection
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"sync"
+	"time"
+
+	cfg "remaster/shared"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	awsconfig "github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/credentials"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+)
+
+// ErrObjectNotFound - HeadObject found no object under the key
+var ErrObjectNotFound = errors.New("object not found")
+
+// S3API - subset of the s3 client we use, allows mocking in services
+type S3API interface {
+	PutObject(ctx context.Context, key string, body io.Reader, contentType string) error
+	GetPresignedURL(ctx context.Context, key string, ttl time.Duration) (string, error)
+	GetPresignedUploadURL(ctx context.Context, key, contentType string, size int64, ttl time.Duration) (string, error)
+	HeadObject(ctx context.Context, key string) (*ObjectInfo, error)
+	DeleteObject(ctx context.Context, key string) error
+}
+
+// ObjectInfo - metadata of a stored object
+type ObjectInfo struct {
+	Size        int64
+	ContentType string
+}
+
+type S3Manager struct {
+	client  *s3.Client
+	presign *s3.PresignClient
+	config  *cfg.AWSConfig
+	mu      sync.RWMutex
+}
+
+var (
+	s3Instance *S3Manager
+	s3Once     sync.Once
+)
+
+// singleton
+func NewS3Manager(cfg *cfg.AWSConfig) *S3Manager {
+	s3Once.Do(func() {
+		s3Instance = &S3Manager{config: cfg}
+	})
+	return s3Instance
+}
+
+func (m *S3Manager) Connect(ctx context.Context) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.client != nil {
+		log.Println("S3 client already initialized")
+		return nil
+	}
+
+	region := m.config.S3Region
+	if region == "" {
+		region = m.config.Region
+	}
+
+	awsCfg, err := awsconfig.LoadDefaultConfig(ctx,
+		awsconfig.WithRegion(region),
+		awsconfig.WithCredentialsProvider(credentials.NewStaticCredentialsProvider(
+			m.config.AccessKeyID, m.config.SecretKey, "",
+		)),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to load AWS config: %w", err)
+	}
+
+	client := s3.NewFromConfig(awsCfg, func(o *s3.Options) {
+		// custom endpoint for minio / localstack
+		if m.config.Endpoint != "" {
+			o.BaseEndpoint = aws.String(m.config.Endpoint)
+			o.UsePathStyle = true
+		}
+	})
+
+	headCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	if _, err := client.HeadBucket(headCtx, &s3.HeadBucketInput{Bucket: aws.String(m.config.S3Bucket)}); err != nil {
+		return fmt.Errorf("failed to reach S3 bucket %s: %w", m.config.S3Bucket, err)
+	}
+
+	m.client = client
+	m.presign = s3.NewPresignClient(client)
+
+	log.Printf("Successfully connected to S3 bucket: %s", m.config.S3Bucket)
+	return nil
+}
+
+func (m *S3Manager) Disconnect() error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	// sdk client holds no persistent connections
+	m.client = nil
+	m.presign = nil
+	log.Println("S3 client released")
+	return nil
+}
+
+func (m *S3Manager) HealthCheck(ctx context.Context) error {
+	client, err := m.getClient()
+	if err != nil {
+		return err
+	}
+	_, err = client.HeadBucket(ctx, &s3.HeadBucketInput{Bucket: aws.String(m.config.S3Bucket)})
+	return err
+}
+
+func (m *S3Manager) getClient() (*s3.Client, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	if m.client == nil {
+		return nil, fmt.Errorf("S3 client not initialized")
+	}
+	return m.client, nil
+}
+
+func (m *S3Manager) getPresignClient() (*s3.PresignClient, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	if m.presign == nil {
+		return nil, fmt.Errorf("S3 client not initialized")
+	}
+	return m.presign, nil
+}
+
+// === HELPER FUNCTIONS ===
+
+func (m *S3Manager) PutObject(ctx context.Context, key string, body io.Reader, contentType string) error {
+	client, err := m.getClient()
+	if err != nil {
+		return err
+	}
+
+	_, err = client.PutObject(ctx, &s3.PutObjectInput{
+		Bucket:      aws.String(m.config.S3Bucket),
+		Key:         aws.String(key),
+		Body:        body,
+		ContentType: aws.String(contentType),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to put object %s: %w", key, err)
+	}
+	return nil
+}
+
+// GetPresignedURL returns a temporary download url
+func (m *S3Manager) GetPresignedURL(ctx context.Context, key string, ttl time.Duration) (string, error) {
+	presign, err := m.getPresignClient()
+	if err != nil {
+		return "", err
+	}
+
+	req, err := presign.PresignGetObject(ctx, &s3.GetObjectInput{
+		Bucket: aws.String(m.config.S3Bucket),
+		Key:    aws.String(key),
+	}, s3.WithPresignExpires(ttl))
+	if err != nil {
+		return "", fmt.Errorf("failed to presign get %s: %w", key, err)
+	}
+	return req.URL, nil
+}
+
+// GetPresignedUploadURL returns a temporary PUT url, content type and size are signed
+// so S3 rejects uploads that do not match what was validated
+func (m *S3Manager) GetPresignedUploadURL(ctx context.Context, key, contentType string, size int64, ttl time.Duration) (string, error) {
+	presign, err := m.getPresignClient()
+	if err != nil {
+		return "", err
+	}
+
+	req, err := presign.PresignPutObject(ctx, &s3.PutObjectInput{
+		Bucket:        aws.String(m.config.S3Bucket),
+		Key:           aws.String(key),
+		ContentType:   aws.String(contentType),
+		ContentLength: aws.Int64(size),
+	}, s3.WithPresignExpires(ttl))
+	if err != nil {
+		return "", fmt.Errorf("failed to presign put %s: %w", key, err)
+	}
+	return req.URL, nil
+}
+
+// HeadObject reads the metadata of a stored object, ErrObjectNotFound when nothing was put under the key
+func (m *S3Manager) HeadObject(ctx context.Context, key string) (*ObjectInfo, error) {
+	client, err := m.getClient()
+	if err != nil {
+		return nil, err
+	}
+
+	out, err := client.HeadObject(ctx, &s3.HeadObjectInput{
+		Bucket: aws.String(m.config.S3Bucket),
+		Key:    aws.String(key),
+	})
+	if err != nil {
+		var notFound *types.NotFound
+		if errors.As(err, &notFound) {
+			return nil, ErrObjectNotFound
+		}
+		return nil, fmt.Errorf("failed to head object %s: %w", key, err)
+	}
+	return &ObjectInfo{
+		Size:        aws.ToInt64(out.ContentLength),
+		ContentType: aws.ToString(out.ContentType),
+	}, nil
+}
+
+func (m *S3Manager) DeleteObject(ctx context.Context, key string) error {
+	client, err := m.getClient()
+	if err != nil {
+		return err
+	}
+
+	_, err = client.DeleteObject(ctx, &s3.DeleteObjectInput{
+		Bucket: aws.String(m.config.S3Bucket),
+		Key:    aws.String(key),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to delete object %s: %w", key, err)
+	}
+	return nil
+}
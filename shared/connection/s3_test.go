@@ -0,0 +1,87 @@
+package connection
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	cfg "remaster/shared"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/credentials"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+)
+
+// newTestS3Manager points the sdk client at endpoint, presigning never leaves the process
+func newTestS3Manager(t *testing.T, endpoint string) *S3Manager {
+	t.Helper()
+	client := s3.New(s3.Options{
+		Region:       "eu-central-1",
+		Credentials:  credentials.NewStaticCredentialsProvider("AKIATEST", "secret", ""),
+		BaseEndpoint: aws.String(endpoint),
+		UsePathStyle: true,
+	})
+	return &S3Manager{
+		client:  client,
+		presign: s3.NewPresignClient(client),
+		config:  &cfg.AWSConfig{S3Bucket: "remaster-media"},
+	}
+}
+
+func TestGetPresignedUploadURLSignsContentTypeAndLength(t *testing.T) {
+	m := newTestS3Manager(t, "http://localhost:9000")
+
+	raw, err := m.GetPresignedUploadURL(context.Background(), "user/owner/media.jpg", "image/jpeg", 1024, 15*time.Minute)
+	if err != nil {
+		t.Fatalf("GetPresignedUploadURL: %v", err)
+	}
+	u, err := url.Parse(raw)
+	if err != nil {
+		t.Fatalf("parse %q: %v", raw, err)
+	}
+
+	if u.Path != "/remaster-media/user/owner/media.jpg" {
+		t.Fatalf("path = %q, want the bucket and key", u.Path)
+	}
+	signed := strings.Split(u.Query().Get("X-Amz-SignedHeaders"), ";")
+	for _, header := range []string{"content-type", "content-length"} {
+		if !slices.Contains(signed, header) {
+			t.Errorf("signed headers %v do not include %s", signed, header)
+		}
+	}
+	if got := u.Query().Get("X-Amz-Expires"); got != "900" {
+		t.Fatalf("X-Amz-Expires = %q, want 900", got)
+	}
+}
+
+func TestHeadObjectMapsMissingObjectToErrObjectNotFound(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/remaster-media/user/owner/present.jpg" {
+			w.Header().Set("Content-Type", "image/png")
+			w.Header().Set("Content-Length", "2048")
+			w.WriteHeader(http.StatusOK)
+			return
+		}
+		w.WriteHeader(http.StatusNotFound)
+	}))
+	defer srv.Close()
+	m := newTestS3Manager(t, srv.URL)
+
+	info, err := m.HeadObject(context.Background(), "user/owner/present.jpg")
+	if err != nil {
+		t.Fatalf("HeadObject of a stored object: %v", err)
+	}
+	if info.Size != 2048 || info.ContentType != "image/png" {
+		t.Fatalf("object info = %+v, want 2048 bytes of image/png", info)
+	}
+
+	if _, err := m.HeadObject(context.Background(), "user/owner/missing.jpg"); !errors.Is(err, ErrObjectNotFound) {
+		t.Fatalf("HeadObject of a missing object = %v, want ErrObjectNotFound", err)
+	}
+}
@@ -0,0 +1,72 @@
+package ctxkeys
+
+import (
+	"context"
+	"log/slog"
+)
+
+// key is unexported so no other package, and no plain string, can collide with these
+type key int
+
+const (
+	loggerKey key = iota
+	correlationIDKey
+	requestIDKey
+	userIDKey
+	userTypeKey
+	orgIDKey
+)
+
+func WithLogger(ctx context.Context, l *slog.Logger) context.Context {
+	return context.WithValue(ctx, loggerKey, l)
+}
+
+// Logger returns the request scoped logger, nil when none was set
+func Logger(ctx context.Context) *slog.Logger {
+	l, _ := ctx.Value(loggerKey).(*slog.Logger)
+	return l
+}
+
+func WithCorrelationID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, correlationIDKey, id)
+}
+
+func CorrelationID(ctx context.Context) string {
+	return stringValue(ctx, correlationIDKey)
+}
+
+func WithRequestID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, requestIDKey, id)
+}
+
+func RequestID(ctx context.Context) string {
+	return stringValue(ctx, requestIDKey)
+}
+
+// WithUser stores the authenticated caller, userType is the role ("client", "anonymous", ...)
+func WithUser(ctx context.Context, userID, userType string) context.Context {
+	ctx = context.WithValue(ctx, userIDKey, userID)
+	return context.WithValue(ctx, userTypeKey, userType)
+}
+
+func UserID(ctx context.Context) string {
+	return stringValue(ctx, userIDKey)
+}
+
+func UserType(ctx context.Context) string {
+	return stringValue(ctx, userTypeKey)
+}
+
+// WithOrgID stores the caller's organization, only multi-tenant users have one
+func WithOrgID(ctx context.Context, orgID string) context.Context {
+	return context.WithValue(ctx, orgIDKey, orgID)
+}
+
+func OrgID(ctx context.Context) string {
+	return stringValue(ctx, orgIDKey)
+}
+
+func stringValue(ctx context.Context, k key) string {
+	v, _ := ctx.Value(k).(string)
+	return v
+}
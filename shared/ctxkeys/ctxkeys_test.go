@@ -0,0 +1,64 @@
+package ctxkeys
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"testing"
+)
+
+func TestValuesRoundTrip(t *testing.T) {
+	l := slog.New(slog.NewTextHandler(io.Discard, nil))
+	ctx := context.Background()
+	ctx = WithLogger(ctx, l)
+	ctx = WithCorrelationID(ctx, "cid-1")
+	ctx = WithRequestID(ctx, "rid-1")
+	ctx = WithUser(ctx, "u-1", "client")
+	ctx = WithOrgID(ctx, "org-1")
+
+	if Logger(ctx) != l {
+		t.Error("Logger did not return the stored logger")
+	}
+	for name, got := range map[string][2]string{
+		"correlation id": {CorrelationID(ctx), "cid-1"},
+		"request id":     {RequestID(ctx), "rid-1"},
+		"user id":        {UserID(ctx), "u-1"},
+		"user type":      {UserType(ctx), "client"},
+		"org id":         {OrgID(ctx), "org-1"},
+	} {
+		if got[0] != got[1] {
+			t.Errorf("%s = %q, want %q", name, got[0], got[1])
+		}
+	}
+}
+
+func TestMissingValuesAreZero(t *testing.T) {
+	ctx := context.Background()
+	if Logger(ctx) != nil || CorrelationID(ctx) != "" || RequestID(ctx) != "" ||
+		UserID(ctx) != "" || UserType(ctx) != "" || OrgID(ctx) != "" {
+		t.Fatal("empty context returned a value")
+	}
+}
+
+func TestKeysDoNotCollideWithPlainKeys(t *testing.T) {
+	ctx := context.Background()
+	// what call sites used before the typed keys, and the ints the keys are built on
+	for _, k := range []any{"logger", "correlation_id", "request_id", "user_id", "user_type", "org_id"} {
+		ctx = context.WithValue(ctx, k, "plain")
+	}
+	for i := range 6 {
+		ctx = context.WithValue(ctx, i, "plain")
+	}
+	if Logger(ctx) != nil || CorrelationID(ctx) != "" || RequestID(ctx) != "" ||
+		UserID(ctx) != "" || UserType(ctx) != "" || OrgID(ctx) != "" {
+		t.Fatal("a plain key was read as a typed one")
+	}
+
+	ctx = WithUser(ctx, "u-1", "admin")
+	if v := ctx.Value("user_id"); v != "plain" {
+		t.Fatalf(`Value("user_id") = %v, the typed key overwrote it`, v)
+	}
+	if UserID(ctx) != "u-1" || UserType(ctx) != "admin" {
+		t.Fatal("typed values lost next to plain keys")
+	}
+}
@@ -0,0 +1,164 @@
+package db
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const (
+	DefaultPageSize int64 = 20
+	MaxPageSize     int64 = 100
+)
+
+// PageRequest is 1-based, zero values fall back to the first page of DefaultPageSize
+type PageRequest struct {
+	Page     int64 `json:"page"`
+	PageSize int64 `json:"page_size"`
+}
+
+type PageResponse[T any] struct {
+	Items      []T   `json:"items"`
+	Total      int64 `json:"total"`
+	Page       int64 `json:"page"`
+	PageSize   int64 `json:"page_size"`
+	TotalPages int64 `json:"total_pages"`
+}
+
+// Normalize clamps page and page size into valid bounds
+func (p PageRequest) Normalize() PageRequest {
+	if p.Page < 1 {
+		p.Page = 1
+	}
+	switch {
+	case p.PageSize < 1:
+		p.PageSize = DefaultPageSize
+	case p.PageSize > MaxPageSize:
+		p.PageSize = MaxPageSize
+	}
+	return p
+}
+
+func (p PageRequest) Skip() int64 {
+	return (p.Page - 1) * p.PageSize
+}
+
+// Paginate runs filter against col with skip/limit and counts all matching documents
+func Paginate[T any](ctx context.Context, col *mongo.Collection, filter any, page PageRequest, sort bson.D) (*PageResponse[T], error) {
+	page = page.Normalize()
+
+	total, err := col.CountDocuments(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+
+	opts := options.Find().SetSkip(page.Skip()).SetLimit(page.PageSize)
+	if len(sort) > 0 {
+		opts.SetSort(sort)
+	}
+
+	cursor, err := col.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	items := make([]T, 0, page.PageSize)
+	if err := cursor.All(ctx, &items); err != nil {
+		return nil, err
+	}
+
+	return &PageResponse[T]{
+		Items:      items,
+		Total:      total,
+		Page:       page.Page,
+		PageSize:   page.PageSize,
+		TotalPages: (total + page.PageSize - 1) / page.PageSize,
+	}, nil
+}
+
+// CursorRequest pages by _id instead of offset, an empty Cursor starts from the beginning
+type CursorRequest struct {
+	Cursor string `json:"cursor"`
+	Limit  int64  `json:"limit"`
+}
+
+// CursorResponse has an empty NextCursor on the last page
+type CursorResponse[T any] struct {
+	Items      []T    `json:"items"`
+	NextCursor string `json:"next_cursor,omitempty"`
+}
+
+// ErrInvalidCursor is returned for cursors that weren't produced by CursorPaginate
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+func (r CursorRequest) limit() int64 {
+	switch {
+	case r.Limit < 1:
+		return DefaultPageSize
+	case r.Limit > MaxPageSize:
+		return MaxPageSize
+	default:
+		return r.Limit
+	}
+}
+
+// EncodeCursor makes an opaque cursor from the last _id of a page
+func EncodeCursor(id primitive.ObjectID) string {
+	return base64.RawURLEncoding.EncodeToString(id[:])
+}
+
+func DecodeCursor(cursor string) (primitive.ObjectID, error) {
+	var id primitive.ObjectID
+	raw, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil || len(raw) != len(id) {
+		return id, ErrInvalidCursor
+	}
+	copy(id[:], raw)
+	return id, nil
+}
+
+// CursorPaginate walks filter in _id order (newest first when descending) with a
+// keyset on _id, so pages stay stable while documents are inserted. idOf returns
+// a decoded item's _id for the next cursor
+func CursorPaginate[T any](ctx context.Context, col *mongo.Collection, filter bson.M, req CursorRequest, descending bool, idOf func(T) primitive.ObjectID) (*CursorResponse[T], error) {
+	limit := req.limit()
+	order, op := 1, "$gt"
+	if descending {
+		order, op = -1, "$lt"
+	}
+
+	query := filter
+	if req.Cursor != "" {
+		after, err := DecodeCursor(req.Cursor)
+		if err != nil {
+			return nil, err
+		}
+		query = bson.M{"$and": bson.A{filter, bson.M{"_id": bson.M{op: after}}}}
+	}
+
+	// one extra document tells whether there is a next page
+	opts := options.Find().SetSort(bson.D{{Key: "_id", Value: order}}).SetLimit(limit + 1)
+	cursor, err := col.Find(ctx, query, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	items := make([]T, 0, limit+1)
+	if err := cursor.All(ctx, &items); err != nil {
+		return nil, err
+	}
+
+	resp := &CursorResponse[T]{Items: items}
+	if int64(len(items)) > limit {
+		resp.Items = items[:limit]
+		resp.NextCursor = EncodeCursor(idOf(resp.Items[limit-1]))
+	}
+	return resp, nil
+}
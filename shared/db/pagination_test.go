@@ -0,0 +1,163 @@
+package db
+
+import (
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/integration/mtest"
+)
+
+type item struct {
+	Name string `bson:"name"`
+}
+
+// mockPage queues the count and find replies Paginate expects, in that order
+func mockPage(mt *mtest.T, total int64, names ...string) {
+	ns := mt.Coll.Database().Name() + "." + mt.Coll.Name()
+	count := mtest.CreateCursorResponse(0, ns, mtest.FirstBatch)
+	if total > 0 {
+		count = mtest.CreateCursorResponse(0, ns, mtest.FirstBatch, bson.D{{Key: "n", Value: total}})
+	}
+	docs := make([]bson.D, 0, len(names))
+	for _, name := range names {
+		docs = append(docs, bson.D{{Key: "name", Value: name}})
+	}
+	mt.AddMockResponses(count, mtest.CreateCursorResponse(0, ns, mtest.FirstBatch, docs...))
+}
+
+func TestPaginate(t *testing.T) {
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+
+	tests := []struct {
+		name       string
+		total      int64
+		page       PageRequest
+		returned   []string
+		skip       int64
+		totalPages int64
+	}{
+		{"first page", 5, PageRequest{Page: 1, PageSize: 2}, []string{"a", "b"}, 0, 3},
+		{"last page", 5, PageRequest{Page: 3, PageSize: 2}, []string{"e"}, 4, 3},
+		{"empty collection", 0, PageRequest{Page: 1, PageSize: 2}, nil, 0, 0},
+	}
+	for _, tt := range tests {
+		mt.Run(tt.name, func(mt *mtest.T) {
+			mockPage(mt, tt.total, tt.returned...)
+
+			resp, err := Paginate[item](mt.Context(), mt.Coll, bson.M{}, tt.page, bson.D{{Key: "name", Value: 1}})
+			if err != nil {
+				mt.Fatalf("Paginate: %v", err)
+			}
+			if resp.Total != tt.total || resp.TotalPages != tt.totalPages {
+				mt.Fatalf("total = %d over %d pages, want %d over %d", resp.Total, resp.TotalPages, tt.total, tt.totalPages)
+			}
+			if resp.Page != tt.page.Page || resp.PageSize != tt.page.PageSize {
+				mt.Fatalf("page = %d/%d, want %d/%d", resp.Page, resp.PageSize, tt.page.Page, tt.page.PageSize)
+			}
+			if resp.Items == nil || len(resp.Items) != len(tt.returned) {
+				mt.Fatalf("items = %v, want %d", resp.Items, len(tt.returned))
+			}
+
+			mt.GetStartedEvent() // count
+			find := mt.GetStartedEvent().Command
+			if got := find.Lookup("skip"); tt.skip > 0 && got.AsInt64() != tt.skip {
+				mt.Fatalf("skip = %v, want %d", got, tt.skip)
+			}
+			if got := find.Lookup("limit").AsInt64(); got != tt.page.PageSize {
+				mt.Fatalf("limit = %d, want %d", got, tt.page.PageSize)
+			}
+		})
+	}
+}
+
+func TestPageRequestNormalize(t *testing.T) {
+	tests := []struct {
+		in, want PageRequest
+	}{
+		{PageRequest{}, PageRequest{Page: 1, PageSize: DefaultPageSize}},
+		{PageRequest{Page: -2, PageSize: 10}, PageRequest{Page: 1, PageSize: 10}},
+		{PageRequest{Page: 4, PageSize: MaxPageSize + 1}, PageRequest{Page: 4, PageSize: MaxPageSize}},
+	}
+	for _, tt := range tests {
+		if got := tt.in.Normalize(); got != tt.want {
+			t.Errorf("Normalize(%+v) = %+v, want %+v", tt.in, got, tt.want)
+		}
+	}
+}
+
+type keyed struct {
+	ID primitive.ObjectID `bson:"_id"`
+}
+
+func TestCursorPaginateWalksEveryDocumentOnce(t *testing.T) {
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+
+	mt.Run("walk", func(mt *mtest.T) {
+		start := time.Now()
+		ids := make([]primitive.ObjectID, 5)
+		for i := range ids {
+			ids[i] = primitive.NewObjectIDFromTimestamp(start.Add(time.Duration(i) * time.Second))
+		}
+		ns := mt.Coll.Database().Name() + "." + mt.Coll.Name()
+		// the mock plays the server, answering each page from the requested _id on
+		reply := func(from int) {
+			docs := make([]bson.D, 0, 3)
+			for _, id := range ids[from:min(from+3, len(ids))] {
+				docs = append(docs, bson.D{{Key: "_id", Value: id}})
+			}
+			mt.AddMockResponses(mtest.CreateCursorResponse(0, ns, mtest.FirstBatch, docs...))
+		}
+
+		var walked []primitive.ObjectID
+		req := CursorRequest{Limit: 2}
+		for page := 0; ; page++ {
+			reply(len(walked))
+			resp, err := CursorPaginate(mt.Context(), mt.Coll, bson.M{"user_id": "u1"}, req, false, func(k keyed) primitive.ObjectID { return k.ID })
+			if err != nil {
+				mt.Fatalf("page %d: %v", page, err)
+			}
+
+			find := mt.GetStartedEvent().Command
+			if got := find.Lookup("limit").AsInt64(); got != 3 {
+				mt.Fatalf("page %d limit = %d, want one past the page size", page, got)
+			}
+			if req.Cursor != "" {
+				after := find.Lookup("filter", "$and").Array().Index(1).Value().Document().Lookup("_id", "$gt").ObjectID()
+				if after != walked[len(walked)-1] {
+					mt.Fatalf("page %d starts after %s, want the previous page's last id %s", page, after.Hex(), walked[len(walked)-1].Hex())
+				}
+			}
+
+			for _, k := range resp.Items {
+				walked = append(walked, k.ID)
+			}
+			if resp.NextCursor == "" {
+				break
+			}
+			req.Cursor = resp.NextCursor
+		}
+
+		if len(walked) != len(ids) {
+			mt.Fatalf("walked %d documents, want %d", len(walked), len(ids))
+		}
+		for i := range ids {
+			if walked[i] != ids[i] {
+				mt.Fatalf("document %d = %s, want %s with no gaps or repeats", i, walked[i].Hex(), ids[i].Hex())
+			}
+		}
+	})
+}
+
+func TestDecodeCursor(t *testing.T) {
+	id := primitive.NewObjectID()
+	if got, err := DecodeCursor(EncodeCursor(id)); err != nil || got != id {
+		t.Fatalf("DecodeCursor(EncodeCursor(%s)) = %s, %v", id.Hex(), got.Hex(), err)
+	}
+	for _, bad := range []string{"not base64!", "c2hvcnQ", id.Hex()} {
+		if _, err := DecodeCursor(bad); err != ErrInvalidCursor {
+			t.Errorf("DecodeCursor(%q) = %v, want ErrInvalidCursor", bad, err)
+		}
+	}
+}
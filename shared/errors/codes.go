@@ -0,0 +1,85 @@
+package errors
+
+import (
+	"net/http"
+	"sort"
+
+	"google.golang.org/grpc/codes"
+)
+
+// ErrorCode is the stable, client facing identifier of an error. Codes are
+// part of the public API: add new ones freely, but renaming or removing one
+// requires bumping CatalogVersion
+type ErrorCode string
+
+const CatalogVersion = 1
+
+const (
+	CodeValidation         ErrorCode = "VALIDATION_ERROR"
+	CodeBadRequest         ErrorCode = "BAD_REQUEST"
+	CodeNotFound           ErrorCode = "NOT_FOUND"
+	CodeConflict           ErrorCode = "CONFLICT_ERROR"
+	CodeUnauthorized       ErrorCode = "UNAUTHORIZED"
+	CodeForbidden          ErrorCode = "FORBIDDEN"
+	CodePermission         ErrorCode = "PERMISSION_ERROR"
+	CodeRateLimitExceeded  ErrorCode = "RATE_LIMIT_EXCEEDED"
+	CodeTooManyRequests    ErrorCode = "TOO_MANY_REQUESTS"
+	CodeInternal           ErrorCode = "INTERNAL_ERROR"
+	CodeDatabase           ErrorCode = "DATABASE_ERROR"
+	CodeServiceUnavailable ErrorCode = "SERVICE_UNAVAILABLE"
+	CodeDeadlineExceeded   ErrorCode = "DEADLINE_EXCEEDED"
+	CodeClientClosed       ErrorCode = "CLIENT_CLOSED_REQUEST"
+)
+
+// CodeSpec - defaults every error with the code is created with
+type CodeSpec struct {
+	Type       ErrorType
+	HTTPStatus int
+	GRPCCode   codes.Code
+	Retryable  bool
+}
+
+var catalog = map[ErrorCode]CodeSpec{
+	CodeValidation:         {ErrorTypeValidation, http.StatusBadRequest, codes.InvalidArgument, false},
+	CodeBadRequest:         {ErrorTypeBadRequest, http.StatusBadRequest, codes.InvalidArgument, false},
+	CodeNotFound:           {ErrorTypeNotFound, http.StatusNotFound, codes.NotFound, false},
+	CodeConflict:           {ErrorTypeConflict, http.StatusConflict, codes.AlreadyExists, false},
+	CodeUnauthorized:       {ErrorTypeUnauthorized, http.StatusUnauthorized, codes.Unauthenticated, false},
+	CodeForbidden:          {ErrorTypeForbidden, http.StatusForbidden, codes.PermissionDenied, false},
+	CodePermission:         {ErrorTypeForbidden, http.StatusForbidden, codes.PermissionDenied, false},
+	CodeRateLimitExceeded:  {ErrorTypeRateLimit, http.StatusTooManyRequests, codes.ResourceExhausted, true},
+	CodeTooManyRequests:    {ErrorTypeRateLimit, http.StatusTooManyRequests, codes.ResourceExhausted, true},
+	CodeInternal:           {ErrorTypeInternal, http.StatusInternalServerError, codes.Internal, false},
+	CodeDatabase:           {ErrorTypeDatabase, http.StatusInternalServerError, codes.Internal, false},
+	CodeServiceUnavailable: {ErrorTypeUnavailable, http.StatusServiceUnavailable, codes.Unavailable, true},
+	CodeDeadlineExceeded:   {ErrorTypeUnavailable, http.StatusGatewayTimeout, codes.DeadlineExceeded, true},
+	CodeClientClosed:       {ErrorTypeUnavailable, StatusClientClosedRequest, codes.Canceled, false},
+}
+
+// LookupCode returns the registered defaults for a code
+func LookupCode(code ErrorCode) (CodeSpec, bool) {
+	spec, ok := catalog[code]
+	return spec, ok
+}
+
+// Codes lists every registered code, sorted, for docs and client generation
+func Codes() []ErrorCode {
+	list := make([]ErrorCode, 0, len(catalog))
+	for code := range catalog {
+		list = append(list, code)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i] < list[j] })
+	return list
+}
+
+// newCatalogError builds an AppError from the code's registered defaults,
+// unknown codes fall back to internal so a typo can't leak a 200-ish status
+func newCatalogError(code ErrorCode, msg string, cause error, details map[string]string) *AppError {
+	spec, ok := catalog[code]
+	if !ok {
+		spec = catalog[CodeInternal]
+	}
+	appErr := NewAppError(spec.Type, code, msg, spec.HTTPStatus, cause, details)
+	appErr.Retryable = spec.Retryable
+	return appErr
+}
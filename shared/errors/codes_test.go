@@ -0,0 +1,81 @@
+package errors
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestEveryErrorTypeHasACode(t *testing.T) {
+	covered := make(map[ErrorType]bool)
+	for _, code := range Codes() {
+		spec, _ := LookupCode(code)
+		covered[spec.Type] = true
+	}
+
+	for et := ErrorTypeValidation; et <= ErrorTypeUnavailable; et++ {
+		if et.String() == "unknown" {
+			t.Fatalf("error type %d has no name", et)
+		}
+		if !covered[et] {
+			t.Errorf("error type %s has no registered code", et)
+		}
+	}
+}
+
+func TestCatalogCodesAreUniqueAndComplete(t *testing.T) {
+	codes := Codes()
+	if !slices.IsSorted(codes) {
+		t.Fatalf("Codes() = %v, want sorted", codes)
+	}
+	if len(slices.Compact(slices.Clone(codes))) != len(codes) {
+		t.Fatalf("Codes() = %v, has duplicates", codes)
+	}
+
+	for _, code := range codes {
+		spec, ok := LookupCode(code)
+		if !ok {
+			t.Fatalf("LookupCode(%s) not found", code)
+		}
+		if code == "" || spec.HTTPStatus < 400 {
+			t.Errorf("%q = %+v, want a non-empty code with an error status", code, spec)
+		}
+	}
+}
+
+func TestConstructorsUseTheirCatalogEntry(t *testing.T) {
+	constructors := map[ErrorCode]*AppError{
+		CodeValidation:         NewValidationError("msg", nil),
+		CodeBadRequest:         NewBadRequestError("msg"),
+		CodeNotFound:           NewNotFoundError("msg", nil),
+		CodeConflict:           NewConflictError("msg", nil),
+		CodeUnauthorized:       NewUnauthorizedError("msg"),
+		CodeForbidden:          NewForbiddenError("msg"),
+		CodePermission:         NewPermissionError("msg"),
+		CodeRateLimitExceeded:  NewRateLimitError("msg"),
+		CodeTooManyRequests:    NewTooManyRequestsError("msg"),
+		CodeInternal:           NewInternalError("msg", nil),
+		CodeDatabase:           NewDatabaseError("msg", nil),
+		CodeServiceUnavailable: NewServiceUnavailableError("msg", nil),
+		CodeDeadlineExceeded:   NewDeadlineExceededError("msg", nil),
+		CodeClientClosed:       NewClientClosedError("msg", nil),
+	}
+	// a new code needs a constructor, or at least an entry here
+	if len(constructors) != len(Codes()) {
+		t.Fatalf("%d constructors for %d codes", len(constructors), len(Codes()))
+	}
+
+	for code, appErr := range constructors {
+		spec, _ := LookupCode(code)
+		if appErr.Code != code || appErr.Type != spec.Type || appErr.StatusCode != spec.HTTPStatus || appErr.Retryable != spec.Retryable {
+			t.Errorf("%s constructor = {%s %s %d %v}, want %+v", code, appErr.Code, appErr.Type, appErr.StatusCode, appErr.Retryable, spec)
+		}
+	}
+}
+
+func TestUnknownCodeFallsBackToInternal(t *testing.T) {
+	appErr := newCatalogError("NO_SUCH_CODE", "msg", nil, nil)
+	internal, _ := LookupCode(CodeInternal)
+	if appErr.StatusCode != internal.HTTPStatus || appErr.Type != ErrorTypeInternal {
+		t.Fatalf("unknown code = %d %s, want the internal defaults", appErr.StatusCode, appErr.Type)
+	}
+}
@@ -2,16 +2,31 @@ package errors
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
 	"log/slog"
+	"math"
 	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"google.golang.org/genproto/googleapis/rpc/errdetails"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/protoadapt"
+	"google.golang.org/protobuf/types/known/durationpb"
 )
 
+// RequestStartKey is the gin context key holding the request's start time,
+// the gateway sets it so timeouts can report how long the call ran
+const RequestStartKey = "request_start"
+
+// StatusClientClosedRequest is the nginx convention for a client that hung up before the response
+const StatusClientClosedRequest = 499
+
 type ErrorHandler struct {
 	logger *slog.Logger
 }
@@ -30,24 +45,62 @@ type ErrorResponse struct {
 	Details any    `json:"details,omitempty"`
 }
 
+// RFC 7807 problem document, code and details are extension members
+type ProblemDetails struct {
+	Type     string `json:"type"`
+	Title    string `json:"title"`
+	Status   int    `json:"status"`
+	Detail   string `json:"detail,omitempty"`
+	Instance string `json:"instance,omitempty"`
+	Code     string `json:"code"`
+	Details  any    `json:"details,omitempty"`
+}
+
+const problemContentType = "application/problem+json"
+
+// WriteError writes resp as a problem document when the client asks for
+// application/problem+json, otherwise as the plain ErrorResponse
+func WriteError(c *gin.Context, status int, resp ErrorResponse) {
+	if !strings.Contains(c.GetHeader("Accept"), problemContentType) {
+		c.JSON(status, resp)
+		return
+	}
+
+	body, err := json.Marshal(ProblemDetails{
+		Type:     "urn:remaster:error:" + strings.ToLower(resp.Code),
+		Title:    http.StatusText(status),
+		Status:   status,
+		Detail:   resp.Error,
+		Instance: c.Request.URL.Path,
+		Code:     resp.Code,
+		Details:  resp.Details,
+	})
+	if err != nil {
+		c.JSON(status, resp)
+		return
+	}
+	c.Data(status, problemContentType, body)
+}
+
 // ---- HTTP error handling ----
 func (eh *ErrorHandler) HandleGinError(c *gin.Context, err error) {
 	appErr, ok := err.(*AppError)
 	if !ok {
 		eh.logError(c.Request.Context(), eh.logger, err)
-		c.JSON(http.StatusInternalServerError, ErrorResponse{
+		WriteError(c, http.StatusInternalServerError, ErrorResponse{
 			Success: false,
 			Error:   "Internal server error",
-			Code:    "INTERNAL_ERROR",
+			Code:    string(CodeInternal),
 		})
 		return
 	}
 
 	eh.logError(c.Request.Context(), eh.logger, appErr)
-	c.JSON(appErr.StatusCode, ErrorResponse{
+	setRetryAfter(c, appErr.RetryAfterSeconds())
+	WriteError(c, appErr.StatusCode, ErrorResponse{
 		Success: false,
 		Error:   appErr.Message,
-		Code:    appErr.Code,
+		Code:    string(appErr.Code),
 		Details: appErr.Details,
 	})
 }
@@ -56,7 +109,44 @@ func (eh *ErrorHandler) HandleGinError(c *gin.Context, err error) {
 func (eh *ErrorHandler) HandleGrpcError(err error) error {
 	var appErr *AppError
 	if errors.As(err, &appErr) {
-		return status.Error(appErr.Type.ToGrpcCode(), appErr.Message)
+		grpcCode := appErr.Type.ToGrpcCode()
+		if spec, ok := LookupCode(appErr.Code); ok {
+			grpcCode = spec.GRPCCode
+		}
+		st := status.New(grpcCode, appErr.Message)
+
+		// the catalog code travels as ErrorInfo.Reason, the gateway returns it as is
+		details := []protoadapt.MessageV1{&errdetails.ErrorInfo{
+			Reason: string(appErr.Code),
+			Domain: "remaster",
+		}}
+		// keep field violations so the gateway can rebuild them
+		if len(appErr.Details) > 0 {
+			fields := make([]string, 0, len(appErr.Details))
+			for field := range appErr.Details {
+				fields = append(fields, field)
+			}
+			sort.Strings(fields)
+
+			br := &errdetails.BadRequest{}
+			for _, field := range fields {
+				br.FieldViolations = append(br.FieldViolations, &errdetails.BadRequest_FieldViolation{
+					Field:       field,
+					Description: appErr.Details[field],
+				})
+			}
+			details = append(details, br)
+		}
+		if appErr.RetryAfter > 0 {
+			details = append(details, &errdetails.RetryInfo{RetryDelay: durationpb.New(appErr.RetryAfter)})
+		}
+
+		if withDetails, err := st.WithDetails(details...); err == nil {
+			st = withDetails
+		} else {
+			eh.logger.Warn("failed to attach error details", slog.Any("error", err))
+		}
+		return st.Err()
 	}
 	// fallback
 	return status.Error(codes.Internal, "Internal server error")
@@ -66,15 +156,16 @@ func (eh *ErrorHandler) HandleHttpError(c *gin.Context, err error) {
 	var appErr *AppError
 	if errors.As(err, &appErr) {
 		eh.logError(c.Request.Context(), eh.logger, err)
+		setRetryAfter(c, appErr.RetryAfterSeconds())
 
 		response := ErrorResponse{
 			Success: false,
 			Error:   appErr.Message,
-			Code:    appErr.Code,
+			Code:    string(appErr.Code),
 			Details: appErr.Details,
 		}
 
-		c.JSON(appErr.StatusCode, response)
+		WriteError(c, appErr.StatusCode, response)
 		return
 	}
 
@@ -86,10 +177,10 @@ func (eh *ErrorHandler) HandleHttpError(c *gin.Context, err error) {
 	response := ErrorResponse{
 		Success: false,
 		Error:   "Internal server error",
-		Code:    "INTERNAL_ERROR",
+		Code:    string(CodeInternal),
 	}
 
-	c.JSON(http.StatusInternalServerError, response)
+	WriteError(c, http.StatusInternalServerError, response)
 }
 
 // ---- gRPC → HTTP (для API Gateway) ----
@@ -104,10 +195,10 @@ func (eh *ErrorHandler) HandleGrpcToHttp(c *gin.Context, err error) {
 		resp := ErrorResponse{
 			Success: false,
 			Error:   "Internal server error",
-			Code:    "INTERNAL_ERROR",
+			Code:    string(CodeInternal),
 		}
 
-		c.JSON(http.StatusInternalServerError, resp)
+		WriteError(c, http.StatusInternalServerError, resp)
 		return
 	}
 
@@ -141,7 +232,24 @@ func (eh *ErrorHandler) HandleGrpcToHttp(c *gin.Context, err error) {
 		case *errdetails.ResourceInfo:
 			details["resource_type"] = info.ResourceType
 			details["resource_name"] = info.ResourceName
+		case *errdetails.RetryInfo:
+			if delay := info.GetRetryDelay(); delay != nil {
+				setRetryAfter(c, int(math.Ceil(delay.AsDuration().Seconds())))
+			}
+		}
+	}
+
+	// a cancelled call is the client going away, an exceeded deadline is us or upstream being slow
+	switch st.Code() {
+	case codes.Canceled:
+		if errors.Is(c.Request.Context().Err(), context.Canceled) {
+			httpStatus = StatusClientClosedRequest
+			resp.Error = "Client closed request"
 		}
+		setElapsed(c, details)
+	case codes.DeadlineExceeded:
+		resp.Error = "Upstream service timed out"
+		setElapsed(c, details)
 	}
 
 	if len(details) > 0 {
@@ -162,7 +270,19 @@ func (eh *ErrorHandler) HandleGrpcToHttp(c *gin.Context, err error) {
 		slog.String("message", st.Message()),
 	)
 
-	c.JSON(httpStatus, resp)
+	WriteError(c, httpStatus, resp)
+}
+
+func setElapsed(c *gin.Context, details map[string]any) {
+	if start := c.GetTime(RequestStartKey); !start.IsZero() {
+		details["elapsed_ms"] = time.Since(start).Milliseconds()
+	}
+}
+
+func setRetryAfter(c *gin.Context, seconds int) {
+	if seconds > 0 {
+		c.Header("Retry-After", strconv.Itoa(seconds))
+	}
 }
 
 func (eh *ErrorHandler) logError(ctx context.Context, logger *slog.Logger, err error) {
@@ -174,7 +294,7 @@ func (eh *ErrorHandler) logError(ctx context.Context, logger *slog.Logger, err e
 
 		logAttrs := []slog.Attr{
 			slog.String("error_type", string(appErr.Type.String())),
-			slog.String("error_code", appErr.Code),
+			slog.String("error_code", string(appErr.Code)),
 			slog.Any("details", appErr.Details),
 		}
 
@@ -0,0 +1,272 @@
+package errors
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/mongo"
+	spb "google.golang.org/genproto/googleapis/rpc/status"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+)
+
+func newTestErrorHandler() *ErrorHandler {
+	return NewErrorHandler(slog.New(slog.NewTextHandler(io.Discard, nil)))
+}
+
+// overTheWire sends err the way grpc does, as a marshalled google.rpc.Status
+func overTheWire(t *testing.T, err error) error {
+	t.Helper()
+	raw, marshalErr := proto.Marshal(status.Convert(err).Proto())
+	if marshalErr != nil {
+		t.Fatalf("marshal status: %v", marshalErr)
+	}
+	var received spb.Status
+	if err := proto.Unmarshal(raw, &received); err != nil {
+		t.Fatalf("unmarshal status: %v", err)
+	}
+	return status.ErrorProto(&received)
+}
+
+// toHTTP runs err through the gateway conversion and decodes the response
+func toHTTP(t *testing.T, err error) (*httptest.ResponseRecorder, ErrorResponse) {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+	rec := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(rec)
+	c.Request = httptest.NewRequest(http.MethodPost, "/api/v1/auth/register", nil)
+
+	newTestErrorHandler().HandleGrpcToHttp(c, err)
+
+	var resp ErrorResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("decode %q: %v", rec.Body.String(), err)
+	}
+	return rec, resp
+}
+
+func TestValidationErrorSurvivesGatewayRoundTrip(t *testing.T) {
+	appErr := NewValidationError("validation failed", map[string]string{
+		"email":    "must be a valid email address",
+		"password": "must be at least 8 characters",
+	})
+
+	grpcErr := overTheWire(t, newTestErrorHandler().HandleGrpcError(appErr))
+	rec, resp := toHTTP(t, grpcErr)
+
+	if rec.Code != http.StatusBadRequest {
+		t.Fatalf("status = %d, want 400", rec.Code)
+	}
+	if resp.Code != string(CodeValidation) {
+		t.Fatalf("code = %q, want %q", resp.Code, CodeValidation)
+	}
+	if resp.Error != "validation failed" {
+		t.Fatalf("error = %q, want the AppError message", resp.Error)
+	}
+
+	details, _ := resp.Details.(map[string]any)
+	violations, _ := details["field_violations"].(map[string]any)
+	if len(violations) != len(appErr.Details) {
+		t.Fatalf("field_violations = %v, want %v", details["field_violations"], appErr.Details)
+	}
+	for field, want := range appErr.Details {
+		if got := violations[field]; got != want {
+			t.Errorf("violation %q = %v, want %q", field, got, want)
+		}
+	}
+}
+
+func TestErrorWithoutDetailsHasNoFieldViolations(t *testing.T) {
+	grpcErr := overTheWire(t, newTestErrorHandler().HandleGrpcError(NewNotFoundError("user not found", nil)))
+	rec, resp := toHTTP(t, grpcErr)
+
+	if rec.Code != http.StatusNotFound {
+		t.Fatalf("status = %d, want 404", rec.Code)
+	}
+	if details, _ := resp.Details.(map[string]any); details["field_violations"] != nil {
+		t.Fatalf("details = %v, want no field_violations", resp.Details)
+	}
+}
+
+func TestRateLimitErrorSetsRetryAfterThroughTheGateway(t *testing.T) {
+	appErr := NewRateLimitError("Too many login attempts").WithRetryAfter(90 * time.Second)
+
+	grpcErr := overTheWire(t, newTestErrorHandler().HandleGrpcError(appErr))
+	rec, resp := toHTTP(t, grpcErr)
+
+	if rec.Code != http.StatusTooManyRequests {
+		t.Fatalf("status = %d, want 429", rec.Code)
+	}
+	if resp.Code != string(CodeRateLimitExceeded) {
+		t.Fatalf("code = %q, want %q", resp.Code, CodeRateLimitExceeded)
+	}
+	if got := rec.Header().Get("Retry-After"); got != "90" {
+		t.Fatalf("Retry-After = %q, want 90", got)
+	}
+}
+
+func TestNewDatabaseErrorRetryable(t *testing.T) {
+	tests := []struct {
+		name  string
+		cause error
+		want  bool
+	}{
+		{"network error", mongo.CommandError{Code: 6, Labels: []string{"NetworkError"}}, true},
+		{"deadline", fmt.Errorf("find user: %w", context.DeadlineExceeded), true},
+		{"duplicate key", mongo.WriteException{WriteErrors: []mongo.WriteError{{Code: 11000}}}, false},
+		{"no cause", nil, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := NewDatabaseError("database error", tt.cause).Retryable; got != tt.want {
+				t.Fatalf("Retryable = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRateLimitErrorsAreRetryable(t *testing.T) {
+	if !NewRateLimitError("Too many requests").Retryable {
+		t.Fatal("rate limit error is not retryable")
+	}
+	if NewValidationError("validation failed", nil).Retryable {
+		t.Fatal("validation error is retryable")
+	}
+}
+
+// handleWithAccept runs HandleGinError for err on a request sending accept
+func handleWithAccept(t *testing.T, err error, accept string) *httptest.ResponseRecorder {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+	rec := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(rec)
+	c.Request = httptest.NewRequest(http.MethodPost, "/api/v1/auth/register", nil)
+	if accept != "" {
+		c.Request.Header.Set("Accept", accept)
+	}
+
+	newTestErrorHandler().HandleGinError(c, err)
+	return rec
+}
+
+func TestHandleGinErrorNegotiatesProblemJSON(t *testing.T) {
+	appErr := NewValidationError("validation failed", map[string]string{"email": "must be a valid email address"})
+
+	for _, accept := range []string{"application/problem+json", "application/problem+json, application/json;q=0.9"} {
+		t.Run(accept, func(t *testing.T) {
+			rec := handleWithAccept(t, appErr, accept)
+			if rec.Code != http.StatusBadRequest {
+				t.Fatalf("status = %d, want 400", rec.Code)
+			}
+			if ct := rec.Header().Get("Content-Type"); ct != problemContentType {
+				t.Fatalf("Content-Type = %q, want %q", ct, problemContentType)
+			}
+
+			var problem ProblemDetails
+			if err := json.Unmarshal(rec.Body.Bytes(), &problem); err != nil {
+				t.Fatalf("decode %q: %v", rec.Body.String(), err)
+			}
+			want := ProblemDetails{
+				Type:     "urn:remaster:error:validation_error",
+				Title:    "Bad Request",
+				Status:   http.StatusBadRequest,
+				Detail:   "validation failed",
+				Instance: "/api/v1/auth/register",
+				Code:     string(CodeValidation),
+			}
+			details, _ := problem.Details.(map[string]any)
+			problem.Details = nil
+			if problem != want {
+				t.Fatalf("problem = %+v, want %+v", problem, want)
+			}
+			if details["email"] != "must be a valid email address" {
+				t.Fatalf("details = %v, want the field violation", details)
+			}
+		})
+	}
+}
+
+func TestHandleGinErrorDefaultsToJSON(t *testing.T) {
+	for _, accept := range []string{"", "application/json", "*/*"} {
+		t.Run(accept, func(t *testing.T) {
+			rec := handleWithAccept(t, NewNotFoundError("user not found", nil), accept)
+			if ct := rec.Header().Get("Content-Type"); ct != "application/json; charset=utf-8" {
+				t.Fatalf("Content-Type = %q, want plain json", ct)
+			}
+			var resp ErrorResponse
+			if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+				t.Fatalf("decode %q: %v", rec.Body.String(), err)
+			}
+			if rec.Code != http.StatusNotFound || resp.Success || resp.Code != string(CodeNotFound) || resp.Error != "user not found" {
+				t.Fatalf("response = %d %+v", rec.Code, resp)
+			}
+		})
+	}
+}
+
+// timedOut converts err for a request that started 250ms ago, cancelled when
+// the client hung up
+func timedOut(t *testing.T, err error, clientGone bool) (*httptest.ResponseRecorder, ErrorResponse) {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+	ctx, cancel := context.WithCancel(context.Background())
+	if clientGone {
+		cancel()
+	} else {
+		defer cancel()
+	}
+
+	rec := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(rec)
+	c.Request = httptest.NewRequest(http.MethodGet, "/v1/media/1", nil).WithContext(ctx)
+	c.Set(RequestStartKey, time.Now().Add(-250*time.Millisecond))
+
+	newTestErrorHandler().HandleGrpcToHttp(c, err)
+
+	var resp ErrorResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("decode %q: %v", rec.Body.String(), err)
+	}
+	return rec, resp
+}
+
+func TestGrpcCancellationAndDeadlineMapToDistinctStatuses(t *testing.T) {
+	tests := []struct {
+		name       string
+		code       codes.Code
+		clientGone bool
+		status     int
+	}{
+		{"client hung up", codes.Canceled, true, StatusClientClosedRequest},
+		{"upstream deadline", codes.DeadlineExceeded, false, http.StatusGatewayTimeout},
+		{"deadline after client left", codes.DeadlineExceeded, true, http.StatusGatewayTimeout},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec, resp := timedOut(t, overTheWire(t, status.Error(tt.code, "call ended")), tt.clientGone)
+			if rec.Code != tt.status {
+				t.Fatalf("status = %d, want %d", rec.Code, tt.status)
+			}
+			details, _ := resp.Details.(map[string]any)
+			if elapsed, ok := details["elapsed_ms"].(float64); !ok || elapsed < 250 {
+				t.Fatalf("details = %v, want elapsed_ms of at least 250", resp.Details)
+			}
+		})
+	}
+}
+
+func TestGrpcCancelledWithClientStillThereIsNotA499(t *testing.T) {
+	rec, _ := timedOut(t, status.Error(codes.Canceled, "upstream cancelled"), false)
+	if rec.Code == StatusClientClosedRequest {
+		t.Fatal("upstream cancellation reported as the client hanging up")
+	}
+}
@@ -1,10 +1,14 @@
 package errors
 
 import (
+	"context"
 	"errors"
 	"fmt"
+	"math"
 	"net/http"
+	"time"
 
+	"go.mongodb.org/mongo-driver/mongo"
 	"google.golang.org/grpc/codes"
 )
 
@@ -21,16 +25,21 @@ const (
 	ErrorTypeRateLimit
 	ErrorTypeInternal
 	ErrorTypeDatabase
+	ErrorTypeUnavailable
 )
 
 // Universal application error
 type AppError struct {
 	Type       ErrorType
-	Code       string
+	Code       ErrorCode
 	Message    string
 	Cause      error
 	StatusCode int
 	Details    map[string]string
+
+	// Retryable tells clients the same request may succeed later
+	Retryable  bool
+	RetryAfter time.Duration
 }
 
 func (e *AppError) Error() string {
@@ -44,8 +53,23 @@ func (e *AppError) Unwrap() error {
 	return e.Cause
 }
 
+// WithRetryAfter marks error as retryable after the given delay
+func (e *AppError) WithRetryAfter(d time.Duration) *AppError {
+	e.Retryable = true
+	e.RetryAfter = d
+	return e
+}
+
+// RetryAfterSeconds - value for the Retry-After header, rounded up
+func (e *AppError) RetryAfterSeconds() int {
+	if e.RetryAfter <= 0 {
+		return 0
+	}
+	return int(math.Ceil(e.RetryAfter.Seconds()))
+}
+
 // Factories for different types of errors
-func NewAppError(errType ErrorType, code, msg string, status int, cause error, details map[string]string) *AppError {
+func NewAppError(errType ErrorType, code ErrorCode, msg string, status int, cause error, details map[string]string) *AppError {
 	return &AppError{
 		Type:       errType,
 		Code:       code,
@@ -57,47 +81,61 @@ func NewAppError(errType ErrorType, code, msg string, status int, cause error, d
 }
 
 func NewValidationError(msg string, details map[string]string) *AppError {
-	return NewAppError(ErrorTypeValidation, "VALIDATION_ERROR", msg, http.StatusBadRequest, nil, details)
+	return newCatalogError(CodeValidation, msg, nil, details)
 }
 
 func NewConflictError(msg string, cause error) *AppError {
-	return NewAppError(ErrorTypeConflict, "CONFLICT_ERROR", msg, http.StatusConflict, cause, nil)
+	return newCatalogError(CodeConflict, msg, cause, nil)
 }
 
 func NewDatabaseError(msg string, cause error) *AppError {
-	return NewAppError(ErrorTypeDatabase, "DATABASE_ERROR", msg, http.StatusInternalServerError, cause, nil)
+	appErr := newCatalogError(CodeDatabase, msg, cause, nil)
+	appErr.Retryable = isTransient(cause)
+	return appErr
 }
 
 func NewInternalError(msg string, cause error) *AppError {
-	return NewAppError(ErrorTypeInternal, "INTERNAL_ERROR", msg, http.StatusInternalServerError, cause, nil)
+	return newCatalogError(CodeInternal, msg, cause, nil)
 }
 
 func NewNotFoundError(msg string, cause error) *AppError {
-	return NewAppError(ErrorTypeNotFound, "NOT_FOUND", msg, http.StatusNotFound, nil, nil)
+	return newCatalogError(CodeNotFound, msg, nil, nil)
 }
 
 func NewUnauthorizedError(msg string) *AppError {
-	return NewAppError(ErrorTypeUnauthorized, "UNAUTHORIZED", msg, http.StatusUnauthorized, nil, nil)
+	return newCatalogError(CodeUnauthorized, msg, nil, nil)
 }
 
 func NewForbiddenError(msg string) *AppError {
-	return NewAppError(ErrorTypeForbidden, "FORBIDDEN", msg, http.StatusForbidden, nil, nil)
+	return newCatalogError(CodeForbidden, msg, nil, nil)
 }
 
 func NewBadRequestError(msg string) *AppError {
-	return NewAppError(ErrorTypeBadRequest, "BAD_REQUEST", msg, http.StatusBadRequest, nil, nil)
+	return newCatalogError(CodeBadRequest, msg, nil, nil)
 }
 
 func NewRateLimitError(msg string) *AppError {
-	return NewAppError(ErrorTypeRateLimit, "RATE_LIMIT_EXCEEDED", msg, http.StatusTooManyRequests, nil, nil)
+	return newCatalogError(CodeRateLimitExceeded, msg, nil, nil)
 }
 
 func NewPermissionError(msg string) *AppError {
-	return NewAppError(ErrorTypeForbidden, "PERMISSION_ERROR", msg, http.StatusForbidden, nil, nil)
+	return newCatalogError(CodePermission, msg, nil, nil)
 }
 
 func NewTooManyRequestsError(msg string) *AppError {
-	return NewAppError(ErrorTypeRateLimit, "TOO_MANY_REQUESTS", msg, http.StatusTooManyRequests, nil, nil)
+	return newCatalogError(CodeTooManyRequests, msg, nil, nil)
+}
+
+func NewServiceUnavailableError(msg string, cause error) *AppError {
+	return newCatalogError(CodeServiceUnavailable, msg, cause, nil)
+}
+
+func NewDeadlineExceededError(msg string, cause error) *AppError {
+	return newCatalogError(CodeDeadlineExceeded, msg, cause, nil)
+}
+
+func NewClientClosedError(msg string, cause error) *AppError {
+	return newCatalogError(CodeClientClosed, msg, cause, nil)
 }
 
 // -------- Mapping --------
@@ -122,6 +160,8 @@ func (et ErrorType) String() string {
 		return "internal"
 	case ErrorTypeDatabase:
 		return "database"
+	case ErrorTypeUnavailable:
+		return "unavailable"
 	default:
 		return "unknown"
 	}
@@ -144,6 +184,8 @@ func (et ErrorType) ToGrpcCode() codes.Code {
 		return codes.ResourceExhausted
 	case ErrorTypeDatabase, ErrorTypeInternal:
 		return codes.Internal
+	case ErrorTypeUnavailable:
+		return codes.Unavailable
 	default:
 		return codes.Internal
 	}
@@ -183,6 +225,14 @@ func GrpcToHTTP(code codes.Code) int {
 
 // -------- Helper --------
 
+// timeouts and dropped connections are worth retrying, constraint violations are not
+func isTransient(err error) bool {
+	if err == nil {
+		return false
+	}
+	return mongo.IsTimeout(err) || mongo.IsNetworkError(err) || errors.Is(err, context.DeadlineExceeded)
+}
+
 func AsAppError(err error) (*AppError, bool) {
 	var appErr *AppError
 	if errors.As(err, &appErr) {
@@ -24,6 +24,14 @@ func (v *Validator) Valid() bool {
 	return len(v.Errors) == 0
 }
 
+// Err returns a validation AppError carrying the collected errors, or nil.
+func (v *Validator) Err() error {
+	if v.Valid() {
+		return nil
+	}
+	return NewValidationError("validation failed", v.Errors)
+}
+
 // AddError adds an error message to the map (so long as no entry already exists for
 // the given key).
 func (v *Validator) AddError(key, message string) {
@@ -0,0 +1,179 @@
+package events
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	"remaster/shared/connection"
+
+	"github.com/segmentio/kafka-go"
+)
+
+// user lifecycle event types
+const (
+	UserRegistered      = "user.registered"
+	UserLoggedIn        = "user.logged_in"
+	UserPasswordChanged = "user.password_changed"
+	// login from a device and IP never seen for the user, consumers may ask for email confirmation
+	UserNewDeviceLogin = "user.new_device_login"
+)
+
+type UserEvent struct {
+	Type       string            `json:"type"`
+	UserID     string            `json:"user_id"`
+	Email      string            `json:"email,omitempty"`
+	UserType   string            `json:"user_type,omitempty"`
+	OccurredAt time.Time         `json:"occurred_at"`
+	Metadata   map[string]string `json:"metadata,omitempty"`
+}
+
+type Publisher interface {
+	Publish(ctx context.Context, event UserEvent) error
+}
+
+// ==== Kafka ====
+
+type KafkaPublisher struct {
+	mgr   *connection.KafkaManager
+	topic string
+}
+
+func NewKafkaPublisher(mgr *connection.KafkaManager, topic string) *KafkaPublisher {
+	return &KafkaPublisher{mgr: mgr, topic: topic}
+}
+
+// keyed by user id so events of one user stay ordered
+func (p *KafkaPublisher) Publish(ctx context.Context, event UserEvent) error {
+	if event.OccurredAt.IsZero() {
+		event.OccurredAt = time.Now().UTC()
+	}
+	return p.mgr.PublishJSON(ctx, p.topic, event.UserID, event)
+}
+
+// UserEventHandler decodes user events for a KafkaConsumer
+func UserEventHandler(handle func(ctx context.Context, event UserEvent) error) connection.MessageHandler {
+	return func(ctx context.Context, msg kafka.Message) error {
+		var event UserEvent
+		if err := json.Unmarshal(msg.Value, &event); err != nil {
+			return fmt.Errorf("failed to decode user event: %w", err)
+		}
+		return handle(ctx, event)
+	}
+}
+
+// ==== Async ====
+
+// ErrQueueFull - the event was dropped because the publisher fell behind
+var ErrQueueFull = errors.New("event queue is full")
+
+// AsyncPublisher queues events and hands them to next from Run, so a slow broker
+// never holds up the request that produced the event
+type AsyncPublisher struct {
+	next    Publisher
+	queue   chan UserEvent
+	timeout time.Duration
+	logger  *slog.Logger
+}
+
+func NewAsyncPublisher(next Publisher, queueSize int, timeout time.Duration, logger *slog.Logger) *AsyncPublisher {
+	return &AsyncPublisher{
+		next:    next,
+		queue:   make(chan UserEvent, queueSize),
+		timeout: timeout,
+		logger:  logger.With(slog.String("events", "async-publisher")),
+	}
+}
+
+// Publish never blocks, the event is dropped with ErrQueueFull when the queue is full
+func (p *AsyncPublisher) Publish(_ context.Context, event UserEvent) error {
+	select {
+	case p.queue <- event:
+		return nil
+	default:
+		return ErrQueueFull
+	}
+}
+
+// Run publishes queued events until ctx is done, then flushes what is left for at most one timeout
+func (p *AsyncPublisher) Run(ctx context.Context) error {
+	for {
+		select {
+		case event := <-p.queue:
+			p.publish(context.WithoutCancel(ctx), event)
+		case <-ctx.Done():
+			p.flush()
+			return nil
+		}
+	}
+}
+
+func (p *AsyncPublisher) flush() {
+	ctx, cancel := context.WithTimeout(context.Background(), p.timeout)
+	defer cancel()
+
+	for ctx.Err() == nil {
+		select {
+		case event := <-p.queue:
+			p.publish(ctx, event)
+		default:
+			return
+		}
+	}
+	if n := len(p.queue); n > 0 {
+		p.logger.Warn("Dropping unpublished events on shutdown", "count", n)
+	}
+}
+
+func (p *AsyncPublisher) publish(ctx context.Context, event UserEvent) {
+	ctx, cancel := context.WithTimeout(ctx, p.timeout)
+	defer cancel()
+
+	if err := p.next.Publish(ctx, event); err != nil {
+		p.logger.Error("Failed to publish user event", "type", event.Type, "user_id", event.UserID, "error", err)
+	}
+}
+
+// ==== Noop ====
+
+// NoopPublisher is used when kafka is disabled
+type NoopPublisher struct{}
+
+func (NoopPublisher) Publish(context.Context, UserEvent) error { return nil }
+
+// ==== Recording ====
+
+// RecordingPublisher keeps published events in memory, for tests
+type RecordingPublisher struct {
+	mu     sync.Mutex
+	events []UserEvent
+}
+
+func (p *RecordingPublisher) Publish(_ context.Context, event UserEvent) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.events = append(p.events, event)
+	return nil
+}
+
+// Events returns a copy of what was published so far, oldest first
+func (p *RecordingPublisher) Events() []UserEvent {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return append([]UserEvent(nil), p.events...)
+}
+
+// Types lists the types of the published events, oldest first
+func (p *RecordingPublisher) Types() []string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	types := make([]string, len(p.events))
+	for i, e := range p.events {
+		types[i] = e.Type
+	}
+	return types
+}
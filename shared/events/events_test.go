@@ -0,0 +1,135 @@
+package events
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/segmentio/kafka-go"
+)
+
+// blockingPublisher holds every publish until release is closed or ctx ends
+type blockingPublisher struct {
+	release   chan struct{}
+	published chan UserEvent
+}
+
+func (p *blockingPublisher) Publish(ctx context.Context, event UserEvent) error {
+	select {
+	case <-p.release:
+		p.published <- event
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func newAsync(next Publisher, queueSize int, timeout time.Duration) *AsyncPublisher {
+	return NewAsyncPublisher(next, queueSize, timeout, slog.New(slog.NewTextHandler(io.Discard, nil)))
+}
+
+func TestAsyncPublisherDoesNotWaitForTheBroker(t *testing.T) {
+	slow := &blockingPublisher{release: make(chan struct{}), published: make(chan UserEvent, 1)}
+	p := newAsync(slow, 4, time.Minute)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		_ = p.Run(ctx)
+		close(done)
+	}()
+
+	start := time.Now()
+	if err := p.Publish(context.Background(), UserEvent{Type: UserLoggedIn, UserID: "u1"}); err != nil {
+		t.Fatalf("Publish: %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > 100*time.Millisecond {
+		t.Fatalf("Publish took %s while the broker was stuck", elapsed)
+	}
+
+	close(slow.release)
+	select {
+	case event := <-slow.published:
+		if event.UserID != "u1" {
+			t.Fatalf("published %+v, want the queued event", event)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("queued event never reached the broker")
+	}
+
+	cancel()
+	<-done
+}
+
+func TestAsyncPublisherDropsWhenQueueIsFull(t *testing.T) {
+	p := newAsync(NoopPublisher{}, 1, time.Second)
+
+	if err := p.Publish(context.Background(), UserEvent{Type: UserRegistered}); err != nil {
+		t.Fatalf("first Publish: %v", err)
+	}
+	if err := p.Publish(context.Background(), UserEvent{Type: UserRegistered}); !errors.Is(err, ErrQueueFull) {
+		t.Fatalf("second Publish = %v, want ErrQueueFull", err)
+	}
+}
+
+func TestAsyncPublisherFlushesOnShutdown(t *testing.T) {
+	rec := &RecordingPublisher{}
+	p := newAsync(rec, 4, time.Second)
+	for _, typ := range []string{UserRegistered, UserLoggedIn} {
+		if err := p.Publish(context.Background(), UserEvent{Type: typ}); err != nil {
+			t.Fatalf("Publish: %v", err)
+		}
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := p.Run(ctx); err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+
+	got := rec.Types()
+	if len(got) != 2 || got[0] != UserRegistered || got[1] != UserLoggedIn {
+		t.Fatalf("flushed %v, want both queued events in order", got)
+	}
+}
+
+func TestAsyncPublisherBoundsEachWriteByTimeout(t *testing.T) {
+	stuck := &blockingPublisher{release: make(chan struct{}), published: make(chan UserEvent, 1)}
+	p := newAsync(stuck, 4, 20*time.Millisecond)
+	if err := p.Publish(context.Background(), UserEvent{Type: UserLoggedIn}); err != nil {
+		t.Fatalf("Publish: %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	start := time.Now()
+	_ = p.Run(ctx)
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Fatalf("shutdown flush took %s, want it cut off by the publish timeout", elapsed)
+	}
+}
+
+func TestUserEventHandlerDecodesMessages(t *testing.T) {
+	var got UserEvent
+	handler := UserEventHandler(func(_ context.Context, event UserEvent) error {
+		got = event
+		return nil
+	})
+
+	value, _ := json.Marshal(UserEvent{Type: UserRegistered, UserID: "u1", Email: "a@example.com"})
+	if err := handler(context.Background(), kafka.Message{Value: value}); err != nil {
+		t.Fatalf("handler: %v", err)
+	}
+	if got.Type != UserRegistered || got.UserID != "u1" {
+		t.Fatalf("decoded %+v, want the published event", got)
+	}
+
+	// undecodable messages go back to the consumer as errors, to be retried then skipped
+	if err := handler(context.Background(), kafka.Message{Value: []byte("{")}); err == nil {
+		t.Fatal("malformed message accepted")
+	}
+}
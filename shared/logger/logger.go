@@ -10,6 +10,7 @@ import (
 	"sync"
 
 	config "remaster/shared"
+	"remaster/shared/ctxkeys"
 
 	"github.com/fatih/color"
 )
@@ -21,12 +22,23 @@ const (
 	RequestIDKey     = "request_id"
 )
 
+// RedactedValue replaces the value of any attribute whose key is redacted
+const RedactedValue = "[REDACTED]"
+
 type PrettyHandler struct {
+	level  slog.Leveler
+	redact map[string]struct{}
+	// attrs from With, keys already carry the group prefix
+	attrs  []slog.Attr
+	groups []string
 }
 
 var (
 	once     sync.Once
 	instance *slog.Logger
+
+	// shared by every logger from New, SetLevel changes it at runtime
+	level = new(slog.LevelVar)
 )
 
 func init() {
@@ -61,7 +73,7 @@ func (h PrettyHandler) Handle(ctx context.Context, r slog.Record) error {
 	var correlationID, service, requestID string
 	attrs := map[string]any{}
 
-	r.Attrs(func(a slog.Attr) bool {
+	collect := func(a slog.Attr) {
 		switch a.Key {
 		case CorrelationIDKey:
 			correlationID = a.Value.String()
@@ -73,6 +85,12 @@ func (h PrettyHandler) Handle(ctx context.Context, r slog.Record) error {
 		default:
 			attrs[a.Key] = a.Value.Any()
 		}
+	}
+	for _, a := range h.attrs {
+		collect(a)
+	}
+	r.Attrs(func(a slog.Attr) bool {
+		collect(h.qualify(redactAttr(h.redact, a)))
 		return true
 	})
 
@@ -108,39 +126,55 @@ func (h PrettyHandler) Handle(ctx context.Context, r slog.Record) error {
 	return nil
 }
 
-func (h PrettyHandler) Enabled(ctx context.Context, level slog.Level) bool {
-	return true
+func (h PrettyHandler) Enabled(ctx context.Context, l slog.Level) bool {
+	return h.level == nil || l >= h.level.Level()
 }
 
 func (h PrettyHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	if len(attrs) == 0 {
+		return h
+	}
+	merged := make([]slog.Attr, 0, len(h.attrs)+len(attrs))
+	merged = append(merged, h.attrs...)
+	for _, a := range attrs {
+		merged = append(merged, h.qualify(redactAttr(h.redact, a)))
+	}
+	h.attrs = merged
 	return h
 }
 
 func (h PrettyHandler) WithGroup(name string) slog.Handler {
+	if name == "" {
+		return h
+	}
+	h.groups = append(h.groups[:len(h.groups):len(h.groups)], name)
 	return h
 }
 
+// qualify prefixes a's key with the open groups, e.g. "http.status"
+func (h PrettyHandler) qualify(a slog.Attr) slog.Attr {
+	if len(h.groups) == 0 {
+		return a
+	}
+	a.Key = strings.Join(h.groups, ".") + "." + a.Key
+	return a
+}
+
 // New creates a new structured logger
 func New(cfg config.LogConfig) *slog.Logger {
-	var level slog.Level
-	switch strings.ToLower(cfg.Level) {
-	case "debug":
-		level = slog.LevelDebug
-	case "warn":
-		level = slog.LevelWarn
-	case "error":
-		level = slog.LevelError
-	default:
-		level = slog.LevelInfo
-	}
+	SetLevel(cfg.Level)
 
+	redact := redactSet(cfg.RedactKeys)
 	opts := &slog.HandlerOptions{
 		Level: level,
+		ReplaceAttr: func(_ []string, a slog.Attr) slog.Attr {
+			return redactAttr(redact, a)
+		},
 	}
 
 	var handler slog.Handler
 	if cfg.Format == "pretty" {
-		handler = PrettyHandler{}
+		handler = PrettyHandler{level: level, redact: redact}
 	} else {
 		// Production JSON format
 		handler = slog.NewJSONHandler(os.Stdout, opts)
@@ -151,6 +185,48 @@ func New(cfg config.LogConfig) *slog.Logger {
 	)
 }
 
+func redactSet(keys []string) map[string]struct{} {
+	set := make(map[string]struct{}, len(keys))
+	for _, k := range keys {
+		set[strings.ToLower(k)] = struct{}{}
+	}
+	return set
+}
+
+// redactAttr masks a's value when its key is in the set, groups are walked so
+// nested keys are masked as well
+func redactAttr(set map[string]struct{}, a slog.Attr) slog.Attr {
+	if len(set) == 0 {
+		return a
+	}
+	if _, ok := set[strings.ToLower(a.Key)]; ok {
+		return slog.String(a.Key, RedactedValue)
+	}
+	if a.Value.Kind() == slog.KindGroup {
+		group := a.Value.Group()
+		masked := make([]any, len(group))
+		for i, ga := range group {
+			masked[i] = redactAttr(set, ga)
+		}
+		return slog.Group(a.Key, masked...)
+	}
+	return a
+}
+
+// SetLevel changes the level of every logger built by New, unknown names mean info
+func SetLevel(name string) {
+	switch strings.ToLower(name) {
+	case "debug":
+		level.Set(slog.LevelDebug)
+	case "warn":
+		level.Set(slog.LevelWarn)
+	case "error":
+		level.Set(slog.LevelError)
+	default:
+		level.Set(slog.LevelInfo)
+	}
+}
+
 // WithService adds service context to logger
 func WithService(logger *slog.Logger, serviceName string) *slog.Logger {
 	return logger.With(slog.String(ServiceNameKey, serviceName))
@@ -168,7 +244,7 @@ func WithRequestID(logger *slog.Logger, requestID string) *slog.Logger {
 
 // FromContext extracts logger from context or returns default
 func FromContext(ctx context.Context, defaultLogger *slog.Logger) *slog.Logger {
-	if logger, ok := ctx.Value("logger").(*slog.Logger); ok {
+	if logger := ctxkeys.Logger(ctx); logger != nil {
 		return logger
 	}
 	return defaultLogger
@@ -176,7 +252,5 @@ func FromContext(ctx context.Context, defaultLogger *slog.Logger) *slog.Logger {
 
 // ToContext adds logger to context
 func ToContext(ctx context.Context, log *slog.Logger) context.Context {
-	type contextKey string
-	const loggerKey contextKey = "logger"
-	return context.WithValue(ctx, loggerKey, log)
+	return ctxkeys.WithLogger(ctx, log)
 }
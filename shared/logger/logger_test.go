@@ -0,0 +1,154 @@
+package logger
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	config "remaster/shared"
+
+	"github.com/spf13/viper"
+)
+
+func TestLogLevelReloadsWhenConfigFileChanges(t *testing.T) {
+	base, err := os.ReadFile(filepath.Join("..", "..", "config.yaml"))
+	if err != nil {
+		t.Fatalf("read base config: %v", err)
+	}
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(path, base, 0o644); err != nil {
+		t.Fatalf("write config: %v", err)
+	}
+	t.Chdir(filepath.Dir(path))
+	viper.Reset()
+	t.Cleanup(viper.Reset)
+	t.Cleanup(func() { SetLevel("info") })
+
+	c, err := config.LoadConfig()
+	if err != nil {
+		t.Fatalf("LoadConfig: %v", err)
+	}
+	log := New(c.Log)
+	ctx := context.Background()
+	if !log.Enabled(ctx, slog.LevelDebug) {
+		t.Fatalf("level %q from the base config does not enable debug", c.Log.Level)
+	}
+
+	levels := make(chan string, 8)
+	config.WatchConfig(func(next *config.Config) {
+		SetLevel(next.Log.Level)
+		levels <- next.Log.Level
+	})
+
+	changed := strings.Replace(string(base), "level: debug", "level: error", 1)
+	if err := os.WriteFile(path, []byte(changed), 0o644); err != nil {
+		t.Fatalf("rewrite config: %v", err)
+	}
+
+	// a rewrite can fire more than one event, wait for the one carrying the edit
+	timeout := time.After(5 * time.Second)
+	for reloaded := false; !reloaded; {
+		select {
+		case l := <-levels:
+			reloaded = l == "error"
+		case <-timeout:
+			t.Fatal("config change was not picked up")
+		}
+	}
+
+	if log.Enabled(ctx, slog.LevelWarn) || !log.Enabled(ctx, slog.LevelError) {
+		t.Fatal("the existing logger did not move to the reloaded error level")
+	}
+}
+
+// captureStdout returns what fn printed, both handlers write straight to stdout
+func captureStdout(t *testing.T, fn func()) string {
+	t.Helper()
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatalf("pipe: %v", err)
+	}
+	stdout := os.Stdout
+	os.Stdout = w
+	defer func() { os.Stdout = stdout }()
+
+	done := make(chan []byte)
+	go func() {
+		b, _ := io.ReadAll(r)
+		done <- b
+	}()
+	fn()
+	_ = w.Close()
+	return string(<-done)
+}
+
+func TestRedactsSensitiveKeys(t *testing.T) {
+	keys := []string{"token", "password", "access_token", "refresh_token", "secret", "authorization"}
+	for _, format := range []string{"pretty", "json"} {
+		t.Run(format, func(t *testing.T) {
+			t.Cleanup(func() { SetLevel("info") })
+
+			out := captureStdout(t, func() {
+				// the JSON handler binds stdout when it is built
+				log := New(config.LogConfig{Level: "info", Format: format, RedactKeys: keys})
+				args := []any{"user_id", "u-1"}
+				for _, k := range keys {
+					args = append(args, k, "leaked-"+k)
+				}
+				log.Info("login", args...)
+				log.With("Authorization", "leaked-header").Info("with")
+				log.Info("nested", slog.Group("req", "password", "leaked-nested"))
+			})
+
+			if strings.Contains(out, "leaked-") {
+				t.Fatalf("secret value in output:\n%s", out)
+			}
+			if got := strings.Count(out, RedactedValue); got != len(keys)+2 {
+				t.Fatalf("%d values redacted, want %d:\n%s", got, len(keys)+2, out)
+			}
+			if !strings.Contains(out, "u-1") {
+				t.Fatalf("non-sensitive value missing:\n%s", out)
+			}
+		})
+	}
+}
+
+func TestPrettyHandlerKeepsWithAttrsAndGroups(t *testing.T) {
+	t.Cleanup(func() { SetLevel("info") })
+
+	out := captureStdout(t, func() {
+		log := New(config.LogConfig{Level: "info", Format: "pretty"})
+		log = WithRequestID(WithCorrelationID(WithService(log, "auth"), "cid-1"), "rid-1")
+		log.With("tenant", "acme").WithGroup("http").With("method", "GET").Info("served", "status", 200)
+	})
+
+	for _, want := range []string{"[svc:auth cid:cid-1 rid:rid-1] served", "app", "remaster", "tenant", "acme", "http.method", "GET", "http.status", "200"} {
+		if !strings.Contains(out, want) {
+			t.Fatalf("output lacks %q:\n%s", want, out)
+		}
+	}
+	// attrs added before the group stay unqualified
+	if strings.Contains(out, "http.tenant") {
+		t.Fatalf("group applied to an earlier attr:\n%s", out)
+	}
+}
+
+func TestPrettyHandlerWithDoesNotLeakBetweenLoggers(t *testing.T) {
+	t.Cleanup(func() { SetLevel("info") })
+
+	out := captureStdout(t, func() {
+		base := New(config.LogConfig{Level: "info", Format: "pretty"}).WithGroup("g")
+		base.With("a", "1").Info("first")
+		base.With("b", "2").Info("second")
+	})
+
+	first, second, ok := strings.Cut(out, "second")
+	if !ok || !strings.Contains(first, "g.a") || strings.Contains(second, "g.a") || !strings.Contains(second, "g.b") {
+		t.Fatalf("sibling loggers share attrs:\n%s", out)
+	}
+}
@@ -0,0 +1,63 @@
+package auth
+
+import (
+	"slices"
+
+	et "remaster/shared/errors"
+)
+
+// Validate methods are picked up by the server ValidationUnary interceptor, they only
+// check shape, business rules stay in the service. Messages that map to a tagged auth
+// model (register, login, ...) are validated by the service's ValidateRequest instead
+
+func (r *ValidateTokenRequest) Validate() error {
+	v := et.New()
+	v.Check(r.AccessToken != "", "access_token", "is required")
+	return v.Err()
+}
+
+func (r *ValidateTokensRequest) Validate() error {
+	v := et.New()
+	v.Check(len(r.AccessTokens) > 0, "access_tokens", "is required")
+	return v.Err()
+}
+
+func (r *UnlockAccountRequest) Validate() error {
+	v := et.New()
+	v.Check(r.UserId != "", "user_id", "is required")
+	v.Check(r.ActorId != "", "actor_id", "is required")
+	return v.Err()
+}
+
+// the user fields are checked by the auth service against its model
+func (r *AdminCreateUserRequest) Validate() error {
+	v := et.New()
+	v.Check(r.ActorId != "", "actor_id", "is required")
+	return v.Err()
+}
+
+func (r *SearchUsersRequest) Validate() error {
+	v := et.New()
+	v.Check(r.Page >= 0, "page", "must not be negative")
+	v.Check(r.PageSize >= 0, "page_size", "must not be negative")
+	return v.Err()
+}
+
+func (r *ListSessionsRequest) Validate() error {
+	v := et.New()
+	v.Check(r.UserId != "", "user_id", "is required")
+	v.Check(r.Limit >= 0, "limit", "must not be negative")
+	return v.Err()
+}
+
+func (r *GetUsersRequest) Validate() error {
+	v := et.New()
+	v.Check(!slices.Contains(r.UserIds, ""), "user_ids", "must not contain empty ids")
+	return v.Err()
+}
+
+func (r *LogoutRequest) Validate() error {
+	v := et.New()
+	v.Check(r.RefreshToken != "", "refresh_token", "is required")
+	return v.Err()
+}
@@ -7,17 +7,29 @@ import (
 	"net"
 	"time"
 
+	"github.com/prometheus/client_golang/prometheus"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/health"
 	"google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/keepalive"
 	"google.golang.org/grpc/reflection"
 
 	cfg "remaster/shared"
+	"remaster/shared/errors"
+)
+
+// health service names for kubernetes grpc probes, "" mirrors readiness
+const (
+	LivenessService  = "liveness"
+	ReadinessService = "readiness"
 )
 
 type InterceptorConfig struct {
 	EnableLogging  bool
 	EnableRecovery bool
+	EnableMetrics  bool
+	// registry for EnableMetrics, nil = prometheus default registry
+	MetricsRegistry prometheus.Registerer
 }
 
 type GRPCServerConfig struct {
@@ -27,19 +39,42 @@ type GRPCServerConfig struct {
 	EnableHealthCheck bool
 	EnableReflection  bool
 	InterceptorConfig InterceptorConfig
+	ErrorHandler      *errors.ErrorHandler
+	// calls slower than this are logged at warn, 0 = off
+	SlowRequestThreshold time.Duration
 }
 
 type GRPCServerManager struct {
 	server   *grpc.Server
+	health   *health.Server
 	listener net.Listener
 	logger   *slog.Logger
 	config   GRPCServerConfig
+	// closed once graceful shutdown has drained in-flight calls
+	drained chan struct{}
 }
 
 func (m *GRPCServerManager) GetGRPCServer() *grpc.Server {
 	return m.server
 }
 
+// serverOptions maps config limits to grpc server options, zero values keep grpc defaults
+func serverOptions(c *cfg.GRPCConfig) []grpc.ServerOption {
+	opts := []grpc.ServerOption{
+		grpc.MaxRecvMsgSize(c.MaxReceiveSize),
+		grpc.MaxSendMsgSize(c.MaxSendSize),
+		grpc.KeepaliveParams(keepalive.ServerParameters{
+			MaxConnectionIdle:     c.MaxConnectionIdle,
+			MaxConnectionAge:      c.MaxConnectionAge,
+			MaxConnectionAgeGrace: c.MaxConnectionAgeGrace,
+		}),
+	}
+	if c.MaxConcurrentStreams > 0 {
+		opts = append(opts, grpc.MaxConcurrentStreams(c.MaxConcurrentStreams))
+	}
+	return opts
+}
+
 func NewGRPCServer(cfg GRPCServerConfig) (*GRPCServerManager, error) {
 	cfg.Logger.Info("Creating gRPC server",
 		"address", cfg.Address,
@@ -64,20 +99,54 @@ func NewGRPCServer(cfg GRPCServerConfig) (*GRPCServerManager, error) {
 		cfg.Logger.Info("Recovery interceptor enabled")
 	}
 
+	// reject oversized or malformed metadata before it reaches logs and handlers
+	unaryInterceptors = append(unaryInterceptors, MetadataUnary(cfg.Config.MaxMetadataBytes))
+
+	// gzip (registered by the import in interceptors.go) is always accepted on requests,
+	// this only decides how responses are sent
+	if cfg.Config.Compression {
+		unaryInterceptors = append(unaryInterceptors, CompressionUnary(cfg.Config.CompressionMinBytes))
+		cfg.Logger.Info("Response compression enabled", "min_bytes", cfg.Config.CompressionMinBytes)
+	}
+
+	// drop calls that are already past their caller's deadline
+	unaryInterceptors = append(unaryInterceptors, DeadlineUnary())
+
+	// server side deadline for callers that didn't send one
+	if cfg.Config.DefaultTimeout > 0 || len(cfg.Config.MethodTimeouts) > 0 {
+		overrides := make(map[string]time.Duration, len(cfg.Config.MethodTimeouts))
+		for _, mt := range cfg.Config.MethodTimeouts {
+			overrides[mt.Method] = mt.Timeout
+		}
+		unaryInterceptors = append(unaryInterceptors, TimeoutUnary(cfg.Config.DefaultTimeout, overrides))
+	}
+
+	// metrics sit outside logging so they can be enabled on their own
+	if cfg.InterceptorConfig.EnableMetrics {
+		reg := cfg.InterceptorConfig.MetricsRegistry
+		if reg == nil {
+			reg = prometheus.DefaultRegisterer
+		}
+		unaryInterceptors = append(unaryInterceptors, MetricsUnary(reg))
+		cfg.Logger.Info("Metrics interceptor enabled")
+	}
+
 	// correlation id
 	unaryInterceptors = append(unaryInterceptors, CorrelationUnary(cfg.Logger))
 	// logging
 	if cfg.InterceptorConfig.EnableLogging {
-		unaryInterceptors = append(unaryInterceptors, LoggingUnary(cfg.Logger))
+		unaryInterceptors = append(unaryInterceptors, LoggingUnary(cfg.Logger, cfg.Config.LogRequestBodies, cfg.SlowRequestThreshold))
 		streamInterceptors = append(streamInterceptors, nil)
 		cfg.Logger.Info("Logging interceptor enabled")
 	}
 
-	opts := []grpc.ServerOption{
-		grpc.MaxRecvMsgSize(cfg.Config.MaxReceiveSize),
-		grpc.MaxSendMsgSize(cfg.Config.MaxSendSize),
+	// innermost, so invalid requests are still logged and counted
+	if cfg.ErrorHandler != nil {
+		unaryInterceptors = append(unaryInterceptors, ValidationUnary(cfg.ErrorHandler))
 	}
 
+	opts := serverOptions(cfg.Config)
+
 	if len(unaryInterceptors) > 0 {
 		opts = append(opts, grpc.ChainUnaryInterceptor(unaryInterceptors...))
 	}
@@ -88,10 +157,18 @@ func NewGRPCServer(cfg GRPCServerConfig) (*GRPCServerManager, error) {
 	grpcServer := grpc.NewServer(opts...)
 
 	// health + reflection
+	cfg.Logger.Info("gRPC optional services",
+		"health_check", cfg.EnableHealthCheck,
+		"reflection", cfg.EnableReflection,
+	)
+	var hSrv *health.Server
 	if cfg.EnableHealthCheck {
-		hSrv := health.NewServer()
+		hSrv = health.NewServer()
 		grpc_health_v1.RegisterHealthServer(grpcServer, hSrv)
-		// set NOT_SERVING by default, set SERVING in Start() after ListenOK
+		// liveness goes SERVING in Start(), readiness only once MarkReady() is called
+		hSrv.SetServingStatus("", grpc_health_v1.HealthCheckResponse_NOT_SERVING)
+		hSrv.SetServingStatus(LivenessService, grpc_health_v1.HealthCheckResponse_NOT_SERVING)
+		hSrv.SetServingStatus(ReadinessService, grpc_health_v1.HealthCheckResponse_NOT_SERVING)
 		cfg.Logger.Info("Health check service registered")
 	}
 	if cfg.EnableReflection {
@@ -101,9 +178,11 @@ func NewGRPCServer(cfg GRPCServerConfig) (*GRPCServerManager, error) {
 
 	return &GRPCServerManager{
 		server:   grpcServer,
+		health:   hSrv,
 		listener: lis,
 		logger:   cfg.Logger,
 		config:   cfg,
+		drained:  make(chan struct{}),
 	}, nil
 }
 
@@ -114,23 +193,31 @@ func (m *GRPCServerManager) Start(ctx context.Context) error {
 	// Handle graceful shutdown
 	go m.handleShutdown(ctx)
 
+	// listener is already bound, so the process is alive; readiness is up to MarkReady
+	m.setStatus(LivenessService, grpc_health_v1.HealthCheckResponse_SERVING)
+
 	// Start serving (blocking)
-	if err := m.server.Serve(m.listener); err != nil {
-		if err == grpc.ErrServerStopped {
-			m.logger.Info("gRPC server was stopped intentionally")
-			return nil
-		}
+	if err := m.server.Serve(m.listener); err != nil && err != grpc.ErrServerStopped {
 		m.logger.Error("gRPC server failed to serve", "error", err)
 		return fmt.Errorf("gRPC server failed: %w", err)
 	}
 
+	// Serve returns as soon as GracefulStop begins, wait for in-flight calls so the
+	// caller doesn't close their dependencies underneath them
+	<-m.drained
+	m.logger.Info("gRPC server was stopped intentionally")
 	return nil
 }
 
 func (m *GRPCServerManager) handleShutdown(ctx context.Context) {
+	defer close(m.drained)
+
 	<-ctx.Done()
 	m.logger.Info("Graceful shutdown initiated, stopping gRPC server...")
 
+	// stop load balancers from routing new requests while we drain
+	m.SetServingStatus(grpc_health_v1.HealthCheckResponse_NOT_SERVING)
+
 	shutdownCtx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
 	defer cancel()
 
@@ -149,6 +236,25 @@ func (m *GRPCServerManager) handleShutdown(ctx context.Context) {
 	}
 }
 
+// SetServingStatus updates the overall and readiness health status. No-op when health check is disabled.
+func (m *GRPCServerManager) SetServingStatus(status grpc_health_v1.HealthCheckResponse_ServingStatus) {
+	m.setStatus("", status)
+	m.setStatus(ReadinessService, status)
+}
+
+// MarkReady flips readiness to SERVING once dependencies and indexes are in place
+func (m *GRPCServerManager) MarkReady() {
+	m.SetServingStatus(grpc_health_v1.HealthCheckResponse_SERVING)
+}
+
+func (m *GRPCServerManager) setStatus(service string, status grpc_health_v1.HealthCheckResponse_ServingStatus) {
+	if m.health == nil {
+		return
+	}
+	m.health.SetServingStatus(service, status)
+	m.logger.Info("gRPC health status changed", "service", service, "status", status.String())
+}
+
 func (m *GRPCServerManager) Stop() {
 	m.server.Stop()
 }
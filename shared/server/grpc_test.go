@@ -0,0 +1,271 @@
+package server
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"testing"
+	"time"
+
+	cfg "remaster/shared"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/status"
+)
+
+func newTestGRPCServer(t *testing.T) *GRPCServerManager {
+	t.Helper()
+	return newTestGRPCServerWith(t, &cfg.GRPCConfig{MaxReceiveSize: 4 << 20, MaxSendSize: 4 << 20})
+}
+
+func newTestGRPCServerWith(t *testing.T, c *cfg.GRPCConfig) *GRPCServerManager {
+	t.Helper()
+	m, err := NewGRPCServer(GRPCServerConfig{
+		Address:           "127.0.0.1:0",
+		Logger:            slog.New(slog.NewTextHandler(io.Discard, nil)),
+		Config:            c,
+		EnableHealthCheck: true,
+	})
+	if err != nil {
+		t.Fatalf("NewGRPCServer: %v", err)
+	}
+	return m
+}
+
+// startHealthClient serves m until the test ends and returns a health client for it
+func startHealthClient(t *testing.T, m *GRPCServerManager) grpc_health_v1.HealthClient {
+	t.Helper()
+	ctx, cancel := context.WithCancel(context.Background())
+	stopped := make(chan error, 1)
+	go func() { stopped <- m.Start(ctx) }()
+	t.Cleanup(func() {
+		cancel()
+		<-stopped
+	})
+
+	conn, err := grpc.NewClient(m.listener.Addr().String(), grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatalf("dial: %v", err)
+	}
+	t.Cleanup(func() { _ = conn.Close() })
+	return grpc_health_v1.NewHealthClient(conn)
+}
+
+// openWatch starts a liveness watch and waits for its first update, so the stream is open
+func openWatch(t *testing.T, ctx context.Context, client grpc_health_v1.HealthClient) grpc_health_v1.Health_WatchClient {
+	t.Helper()
+	watch, err := client.Watch(ctx, &grpc_health_v1.HealthCheckRequest{Service: LivenessService}, grpc.WaitForReady(true))
+	if err != nil {
+		t.Fatalf("Watch: %v", err)
+	}
+	if _, err := watch.Recv(); err != nil {
+		t.Fatalf("first watch update: %v", err)
+	}
+	return watch
+}
+
+// assertStatus asks the health server in-process, which also works before Serve and after GOAWAY
+func assertStatus(t *testing.T, m *GRPCServerManager, service string, want grpc_health_v1.HealthCheckResponse_ServingStatus) {
+	t.Helper()
+	resp, err := m.health.Check(context.Background(), &grpc_health_v1.HealthCheckRequest{Service: service})
+	if err != nil {
+		t.Fatalf("Check(%q): %v", service, err)
+	}
+	if resp.Status != want {
+		t.Fatalf("status of %q = %s, want %s", service, resp.Status, want)
+	}
+}
+
+func TestGRPCHealthStatusAroundShutdown(t *testing.T) {
+	m := newTestGRPCServer(t)
+	for _, service := range []string{"", LivenessService, ReadinessService} {
+		assertStatus(t, m, service, grpc_health_v1.HealthCheckResponse_NOT_SERVING)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	stopped := make(chan error, 1)
+	go func() { stopped <- m.Start(ctx) }()
+
+	conn, err := grpc.NewClient(m.listener.Addr().String(), grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatalf("dial: %v", err)
+	}
+	defer conn.Close()
+	client := grpc_health_v1.NewHealthClient(conn)
+
+	live, err := client.Check(ctx, &grpc_health_v1.HealthCheckRequest{Service: LivenessService}, grpc.WaitForReady(true))
+	if err != nil {
+		t.Fatalf("liveness Check: %v", err)
+	}
+	if live.Status != grpc_health_v1.HealthCheckResponse_SERVING {
+		t.Fatalf("liveness after Start = %s, want SERVING", live.Status)
+	}
+	assertStatus(t, m, ReadinessService, grpc_health_v1.HealthCheckResponse_NOT_SERVING)
+
+	m.MarkReady()
+	assertStatus(t, m, "", grpc_health_v1.HealthCheckResponse_SERVING)
+	assertStatus(t, m, ReadinessService, grpc_health_v1.HealthCheckResponse_SERVING)
+
+	// an open watch is an in-flight call, GracefulStop waits for it so the drain can be observed
+	watchCtx, stopWatch := context.WithCancel(context.Background())
+	defer stopWatch()
+	watch, err := client.Watch(watchCtx, &grpc_health_v1.HealthCheckRequest{Service: ""})
+	if err != nil {
+		t.Fatalf("Watch: %v", err)
+	}
+	if resp, err := watch.Recv(); err != nil || resp.Status != grpc_health_v1.HealthCheckResponse_SERVING {
+		t.Fatalf("first watch update = %v, %v, want SERVING", resp, err)
+	}
+
+	cancel()
+
+	// the flip reaches connected clients before the stream is drained
+	if resp, err := watch.Recv(); err != nil || resp.Status != grpc_health_v1.HealthCheckResponse_NOT_SERVING {
+		t.Fatalf("watch update on shutdown = %v, %v, want NOT_SERVING", resp, err)
+	}
+	select {
+	case err := <-stopped:
+		t.Fatalf("Start returned (%v) while a call was still in flight", err)
+	default:
+	}
+	assertStatus(t, m, "", grpc_health_v1.HealthCheckResponse_NOT_SERVING)
+	assertStatus(t, m, ReadinessService, grpc_health_v1.HealthCheckResponse_NOT_SERVING)
+
+	stopWatch()
+	select {
+	case err := <-stopped:
+		if err != nil {
+			t.Fatalf("Start: %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Start did not return after the drain")
+	}
+}
+
+func TestSetServingStatusWithoutHealthCheck(t *testing.T) {
+	m, err := NewGRPCServer(GRPCServerConfig{
+		Address: "127.0.0.1:0",
+		Logger:  slog.New(slog.NewTextHandler(io.Discard, nil)),
+		Config:  &cfg.GRPCConfig{MaxReceiveSize: 4 << 20, MaxSendSize: 4 << 20},
+	})
+	if err != nil {
+		t.Fatalf("NewGRPCServer: %v", err)
+	}
+	defer m.listener.Close()
+
+	// no health server registered, must not panic
+	m.SetServingStatus(grpc_health_v1.HealthCheckResponse_SERVING)
+}
+
+func TestGRPCMaxConcurrentStreamsFromConfig(t *testing.T) {
+	for _, tt := range []struct {
+		name    string
+		streams uint32
+		blocked bool
+	}{
+		{"limited to one", 1, true},
+		{"unlimited", 0, false},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			m := newTestGRPCServerWith(t, &cfg.GRPCConfig{MaxReceiveSize: 4 << 20, MaxSendSize: 4 << 20, MaxConcurrentStreams: tt.streams})
+			client := startHealthClient(t, m)
+
+			watchCtx, stopWatch := context.WithCancel(context.Background())
+			defer stopWatch()
+			openWatch(t, watchCtx, client)
+
+			// the open watch holds the only stream, a second call waits for it
+			ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+			defer cancel()
+			_, err := client.Check(ctx, &grpc_health_v1.HealthCheckRequest{Service: LivenessService})
+			if blocked := status.Code(err) == codes.DeadlineExceeded; blocked != tt.blocked {
+				t.Fatalf("second call error = %v, want blocked = %v", err, tt.blocked)
+			}
+		})
+	}
+}
+
+func TestGRPCMaxConnectionAgeFromConfig(t *testing.T) {
+	m := newTestGRPCServerWith(t, &cfg.GRPCConfig{
+		MaxReceiveSize:        4 << 20,
+		MaxSendSize:           4 << 20,
+		MaxConnectionAge:      100 * time.Millisecond,
+		MaxConnectionAgeGrace: 100 * time.Millisecond,
+	})
+	client := startHealthClient(t, m)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	watch := openWatch(t, ctx, client)
+
+	// past age and grace the server closes the connection under the open stream
+	_, err := watch.Recv()
+	if status.Code(err) != codes.Unavailable {
+		t.Fatalf("watch after max connection age = %v, want Unavailable", err)
+	}
+}
+
+func TestGRPCReadinessWaitsForMarkReady(t *testing.T) {
+	m := newTestGRPCServer(t)
+	client := startHealthClient(t, m)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	// a probe hitting the process before its dependencies are up
+	live, err := client.Check(ctx, &grpc_health_v1.HealthCheckRequest{Service: LivenessService}, grpc.WaitForReady(true))
+	if err != nil || live.Status != grpc_health_v1.HealthCheckResponse_SERVING {
+		t.Fatalf("liveness = %v, %v, want SERVING once started", live, err)
+	}
+	watch, err := client.Watch(ctx, &grpc_health_v1.HealthCheckRequest{Service: ReadinessService})
+	if err != nil {
+		t.Fatalf("Watch: %v", err)
+	}
+	if resp, err := watch.Recv(); err != nil || resp.Status != grpc_health_v1.HealthCheckResponse_NOT_SERVING {
+		t.Fatalf("readiness before MarkReady = %v, %v, want NOT_SERVING", resp, err)
+	}
+
+	m.MarkReady()
+
+	if resp, err := watch.Recv(); err != nil || resp.Status != grpc_health_v1.HealthCheckResponse_SERVING {
+		t.Fatalf("readiness after MarkReady = %v, %v, want SERVING", resp, err)
+	}
+	overall, err := client.Check(ctx, &grpc_health_v1.HealthCheckRequest{Service: ""})
+	if err != nil || overall.Status != grpc_health_v1.HealthCheckResponse_SERVING {
+		t.Fatalf("overall = %v, %v, want it to mirror readiness", overall, err)
+	}
+}
+
+func TestGRPCOptionalServicesFollowFlags(t *testing.T) {
+	const (
+		reflectionService = "grpc.reflection.v1.ServerReflection"
+		healthService     = "grpc.health.v1.Health"
+	)
+	for _, tt := range []struct{ reflection, health bool }{
+		{false, false}, {true, false}, {false, true}, {true, true},
+	} {
+		m, err := NewGRPCServer(GRPCServerConfig{
+			Address:           "127.0.0.1:0",
+			Logger:            slog.New(slog.NewTextHandler(io.Discard, nil)),
+			Config:            &cfg.GRPCConfig{},
+			EnableHealthCheck: tt.health,
+			EnableReflection:  tt.reflection,
+		})
+		if err != nil {
+			t.Fatalf("NewGRPCServer: %v", err)
+		}
+		services := m.server.GetServiceInfo()
+		_ = m.listener.Close()
+
+		if _, ok := services[reflectionService]; ok != tt.reflection {
+			t.Errorf("reflection=%v: reflection registered = %v", tt.reflection, ok)
+		}
+		if _, ok := services[healthService]; ok != tt.health {
+			t.Errorf("health=%v: health registered = %v", tt.health, ok)
+		}
+	}
+}
@@ -0,0 +1,123 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/http"
+	"sync/atomic"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+type HTTPServerConfig struct {
+	Address string
+	Logger  *slog.Logger
+	// gatherer for /metrics, nil = prometheus default gatherer
+	Gatherer prometheus.Gatherer
+}
+
+// HTTPServerManager serves plain http probes and metrics for load balancers and scrapers
+// that don't speak grpc health checks
+type HTTPServerManager struct {
+	server   *http.Server
+	mux      *http.ServeMux
+	listener net.Listener
+	logger   *slog.Logger
+	ready    atomic.Bool
+	drained  chan struct{}
+}
+
+func NewHTTPServer(cfg HTTPServerConfig) (*HTTPServerManager, error) {
+	lis, err := net.Listen("tcp", cfg.Address)
+	if err != nil {
+		cfg.Logger.Error("Failed to create HTTP listener", "address", cfg.Address, "error", err)
+		return nil, fmt.Errorf("failed to create listener: %w", err)
+	}
+
+	gatherer := cfg.Gatherer
+	if gatherer == nil {
+		gatherer = prometheus.DefaultGatherer
+	}
+
+	mux := http.NewServeMux()
+	m := &HTTPServerManager{
+		mux:      mux,
+		listener: lis,
+		logger:   cfg.Logger,
+		drained:  make(chan struct{}),
+	}
+
+	mux.HandleFunc("GET /health", func(w http.ResponseWriter, r *http.Request) {
+		writeStatus(w, http.StatusOK, "ok")
+	})
+	mux.HandleFunc("GET /ready", func(w http.ResponseWriter, r *http.Request) {
+		if !m.ready.Load() {
+			writeStatus(w, http.StatusServiceUnavailable, "not ready")
+			return
+		}
+		writeStatus(w, http.StatusOK, "ready")
+	})
+	mux.Handle("GET /metrics", promhttp.HandlerFor(gatherer, promhttp.HandlerOpts{}))
+
+	m.server = &http.Server{
+		Handler:           mux,
+		ReadHeaderTimeout: 5 * time.Second,
+	}
+
+	cfg.Logger.Info("HTTP ops server created", "address", cfg.Address)
+	return m, nil
+}
+
+func writeStatus(w http.ResponseWriter, code int, status string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	fmt.Fprintf(w, `{"status":%q}`, status)
+}
+
+// Handle adds a service specific route next to the probes, call it before Start
+func (m *HTTPServerManager) Handle(pattern string, handler http.Handler) {
+	m.mux.Handle(pattern, handler)
+	m.logger.Info("HTTP route registered", "pattern", pattern)
+}
+
+// Start HTTP server, blocks until ctx is cancelled
+func (m *HTTPServerManager) Start(ctx context.Context) error {
+	m.logger.Info("Starting HTTP ops server", "address", m.listener.Addr().String())
+
+	go m.handleShutdown(ctx)
+
+	if err := m.server.Serve(m.listener); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		m.logger.Error("HTTP ops server failed to serve", "error", err)
+		return fmt.Errorf("HTTP server failed: %w", err)
+	}
+
+	// Serve returns once Shutdown starts, not when it is done
+	<-m.drained
+	m.logger.Info("HTTP ops server stopped")
+	return nil
+}
+
+func (m *HTTPServerManager) handleShutdown(ctx context.Context) {
+	defer close(m.drained)
+
+	<-ctx.Done()
+	m.SetReady(false)
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	if err := m.server.Shutdown(shutdownCtx); err != nil {
+		m.logger.Warn("HTTP ops server shutdown timeout, forcing close", "error", err)
+		_ = m.server.Close()
+	}
+}
+
+// SetReady toggles the /ready response
+func (m *HTTPServerManager) SetReady(ready bool) {
+	m.ready.Store(ready)
+}
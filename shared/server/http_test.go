@@ -0,0 +1,115 @@
+package server
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// startHTTPServer serves m until the test ends and returns its base url
+func startHTTPServer(t *testing.T, m *HTTPServerManager) string {
+	t.Helper()
+	ctx, cancel := context.WithCancel(context.Background())
+	stopped := make(chan error, 1)
+	go func() { stopped <- m.Start(ctx) }()
+	t.Cleanup(func() {
+		cancel()
+		if err := <-stopped; err != nil {
+			t.Errorf("Start: %v", err)
+		}
+	})
+	return "http://" + m.listener.Addr().String()
+}
+
+func get(t *testing.T, url string) (int, string) {
+	t.Helper()
+	resp, err := http.Get(url)
+	if err != nil {
+		t.Fatalf("GET %s: %v", url, err)
+	}
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(resp.Body)
+	return resp.StatusCode, string(body)
+}
+
+func TestHTTPServerServesProbesAndMetrics(t *testing.T) {
+	registry := prometheus.NewRegistry()
+	requests := prometheus.NewCounter(prometheus.CounterOpts{Name: "test_requests_total", Help: "test"})
+	registry.MustRegister(requests)
+	requests.Add(3)
+
+	m, err := NewHTTPServer(HTTPServerConfig{
+		Address:  "127.0.0.1:0",
+		Logger:   slog.New(slog.NewTextHandler(io.Discard, nil)),
+		Gatherer: registry,
+	})
+	if err != nil {
+		t.Fatalf("NewHTTPServer: %v", err)
+	}
+	m.Handle("GET /v1/ping", http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) { _, _ = io.WriteString(w, "pong") }))
+	base := startHTTPServer(t, m)
+
+	if code, body := get(t, base+"/health"); code != http.StatusOK || body != `{"status":"ok"}` {
+		t.Fatalf("/health = %d %s", code, body)
+	}
+	if code, _ := get(t, base+"/ready"); code != http.StatusServiceUnavailable {
+		t.Fatalf("/ready before SetReady = %d, want 503", code)
+	}
+	m.SetReady(true)
+	if code, body := get(t, base+"/ready"); code != http.StatusOK || body != `{"status":"ready"}` {
+		t.Fatalf("/ready = %d %s", code, body)
+	}
+	if code, body := get(t, base+"/metrics"); code != http.StatusOK || !strings.Contains(body, "test_requests_total 3") {
+		t.Fatalf("/metrics = %d, missing the registered counter:\n%s", code, body)
+	}
+	if code, body := get(t, base+"/v1/ping"); code != http.StatusOK || body != "pong" {
+		t.Fatalf("/v1/ping = %d %s", code, body)
+	}
+
+	resp, err := http.Post(base+"/health", "application/json", nil)
+	if err != nil {
+		t.Fatalf("POST /health: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusMethodNotAllowed {
+		t.Fatalf("POST /health = %d, want 405", resp.StatusCode)
+	}
+}
+
+func TestHTTPServerStopsWithContext(t *testing.T) {
+	m, err := NewHTTPServer(HTTPServerConfig{Address: "127.0.0.1:0", Logger: slog.New(slog.NewTextHandler(io.Discard, nil))})
+	if err != nil {
+		t.Fatalf("NewHTTPServer: %v", err)
+	}
+	m.SetReady(true)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	stopped := make(chan error, 1)
+	go func() { stopped <- m.Start(ctx) }()
+	base := "http://" + m.listener.Addr().String()
+	if code, _ := get(t, base+"/ready"); code != http.StatusOK {
+		t.Fatalf("/ready = %d, want 200", code)
+	}
+
+	cancel()
+	select {
+	case err := <-stopped:
+		if err != nil {
+			t.Fatalf("Start = %v, want a clean stop", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Start did not return after cancel")
+	}
+	if m.ready.Load() {
+		t.Fatal("still ready after shutdown")
+	}
+	if _, err := http.Get(base + "/health"); err == nil {
+		t.Fatal("server still accepting after shutdown")
+	}
+}
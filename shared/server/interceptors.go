@@ -7,12 +7,20 @@ import (
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/encoding"
+	"google.golang.org/grpc/encoding/gzip"
 	"google.golang.org/grpc/metadata"
 	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
 
+	"remaster/shared/ctxkeys"
+	"remaster/shared/errors"
 	"remaster/shared/logger"
+	"remaster/shared/validation"
 )
 
 // RecoveryUnary — catches panics, logs the stack, and returns an internal status (without panic details).
@@ -51,6 +59,97 @@ func RecoveryStream(logger *slog.Logger) grpc.StreamServerInterceptor {
 	}
 }
 
+// headers that are copied into logs and models, so their format is enforced
+var idMetadataKeys = []string{"x-correlation-id", "correlation-id", "x-request-id", "x-device-id", "x-org-id"}
+
+// MetadataUnary — rejects requests whose metadata exceeds maxBytes (0 = no limit) or that carry
+// malformed id headers, before anything downstream trusts them.
+func MetadataUnary(maxBytes int) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		md, ok := metadata.FromIncomingContext(ctx)
+		if !ok {
+			return handler(ctx, req)
+		}
+
+		if maxBytes > 0 {
+			size := 0
+			for k, vals := range md {
+				for _, v := range vals {
+					size += len(k) + len(v)
+				}
+			}
+			if size > maxBytes {
+				return nil, status.Errorf(codes.InvalidArgument, "request metadata exceeds %d bytes", maxBytes)
+			}
+		}
+
+		for _, key := range idMetadataKeys {
+			for _, v := range md.Get(key) {
+				if !validation.ValidHeaderID(v) {
+					return nil, status.Errorf(codes.InvalidArgument, "malformed %s header", key)
+				}
+			}
+		}
+
+		return handler(ctx, req)
+	}
+}
+
+// CompressionUnary — gzips responses of at least minBytes and sends smaller ones uncompressed,
+// regardless of how the request came in. Clients that don't accept gzip keep the default.
+func CompressionUnary(minBytes int) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		resp, err := handler(ctx, req)
+		if err != nil {
+			return resp, err
+		}
+		if m, ok := resp.(proto.Message); ok {
+			name := encoding.Identity
+			if proto.Size(m) >= minBytes {
+				name = gzip.Name
+			}
+			_ = grpc.SetSendCompressor(ctx, name)
+		}
+		return resp, nil
+	}
+}
+
+// DeadlineUnary — rejects calls whose context already ended, e.g. a deadline the caller
+// spent upstream, before any work is done for a result nobody waits for.
+func DeadlineUnary() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		switch ctx.Err() {
+		case nil:
+		case context.DeadlineExceeded:
+			return nil, status.Error(codes.DeadlineExceeded, "deadline exceeded before the call started")
+		default:
+			return nil, status.Error(codes.Canceled, "call cancelled before it started")
+		}
+		return handler(ctx, req)
+	}
+}
+
+// TimeoutUnary — gives calls without a deadline a server side one, overrides are keyed by full method name.
+func TimeoutUnary(defaultTimeout time.Duration, overrides map[string]time.Duration) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		if _, ok := ctx.Deadline(); ok {
+			return handler(ctx, req)
+		}
+
+		timeout := defaultTimeout
+		if t, ok := overrides[info.FullMethod]; ok {
+			timeout = t
+		}
+		if timeout <= 0 {
+			return handler(ctx, req)
+		}
+
+		ctx, cancel := context.WithTimeout(ctx, timeout)
+		defer cancel()
+		return handler(ctx, req)
+	}
+}
+
 // CorrelationUnary — takes x-correlation-id or correlation-id headers and creates a correlation id.
 func CorrelationUnary(baseLogger *slog.Logger) grpc.UnaryServerInterceptor {
 	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
@@ -66,33 +165,109 @@ func CorrelationUnary(baseLogger *slog.Logger) grpc.UnaryServerInterceptor {
 		}
 
 		// create request logger and inject into context
-		reqLogger := baseLogger.With(slog.String("correlation_id", cid))
+		reqLogger := logger.WithCorrelationID(baseLogger, cid)
+		ctx = ctxkeys.WithCorrelationID(ctx, cid)
 		ctx = logger.ToContext(ctx, reqLogger)
 		return handler(ctx, req)
 	}
 }
 
-// LoggingUnary - logs gRPC calls and their duration. without body and auth headers
-func LoggingUnary(baseLogger *slog.Logger) grpc.UnaryServerInterceptor {
+// LoggingUnary - logs gRPC calls and their duration, without auth headers. Bodies are only
+// logged with logBodies at debug level, after RedactProto masked credentials. Calls slower
+// than slowThreshold (0 = off) get slow_request=true and at least warn level
+func LoggingUnary(baseLogger *slog.Logger, logBodies bool, slowThreshold time.Duration) grpc.UnaryServerInterceptor {
 	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (resp any, err error) {
 		start := time.Now()
 		reqLogger := logger.FromContext(ctx, baseLogger)
 
+		if logBodies && reqLogger.Enabled(ctx, slog.LevelDebug) {
+			if body, ok := redactedJSON(req); ok {
+				reqLogger.LogAttrs(ctx, slog.LevelDebug, "gRPC request",
+					slog.String("method", info.FullMethod),
+					slog.String("body", body),
+				)
+			}
+		}
+
 		resp, err = handler(ctx, req)
 		duration := time.Since(start)
 
+		attrs := []slog.Attr{
+			slog.String("method", info.FullMethod),
+			slog.Duration("duration", duration),
+		}
+		slow := slowThreshold > 0 && duration > slowThreshold
+		if slow {
+			attrs = append(attrs, slog.Bool("slow_request", true))
+		}
+
 		if err != nil {
-			reqLogger.LogAttrs(ctx, slog.LevelError, "gRPC call failed",
-				slog.String("method", info.FullMethod),
-				slog.Duration("duration", duration),
-				slog.Any("error", err),
-			)
+			attrs = append(attrs, slog.Any("error", err))
+			reqLogger.LogAttrs(ctx, slog.LevelError, "gRPC call failed", attrs...)
 		} else {
-			reqLogger.LogAttrs(ctx, slog.LevelInfo, "gRPC call completed",
-				slog.String("method", info.FullMethod),
-				slog.Duration("duration", duration),
-			)
+			level := slog.LevelInfo
+			if slow {
+				level = slog.LevelWarn
+			}
+			reqLogger.LogAttrs(ctx, level, "gRPC call completed", attrs...)
 		}
 		return resp, err
 	}
 }
+
+// MetricsUnary - records call count and latency per method and status code. Registers its
+// collectors on reg, so build it once per registry
+func MetricsUnary(reg prometheus.Registerer) grpc.UnaryServerInterceptor {
+	factory := promauto.With(reg)
+	requests := factory.NewCounterVec(prometheus.CounterOpts{
+		Name: "grpc_server_requests_total",
+		Help: "Total gRPC calls handled, by method and status code.",
+	}, []string{"method", "code"})
+	latency := factory.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "grpc_server_request_duration_seconds",
+		Help:    "gRPC call latency, by method and status code.",
+		Buckets: prometheus.DefBuckets,
+	}, []string{"method", "code"})
+
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		start := time.Now()
+		resp, err := handler(ctx, req)
+
+		code := status.Code(err).String()
+		requests.WithLabelValues(info.FullMethod, code).Inc()
+		latency.WithLabelValues(info.FullMethod, code).Observe(time.Since(start).Seconds())
+		return resp, err
+	}
+}
+
+// RequestValidator is implemented by service implementations that check requests against
+// their own models, so a rule isn't kept in the proto package and the service both
+type RequestValidator interface {
+	ValidateRequest(req any) error
+}
+
+// ValidationUnary - rejects requests before the handler runs when the message's Validate()
+// error method or the service's RequestValidator fails. Field errors travel as BadRequest details
+func ValidationUnary(eh *errors.ErrorHandler) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		if err := validateRequest(req, info.Server); err != nil {
+			if _, isApp := errors.AsAppError(err); !isApp {
+				err = errors.NewValidationError(err.Error(), nil)
+			}
+			return nil, eh.HandleGrpcError(err)
+		}
+		return handler(ctx, req)
+	}
+}
+
+func validateRequest(req, srv any) error {
+	if v, ok := req.(interface{ Validate() error }); ok {
+		if err := v.Validate(); err != nil {
+			return err
+		}
+	}
+	if v, ok := srv.(RequestValidator); ok {
+		return v.ValidateRequest(req)
+	}
+	return nil
+}
@@ -0,0 +1,319 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"strings"
+	"testing"
+	"time"
+
+	"remaster/shared/errors"
+	"remaster/shared/validation"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+type selfValidating struct {
+	err error
+}
+
+func (m selfValidating) Validate() error { return m.err }
+
+type modelValidatingServer struct{}
+
+func (modelValidatingServer) ValidateRequest(req any) error {
+	return errors.NewValidationError("validation failed", map[string]string{"email": "must be a valid email address"})
+}
+
+func TestValidationUnaryRejectsInvalidRequests(t *testing.T) {
+	eh := errors.NewErrorHandler(slog.New(slog.NewTextHandler(io.Discard, nil)))
+	interceptor := ValidationUnary(eh)
+
+	tests := []struct {
+		name   string
+		req    any
+		server any
+		field  string
+	}{
+		{
+			name:  "message Validate",
+			req:   selfValidating{err: errors.NewValidationError("validation failed", map[string]string{"user_id": "is required"})},
+			field: "user_id",
+		},
+		{
+			name:   "service RequestValidator",
+			req:    selfValidating{},
+			server: modelValidatingServer{},
+			field:  "email",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			called := false
+			handler := func(ctx context.Context, req any) (any, error) {
+				called = true
+				return nil, nil
+			}
+
+			_, err := interceptor(context.Background(), tt.req, &grpc.UnaryServerInfo{Server: tt.server, FullMethod: "/test.Service/Call"}, handler)
+			if called {
+				t.Fatal("handler ran for an invalid request")
+			}
+			st := status.Convert(err)
+			if st.Code() != codes.InvalidArgument {
+				t.Fatalf("code = %s, want InvalidArgument", st.Code())
+			}
+			if !hasFieldViolation(st, tt.field) {
+				t.Fatalf("details %v carry no violation for %q", st.Details(), tt.field)
+			}
+		})
+	}
+}
+
+func TestValidationUnaryPassesValidRequests(t *testing.T) {
+	eh := errors.NewErrorHandler(slog.New(slog.NewTextHandler(io.Discard, nil)))
+	called := false
+	_, err := ValidationUnary(eh)(context.Background(), selfValidating{}, &grpc.UnaryServerInfo{}, func(ctx context.Context, req any) (any, error) {
+		called = true
+		return nil, nil
+	})
+	if err != nil || !called {
+		t.Fatalf("err = %v, handler called = %v, want the request through", err, called)
+	}
+}
+
+func hasFieldViolation(st *status.Status, field string) bool {
+	for _, d := range st.Details() {
+		if br, ok := d.(*errdetails.BadRequest); ok {
+			for _, v := range br.FieldViolations {
+				if v.Field == field {
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
+
+func TestDeadlineUnary(t *testing.T) {
+	expired, cancelExpired := context.WithDeadline(context.Background(), time.Now().Add(-time.Second))
+	defer cancelExpired()
+	cancelled, cancel := context.WithCancel(context.Background())
+	cancel()
+	valid, cancelValid := context.WithTimeout(context.Background(), time.Minute)
+	defer cancelValid()
+
+	tests := []struct {
+		name string
+		ctx  context.Context
+		want codes.Code
+	}{
+		{"expired deadline", expired, codes.DeadlineExceeded},
+		{"cancelled", cancelled, codes.Canceled},
+		{"valid deadline", valid, codes.OK},
+		{"no deadline", context.Background(), codes.OK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			called := false
+			_, err := DeadlineUnary()(tt.ctx, struct{}{}, &grpc.UnaryServerInfo{FullMethod: "/test.Service/Call"}, func(ctx context.Context, req any) (any, error) {
+				called = true
+				return nil, nil
+			})
+			if got := status.Code(err); got != tt.want {
+				t.Fatalf("code = %s, want %s", got, tt.want)
+			}
+			if called != (tt.want == codes.OK) {
+				t.Fatalf("handler called = %v for %s", called, tt.name)
+			}
+		})
+	}
+}
+
+// sleepingHandler works for d unless its context ends first
+func sleepingHandler(d time.Duration) grpc.UnaryHandler {
+	return func(ctx context.Context, req any) (any, error) {
+		select {
+		case <-time.After(d):
+			return "done", nil
+		case <-ctx.Done():
+			return nil, status.FromContextError(ctx.Err()).Err()
+		}
+	}
+}
+
+func TestTimeoutUnary(t *testing.T) {
+	const slow = "/test.Service/Slow"
+	interceptor := TimeoutUnary(50*time.Millisecond, map[string]time.Duration{
+		slow:                      time.Second,
+		"/test.Service/Unbounded": 0,
+	})
+
+	tests := []struct {
+		name    string
+		method  string
+		work    time.Duration
+		want    codes.Code
+		maxTime time.Duration
+	}{
+		{"default timeout cuts a slow handler", "/test.Service/Call", time.Second, codes.DeadlineExceeded, 500 * time.Millisecond},
+		{"fast handler finishes", "/test.Service/Call", 0, codes.OK, 500 * time.Millisecond},
+		{"override gives more time", slow, 200 * time.Millisecond, codes.OK, time.Second},
+		{"zero override disables the timeout", "/test.Service/Unbounded", 200 * time.Millisecond, codes.OK, time.Second},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			start := time.Now()
+			_, err := interceptor(context.Background(), struct{}{}, &grpc.UnaryServerInfo{FullMethod: tt.method}, sleepingHandler(tt.work))
+			if got := status.Code(err); got != tt.want {
+				t.Fatalf("code = %s, want %s", got, tt.want)
+			}
+			if elapsed := time.Since(start); elapsed > tt.maxTime {
+				t.Fatalf("call took %v, want under %v", elapsed, tt.maxTime)
+			}
+		})
+	}
+}
+
+func TestTimeoutUnaryKeepsCallerDeadline(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Hour)
+	defer cancel()
+	want, _ := ctx.Deadline()
+
+	_, err := TimeoutUnary(50*time.Millisecond, nil)(ctx, struct{}{}, &grpc.UnaryServerInfo{FullMethod: "/test.Service/Call"},
+		func(ctx context.Context, req any) (any, error) {
+			if got, _ := ctx.Deadline(); !got.Equal(want) {
+				t.Errorf("deadline = %v, want the caller's %v", got, want)
+			}
+			return nil, nil
+		})
+	if err != nil {
+		t.Fatalf("call: %v", err)
+	}
+}
+
+func TestMetricsUnaryCountsAndTimesEachCall(t *testing.T) {
+	reg := prometheus.NewRegistry()
+	interceptor := MetricsUnary(reg)
+	info := &grpc.UnaryServerInfo{FullMethod: "/test.Service/Call"}
+
+	ok := func(ctx context.Context, req any) (any, error) { return "ok", nil }
+	notFound := func(ctx context.Context, req any) (any, error) { return nil, status.Error(codes.NotFound, "missing") }
+	for _, handler := range []grpc.UnaryHandler{ok, ok, notFound} {
+		_, _ = interceptor(context.Background(), struct{}{}, info, handler)
+	}
+
+	families, err := reg.Gather()
+	if err != nil {
+		t.Fatalf("Gather: %v", err)
+	}
+	counts := map[string]float64{}
+	observations := map[string]uint64{}
+	for _, f := range families {
+		for _, m := range f.GetMetric() {
+			labels := map[string]string{}
+			for _, l := range m.GetLabel() {
+				labels[l.GetName()] = l.GetValue()
+			}
+			if labels["method"] != info.FullMethod {
+				t.Fatalf("%s method label = %q", f.GetName(), labels["method"])
+			}
+			switch f.GetName() {
+			case "grpc_server_requests_total":
+				counts[labels["code"]] = m.GetCounter().GetValue()
+			case "grpc_server_request_duration_seconds":
+				observations[labels["code"]] = m.GetHistogram().GetSampleCount()
+			}
+		}
+	}
+
+	want := map[string]float64{"OK": 2, "NotFound": 1}
+	for code, n := range want {
+		if counts[code] != n {
+			t.Errorf("requests_total{code=%s} = %v, want %v", code, counts[code], n)
+		}
+		if observations[code] != uint64(n) {
+			t.Errorf("duration observations{code=%s} = %d, want %v", code, observations[code], n)
+		}
+	}
+}
+
+func TestMetadataUnary(t *testing.T) {
+	tests := []struct {
+		name     string
+		md       metadata.MD
+		maxBytes int
+		code     codes.Code
+	}{
+		{"valid ids", metadata.Pairs("x-correlation-id", "0b6e2c1e-8a4f-4a3b-9d5e-1c2f3a4b5c6d", "x-device-id", "ios.device_01"), 1024, codes.OK},
+		{"no limit", metadata.Pairs("x-padding", strings.Repeat("a", 64<<10)), 0, codes.OK},
+		{"oversized metadata", metadata.Pairs("x-padding", strings.Repeat("a", 1024)), 1024, codes.InvalidArgument},
+		{"many small headers", metadata.Pairs("a", strings.Repeat("a", 600), "b", strings.Repeat("b", 600)), 1024, codes.InvalidArgument},
+		{"correlation id with spaces", metadata.Pairs("x-correlation-id", "not a uuid"), 1024, codes.InvalidArgument},
+		{"header injection", metadata.Pairs("correlation-id", "id\r\nx-admin: true"), 1024, codes.InvalidArgument},
+		{"overlong device id", metadata.Pairs("x-device-id", strings.Repeat("d", validation.MaxHeaderIDLength+1)), 0, codes.InvalidArgument},
+		{"empty request id", metadata.Pairs("x-request-id", ""), 1024, codes.InvalidArgument},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			called := false
+			ctx := metadata.NewIncomingContext(context.Background(), tt.md)
+			_, err := MetadataUnary(tt.maxBytes)(ctx, nil, &grpc.UnaryServerInfo{FullMethod: "/auth.AuthService/Login"},
+				func(context.Context, any) (any, error) {
+					called = true
+					return nil, nil
+				})
+			if got := status.Code(err); got != tt.code {
+				t.Fatalf("code = %s (%v), want %s", got, err, tt.code)
+			}
+			if called != (tt.code == codes.OK) {
+				t.Fatalf("handler called = %v for code %s", called, tt.code)
+			}
+		})
+	}
+}
+
+func TestLoggingUnaryFlagsSlowCalls(t *testing.T) {
+	tests := []struct {
+		name      string
+		threshold time.Duration
+		delay     time.Duration
+		err       error
+		level     string
+		slow      bool
+	}{
+		{"fast", 50 * time.Millisecond, 0, nil, "INFO", false},
+		{"slow", 20 * time.Millisecond, 50 * time.Millisecond, nil, "WARN", true},
+		{"slow and failed", 20 * time.Millisecond, 50 * time.Millisecond, status.Error(codes.Internal, "boom"), "ERROR", true},
+		{"threshold off", 0, 50 * time.Millisecond, nil, "INFO", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf strings.Builder
+			interceptor := LoggingUnary(slog.New(slog.NewJSONHandler(&buf, nil)), false, tt.threshold)
+			handler := func(ctx context.Context, req any) (any, error) {
+				time.Sleep(tt.delay)
+				return nil, tt.err
+			}
+			_, _ = interceptor(context.Background(), nil, &grpc.UnaryServerInfo{FullMethod: "/test.Service/Call"}, handler)
+
+			var line map[string]any
+			if err := json.Unmarshal([]byte(buf.String()), &line); err != nil {
+				t.Fatalf("decode log %q: %v", buf.String(), err)
+			}
+			if line["level"] != tt.level {
+				t.Fatalf("level = %v, want %s", line["level"], tt.level)
+			}
+			if _, got := line["slow_request"]; got != tt.slow {
+				t.Fatalf("slow_request present = %v, want %v: %v", got, tt.slow, line)
+			}
+		})
+	}
+}
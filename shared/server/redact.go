@@ -0,0 +1,65 @@
+package server
+
+import (
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+const redactedValue = "[REDACTED]"
+
+// proto field names that never reach logs, matched in every message type and nested message
+var sensitiveFields = map[protoreflect.Name]bool{
+	"password":      true,
+	"old_password":  true,
+	"new_password":  true,
+	"id_token":      true,
+	"access_token":  true,
+	"refresh_token": true,
+}
+
+// RedactProto returns a copy of m with sensitive fields masked, m itself is left untouched
+func RedactProto(m proto.Message) proto.Message {
+	clone := proto.Clone(m)
+	redactMessage(clone.ProtoReflect())
+	return clone
+}
+
+// redactedJSON renders req for debug logs, non proto requests are skipped
+func redactedJSON(req any) (string, bool) {
+	m, ok := req.(proto.Message)
+	if !ok {
+		return "", false
+	}
+	b, err := protojson.Marshal(RedactProto(m))
+	if err != nil {
+		return "", false
+	}
+	return string(b), true
+}
+
+func redactMessage(m protoreflect.Message) {
+	m.Range(func(fd protoreflect.FieldDescriptor, v protoreflect.Value) bool {
+		switch {
+		case sensitiveFields[fd.Name()]:
+			if fd.Kind() == protoreflect.StringKind && !fd.IsList() && !fd.IsMap() {
+				m.Set(fd, protoreflect.ValueOfString(redactedValue))
+			} else {
+				m.Clear(fd)
+			}
+		case fd.IsList() && fd.Message() != nil:
+			list := v.List()
+			for i := 0; i < list.Len(); i++ {
+				redactMessage(list.Get(i).Message())
+			}
+		case fd.IsMap() && fd.MapValue().Message() != nil:
+			v.Map().Range(func(_ protoreflect.MapKey, mv protoreflect.Value) bool {
+				redactMessage(mv.Message())
+				return true
+			})
+		case fd.Message() != nil && !fd.IsMap():
+			redactMessage(v.Message())
+		}
+		return true
+	})
+}
@@ -0,0 +1,93 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"strings"
+	"testing"
+
+	pb "remaster/shared/proto/auth"
+
+	"google.golang.org/grpc"
+	"google.golang.org/protobuf/proto"
+)
+
+const secret = "s3cr3t-value"
+
+func TestRedactProtoMasksCredentials(t *testing.T) {
+	tests := []struct {
+		name string
+		msg  proto.Message
+		keep string
+	}{
+		{"login", &pb.LoginRequest{Email: "a@example.com", Password: secret}, "a@example.com"},
+		{"change password", &pb.ChangePasswordRequest{UserId: "u1", OldPassword: secret, NewPassword: secret}, "u1"},
+		{"oauth", &pb.OAuthLoginRequest{Provider: "google", IdToken: secret}, "google"},
+		{"refresh", &pb.RefreshTokenRequest{RefreshToken: secret}, ""},
+		{"validate", &pb.ValidateTokenRequest{AccessToken: secret}, ""},
+		{"tokens in a response", &pb.RegisterResponse{UserId: "u1", AccessToken: secret, RefreshToken: secret}, "u1"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			original := proto.Clone(tt.msg)
+
+			body, ok := redactedJSON(tt.msg)
+			if !ok {
+				t.Fatal("proto message was not rendered")
+			}
+			if strings.Contains(body, secret) {
+				t.Fatalf("body %s leaks the secret", body)
+			}
+			if !strings.Contains(body, redactedValue) || !strings.Contains(body, tt.keep) {
+				t.Fatalf("body %s, want credentials masked and %q kept", body, tt.keep)
+			}
+			if !proto.Equal(tt.msg, original) {
+				t.Fatalf("request was modified to %v", tt.msg)
+			}
+		})
+	}
+}
+
+func TestRedactedJSONSkipsNonProtoRequests(t *testing.T) {
+	if body, ok := redactedJSON(struct{ Password string }{secret}); ok {
+		t.Fatalf("rendered %s for a non proto request", body)
+	}
+}
+
+func TestLoggingUnaryRequestBodies(t *testing.T) {
+	tests := []struct {
+		name      string
+		level     slog.Level
+		logBodies bool
+		wantBody  bool
+	}{
+		{"enabled at debug", slog.LevelDebug, true, true},
+		{"disabled", slog.LevelDebug, false, false},
+		{"enabled above debug", slog.LevelInfo, true, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			log := slog.New(slog.NewJSONHandler(&buf, &slog.HandlerOptions{Level: tt.level}))
+			interceptor := LoggingUnary(log, tt.logBodies, 0)
+
+			req := &pb.LoginRequest{Email: "a@example.com", Password: secret}
+			handler := func(ctx context.Context, req any) (any, error) { return &pb.LoginResponse{}, nil }
+			if _, err := interceptor(context.Background(), req, &grpc.UnaryServerInfo{FullMethod: "/auth.AuthService/Login"}, handler); err != nil {
+				t.Fatalf("interceptor: %v", err)
+			}
+
+			out := buf.String()
+			if strings.Contains(out, secret) {
+				t.Fatalf("logs leak the password:\n%s", out)
+			}
+			if got := strings.Contains(out, `"msg":"gRPC request"`); got != tt.wantBody {
+				t.Fatalf("body logged = %v, want %v:\n%s", got, tt.wantBody, out)
+			}
+			if tt.wantBody && !strings.Contains(out, "a@example.com") {
+				t.Fatalf("body line lacks the non sensitive fields:\n%s", out)
+			}
+		})
+	}
+}
@@ -4,17 +4,21 @@ import (
 	"context"
 	"fmt"
 	"log/slog"
+	"net/http"
 	"os"
 	"os/signal"
 	"syscall"
 	"time"
 
+	"github.com/prometheus/client_golang/prometheus"
 	"golang.org/x/sync/errgroup"
 	"google.golang.org/grpc"
 
 	cfg "remaster/shared"
 	"remaster/shared/connection"
 	"remaster/shared/errors"
+	sharedlog "remaster/shared/logger"
+	"remaster/shared/worker"
 )
 
 type Server struct {
@@ -25,12 +29,16 @@ type Server struct {
 	// Core components
 	ErrorHandler *errors.ErrorHandler
 	GRPCManager  *GRPCServerManager
+	// nil unless enable_http is set for the service
+	HTTPManager *HTTPServerManager
+	// background jobs, they run for as long as the servers do
+	Workers *worker.Registry
 
 	// Optional dependencies
 	MongoMgr *connection.MongoManager
 	RedisMgr *connection.RedisManager
-	// KafkaMgr *connection.KafkaManager
-	// AWSMgr   *connection.AWSManager
+	KafkaMgr *connection.KafkaManager
+	AWSMgr   *connection.S3Manager
 
 	// Internal
 	cancelFunc context.CancelFunc
@@ -42,8 +50,6 @@ type ServerConfig struct {
 	Logger *slog.Logger
 
 	// gRPC settings
-	EnableHealthCheck bool
-	EnableReflection  bool
 	InterceptorConfig InterceptorConfig
 
 	// Optional dependencies
@@ -62,6 +68,7 @@ func NewServer(config ServerConfig) (*Server, error) {
 		Config:       config.Config,
 		Logger:       logger,
 		ErrorHandler: errors.NewErrorHandler(logger),
+		Workers:      worker.NewRegistry(logger),
 	}
 
 	// Apply optional dependencies
@@ -81,9 +88,12 @@ func NewServer(config ServerConfig) (*Server, error) {
 		Address:           grpcAddr,
 		Logger:            logger,
 		Config:            &config.Config.GRPC,
-		EnableHealthCheck: config.EnableHealthCheck,
-		EnableReflection:  config.EnableReflection,
+		EnableHealthCheck: config.Config.GRPC.EnableHealthCheck,
+		EnableReflection:  config.Config.GRPC.EnableReflection,
 		InterceptorConfig: config.InterceptorConfig,
+		ErrorHandler:      server.ErrorHandler,
+
+		SlowRequestThreshold: config.Config.Log.SlowRequestThreshold,
 	}
 
 	grpcMgr, err := NewGRPCServer(grpcCfg)
@@ -94,6 +104,33 @@ func NewServer(config ServerConfig) (*Server, error) {
 	server.GRPCManager = grpcMgr
 	logger.Info("gRPC server created successfully", "address", grpcAddr)
 
+	httpAddr, httpEnabled, err := config.Config.GetServiceHTTPAddr(config.Name)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get HTTP address: %w", err)
+	}
+	if httpEnabled {
+		var gatherer prometheus.Gatherer
+		if g, ok := config.InterceptorConfig.MetricsRegistry.(prometheus.Gatherer); ok {
+			gatherer = g
+		}
+		httpMgr, err := NewHTTPServer(HTTPServerConfig{
+			Address:  httpAddr,
+			Logger:   logger,
+			Gatherer: gatherer,
+		})
+		if err != nil {
+			grpcMgr.Stop()
+			return nil, fmt.Errorf("failed to create HTTP server: %w", err)
+		}
+		server.HTTPManager = httpMgr
+	}
+
+	// only the log level is safe to change without a restart here
+	cfg.WatchConfig(func(next *cfg.Config) {
+		sharedlog.SetLevel(next.Log.Level)
+		logger.Info("Log level reloaded", "level", next.Log.Level)
+	})
+
 	return server, nil
 }
 
@@ -133,6 +170,38 @@ func WithRedis(ctx context.Context) ServerOption {
 	}
 }
 
+func WithKafka(ctx context.Context) ServerOption {
+	return func(s *Server) error {
+		s.Logger.Info("Connecting to Kafka...")
+
+		mgr := connection.NewKafkaManager(&s.Config.Kafka)
+		if err := mgr.Connect(ctx); err != nil {
+			s.Logger.Error("Failed to connect to Kafka", "error", err)
+			return fmt.Errorf("kafka connection failed: %w", err)
+		}
+
+		s.KafkaMgr = mgr
+		s.Logger.Info("Kafka connected successfully")
+		return nil
+	}
+}
+
+func WithAWS(ctx context.Context) ServerOption {
+	return func(s *Server) error {
+		s.Logger.Info("Connecting to S3...")
+
+		mgr := connection.NewS3Manager(&s.Config.AWS)
+		if err := mgr.Connect(ctx); err != nil {
+			s.Logger.Error("Failed to connect to S3", "error", err)
+			return fmt.Errorf("s3 connection failed: %w", err)
+		}
+
+		s.AWSMgr = mgr
+		s.Logger.Info("S3 connected successfully")
+		return nil
+	}
+}
+
 // ============================================================================
 // Server Lifecycle
 // ============================================================================
@@ -141,6 +210,29 @@ func (s *Server) GetGRPCServer() *grpc.Server {
 	return s.GRPCManager.GetGRPCServer()
 }
 
+// MarkReady reports the service as ready to the readiness probe, call it after
+// startup work such as index creation has finished
+func (s *Server) MarkReady() {
+	s.GRPCManager.MarkReady()
+	if s.HTTPManager != nil {
+		s.HTTPManager.SetReady(true)
+	}
+}
+
+// HandleHTTP adds a route to the ops HTTP server, false when enable_http is off for the service
+func (s *Server) HandleHTTP(pattern string, handler http.Handler) bool {
+	if s.HTTPManager == nil {
+		return false
+	}
+	s.HTTPManager.Handle(pattern, handler)
+	return true
+}
+
+// RegisterWorker adds a background job that starts with Start and stops on shutdown
+func (s *Server) RegisterWorker(w worker.Worker) {
+	s.Workers.Register(w)
+}
+
 func (s *Server) Start(ctx context.Context) error {
 	s.Logger.Info("Starting server for", "service", s.Name)
 
@@ -154,21 +246,33 @@ func (s *Server) Start(ctx context.Context) error {
 		return s.GRPCManager.Start(gCtx)
 	})
 
+	if s.HTTPManager != nil {
+		g.Go(func() error {
+			return s.HTTPManager.Start(gCtx)
+		})
+	}
+
+	if s.Workers.Len() > 0 {
+		g.Go(func() error {
+			return s.Workers.Run(gCtx)
+		})
+	}
+
 	// Wait for shutdown signal
 	g.Go(func() error {
-		s.waitForShutdownSignal(cancel)
+		s.waitForShutdownSignal(gCtx, cancel)
 		return nil
 	})
 
-	// Wait for all goroutines
-	if err := g.Wait(); err != nil {
+	// servers only return once drained and workers once stopped, so dependencies
+	// close after the last in-flight call and also when a server failed
+	err := g.Wait()
+	if err != nil {
 		s.Logger.Error("Server stopped with error", "error", err)
-		return err
 	}
 
-	// Cleanup
 	s.cleanup(context.Background())
-	return nil
+	return err
 }
 
 func (s *Server) Shutdown() {
@@ -177,13 +281,18 @@ func (s *Server) Shutdown() {
 	}
 }
 
-func (s *Server) waitForShutdownSignal(cancel context.CancelFunc) {
+// waitForShutdownSignal also returns when ctx ends, e.g. a server failed or Shutdown was called
+func (s *Server) waitForShutdownSignal(ctx context.Context, cancel context.CancelFunc) {
 	sigChan := make(chan os.Signal, 1)
 	signal.Notify(sigChan, syscall.SIGINT, syscall.SIGTERM)
+	defer signal.Stop(sigChan)
 
-	sig := <-sigChan
-	s.Logger.Info("Received shutdown signal", "signal", sig.String())
-	cancel()
+	select {
+	case sig := <-sigChan:
+		s.Logger.Info("Received shutdown signal", "signal", sig.String())
+		cancel()
+	case <-ctx.Done():
+	}
 }
 
 func (s *Server) cleanup(ctx context.Context) {
@@ -209,5 +318,21 @@ func (s *Server) cleanup(ctx context.Context) {
 		}
 	}
 
+	if s.KafkaMgr != nil {
+		if err := s.KafkaMgr.Disconnect(); err != nil {
+			s.Logger.Error("Error closing Kafka", "error", err)
+		} else {
+			s.Logger.Info("Kafka connection closed")
+		}
+	}
+
+	if s.AWSMgr != nil {
+		if err := s.AWSMgr.Disconnect(); err != nil {
+			s.Logger.Error("Error closing S3", "error", err)
+		} else {
+			s.Logger.Info("S3 client closed")
+		}
+	}
+
 	s.Logger.Info("Cleanup completed")
 }
@@ -0,0 +1,221 @@
+package server
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	cfg "remaster/shared"
+	"remaster/shared/connection"
+	pb "remaster/shared/proto/auth"
+	"remaster/shared/worker"
+
+	"github.com/alicebob/miniredis/v2"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+)
+
+// fakeBroker accepts TCP connections, which is all the Kafka manager's ping needs
+func fakeBroker(t *testing.T) string {
+	t.Helper()
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	t.Cleanup(func() { _ = lis.Close() })
+	go func() {
+		for {
+			conn, err := lis.Accept()
+			if err != nil {
+				return
+			}
+			_ = conn.Close()
+		}
+	}()
+	return lis.Addr().String()
+}
+
+// fakeS3 answers HeadBucket for bucket and 404s everything else
+func fakeS3(t *testing.T, bucket string) string {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodHead && r.URL.Path == "/"+bucket {
+			w.WriteHeader(http.StatusOK)
+			return
+		}
+		w.WriteHeader(http.StatusNotFound)
+	}))
+	t.Cleanup(srv.Close)
+	return srv.URL
+}
+
+// the managers are process-wide singletons holding the first config they see,
+// so one test drives both options through failure, success and cleanup
+func TestWithKafkaAndWithAWSAttachAndCleanUp(t *testing.T) {
+	ctx := context.Background()
+	s := &Server{
+		Name:   "test",
+		Logger: slog.New(slog.NewTextHandler(io.Discard, nil)),
+		Config: &cfg.Config{
+			Kafka: cfg.KafkaConfig{Brokers: []string{"127.0.0.1:1"}},
+			AWS: cfg.AWSConfig{
+				Region:      "us-east-1",
+				AccessKeyID: "test",
+				SecretKey:   "test",
+				S3Bucket:    "media",
+				Endpoint:    fakeS3(t, "other-bucket"),
+			},
+		},
+	}
+
+	if err := WithKafka(ctx)(s); err == nil || s.KafkaMgr != nil {
+		t.Fatalf("WithKafka against a dead broker = %v, manager %v, want an error and none attached", err, s.KafkaMgr)
+	}
+	if err := WithAWS(ctx)(s); err == nil || s.AWSMgr != nil {
+		t.Fatalf("WithAWS against a missing bucket = %v, manager %v, want an error and none attached", err, s.AWSMgr)
+	}
+
+	s.Config.Kafka.Brokers = []string{fakeBroker(t)}
+	s.Config.AWS.Endpoint = fakeS3(t, "media")
+	for name, opt := range map[string]ServerOption{"WithKafka": WithKafka(ctx), "WithAWS": WithAWS(ctx)} {
+		if err := opt(s); err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+	}
+	if s.KafkaMgr == nil || s.AWSMgr == nil {
+		t.Fatalf("managers = %v, %v, want both attached", s.KafkaMgr, s.AWSMgr)
+	}
+	if err := s.KafkaMgr.HealthCheck(ctx); err != nil {
+		t.Fatalf("kafka HealthCheck: %v", err)
+	}
+	if err := s.AWSMgr.HealthCheck(ctx); err != nil {
+		t.Fatalf("s3 HealthCheck: %v", err)
+	}
+
+	s.cleanup(ctx)
+
+	if err := s.KafkaMgr.HealthCheck(ctx); err == nil {
+		t.Fatal("kafka still connected after cleanup")
+	}
+	if err := s.AWSMgr.HealthCheck(ctx); err == nil {
+		t.Fatal("s3 client still held after cleanup")
+	}
+}
+
+// drainingAuth holds its Health call open until released, then uses redis
+type drainingAuth struct {
+	pb.UnimplementedAuthServiceServer
+	redis   *connection.RedisManager
+	entered chan struct{}
+	release chan struct{}
+	used    chan error
+}
+
+func (a *drainingAuth) Health(ctx context.Context, _ *pb.HealthRequest) (*pb.HealthResponse, error) {
+	close(a.entered)
+	<-a.release
+	a.used <- a.redis.HealthCheck(context.Background())
+	return &pb.HealthResponse{Status: "ok"}, nil
+}
+
+func TestShutdownDrainsCallsBeforeClosingDependencies(t *testing.T) {
+	mr := miniredis.RunT(t)
+	host, port, _ := net.SplitHostPort(mr.Addr())
+	// process-wide singleton, only this test connects it
+	redisMgr := connection.NewRedisManager(&cfg.RedisConfig{Mode: "single", Host: host, Port: port})
+	if err := redisMgr.Connect(context.Background()); err != nil {
+		t.Fatalf("redis Connect: %v", err)
+	}
+
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	s := &Server{
+		Name:        "test",
+		Logger:      logger,
+		Config:      &cfg.Config{},
+		GRPCManager: newTestGRPCServer(t),
+		Workers:     worker.NewRegistry(logger),
+		RedisMgr:    redisMgr,
+	}
+	auth := &drainingAuth{redis: redisMgr, entered: make(chan struct{}), release: make(chan struct{}), used: make(chan error, 1)}
+	pb.RegisterAuthServiceServer(s.GetGRPCServer(), auth)
+
+	stopped := make(chan error, 1)
+	go func() { stopped <- s.Start(context.Background()) }()
+
+	conn, err := grpc.NewClient(s.GRPCManager.listener.Addr().String(), grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatalf("dial: %v", err)
+	}
+	defer conn.Close()
+	called := make(chan error, 1)
+	go func() {
+		_, err := pb.NewAuthServiceClient(conn).Health(context.Background(), &pb.HealthRequest{}, grpc.WaitForReady(true))
+		called <- err
+	}()
+
+	<-auth.entered
+	s.Shutdown()
+
+	// cleanup must wait for the in-flight call however long it takes
+	select {
+	case err := <-stopped:
+		t.Fatalf("Start returned (%v) while a call was still running", err)
+	case <-time.After(200 * time.Millisecond):
+	}
+
+	close(auth.release)
+	if err := <-auth.used; err != nil {
+		t.Fatalf("redis closed under the draining call: %v", err)
+	}
+	if err := <-called; err != nil {
+		t.Fatalf("in-flight call failed: %v", err)
+	}
+	if err := <-stopped; err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+	if err := redisMgr.HealthCheck(context.Background()); err == nil {
+		t.Fatal("redis still open after shutdown")
+	}
+}
+
+func TestServerRunsWorkersUntilShutdown(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	s := &Server{
+		Name:        "test",
+		Logger:      logger,
+		Config:      &cfg.Config{},
+		GRPCManager: newTestGRPCServer(t),
+		Workers:     worker.NewRegistry(logger),
+	}
+	running := make(chan struct{})
+	stopped := make(chan struct{})
+	s.RegisterWorker(worker.Func("job", func(ctx context.Context) error {
+		close(running)
+		<-ctx.Done()
+		close(stopped)
+		return nil
+	}))
+
+	done := make(chan error, 1)
+	go func() { done <- s.Start(context.Background()) }()
+
+	select {
+	case <-running:
+	case <-time.After(2 * time.Second):
+		t.Fatal("worker not started by Start")
+	}
+	s.Shutdown()
+	select {
+	case <-stopped:
+	case <-time.After(2 * time.Second):
+		t.Fatal("worker still running after Shutdown")
+	}
+	if err := <-done; err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+}
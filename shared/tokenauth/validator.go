@@ -0,0 +1,76 @@
+package tokenauth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	auth_pb "remaster/shared/proto/auth"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// ErrInvalidToken is wrapped by validators for tokens that are malformed, expired or
+// revoked, any other error means the validator itself failed
+var ErrInvalidToken = errors.New("invalid token")
+
+// IsInvalidToken tells a rejected token apart from a validator that failed
+func IsInvalidToken(err error) bool {
+	return errors.Is(err, ErrInvalidToken)
+}
+
+// ScopePasswordChange marks the access token a login with an expired password gets,
+// it must not be accepted for anything but changing that password
+const ScopePasswordChange = "password_change"
+
+// Claims is what callers learn about a valid access token
+type Claims struct {
+	UserID   string
+	UserType string
+	Email    string
+	OrgID    string // empty for single-tenant users
+	Scope    string // empty for full access tokens
+	// access token expiry and whether less than the refresh hint threshold is left
+	ExpiresAt     time.Time
+	ShouldRefresh bool
+}
+
+// TokenValidator checks access tokens. The local JWT implementation is fast but needs
+// the signing key, the remote one asks the auth service, which also sees user state
+type TokenValidator interface {
+	Validate(ctx context.Context, token string) (*Claims, error)
+}
+
+// RemoteValidator validates through the auth service's ValidateToken RPC
+type RemoteValidator struct {
+	client auth_pb.AuthServiceClient
+}
+
+func NewRemoteValidator(client auth_pb.AuthServiceClient) *RemoteValidator {
+	return &RemoteValidator{client: client}
+}
+
+func (v *RemoteValidator) Validate(ctx context.Context, token string) (*Claims, error) {
+	resp, err := v.client.ValidateToken(ctx, &auth_pb.ValidateTokenRequest{AccessToken: token})
+	if err != nil {
+		if status.Code(err) == codes.Unauthenticated {
+			return nil, fmt.Errorf("%w: %s", ErrInvalidToken, status.Convert(err).Message())
+		}
+		return nil, fmt.Errorf("validate token: %w", err)
+	}
+	if !resp.Valid {
+		return nil, ErrInvalidToken
+	}
+
+	return &Claims{
+		UserID:        resp.UserId,
+		UserType:      resp.UserType,
+		Email:         resp.Email,
+		OrgID:         resp.OrgId,
+		Scope:         resp.Scope,
+		ExpiresAt:     time.Unix(resp.ExpiresAt, 0),
+		ShouldRefresh: resp.ShouldRefresh,
+	}, nil
+}
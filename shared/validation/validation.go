@@ -0,0 +1,117 @@
+package validation
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+
+	et "remaster/shared/errors"
+
+	"github.com/go-playground/validator/v10"
+)
+
+var (
+	once     sync.Once
+	instance *validator.Validate
+)
+
+// Get returns shared validator instance (reports fields by their json names)
+func Get() *validator.Validate {
+	once.Do(func() {
+		instance = validator.New(validator.WithRequiredStructEnabled())
+		instance.RegisterTagNameFunc(JSONTagName)
+	})
+	return instance
+}
+
+// JSONTagName reports a struct field by its json name, register it on other
+// validator instances (e.g. gin's) to get matching field names
+func JSONTagName(fld reflect.StructField) string {
+	name := strings.SplitN(fld.Tag.Get("json"), ",", 2)[0]
+	if name == "-" {
+		return ""
+	}
+	if name == "" {
+		return fld.Name
+	}
+	return name
+}
+
+// Validate checks `validate` struct tags and returns a validation AppError
+// with a field -> message map in Details
+func Validate(s any) error {
+	err := Get().Struct(s)
+	if err == nil {
+		return nil
+	}
+
+	var ve validator.ValidationErrors
+	if errors.As(err, &ve) {
+		return et.NewValidationError("validation failed", FieldErrors(ve))
+	}
+	return et.NewValidationError("validation failed", map[string]string{"error": err.Error()})
+}
+
+// FieldErrors converts validator errors into field -> message map
+func FieldErrors(ve validator.ValidationErrors) map[string]string {
+	details := make(map[string]string, len(ve))
+	for _, fe := range ve {
+		field := fe.Field()
+		if _, exists := details[field]; !exists {
+			details[field] = message(fe)
+		}
+	}
+	return details
+}
+
+func message(fe validator.FieldError) string {
+	isString := fe.Kind() == reflect.String
+
+	switch fe.Tag() {
+	case "required":
+		return "is required"
+	case "email":
+		return "must be a valid email address"
+	case "url":
+		return "must be a valid URL"
+	case "min":
+		if isString {
+			return fmt.Sprintf("must be at least %s characters long", fe.Param())
+		}
+		return fmt.Sprintf("must be at least %s", fe.Param())
+	case "max":
+		if isString {
+			return fmt.Sprintf("must be at most %s characters long", fe.Param())
+		}
+		return fmt.Sprintf("must be at most %s", fe.Param())
+	case "len":
+		return fmt.Sprintf("must be exactly %s characters long", fe.Param())
+	case "oneof":
+		return fmt.Sprintf("must be one of: %s", strings.ReplaceAll(fe.Param(), " ", ", "))
+	default:
+		return fmt.Sprintf("failed on '%s' rule", fe.Tag())
+	}
+}
+
+// MaxHeaderIDLength bounds correlation, request and device ids passed between services
+const MaxHeaderIDLength = 128
+
+// ValidHeaderID reports whether s is usable as a correlation, request or device id:
+// 1..MaxHeaderIDLength chars of letters, digits and . _ : - (covers UUIDs)
+func ValidHeaderID(s string) bool {
+	if s == "" || len(s) > MaxHeaderIDLength {
+		return false
+	}
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case c >= 'a' && c <= 'z', c >= 'A' && c <= 'Z', c >= '0' && c <= '9':
+		case c == '.', c == '_', c == ':', c == '-':
+		default:
+			return false
+		}
+	}
+	return true
+}
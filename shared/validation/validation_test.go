@@ -0,0 +1,98 @@
+package validation
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	et "remaster/shared/errors"
+)
+
+type signUp struct {
+	Email    string `json:"email" validate:"required,email"`
+	Password string `json:"password" validate:"required,min=8"`
+	Name     string `json:"first_name" validate:"required,min=2,max=50"`
+	UserType string `json:"user_type" validate:"required,oneof=client master"`
+	Age      int    `json:"age" validate:"min=18"`
+	Internal string `json:"-" validate:"required"`
+}
+
+func TestValidateReportsFieldsByJSONName(t *testing.T) {
+	err := Validate(&signUp{
+		Email:    "not-an-email",
+		Password: "short",
+		Name:     strings.Repeat("x", 51),
+		UserType: "admin",
+		Age:      16,
+		Internal: "set",
+	})
+
+	var appErr *et.AppError
+	if !errors.As(err, &appErr) || appErr.Code != et.CodeValidation {
+		t.Fatalf("error = %v, want a validation AppError", err)
+	}
+	want := map[string]string{
+		"email":      "must be a valid email address",
+		"password":   "must be at least 8 characters long",
+		"first_name": "must be at most 50 characters long",
+		"user_type":  "must be one of: client, master",
+		"age":        "must be at least 18",
+	}
+	if len(appErr.Details) != len(want) {
+		t.Fatalf("details = %v, want %v", appErr.Details, want)
+	}
+	for field, msg := range want {
+		if got := appErr.Details[field]; got != msg {
+			t.Errorf("details[%q] = %q, want %q", field, got, msg)
+		}
+	}
+}
+
+func TestValidateRequiredAndHiddenFields(t *testing.T) {
+	err := Validate(&signUp{Age: 18})
+
+	appErr, ok := et.AsAppError(err)
+	if !ok {
+		t.Fatalf("error = %v, want an AppError", err)
+	}
+	for _, field := range []string{"email", "password", "first_name", "user_type"} {
+		if got := appErr.Details[field]; got != "is required" {
+			t.Errorf("details[%q] = %q, want is required", field, got)
+		}
+	}
+	// fields hidden from json are reported by their Go name
+	if got := appErr.Details["Internal"]; got != "is required" {
+		t.Errorf("details[Internal] = %q, want is required", got)
+	}
+}
+
+func TestValidateAcceptsValidStruct(t *testing.T) {
+	err := Validate(&signUp{
+		Email:    "user@example.com",
+		Password: "long-enough",
+		Name:     "Ann",
+		UserType: "client",
+		Age:      30,
+		Internal: "set",
+	})
+	if err != nil {
+		t.Fatalf("Validate = %v, want nil", err)
+	}
+}
+
+func TestValidHeaderID(t *testing.T) {
+	tests := map[string]bool{
+		"0b6e2c1e-8a4f-4a3b-9d5e-1c2f3a4b5c6d":   true,
+		"trace.id_01:a-b":                        true,
+		"":                                       false,
+		strings.Repeat("a", MaxHeaderIDLength):   true,
+		strings.Repeat("a", MaxHeaderIDLength+1): false,
+		"id with spaces":                         false,
+		"id\r\ninjected: header":                 false,
+	}
+	for id, want := range tests {
+		if got := ValidHeaderID(id); got != want {
+			t.Errorf("ValidHeaderID(%q) = %v, want %v", id, got, want)
+		}
+	}
+}
@@ -0,0 +1,95 @@
+package worker
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"sync"
+
+	"golang.org/x/sync/errgroup"
+)
+
+// Worker is a long running background job, Run blocks until ctx is done.
+// Returning an error stops every other worker and the server with it
+type Worker interface {
+	Name() string
+	Run(ctx context.Context) error
+}
+
+type funcWorker struct {
+	name string
+	run  func(ctx context.Context) error
+}
+
+func (w funcWorker) Name() string                  { return w.name }
+func (w funcWorker) Run(ctx context.Context) error { return w.run(ctx) }
+
+// Func turns a plain function into a Worker
+func Func(name string, run func(ctx context.Context) error) Worker {
+	return funcWorker{name: name, run: run}
+}
+
+// Registry holds the workers of a service and runs them alongside its servers
+type Registry struct {
+	logger  *slog.Logger
+	mu      sync.Mutex
+	workers []Worker
+	started bool
+}
+
+func NewRegistry(logger *slog.Logger) *Registry {
+	return &Registry{logger: logger}
+}
+
+// Register adds w, it must be called before Run
+func (r *Registry) Register(w Worker) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.started {
+		panic(fmt.Sprintf("worker %q registered after start", w.Name()))
+	}
+	r.workers = append(r.workers, w)
+}
+
+func (r *Registry) Len() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return len(r.workers)
+}
+
+// Run starts every registered worker and waits until all of them returned.
+// The first failure (or panic) cancels the others and is returned
+func (r *Registry) Run(ctx context.Context) error {
+	r.mu.Lock()
+	r.started = true
+	workers := r.workers
+	r.mu.Unlock()
+
+	g, gCtx := errgroup.WithContext(ctx)
+	for _, w := range workers {
+		g.Go(func() error {
+			return r.run(gCtx, w)
+		})
+	}
+	return g.Wait()
+}
+
+func (r *Registry) run(ctx context.Context, w Worker) (err error) {
+	log := r.logger.With(slog.String("worker", w.Name()))
+	defer func() {
+		if rec := recover(); rec != nil {
+			err = fmt.Errorf("worker %s panicked: %v", w.Name(), rec)
+		}
+		if err != nil {
+			log.Error("Worker failed", "error", err)
+			return
+		}
+		log.Info("Worker stopped")
+	}()
+
+	log.Info("Worker started")
+	if err := w.Run(ctx); err != nil && ctx.Err() == nil {
+		return fmt.Errorf("worker %s: %w", w.Name(), err)
+	}
+	return nil
+}
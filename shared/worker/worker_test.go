@@ -0,0 +1,106 @@
+package worker
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"strings"
+	"testing"
+	"time"
+)
+
+func newTestRegistry() *Registry {
+	return NewRegistry(slog.New(slog.NewTextHandler(io.Discard, nil)))
+}
+
+// runAsync runs r until ctx is done and returns Run's result
+func runAsync(ctx context.Context, r *Registry) <-chan error {
+	done := make(chan error, 1)
+	go func() { done <- r.Run(ctx) }()
+	return done
+}
+
+func waitFor[T any](t *testing.T, ch <-chan T, what string) T {
+	t.Helper()
+	select {
+	case v := <-ch:
+		return v
+	case <-time.After(2 * time.Second):
+		t.Fatalf("timed out waiting for %s", what)
+		var zero T
+		return zero
+	}
+}
+
+func TestRegistryStartsWorkersAndStopsThemOnCancel(t *testing.T) {
+	r := newTestRegistry()
+	started := make(chan string, 2)
+	stopped := make(chan string, 2)
+	for _, name := range []string{"cleanup", "consumer"} {
+		r.Register(Func(name, func(ctx context.Context) error {
+			started <- name
+			<-ctx.Done()
+			stopped <- name
+			return ctx.Err()
+		}))
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := runAsync(ctx, r)
+	waitFor(t, started, "the first worker to start")
+	waitFor(t, started, "the second worker to start")
+
+	cancel()
+	waitFor(t, stopped, "the first worker to stop")
+	waitFor(t, stopped, "the second worker to stop")
+	// returning ctx.Err() on shutdown is not a failure
+	if err := waitFor(t, done, "Run to return"); err != nil {
+		t.Fatalf("Run = %v, want nil after cancel", err)
+	}
+}
+
+func TestRegistryFailureStopsTheOtherWorkers(t *testing.T) {
+	tests := []struct {
+		name string
+		run  func(ctx context.Context) error
+		want string
+	}{
+		{"error", func(context.Context) error { return errors.New("broker gone") }, "worker broken: broker gone"},
+		{"panic", func(context.Context) error { panic("nil map") }, "worker broken panicked: nil map"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := newTestRegistry()
+			stopped := make(chan struct{})
+			r.Register(Func("healthy", func(ctx context.Context) error {
+				<-ctx.Done()
+				close(stopped)
+				return nil
+			}))
+			r.Register(Func("broken", tt.run))
+
+			err := waitFor(t, runAsync(context.Background(), r), "Run to return")
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Fatalf("Run = %v, want %q", err, tt.want)
+			}
+			waitFor(t, stopped, "the healthy worker to stop")
+		})
+	}
+}
+
+func TestRegisterAfterStartPanics(t *testing.T) {
+	r := newTestRegistry()
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := r.Run(ctx); err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Fatal("Register after Run did not panic")
+		}
+	}()
+	r.Register(Func("late", func(context.Context) error { return nil }))
+}